@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -35,6 +37,9 @@ type Change struct {
 	SpawnTime time.Time `json:"spawn-time,omitempty"`
 	ReadyTime time.Time `json:"ready-time,omitempty"`
 
+	// RetryOf is the ID of the change this change retries, if any.
+	RetryOf string `json:"retry-of,omitempty"`
+
 	data map[string]*json.RawMessage
 }
 
@@ -62,6 +67,11 @@ type Task struct {
 	SpawnTime time.Time `json:"spawn-time,omitempty"`
 	ReadyTime time.Time `json:"ready-time,omitempty"`
 
+	// Retries is the number of times the task has been retried, and
+	// LastRetryTime the time it was last retried.
+	Retries       int       `json:"retries,omitempty"`
+	LastRetryTime time.Time `json:"last-retry-time,omitempty"`
+
 	Data map[string]*json.RawMessage
 }
 
@@ -98,26 +108,46 @@ func (client *Client) Change(id string) (*Change, error) {
 	return &chgd.Change, nil
 }
 
+type changeActionData struct {
+	Action  string   `json:"action"`
+	TaskIDs []string `json:"task-ids,omitempty"`
+}
+
 // Abort attempts to abort a change that is not yet ready.
 func (client *Client) Abort(id string) (*Change, error) {
-	var postData struct {
-		Action string `json:"action"`
-	}
-	postData.Action = "abort"
+	return client.AbortTasks(id, nil)
+}
 
+// AbortTasks attempts to abort the given tasks of a change, and the tasks
+// that wait for them. If taskIDs is empty, the whole change is aborted.
+func (client *Client) AbortTasks(changeID string, taskIDs []string) (*Change, error) {
 	var body bytes.Buffer
+	postData := changeActionData{Action: "abort", TaskIDs: taskIDs}
 	if err := json.NewEncoder(&body).Encode(postData); err != nil {
 		return nil, err
 	}
 
 	var chg Change
-	if _, err := client.doSync("POST", "/v1/changes/"+id, nil, nil, &body, &chg); err != nil {
+	if _, err := client.doSync("POST", "/v1/changes/"+changeID, nil, nil, &body, &chg); err != nil {
 		return nil, err
 	}
 
 	return &chg, nil
 }
 
+// Retry retries a change that failed or was aborted, by running its tasks
+// again in a new change. It returns the ID of the new change. Only service
+// changes (such as "start", "stop", and "replan") can be retried.
+func (client *Client) Retry(id string) (changeID string, err error) {
+	var body bytes.Buffer
+	postData := changeActionData{Action: "retry"}
+	if err := json.NewEncoder(&body).Encode(postData); err != nil {
+		return "", err
+	}
+
+	return client.doAsync("POST", "/v1/changes/"+id, nil, nil, &body)
+}
+
 // ChangeSelector represents a selection of changes to query for.
 type ChangeSelector uint8
 
@@ -143,6 +173,26 @@ const (
 type ChangesOptions struct {
 	ServiceName string // if empty, no filtering by service is done
 	Selector    ChangeSelector
+
+	// Kinds, Statuses and TaskKinds, if non-empty, only include changes
+	// with one of the given kinds, with one of the given statuses (for
+	// example "Error"), or with a task of one of the given kinds.
+	Kinds     []string
+	Statuses  []string
+	TaskKinds []string
+
+	// SpawnSince, SpawnUntil, ReadySince and ReadyUntil, if non-zero, only
+	// include changes spawned or made ready in the given (inclusive) range.
+	SpawnSince time.Time
+	SpawnUntil time.Time
+	ReadySince time.Time
+	ReadyUntil time.Time
+
+	// Offset and Limit, if non-zero, skip the Offset most recent matching
+	// changes and return at most Limit of the next most recent. The changes
+	// returned are ordered by spawn time, oldest first.
+	Offset int
+	Limit  int
 }
 
 // Changes fetches information for the changes specified.
@@ -155,6 +205,31 @@ func (client *Client) Changes(opts *ChangesOptions) ([]*Change, error) {
 		if opts.ServiceName != "" {
 			query.Set("for", opts.ServiceName)
 		}
+		if len(opts.Kinds) > 0 {
+			query.Set("kinds", strings.Join(opts.Kinds, ","))
+		}
+		if len(opts.Statuses) > 0 {
+			query.Set("statuses", strings.Join(opts.Statuses, ","))
+		}
+		if len(opts.TaskKinds) > 0 {
+			query.Set("task-kinds", strings.Join(opts.TaskKinds, ","))
+		}
+		for param, t := range map[string]time.Time{
+			"spawn-since": opts.SpawnSince,
+			"spawn-until": opts.SpawnUntil,
+			"ready-since": opts.ReadySince,
+			"ready-until": opts.ReadyUntil,
+		} {
+			if !t.IsZero() {
+				query.Set(param, t.Format(time.RFC3339))
+			}
+		}
+		if opts.Offset != 0 {
+			query.Set("offset", strconv.Itoa(opts.Offset))
+		}
+		if opts.Limit != 0 {
+			query.Set("limit", strconv.Itoa(opts.Limit))
+		}
 	}
 
 	var chgds []changeAndData
@@ -17,6 +17,7 @@ package client_test
 import (
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"time"
 
 	"gopkg.in/check.v1"
@@ -218,6 +219,33 @@ func (cs *clientSuite) TestClientChanges(c *check.C) {
 
 }
 
+func (cs *clientSuite) TestClientChangesFilters(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": []}`
+	chgs, err := cs.cli.Changes(&client.ChangesOptions{
+		Selector:   client.ChangesAll,
+		Kinds:      []string{"start", "stop"},
+		Statuses:   []string{"Error"},
+		TaskKinds:  []string{"exec"},
+		SpawnSince: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		ReadyUntil: time.Date(2023, 2, 3, 4, 5, 6, 0, time.UTC),
+		Offset:     10,
+		Limit:      5,
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(chgs, check.HasLen, 0)
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/changes")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"select":      {"all"},
+		"kinds":       {"start,stop"},
+		"statuses":    {"Error"},
+		"task-kinds":  {"exec"},
+		"spawn-since": {"2023-01-02T03:04:05Z"},
+		"ready-until": {"2023-02-03T04:05:06Z"},
+		"offset":      {"10"},
+		"limit":       {"5"},
+	})
+}
+
 func (cs *clientSuite) TestClientChangesData(c *check.C) {
 	cs.rsp = `{"type": "sync", "result": [{
   "id":   "uno",
@@ -271,3 +299,57 @@ func (cs *clientSuite) TestClientAbort(c *check.C) {
 
 	c.Assert(string(body), check.Equals, "{\"action\":\"abort\"}\n")
 }
+
+func (cs *clientSuite) TestClientAbortTasks(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {
+  "id":   "uno",
+  "kind": "foo",
+  "summary": "...",
+  "status": "Doing",
+  "tasks": [{"id": "3", "kind": "bar", "summary": "...", "status": "Hold", "progress": {"done": 1, "total": 1}}]
+}}`
+
+	chg, err := cs.cli.AbortTasks("uno", []string{"3"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/changes/uno")
+	c.Assert(chg.Tasks, check.HasLen, 1)
+	c.Check(chg.Tasks[0].Status, check.Equals, "Hold")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(body), check.Equals, "{\"action\":\"abort\",\"task-ids\":[\"3\"]}\n")
+}
+
+func (cs *clientSuite) TestClientRetry(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{"type": "async", "status-code": 202, "change": "42"}`
+
+	changeID, err := cs.cli.Retry("uno")
+	c.Assert(err, check.IsNil)
+	c.Check(changeID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/changes/uno")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(body), check.Equals, "{\"action\":\"retry\"}\n")
+}
+
+func (cs *clientSuite) TestClientChangeRetries(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {
+  "id":   "dos",
+  "kind": "foo",
+  "summary": "...",
+  "status": "Do",
+  "retry-of": "uno",
+  "tasks": [{"kind": "bar", "summary": "...", "status": "Do", "progress": {"done": 0, "total": 1}, "retries": 2, "last-retry-time": "2016-04-21T01:02:03Z"}]
+}}`
+
+	chg, err := cs.cli.Change("dos")
+	c.Assert(err, check.IsNil)
+	c.Check(chg.RetryOf, check.Equals, "uno")
+	c.Assert(chg.Tasks, check.HasLen, 1)
+	c.Check(chg.Tasks[0].Retries, check.Equals, 2)
+	c.Check(chg.Tasks[0].LastRetryTime, check.Equals, time.Date(2016, 04, 21, 1, 2, 3, 0, time.UTC))
+}
@@ -15,7 +15,11 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/url"
+	"time"
 )
 
 type ChecksOptions struct {
@@ -66,6 +70,41 @@ type CheckInfo struct {
 	// Threshold is this check's failure threshold, from the layer
 	// configuration.
 	Threshold int `json:"threshold"`
+
+	// Successes is the number of times in a row this check has succeeded
+	// while it's down. It is reset to zero when the check is up again.
+	Successes int `json:"successes,omitempty"`
+
+	// SuccessThreshold is the number of successes in a row needed for a
+	// check that's down to be up again, from the layer configuration.
+	SuccessThreshold int `json:"success-threshold"`
+
+	// Starting is true while the check is in its start period, during
+	// which failures aren't counted towards the failure threshold.
+	Starting bool `json:"starting,omitempty"`
+
+	// Paused is true if the check has been paused (see PauseChecks).
+	Paused bool `json:"paused,omitempty"`
+
+	// History holds the results of the most recent runs of this check,
+	// oldest first. It's kept across plan updates that don't change the
+	// check's configuration.
+	History []CheckResult `json:"history,omitempty"`
+}
+
+// CheckResult holds the result of a single run of a health check.
+type CheckResult struct {
+	// Time is when the check was started.
+	Time time.Time `json:"time"`
+
+	// Duration is how long the check took to complete.
+	Duration time.Duration `json:"duration"`
+
+	// Success is true if the check succeeded.
+	Success bool `json:"success"`
+
+	// Error is the error message if the check failed.
+	Error string `json:"error,omitempty"`
 }
 
 // Checks fetches information about specific health checks (or all of them),
@@ -85,3 +124,60 @@ func (client *Client) Checks(opts *ChecksOptions) ([]*CheckInfo, error) {
 	}
 	return checks, nil
 }
+
+// ChecksActionOptions holds the options for the RunChecks, PauseChecks and
+// ResumeChecks methods.
+type ChecksActionOptions struct {
+	// Names is the list of check names to act on. It must not be empty.
+	Names []string
+}
+
+// CheckRunResult holds the result of running a check with RunChecks.
+type CheckRunResult struct {
+	// Name is the name of the check that was run.
+	Name string `json:"name"`
+
+	CheckResult
+}
+
+// RunChecks runs the specified checks immediately, and waits for them to
+// complete. A check that fails is not an error: see the Success and Error
+// fields of its result.
+func (client *Client) RunChecks(opts *ChecksActionOptions) ([]*CheckRunResult, error) {
+	var results []*CheckRunResult
+	err := client.postChecks("run", opts.Names, &results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// PauseChecks stops the specified checks from running periodically till
+// they're resumed. Paused checks don't trigger their failure actions.
+func (client *Client) PauseChecks(opts *ChecksActionOptions) error {
+	return client.postChecks("pause", opts.Names, nil)
+}
+
+// ResumeChecks resumes running the specified (paused) checks periodically.
+func (client *Client) ResumeChecks(opts *ChecksActionOptions) error {
+	return client.postChecks("resume", opts.Names, nil)
+}
+
+type checksPayload struct {
+	Action string   `json:"action"`
+	Checks []string `json:"checks"`
+}
+
+func (client *Client) postChecks(action string, checks []string, result interface{}) error {
+	payload := checksPayload{
+		Action: action,
+		Checks: checks,
+	}
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(&payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode JSON payload: %w", err)
+	}
+	_, err = client.doSync("POST", "/v1/checks", nil, nil, &body, result)
+	return err
+}
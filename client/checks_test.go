@@ -15,7 +15,9 @@
 package client_test
 
 import (
+	"io/ioutil"
 	"net/url"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -25,8 +27,11 @@ import (
 func (cs *clientSuite) TestChecksGet(c *check.C) {
 	cs.rsp = `{
 		"result": [
-			{"name": "chk1", "status": "up"},
-			{"name": "chk3", "status": "down", "failures": 42}
+			{"name": "chk1", "status": "up", "success-threshold": 1, "starting": true},
+			{"name": "chk3", "status": "down", "failures": 42, "successes": 1, "success-threshold": 2, "history": [
+				{"time": "2023-04-05T06:07:08Z", "duration": 1500000, "success": true},
+				{"time": "2023-04-05T06:07:18Z", "duration": 2000000, "success": false, "error": "exit status 1"}
+			]}
 		],
 		"status": "OK",
 		"status-code": 200,
@@ -41,12 +46,25 @@ func (cs *clientSuite) TestChecksGet(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(checks, check.DeepEquals,
 		[]*client.CheckInfo{{
-			Name:   "chk1",
-			Status: client.CheckStatusUp,
+			Name:             "chk1",
+			Status:           client.CheckStatusUp,
+			SuccessThreshold: 1,
+			Starting:         true,
 		}, {
-			Name:     "chk3",
-			Status:   client.CheckStatusDown,
-			Failures: 42,
+			Name:             "chk3",
+			Status:           client.CheckStatusDown,
+			Failures:         42,
+			Successes:        1,
+			SuccessThreshold: 2,
+			History: []client.CheckResult{{
+				Time:     time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+				Duration: 1500 * time.Microsecond,
+				Success:  true,
+			}, {
+				Time:     time.Date(2023, 4, 5, 6, 7, 18, 0, time.UTC),
+				Duration: 2 * time.Millisecond,
+				Error:    "exit status 1",
+			}},
 		}})
 	c.Assert(cs.req.Method, check.Equals, "GET")
 	c.Assert(cs.req.URL.Path, check.Equals, "/v1/checks")
@@ -55,3 +73,69 @@ func (cs *clientSuite) TestChecksGet(c *check.C) {
 		"names": {"chk1", "chk3"},
 	})
 }
+
+func (cs *clientSuite) TestRunChecks(c *check.C) {
+	cs.rsp = `{
+		"result": [
+			{"name": "chk1", "time": "2023-04-05T06:07:08Z", "duration": 1500000, "success": true},
+			{"name": "chk2", "time": "2023-04-05T06:07:08Z", "duration": 2000000, "success": false, "error": "exit status 1"}
+		],
+		"status": "OK",
+		"status-code": 200,
+		"type": "sync"
+	}`
+
+	results, err := cs.cli.RunChecks(&client.ChecksActionOptions{Names: []string{"chk1", "chk2"}})
+	c.Assert(err, check.IsNil)
+	c.Check(results, check.DeepEquals, []*client.CheckRunResult{{
+		Name: "chk1",
+		CheckResult: client.CheckResult{
+			Time:     time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			Duration: 1500 * time.Microsecond,
+			Success:  true,
+		},
+	}, {
+		Name: "chk2",
+		CheckResult: client.CheckResult{
+			Time:     time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			Duration: 2 * time.Millisecond,
+			Error:    "exit status 1",
+		},
+	}})
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/checks")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"run","checks":["chk1","chk2"]}`+"\n")
+}
+
+func (cs *clientSuite) TestPauseResumeChecks(c *check.C) {
+	cs.rsp = `{"result": true, "status": "OK", "status-code": 200, "type": "sync"}`
+
+	err := cs.cli.PauseChecks(&client.ChecksActionOptions{Names: []string{"chk1"}})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/checks")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"pause","checks":["chk1"]}`+"\n")
+
+	err = cs.cli.ResumeChecks(&client.ChecksActionOptions{Names: []string{"chk1", "chk2"}})
+	c.Assert(err, check.IsNil)
+	body, err = ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"resume","checks":["chk1","chk2"]}`+"\n")
+}
+
+func (cs *clientSuite) TestPauseChecksNotFound(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{
+		"result": {"message": "cannot find check \"nope\": file does not exist"},
+		"status": "Not Found",
+		"status-code": 404,
+		"type": "error"
+	}`
+
+	err := cs.cli.PauseChecks(&client.ChecksActionOptions{Names: []string{"nope"}})
+	c.Assert(err, check.ErrorMatches, `cannot find check "nope": file does not exist`)
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is the type of an event.
+type EventType string
+
+const (
+	// EventService is sent when a service changes state. Name is the
+	// service name, and Old and New its old and new state.
+	EventService EventType = "service"
+
+	// EventCheck is sent when a check goes up or down. Name is the check
+	// name, and Old and New "up" or "down".
+	EventCheck EventType = "check"
+
+	// EventChange is sent when a change's status changes. Name is the
+	// change ID, and Message its summary.
+	EventChange EventType = "change"
+
+	// EventTask is sent when a task's status changes. Name is the task ID,
+	// and Message its summary.
+	EventTask EventType = "task"
+
+	// EventWarning is sent when a warning is added. Message is the warning
+	// message.
+	EventWarning EventType = "warning"
+
+	// EventPlan is sent when the plan is updated.
+	EventPlan EventType = "plan"
+)
+
+// Event is a single state transition in Pebble.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    EventType `json:"type"`
+	Name    string    `json:"name,omitempty"`
+	Old     string    `json:"old,omitempty"`
+	New     string    `json:"new,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// EventsOptions holds the options for an Events call.
+type EventsOptions struct {
+	// Types is the list of event types to receive (nil or empty slice means
+	// all types).
+	Types []EventType
+
+	// Names is the list of names (service names, check names, or change
+	// and task IDs) to receive events for (nil or empty slice means all).
+	Names []string
+}
+
+// EventsIterator iterates over the events returned by Events.
+type EventsIterator struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	event  Event
+	err    error
+	closed int32 // set atomically, as Close may be called during Next
+}
+
+// Events subscribes to events, which are sent as they happen until the
+// context is cancelled or the iterator is closed. For example:
+//
+//	it, err := client.Events(ctx, &EventsOptions{Types: []EventType{EventService}})
+//	if err != nil { ... }
+//	defer it.Close()
+//	for it.Next() {
+//		event := it.Event()
+//		...
+//	}
+//	if err := it.Err(); err != nil { ... }
+func (client *Client) Events(ctx context.Context, opts *EventsOptions) (*EventsIterator, error) {
+	if opts == nil {
+		opts = &EventsOptions{}
+	}
+	query := url.Values{}
+	if len(opts.Types) > 0 {
+		types := make([]string, len(opts.Types))
+		for i, t := range opts.Types {
+			types[i] = string(t)
+		}
+		query.Set("types", strings.Join(types, ","))
+	}
+	if len(opts.Names) > 0 {
+		query.Set("names", strings.Join(opts.Names, ","))
+	}
+	res, err := client.raw(ctx, "GET", "/v1/events", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, parseError(res)
+	}
+	return &EventsIterator{
+		body:   res.Body,
+		reader: bufio.NewReader(res.Body),
+	}, nil
+}
+
+// Next waits for the next event, and reports whether there is one. It
+// returns false when the stream ends, in which case Err returns the error
+// that ended it (nil if the context was cancelled or the iterator closed).
+func (it *EventsIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	b, err := it.reader.ReadBytes('\n')
+	if err != nil {
+		closed := atomic.LoadInt32(&it.closed) != 0
+		if !closed && !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) {
+			it.err = fmt.Errorf("cannot read event: %w", err)
+		}
+		return false
+	}
+	var event Event
+	err = json.Unmarshal(b, &event)
+	if err != nil {
+		it.err = fmt.Errorf("cannot unmarshal event: %w", err)
+		return false
+	}
+	it.event = event
+	return true
+}
+
+// Event returns the event read by the last call to Next.
+func (it *EventsIterator) Event() Event {
+	return it.event
+}
+
+// Err returns the error, if any, that ended the stream of events.
+func (it *EventsIterator) Err() error {
+	return it.err
+}
+
+// Close ends the stream of events.
+func (it *EventsIterator) Close() error {
+	atomic.StoreInt32(&it.closed, 1)
+	return it.body.Close()
+}
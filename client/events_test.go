@@ -0,0 +1,108 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/client"
+)
+
+func (cs *clientSuite) TestEvents(c *check.C) {
+	cs.rsp = `
+{"time":"2023-04-23T01:28:52Z","type":"service","name":"svc1","old":"starting","new":"running"}
+{"time":"2023-04-23T01:28:53Z","type":"check","name":"chk1","old":"up","new":"down"}
+`[1:]
+	it, err := cs.cli.Events(context.Background(), &client.EventsOptions{
+		Types: []client.EventType{client.EventService, client.EventCheck},
+		Names: []string{"svc1", "chk1"},
+	})
+	c.Assert(err, check.IsNil)
+	defer it.Close()
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/events")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"types": []string{"service,check"},
+		"names": []string{"svc1,chk1"},
+	})
+
+	var events []client.Event
+	for it.Next() {
+		events = append(events, it.Event())
+	}
+	c.Assert(it.Err(), check.IsNil)
+	c.Check(events, check.DeepEquals, []client.Event{{
+		Time: time.Date(2023, 4, 23, 1, 28, 52, 0, time.UTC),
+		Type: client.EventService,
+		Name: "svc1",
+		Old:  "starting",
+		New:  "running",
+	}, {
+		Time: time.Date(2023, 4, 23, 1, 28, 53, 0, time.UTC),
+		Type: client.EventCheck,
+		Name: "chk1",
+		Old:  "up",
+		New:  "down",
+	}})
+}
+
+func (cs *clientSuite) TestEventsNilOptions(c *check.C) {
+	cs.rsp = `{"time":"2023-04-23T01:28:52Z","type":"plan"}
+`
+	it, err := cs.cli.Events(context.Background(), nil)
+	c.Assert(err, check.IsNil)
+	defer it.Close()
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/events")
+	c.Check(cs.req.URL.Query(), check.HasLen, 0)
+
+	c.Assert(it.Next(), check.Equals, true)
+	c.Check(it.Event().Type, check.Equals, client.EventPlan)
+	c.Assert(it.Next(), check.Equals, false)
+	c.Check(it.Err(), check.IsNil)
+}
+
+func (cs *clientSuite) TestEventsBadEvent(c *check.C) {
+	cs.rsp = `{"time":"2023-04-23T01:28:52Z","type":"plan"}
+{bad}
+`
+	it, err := cs.cli.Events(context.Background(), &client.EventsOptions{})
+	c.Assert(err, check.IsNil)
+	defer it.Close()
+	c.Check(cs.req.URL.Query(), check.HasLen, 0)
+
+	c.Assert(it.Next(), check.Equals, true)
+	c.Check(it.Event().Type, check.Equals, client.EventPlan)
+	c.Assert(it.Next(), check.Equals, false)
+	c.Check(it.Err(), check.ErrorMatches, "cannot unmarshal event: .*")
+}
+
+func (cs *clientSuite) TestEventsError(c *check.C) {
+	cs.status = http.StatusBadRequest
+	cs.header = http.Header{"Content-Type": []string{"application/json"}}
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 400,
+		"result": {"message": "invalid event type \"foo\""}
+	}`
+	_, err := cs.cli.Events(context.Background(), &client.EventsOptions{
+		Types: []client.EventType{"foo"},
+	})
+	c.Assert(err, check.ErrorMatches, `invalid event type "foo"`)
+}
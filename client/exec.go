@@ -16,11 +16,14 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/canonical/pebble/internals/wsutil"
@@ -77,6 +80,33 @@ type ExecOptions struct {
 	// Standard error stream. If nil, error output is combined with standard
 	// output and goes to the Stdout stream.
 	Stderr io.Writer
+
+	// True to run the command in the background: the server keeps the
+	// command running regardless of client connections, and buffers its
+	// (combined) output. Stdin, Stdout, and Stderr are ignored, and Terminal
+	// must be false. Use ExecAttach with the process's TaskID to read the
+	// output and wait for the command to finish.
+	Detached bool
+
+	// Optional scheduling priority ("niceness") from -20 (highest) to 19
+	// (lowest). If zero, the priority is not changed.
+	Nice int
+
+	// Optional I/O scheduling class ("realtime", "best-effort", or "idle"),
+	// and priority within the class, from 0 (highest) to 7 (lowest). The
+	// priority only applies to the "realtime" and "best-effort" classes.
+	IOClass    string
+	IOPriority *int
+
+	// Optional resource limits keyed by name, for example "nofile" or
+	// "core". Each value is a non-negative integer or "unlimited", and sets
+	// both the soft and hard limit.
+	Rlimits map[string]string
+
+	// Optional memory limit in bytes and CPU limit in number of CPUs (for
+	// example 0.5), applied using a cgroup. If zero, no limit applies.
+	MemoryLimit int64
+	CPULimit    float64
 }
 
 type execPayload struct {
@@ -94,6 +124,13 @@ type execPayload struct {
 	SplitStderr    bool              `json:"split-stderr,omitempty"`
 	Width          int               `json:"width,omitempty"`
 	Height         int               `json:"height,omitempty"`
+	Detached       bool              `json:"detached,omitempty"`
+	Nice           int               `json:"nice,omitempty"`
+	IOClass        string            `json:"io-class,omitempty"`
+	IOPriority     *int              `json:"io-priority,omitempty"`
+	Rlimits        map[string]string `json:"rlimits,omitempty"`
+	MemoryLimit    int64             `json:"memory-limit,omitempty"`
+	CPULimit       float64           `json:"cpu-limit,omitempty"`
 }
 
 type execResult struct {
@@ -103,6 +140,7 @@ type execResult struct {
 // ExecProcess represents a running process. Use Wait to wait for it to finish.
 type ExecProcess struct {
 	changeID    string
+	taskID      string
 	client      *Client
 	timeout     time.Duration
 	writesDone  chan struct{}
@@ -140,9 +178,16 @@ func (client *Client) Exec(opts *ExecOptions) (*ExecProcess, error) {
 		Group:          opts.Group,
 		Terminal:       opts.Terminal,
 		Interactive:    opts.Interactive,
-		SplitStderr:    opts.Stderr != nil,
+		SplitStderr:    opts.Stderr != nil && !opts.Detached,
 		Width:          opts.Width,
 		Height:         opts.Height,
+		Detached:       opts.Detached,
+		Nice:           opts.Nice,
+		IOClass:        opts.IOClass,
+		IOPriority:     opts.IOPriority,
+		Rlimits:        opts.Rlimits,
+		MemoryLimit:    opts.MemoryLimit,
+		CPULimit:       opts.CPULimit,
 	}
 	var body bytes.Buffer
 	err := json.NewEncoder(&body).Encode(&payload)
@@ -162,8 +207,22 @@ func (client *Client) Exec(opts *ExecOptions) (*ExecProcess, error) {
 		return nil, fmt.Errorf("cannot unmarshal JSON response: %w", err)
 	}
 
-	// Connect to the "control" websocket.
 	taskID := result.TaskID
+	if opts.Detached {
+		// Nothing to connect to: clients use ExecAttach to get the output.
+		writesDone := make(chan struct{})
+		close(writesDone)
+		process := &ExecProcess{
+			changeID:   changeID,
+			taskID:     taskID,
+			client:     client,
+			timeout:    opts.Timeout,
+			writesDone: writesDone,
+		}
+		return process, nil
+	}
+
+	// Connect to the "control" websocket.
 	controlConn, err := client.getTaskWebsocket(taskID, "control")
 	if err != nil {
 		return nil, fmt.Errorf(`cannot connect to "control" websocket: %w`, err)
@@ -212,6 +271,7 @@ func (client *Client) Exec(opts *ExecOptions) (*ExecProcess, error) {
 
 	process := &ExecProcess{
 		changeID:    changeID,
+		taskID:      taskID,
 		client:      client,
 		timeout:     opts.Timeout,
 		writesDone:  writesDone,
@@ -221,6 +281,79 @@ func (client *Client) Exec(opts *ExecOptions) (*ExecProcess, error) {
 	return process, nil
 }
 
+type ExecAttachOptions struct {
+	// Required: ID of the task running the detached command, as returned by
+	// ExecProcess.TaskID.
+	TaskID string
+
+	// Output stream (combined stdout and stderr). The output buffered so far
+	// is written first, followed by any new output. If nil, output is
+	// discarded.
+	Stdout io.Writer
+}
+
+// ExecAttach attaches to a command started with ExecOptions.Detached set,
+// returning a value representing the process. Detaching (for example, by
+// exiting without calling Wait) doesn't affect the command.
+func (client *Client) ExecAttach(opts *ExecAttachOptions) (*ExecProcess, error) {
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+
+	changeID, err := client.taskChangeID(opts.TaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	controlConn, err := client.getTaskWebsocket(opts.TaskID, "control")
+	if err != nil {
+		return nil, fmt.Errorf(`cannot connect to "control" websocket: %w`, err)
+	}
+	ioConn, err := client.getTaskWebsocket(opts.TaskID, "stdio")
+	if err != nil {
+		_ = controlConn.Close()
+		return nil, fmt.Errorf(`cannot connect to "stdio" websocket: %w`, err)
+	}
+	stdoutDone := wsutil.WebsocketRecvStream(stdout, ioConn)
+
+	writesDone := make(chan struct{})
+	go func() {
+		<-stdoutDone
+		_ = ioConn.Close()
+		_ = controlConn.Close()
+		close(writesDone)
+	}()
+
+	process := &ExecProcess{
+		changeID:    changeID,
+		taskID:      opts.TaskID,
+		client:      client,
+		writesDone:  writesDone,
+		controlConn: controlConn,
+	}
+	return process, nil
+}
+
+// taskChangeID returns the ID of the change the given task belongs to.
+func (client *Client) taskChangeID(taskID string) (string, error) {
+	execution, err := client.Execution(taskID)
+	if err != nil {
+		return "", err
+	}
+	return execution.ChangeID, nil
+}
+
+// TaskID returns the ID of the task executing the command.
+func (p *ExecProcess) TaskID() string {
+	return p.taskID
+}
+
+// ChangeID returns the ID of the change the command's task belongs to.
+func (p *ExecProcess) ChangeID() string {
+	return p.changeID
+}
+
 // Wait waits for the command process to finish. The returned error is nil if
 // the process runs successfully and returns a zero exit code. If the command
 // fails with a nonzero exit code, the error is of type *ExitError.
@@ -287,8 +420,13 @@ type execResizeArgs struct {
 	Height int `json:"height"`
 }
 
+var errNotAttached = errors.New("cannot control detached process without attaching")
+
 // SendResize sends a resize message to the running process.
 func (p *ExecProcess) SendResize(width, height int) error {
+	if p.controlConn == nil {
+		return errNotAttached
+	}
 	msg := execCommand{
 		Command: "resize",
 		Resize: &execResizeArgs{
@@ -301,6 +439,9 @@ func (p *ExecProcess) SendResize(width, height int) error {
 
 // SendSignal sends a signal to the running process.
 func (p *ExecProcess) SendSignal(signal string) error {
+	if p.controlConn == nil {
+		return errNotAttached
+	}
 	msg := execCommand{
 		Command: "signal",
 		Signal: &execSignalArgs{
@@ -309,3 +450,121 @@ func (p *ExecProcess) SendSignal(signal string) error {
 	}
 	return p.controlConn.WriteJSON(msg)
 }
+
+// ExecutionInfo holds information about a running command.
+type ExecutionInfo struct {
+	// TaskID is the ID of the command's task, which is used to attach to
+	// detached commands and to signal commands.
+	TaskID string `json:"task-id"`
+
+	// ChangeID is the ID of the change the command's task belongs to.
+	ChangeID string `json:"change-id"`
+
+	// Command is the command's arguments, including the executable.
+	Command []string `json:"command"`
+
+	// UserID is the ID of the user the command is running as, and User is
+	// that user's name (empty if it can't be looked up).
+	UserID int    `json:"user-id"`
+	User   string `json:"user"`
+
+	// StartTime is the time the command was started.
+	StartTime time.Time `json:"start-time"`
+
+	// PID is the command's process ID.
+	PID int `json:"pid"`
+
+	// Detached is true if the command was started in detached mode.
+	Detached bool `json:"detached"`
+
+	// Terminal is true if the command is running in a pseudo-terminal.
+	Terminal bool `json:"terminal"`
+
+	// Streams holds the names of the websockets clients are currently
+	// connected to, for example "control" and "stdio".
+	Streams []string `json:"streams"`
+}
+
+// Executions fetches information about the commands that are currently
+// running, ordered by task ID.
+func (client *Client) Executions() ([]*ExecutionInfo, error) {
+	var executions []*ExecutionInfo
+	_, err := client.doSync("GET", "/v1/exec", nil, nil, nil, &executions)
+	if err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
+
+// Execution fetches information about a single command by its task ID. Once
+// the command has finished, only the task and change IDs are set.
+func (client *Client) Execution(taskID string) (*ExecutionInfo, error) {
+	var execution ExecutionInfo
+	_, err := client.doSync("GET", "/v1/exec/"+url.PathEscape(taskID), nil, nil, nil, &execution)
+	if err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+type SignalExecutionOptions struct {
+	// TaskID is the ID of the running command's task.
+	TaskID string
+
+	// Signal is the name of the signal to send, for example "SIGTERM".
+	Signal string
+}
+
+// SignalExecution sends a signal to a running command, without needing to
+// be attached to it.
+func (client *Client) SignalExecution(opts *SignalExecutionOptions) error {
+	return client.postExecAction(opts.TaskID, &execActionPayload{
+		Action: "signal",
+		Signal: opts.Signal,
+	})
+}
+
+// KillExecution kills a running command (by sending it SIGKILL).
+func (client *Client) KillExecution(taskID string) error {
+	return client.postExecAction(taskID, &execActionPayload{Action: "kill"})
+}
+
+type execActionPayload struct {
+	Action string `json:"action"`
+	Signal string `json:"signal,omitempty"`
+}
+
+func (client *Client) postExecAction(taskID string, payload *execActionPayload) error {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode JSON payload: %w", err)
+	}
+	_, err = client.doSync("POST", "/v1/exec/"+url.PathEscape(taskID), nil, nil, &body, nil)
+	return err
+}
+
+// ExecRecording fetches the recording of an exec session that used a
+// terminal, in asciicast v2 format. Sessions are only recorded if the server
+// was started with recording enabled. The caller must close the returned
+// reader.
+func (client *Client) ExecRecording(taskID string) (io.ReadCloser, error) {
+	res, err := client.raw(context.Background(), "GET", "/v1/exec/"+url.PathEscape(taskID)+"/recording", nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		var rsp response
+		err := decodeInto(res.Body, &rsp)
+		if err != nil {
+			return nil, err
+		}
+		err = rsp.err(client)
+		if err == nil {
+			err = fmt.Errorf("server error: %q", res.Status)
+		}
+		return nil, err
+	}
+	return res.Body, nil
+}
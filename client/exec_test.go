@@ -144,6 +144,31 @@ func (s *execSuite) TestOtherOptions(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *execSuite) TestResourceOptions(c *C) {
+	priority := 7
+	opts := &client.ExecOptions{
+		Command:     []string{"pg_dump", "mydb"},
+		Nice:        10,
+		IOClass:     "best-effort",
+		IOPriority:  &priority,
+		Rlimits:     map[string]string{"nofile": "1024", "core": "unlimited"},
+		MemoryLimit: 512 * 1024 * 1024,
+		CPULimit:    0.5,
+	}
+	process, reqBody := s.exec(c, opts, 0)
+	c.Assert(reqBody, DeepEquals, map[string]interface{}{
+		"command":      []interface{}{"pg_dump", "mydb"},
+		"nice":         10.0,
+		"io-class":     "best-effort",
+		"io-priority":  7.0,
+		"rlimits":      map[string]interface{}{"nofile": "1024", "core": "unlimited"},
+		"memory-limit": 536870912.0,
+		"cpu-limit":    0.5,
+	})
+	err := s.wait(c, process)
+	c.Assert(err, IsNil)
+}
+
 func (s *execSuite) TestWaitChangeError(c *C) {
 	opts := &client.ExecOptions{
 		Command: []string{"foo"},
@@ -328,6 +353,198 @@ func (s *execSuite) TestStdinAndStdout(c *C) {
 	})
 }
 
+func (s *execSuite) TestDetached(c *C) {
+	opts := &client.ExecOptions{
+		Command:  []string{"sleep", "60"},
+		Stderr:   ioutil.Discard,
+		Detached: true,
+	}
+	process, reqBody := s.exec(c, opts, 0)
+	c.Assert(reqBody, DeepEquals, map[string]interface{}{
+		"command":  []interface{}{"sleep", "60"},
+		"detached": true,
+	})
+	c.Check(process.TaskID(), Equals, "T123")
+	c.Check(process.ChangeID(), Equals, "123")
+
+	// No websockets are connected until the client attaches.
+	err := process.SendSignal("SIGINT")
+	c.Check(err, ErrorMatches, "cannot control detached process without attaching")
+	c.Check(s.controlWs.writes, HasLen, 0)
+}
+
+func (s *execSuite) TestExecAttach(c *C) {
+	s.rsps = append(s.rsps, `{
+		"result": {"task-id": "T123", "change-id": "123", "streams": []},
+		"status": "OK",
+		"status-code": 200,
+		"type": "sync"
+	}`)
+	s.rsps = append(s.rsps, `{
+		"result": {
+			"id": "123",
+			"kind": "exec",
+			"ready": true,
+			"tasks": [{"data": {"exit-code": 2}, "id": "T123", "kind": "exec"}]
+		},
+		"status": "OK",
+		"status-code": 200,
+		"type": "sync"
+	}`)
+	s.stdioWs.reads = []read{
+		{websocket.BinaryMessage, "output so far\n"},
+		{websocket.BinaryMessage, "new output\n"},
+		{websocket.TextMessage, `{"command":"end"}`},
+	}
+
+	stdout := &bytes.Buffer{}
+	process, err := s.cli.ExecAttach(&client.ExecAttachOptions{
+		TaskID: "T123",
+		Stdout: stdout,
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.req.URL.String(), Equals, "http://localhost/v1/exec/T123")
+	c.Check(process.ChangeID(), Equals, "123")
+
+	err = process.SendSignal("SIGTERM")
+	c.Assert(err, IsNil)
+	c.Check(s.controlWs.writes, DeepEquals, []write{
+		{websocket.TextMessage, `{"command":"signal","signal":{"name":"SIGTERM"}}`},
+	})
+
+	err = process.Wait()
+	exitError, ok := err.(*client.ExitError)
+	c.Assert(ok, Equals, true, Commentf("expected *client.ExitError, got %T", err))
+	c.Check(exitError.ExitCode(), Equals, 2)
+	c.Check(s.req.URL.Path, Equals, "/v1/changes/123/wait")
+	c.Check(stdout.String(), Equals, "output so far\nnew output\n")
+
+	// Nothing is sent on the stdio websocket, as there's no input.
+	c.Check(s.stdioWs.writes, HasLen, 0)
+}
+
+func (s *execSuite) TestExecAttachTaskNotFound(c *C) {
+	s.rsps = append(s.rsps, `{
+		"result": {"message": "cannot find exec task \"T42\""},
+		"status": "Not Found",
+		"status-code": 404,
+		"type": "error"
+	}`)
+	_, err := s.cli.ExecAttach(&client.ExecAttachOptions{TaskID: "T42"})
+	c.Assert(err, ErrorMatches, `cannot find exec task "T42"`)
+	c.Check(s.req.URL.Path, Equals, "/v1/exec/T42")
+}
+
+func (s *execSuite) TestExecutions(c *C) {
+	s.rsp = `{
+		"result": [{
+			"task-id": "T1",
+			"change-id": "1",
+			"command": ["sleep", "10"],
+			"user-id": 1000,
+			"user": "bob",
+			"start-time": "2023-03-09T10:00:00Z",
+			"pid": 1234,
+			"detached": true,
+			"streams": ["stdio"]
+		}],
+		"status": "OK",
+		"status-code": 200,
+		"type": "sync"
+	}`
+	executions, err := s.cli.Executions()
+	c.Assert(err, IsNil)
+	c.Check(s.req.Method, Equals, "GET")
+	c.Check(s.req.URL.Path, Equals, "/v1/exec")
+	c.Check(executions, DeepEquals, []*client.ExecutionInfo{{
+		TaskID:    "T1",
+		ChangeID:  "1",
+		Command:   []string{"sleep", "10"},
+		UserID:    1000,
+		User:      "bob",
+		StartTime: time.Date(2023, 3, 9, 10, 0, 0, 0, time.UTC),
+		PID:       1234,
+		Detached:  true,
+		Streams:   []string{"stdio"},
+	}})
+}
+
+func (s *execSuite) TestExecution(c *C) {
+	s.rsp = `{
+		"result": {"task-id": "T1", "change-id": "1", "streams": []},
+		"status": "OK",
+		"status-code": 200,
+		"type": "sync"
+	}`
+	execution, err := s.cli.Execution("T1")
+	c.Assert(err, IsNil)
+	c.Check(s.req.Method, Equals, "GET")
+	c.Check(s.req.URL.Path, Equals, "/v1/exec/T1")
+	c.Check(execution, DeepEquals, &client.ExecutionInfo{
+		TaskID:   "T1",
+		ChangeID: "1",
+		Streams:  []string{},
+	})
+}
+
+func (s *execSuite) TestSignalExecution(c *C) {
+	s.rsp = `{"type": "sync", "result": true}`
+	err := s.cli.SignalExecution(&client.SignalExecutionOptions{
+		TaskID: "T1",
+		Signal: "SIGHUP",
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.req.Method, Equals, "POST")
+	c.Check(s.req.URL.Path, Equals, "/v1/exec/T1")
+
+	var body map[string]interface{}
+	err = json.NewDecoder(s.req.Body).Decode(&body)
+	c.Assert(err, IsNil)
+	c.Check(body, DeepEquals, map[string]interface{}{
+		"action": "signal",
+		"signal": "SIGHUP",
+	})
+}
+
+func (s *execSuite) TestKillExecution(c *C) {
+	s.rsp = `{"type": "sync", "result": true}`
+	err := s.cli.KillExecution("T1")
+	c.Assert(err, IsNil)
+	c.Check(s.req.Method, Equals, "POST")
+	c.Check(s.req.URL.Path, Equals, "/v1/exec/T1")
+
+	var body map[string]interface{}
+	err = json.NewDecoder(s.req.Body).Decode(&body)
+	c.Assert(err, IsNil)
+	c.Check(body, DeepEquals, map[string]interface{}{"action": "kill"})
+}
+
+func (s *execSuite) TestExecRecording(c *C) {
+	s.rsp = `{"version": 2, "width": 80, "height": 24}
+[0.5, "o", "hello"]
+`
+	reader, err := s.cli.ExecRecording("42")
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	c.Check(s.req.Method, Equals, "GET")
+	c.Check(s.req.URL.Path, Equals, "/v1/exec/42/recording")
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, s.rsp)
+}
+
+func (s *execSuite) TestExecRecordingNotFound(c *C) {
+	s.status = 404
+	s.rsp = `{
+		"result": {"message": "cannot find recording for task \"42\""},
+		"status": "Not Found",
+		"status-code": 404,
+		"type": "error"
+	}`
+	_, err := s.cli.ExecRecording("42")
+	c.Assert(err, ErrorMatches, `cannot find recording for task "42"`)
+}
+
 type testWebsocket struct {
 	reads  []read
 	writes []write
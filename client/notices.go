@@ -0,0 +1,205 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NoticeType is the type of a notice.
+type NoticeType string
+
+const (
+	// CustomNotice is a notice recorded by a client (for example, by
+	// "pebble notify"). Its key must be in the form "domain.com/key".
+	CustomNotice NoticeType = "custom"
+)
+
+// Notice is a deduplicated event: when a notice with the same type and key
+// occurs again, its occurrence count and last-occurred time are updated
+// rather than a new notice being recorded.
+type Notice struct {
+	ID            string            `json:"id"`
+	Type          NoticeType        `json:"type"`
+	Key           string            `json:"key"`
+	FirstOccurred time.Time         `json:"first-occurred"`
+	LastOccurred  time.Time         `json:"last-occurred"`
+	LastRepeated  time.Time         `json:"last-repeated"`
+	Occurrences   int               `json:"occurrences"`
+	LastData      map[string]string `json:"last-data,omitempty"`
+	RepeatAfter   time.Duration     `json:"repeat-after,omitempty"`
+	ExpireAfter   time.Duration     `json:"expire-after,omitempty"`
+}
+
+type jsonNotice struct {
+	Notice
+	RepeatAfter string `json:"repeat-after,omitempty"`
+	ExpireAfter string `json:"expire-after,omitempty"`
+}
+
+func (jn *jsonNotice) notice() (*Notice, error) {
+	notice := jn.Notice
+	var err error
+	if jn.RepeatAfter != "" {
+		notice.RepeatAfter, err = time.ParseDuration(jn.RepeatAfter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repeat-after duration: %w", err)
+		}
+	}
+	if jn.ExpireAfter != "" {
+		notice.ExpireAfter, err = time.ParseDuration(jn.ExpireAfter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expire-after duration: %w", err)
+		}
+	}
+	return &notice, nil
+}
+
+// NotifyOptions holds the options for a Notify call.
+type NotifyOptions struct {
+	// Key is the custom notice's key, in the form "domain.com/key".
+	Key string
+
+	// Data is optional key-value data for this occurrence of the notice.
+	Data map[string]string
+
+	// RepeatAfter, if set, means the notice is only repeated (returned to
+	// clients waiting for new notices) if it occurs this long after it was
+	// last repeated.
+	RepeatAfter time.Duration
+
+	// ExpireAfter, if set, is how long after it last occurred the notice
+	// expires, rather than the default of 7 days.
+	ExpireAfter time.Duration
+}
+
+type noticesAction struct {
+	Action      string            `json:"action"`
+	Type        string            `json:"type"`
+	Key         string            `json:"key"`
+	RepeatAfter string            `json:"repeat-after,omitempty"`
+	ExpireAfter string            `json:"expire-after,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+}
+
+// Notify records an occurrence of a custom notice, and returns the notice's
+// ID.
+func (client *Client) Notify(opts *NotifyOptions) (string, error) {
+	payload := noticesAction{
+		Action: "add",
+		Type:   string(CustomNotice),
+		Key:    opts.Key,
+		Data:   opts.Data,
+	}
+	if opts.RepeatAfter != 0 {
+		payload.RepeatAfter = opts.RepeatAfter.String()
+	}
+	if opts.ExpireAfter != 0 {
+		payload.ExpireAfter = opts.ExpireAfter.String()
+	}
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(&payload); err != nil {
+		return "", err
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	_, err := client.doSync("POST", "/v1/notices", nil, nil, &body, &result)
+	if err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+// NoticesOptions holds the filter options for a Notices or WaitNotices call.
+type NoticesOptions struct {
+	// Types, if not empty, includes only notices whose type is one of these.
+	Types []NoticeType
+
+	// Keys, if not empty, includes only notices whose key is one of these.
+	Keys []string
+
+	// After, if set, includes only notices that were last repeated after
+	// this time.
+	After time.Time
+}
+
+func (opts *NoticesOptions) query() url.Values {
+	query := url.Values{}
+	if opts == nil {
+		return query
+	}
+	if len(opts.Types) > 0 {
+		types := make([]string, len(opts.Types))
+		for i, t := range opts.Types {
+			types[i] = string(t)
+		}
+		query.Set("types", strings.Join(types, ","))
+	}
+	if len(opts.Keys) > 0 {
+		query.Set("keys", strings.Join(opts.Keys, ","))
+	}
+	if !opts.After.IsZero() {
+		query.Set("after", opts.After.Format(time.RFC3339Nano))
+	}
+	return query
+}
+
+// Notices returns the notices that match the filter options, ordered by
+// last-repeated time.
+func (client *Client) Notices(opts *NoticesOptions) ([]*Notice, error) {
+	return client.getNotices(opts.query())
+}
+
+// WaitNotices is like Notices, but if there are no matching notices it waits
+// up to timeout for some to occur. It returns an empty list if none occur
+// within the timeout.
+func (client *Client) WaitNotices(opts *NoticesOptions, timeout time.Duration) ([]*Notice, error) {
+	query := opts.query()
+	query.Set("timeout", timeout.String())
+	return client.getNotices(query)
+}
+
+func (client *Client) getNotices(query url.Values) ([]*Notice, error) {
+	var jns []*jsonNotice
+	_, err := client.doSync("GET", "/v1/notices", query, nil, nil, &jns)
+	if err != nil {
+		return nil, err
+	}
+	notices := make([]*Notice, len(jns))
+	for i, jn := range jns {
+		notices[i], err = jn.notice()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notices, nil
+}
+
+// Notice returns a single notice by ID.
+func (client *Client) Notice(id string) (*Notice, error) {
+	var jn *jsonNotice
+	_, err := client.doSync("GET", "/v1/notices/"+id, nil, nil, nil, &jn)
+	if err != nil {
+		return nil, err
+	}
+	return jn.notice()
+}
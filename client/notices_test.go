@@ -0,0 +1,140 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/client"
+)
+
+func (cs *clientSuite) TestNotify(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": {"id": "7"}}`
+	id, err := cs.cli.Notify(&client.NotifyOptions{
+		Key:         "example.com/foo",
+		Data:        map[string]string{"k": "v"},
+		RepeatAfter: time.Hour,
+		ExpireAfter: 2 * time.Hour,
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "7")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/notices")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var m map[string]interface{}
+	c.Assert(json.Unmarshal(body, &m), check.IsNil)
+	c.Check(m, check.DeepEquals, map[string]interface{}{
+		"action":       "add",
+		"type":         "custom",
+		"key":          "example.com/foo",
+		"repeat-after": "1h0m0s",
+		"expire-after": "2h0m0s",
+		"data":         map[string]interface{}{"k": "v"},
+	})
+}
+
+func (cs *clientSuite) TestNotices(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": [{
+		"id": "1",
+		"type": "custom",
+		"key": "example.com/foo",
+		"first-occurred": "2023-09-05T15:43:00.123Z",
+		"last-occurred": "2023-09-05T17:43:00.567Z",
+		"last-repeated": "2023-09-05T16:43:00Z",
+		"occurrences": 2,
+		"last-data": {"k": "v"},
+		"repeat-after": "1h0m0s",
+		"expire-after": "168h0m0s"
+	}]}`
+	after := time.Date(2023, 9, 5, 12, 0, 0, 1, time.UTC)
+	notices, err := cs.cli.Notices(&client.NoticesOptions{
+		Types: []client.NoticeType{client.CustomNotice},
+		Keys:  []string{"example.com/foo", "example.com/bar"},
+		After: after,
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(notices, check.DeepEquals, []*client.Notice{{
+		ID:            "1",
+		Type:          client.CustomNotice,
+		Key:           "example.com/foo",
+		FirstOccurred: time.Date(2023, 9, 5, 15, 43, 0, 123000000, time.UTC),
+		LastOccurred:  time.Date(2023, 9, 5, 17, 43, 0, 567000000, time.UTC),
+		LastRepeated:  time.Date(2023, 9, 5, 16, 43, 0, 0, time.UTC),
+		Occurrences:   2,
+		LastData:      map[string]string{"k": "v"},
+		RepeatAfter:   time.Hour,
+		ExpireAfter:   7 * 24 * time.Hour,
+	}})
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/notices")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"types": {"custom"},
+		"keys":  {"example.com/foo,example.com/bar"},
+		"after": {"2023-09-05T12:00:00.000000001Z"},
+	})
+}
+
+func (cs *clientSuite) TestWaitNotices(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": []}`
+	notices, err := cs.cli.WaitNotices(&client.NoticesOptions{Keys: []string{"example.com/foo"}}, 30*time.Second)
+	c.Assert(err, check.IsNil)
+	c.Check(notices, check.HasLen, 0)
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/notices")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"keys":    {"example.com/foo"},
+		"timeout": {"30s"},
+	})
+}
+
+func (cs *clientSuite) TestNotice(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": {
+		"id": "2",
+		"type": "custom",
+		"key": "example.com/bar",
+		"first-occurred": "2023-09-05T15:43:00Z",
+		"last-occurred": "2023-09-05T15:43:00Z",
+		"last-repeated": "2023-09-05T15:43:00Z",
+		"occurrences": 1,
+		"expire-after": "168h0m0s"
+	}}`
+	notice, err := cs.cli.Notice("2")
+	c.Assert(err, check.IsNil)
+	t := time.Date(2023, 9, 5, 15, 43, 0, 0, time.UTC)
+	c.Check(notice, check.DeepEquals, &client.Notice{
+		ID:            "2",
+		Type:          client.CustomNotice,
+		Key:           "example.com/bar",
+		FirstOccurred: t,
+		LastOccurred:  t,
+		LastRepeated:  t,
+		Occurrences:   1,
+		ExpireAfter:   7 * 24 * time.Hour,
+	})
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/notices/2")
+}
+
+func (cs *clientSuite) TestNoticeNotFound(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "cannot find notice with id \"3\""}}`
+	_, err := cs.cli.Notice("3")
+	c.Assert(err, check.ErrorMatches, `cannot find notice with id "3"`)
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+// Prune prunes old changes, warnings and notices from the daemon's state
+// immediately, rather than waiting for the next periodic prune. It returns
+// the number of changes pruned.
+func (client *Client) Prune() (prunedChanges int, err error) {
+	var result struct {
+		PrunedChanges int `json:"pruned-changes"`
+	}
+	_, err = client.doSync("POST", "/v1/prune", nil, nil, nil, &result)
+	if err != nil {
+		return 0, err
+	}
+	return result.PrunedChanges, nil
+}
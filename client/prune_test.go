@@ -0,0 +1,34 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client_test
+
+import (
+	"gopkg.in/check.v1"
+)
+
+func (cs *clientSuite) TestPrune(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {"pruned-changes": 3}}`
+	pruned, err := cs.cli.Prune()
+	c.Assert(err, check.IsNil)
+	c.Check(pruned, check.Equals, 3)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/prune")
+}
+
+func (cs *clientSuite) TestPruneError(c *check.C) {
+	cs.rsp = `{"type": "error", "result": {"message": "cannot prune"}}`
+	_, err := cs.cli.Prune()
+	c.Check(err, check.ErrorMatches, "cannot prune")
+}
@@ -43,6 +43,13 @@ type jsonWarning struct {
 type WarningsOptions struct {
 	// All means return all warnings, instead of only the un-okayed ones.
 	All bool
+
+	// Text, if set, only returns warnings whose message contains this
+	// text (ignoring case).
+	Text string
+
+	// Since, if set, only returns warnings last added after this time.
+	Since time.Time
 }
 
 // Warnings returns the list of un-okayed warnings.
@@ -52,6 +59,12 @@ func (client *Client) Warnings(opts WarningsOptions) ([]*Warning, error) {
 	if opts.All {
 		q.Add("select", "all")
 	}
+	if opts.Text != "" {
+		q.Add("text", opts.Text)
+	}
+	if !opts.Since.IsZero() {
+		q.Add("since", opts.Since.Format(time.RFC3339Nano))
+	}
 	_, err := client.doSync("GET", "/v1/warnings", q, nil, nil, &jws)
 
 	ws := make([]*Warning, len(jws))
@@ -80,3 +93,55 @@ func (client *Client) Okay(t time.Time) error {
 	_, err := client.doSync("POST", "/v1/warnings", nil, nil, &body, nil)
 	return err
 }
+
+type warningAction struct {
+	Action      string `json:"action"`
+	Message     string `json:"message"`
+	RepeatAfter string `json:"repeat-after,omitempty"`
+	ExpireAfter string `json:"expire-after,omitempty"`
+}
+
+func (client *Client) doWarningAction(op *warningAction) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(op); err != nil {
+		return err
+	}
+	_, err := client.doSync("POST", "/v1/warnings", nil, nil, &body, nil)
+	return err
+}
+
+// AddWarningOptions holds optional parameters for an AddWarning call.
+type AddWarningOptions struct {
+	// RepeatAfter is how long after it's been okayed the warning is shown
+	// again. If zero, the server's default is used.
+	RepeatAfter time.Duration
+
+	// ExpireAfter is how long after it was last added the warning is
+	// dropped. If zero, the server's default is used.
+	ExpireAfter time.Duration
+}
+
+// AddWarning adds a warning with the given message, or records that it
+// happened again if a warning with that message already exists.
+func (client *Client) AddWarning(message string, opts *AddWarningOptions) error {
+	op := &warningAction{Action: "add", Message: message}
+	if opts != nil {
+		if opts.RepeatAfter != 0 {
+			op.RepeatAfter = opts.RepeatAfter.String()
+		}
+		if opts.ExpireAfter != 0 {
+			op.ExpireAfter = opts.ExpireAfter.String()
+		}
+	}
+	return client.doWarningAction(op)
+}
+
+// OkayWarning asks the server to silence the warning with the given message.
+func (client *Client) OkayWarning(message string) error {
+	return client.doWarningAction(&warningAction{Action: "okay", Message: message})
+}
+
+// DeleteWarning asks the server to delete the warning with the given message.
+func (client *Client) DeleteWarning(message string) error {
+	return client.doWarningAction(&warningAction{Action: "delete", Message: message})
+}
@@ -16,6 +16,7 @@ package client_test
 
 import (
 	"encoding/json"
+	"net/url"
 	"time"
 
 	"gopkg.in/check.v1"
@@ -114,3 +115,65 @@ func (cs *clientSuite) TestOkay(c *check.C) {
 	c.Check(count, check.Equals, 0)
 	c.Check(stamp, check.Equals, time.Time{})
 }
+
+func (cs *clientSuite) TestWarningsFilters(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": []}`
+	since := time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+	ws, err := cs.cli.Warnings(client.WarningsOptions{Text: "disk", Since: since})
+	c.Assert(err, check.IsNil)
+	c.Check(ws, check.HasLen, 0)
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"text":  {"disk"},
+		"since": {"2023-08-01T10:00:00Z"},
+	})
+}
+
+func (cs *clientSuite) testWarningAction(c *check.C, f func() error, expected map[string]interface{}) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": null}`
+	err := f()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v1/warnings")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, expected)
+}
+
+func (cs *clientSuite) TestAddWarning(c *check.C) {
+	cs.testWarningAction(c, func() error {
+		return cs.cli.AddWarning("disk is full", &client.AddWarningOptions{
+			RepeatAfter: time.Hour,
+			ExpireAfter: 48 * time.Hour,
+		})
+	}, map[string]interface{}{
+		"action":       "add",
+		"message":      "disk is full",
+		"repeat-after": "1h0m0s",
+		"expire-after": "48h0m0s",
+	})
+
+	cs.testWarningAction(c, func() error {
+		return cs.cli.AddWarning("disk is full", nil)
+	}, map[string]interface{}{
+		"action":  "add",
+		"message": "disk is full",
+	})
+}
+
+func (cs *clientSuite) TestOkayWarning(c *check.C) {
+	cs.testWarningAction(c, func() error {
+		return cs.cli.OkayWarning("disk is full")
+	}, map[string]interface{}{
+		"action":  "okay",
+		"message": "disk is full",
+	})
+}
+
+func (cs *clientSuite) TestDeleteWarning(c *check.C) {
+	cs.testWarningAction(c, func() error {
+		return cs.cli.DeleteWarning("disk is full")
+	}, map[string]interface{}{
+		"action":  "delete",
+		"message": "disk is full",
+	})
+}
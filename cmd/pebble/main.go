@@ -19,9 +19,18 @@ import (
 	"os"
 
 	"github.com/canonical/pebble/internals/cli"
+	"github.com/canonical/pebble/internals/overlord/cmdstate"
 )
 
 func main() {
+	// Pebble runs itself as the exec helper to set resource limits on exec
+	// commands before executing them.
+	if len(os.Args) > 0 && os.Args[0] == cmdstate.ExecHelperName {
+		err := cmdstate.RunExecHelper(os.Args[1:])
+		fmt.Fprintf(os.Stderr, "cannot execute command: %v\n", err)
+		os.Exit(127)
+	}
+
 	if err := cli.Run(); err != nil {
 		fmt.Fprintf(cli.Stderr, "error: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,55 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"github.com/canonical/go-flags"
+)
+
+var shortAbortHelp = "Abort a pending change"
+var longAbortHelp = `
+The abort command attempts to abort a change that still has pending tasks.
+With --task, only the given tasks, and the tasks that wait for them, are
+aborted.
+`
+
+type cmdAbort struct {
+	changeIDMixin
+	Tasks []string `long:"task"`
+}
+
+func init() {
+	addCommand("abort", shortAbortHelp, longAbortHelp, func() flags.Commander { return &cmdAbort{} },
+		merge(changeIDMixinOptDesc, map[string]string{
+			"task": "Only abort this task (can be repeated)",
+		}), changeIDMixinArgDesc)
+}
+
+func (cmd *cmdAbort) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	id, err := cmd.GetChangeID()
+	if err != nil {
+		if err == noChangeFoundOK {
+			return nil
+		}
+		return err
+	}
+
+	_, err = cmd.client.AbortTasks(id, cmd.Tasks)
+	return err
+}
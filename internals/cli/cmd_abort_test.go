@@ -0,0 +1,70 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestAbort(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/changes/42")
+		body := DecodedRequestBody(c, r)
+		c.Check(body, check.DeepEquals, map[string]interface{}{
+			"action": "abort",
+		})
+		fmt.Fprint(w, `{"type": "sync", "result": {"id": "42", "kind": "start", "status": "Hold", "ready": true}}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"abort", "42"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestAbortTasks(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/changes/42")
+		body := DecodedRequestBody(c, r)
+		c.Check(body, check.DeepEquals, map[string]interface{}{
+			"action":   "abort",
+			"task-ids": []interface{}{"3", "5"},
+		})
+		fmt.Fprint(w, `{"type": "sync", "result": {"id": "42", "kind": "start", "status": "Doing"}}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"abort", "42", "--task", "3", "--task", "5"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestAbortFails(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type": "error", "result": {"message": "cannot abort change 42 with nothing pending"}}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"abort", "42"})
+	c.Assert(err, check.ErrorMatches, "cannot abort change 42 with nothing pending")
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"time"
 
 	"github.com/canonical/go-flags"
 
@@ -28,6 +29,12 @@ var shortChangesHelp = "List system changes"
 var shortTasksHelp = "List a change's tasks"
 var longChangesHelp = `
 The changes command displays a summary of system changes performed recently.
+
+Changes can be filtered by kind, status, task kind, and by when they were
+spawned or became ready. Times are given either in RFC3339 format or as a
+duration before now, for example:
+
+pebble changes svc1 --kind start --status error --since 1h
 `
 var longTasksHelp = `
 The tasks command displays a summary of tasks associated with an individual
@@ -37,6 +44,15 @@ change that happened recently.
 type cmdChanges struct {
 	clientMixin
 	timeMixin
+	Kinds      []string `long:"kind"`
+	Statuses   []string `long:"status"`
+	TaskKinds  []string `long:"task-kind"`
+	Since      string   `long:"since"`
+	Until      string   `long:"until"`
+	ReadySince string   `long:"ready-since"`
+	ReadyUntil string   `long:"ready-until"`
+	Limit      int      `long:"limit"`
+	Offset     int      `long:"offset"`
 	Positional struct {
 		Service string `positional-arg-name:"<service>"`
 	} `positional-args:"yes"`
@@ -49,7 +65,17 @@ type cmdTasks struct {
 
 func init() {
 	addCommand("changes", shortChangesHelp, longChangesHelp,
-		func() flags.Commander { return &cmdChanges{} }, timeDescs, nil)
+		func() flags.Commander { return &cmdChanges{} }, merge(timeDescs, map[string]string{
+			"kind":        "Only list changes of this kind (can be repeated)",
+			"status":      "Only list changes with this status (can be repeated)",
+			"task-kind":   "Only list changes with a task of this kind (can be repeated)",
+			"since":       "Only list changes spawned at or after this time",
+			"until":       "Only list changes spawned at or before this time",
+			"ready-since": "Only list changes that became ready at or after this time",
+			"ready-until": "Only list changes that became ready at or before this time",
+			"limit":       "List at most this many changes (the most recent)",
+			"offset":      "Skip this many of the most recent matching changes",
+		}), nil)
 	addCommand("tasks", shortTasksHelp, longTasksHelp,
 		func() flags.Commander { return &cmdTasks{} },
 		merge(changeIDMixinOptDesc, timeDescs),
@@ -89,9 +115,40 @@ func (c *cmdChanges) Execute(args []string) error {
 		return nil
 	}
 
+	if c.Limit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+	if c.Offset < 0 {
+		return fmt.Errorf("--offset must not be negative")
+	}
 	opts := client.ChangesOptions{
 		ServiceName: c.Positional.Service,
 		Selector:    client.ChangesAll,
+		Kinds:       c.Kinds,
+		Statuses:    c.Statuses,
+		TaskKinds:   c.TaskKinds,
+		Limit:       c.Limit,
+		Offset:      c.Offset,
+	}
+	now := time.Now()
+	for _, tf := range []struct {
+		flag  string
+		value string
+		time  *time.Time
+	}{
+		{"since", c.Since, &opts.SpawnSince},
+		{"until", c.Until, &opts.SpawnUntil},
+		{"ready-since", c.ReadySince, &opts.ReadySince},
+		{"ready-until", c.ReadyUntil, &opts.ReadyUntil},
+	} {
+		if tf.value == "" {
+			continue
+		}
+		t, err := parseTimeOrAgo(tf.value, now)
+		if err != nil {
+			return fmt.Errorf("invalid --%s value: %v", tf.flag, err)
+		}
+		*tf.time = t
 	}
 
 	changes, err := queryChanges(c.client, &opts)
@@ -123,6 +180,19 @@ func (c *cmdChanges) Execute(args []string) error {
 	return nil
 }
 
+// parseTimeOrAgo parses s as an RFC3339 time, or as a duration before now
+// (for example "1h30m").
+func parseTimeOrAgo(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC3339 time or a duration", s)
+	}
+	return t, nil
+}
+
 func (c *cmdTasks) Execute([]string) error {
 	chid, err := c.GetChangeID()
 	if err != nil {
@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -61,6 +62,44 @@ func (s *PebbleSuite) TestNoChanges(c *check.C) {
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *PebbleSuite) TestChangesFilters(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v1/changes")
+		query := r.URL.Query()
+		since, err := time.Parse(time.RFC3339, query.Get("spawn-since"))
+		c.Check(err, check.IsNil)
+		c.Check(time.Since(since) > 59*time.Minute && time.Since(since) < 61*time.Minute, check.Equals, true)
+		query.Del("spawn-since")
+		c.Check(query, check.DeepEquals, url.Values{
+			"for":         {"svc1"},
+			"select":      {"all"},
+			"kinds":       {"start,restart"},
+			"statuses":    {"error"},
+			"task-kinds":  {"exec"},
+			"ready-until": {"2023-01-02T03:04:05Z"},
+			"limit":       {"10"},
+			"offset":      {"20"},
+		})
+		fmt.Fprintln(w, `{"type":"sync", "result": []}"`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"changes", "svc1",
+		"--kind", "start", "--kind", "restart", "--status", "error", "--task-kind", "exec",
+		"--since", "1h", "--ready-until", "2023-01-02T03:04:05Z", "--limit", "10", "--offset", "20"})
+	c.Assert(err, check.ErrorMatches, "no changes found")
+	c.Check(rest, check.HasLen, 1)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestChangesInvalidFilters(c *check.C) {
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"changes", "--since", "yesterday"})
+	c.Check(err, check.ErrorMatches, `invalid --since value: "yesterday" is not an RFC3339 time or a duration`)
+	_, err = cli.Parser(cli.Client()).ParseArgs([]string{"changes", "--limit", "-1"})
+	c.Check(err, check.ErrorMatches, `--limit must not be negative`)
+}
+
 func (s *PebbleSuite) TestGetChangesFails(c *check.C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		c.Check(r.Method, check.Equals, "GET")
@@ -0,0 +1,100 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/canonical/go-flags"
+
+	"github.com/canonical/pebble/client"
+)
+
+type cmdCheck struct {
+	clientMixin
+	Positional struct {
+		Action string   `positional-arg-name:"<action>"`
+		Checks []string `positional-arg-name:"<check>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+var shortCheckHelp = "Run, pause or resume health checks"
+var longCheckHelp = `
+The check command acts on one or more health checks. The action must be one
+of:
+
+run     Run the checks now, wait for them to complete, and show the results.
+        Exits with an error if any of the checks fail.
+pause   Stop running the checks periodically, for example during maintenance.
+        Paused checks don't trigger their failure actions.
+resume  Resume running paused checks periodically.
+
+For example:
+
+pebble check run online
+`
+
+func (cmd *cmdCheck) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	opts := client.ChecksActionOptions{
+		Names: cmd.Positional.Checks,
+	}
+	switch cmd.Positional.Action {
+	case "run":
+		results, err := cmd.client.RunChecks(&opts)
+		if err != nil {
+			return err
+		}
+		return writeRunResults(results)
+	case "pause":
+		return cmd.client.PauseChecks(&opts)
+	case "resume":
+		return cmd.client.ResumeChecks(&opts)
+	default:
+		return fmt.Errorf(`invalid action %q (must be "run", "pause", or "resume")`, cmd.Positional.Action)
+	}
+}
+
+func writeRunResults(results []*client.CheckRunResult) error {
+	w := tabWriter()
+	fmt.Fprintln(w, "Check\tDuration\tResult")
+	var failed []string
+	for _, result := range results {
+		outcome := "ok"
+		if !result.Success {
+			outcome = "error: " + strings.SplitN(result.Error, "\n", 2)[0]
+			failed = append(failed, result.Name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Name, fmtCheckDuration(result.Duration), outcome)
+	}
+	w.Flush()
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("check %q failed", failed[0])
+	default:
+		return fmt.Errorf("%d checks failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+}
+
+func init() {
+	addCommand("check", shortCheckHelp, longCheckHelp, func() flags.Commander { return &cmdCheck{} }, nil, nil)
+}
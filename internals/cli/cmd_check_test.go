@@ -0,0 +1,106 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestCheckRun(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, check.Equals, "POST")
+		c.Assert(r.URL.Path, check.Equals, "/v1/checks")
+		var body map[string]interface{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&body), check.IsNil)
+		c.Check(body, check.DeepEquals, map[string]interface{}{
+			"action": "run",
+			"checks": []interface{}{"chk1", "chk2"},
+		})
+		fmt.Fprint(w, `{
+    "type": "sync",
+    "status-code": 200,
+    "result": [
+		{"name": "chk1", "time": "2023-04-05T06:07:08Z", "duration": 1234567, "success": true},
+		{"name": "chk2", "time": "2023-04-05T06:07:08Z", "duration": 2000000, "success": true}
+	]
+}`)
+	})
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"check", "run", "chk1", "chk2"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `
+Check  Duration  Result
+chk1   1ms       ok
+chk2   2ms       ok
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestCheckRunFailed(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+    "type": "sync",
+    "status-code": 200,
+    "result": [
+		{"name": "chk1", "time": "2023-04-05T06:07:08Z", "duration": 3000000000, "success": false, "error": "exec check timed out"}
+	]
+}`)
+	})
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"check", "run", "chk1"})
+	c.Assert(err, check.ErrorMatches, `check "chk1" failed`)
+	c.Check(s.Stdout(), check.Equals, `
+Check  Duration  Result
+chk1   3s        error: exec check timed out
+`[1:])
+}
+
+func (s *PebbleSuite) TestCheckPauseResume(c *check.C) {
+	var actions []string
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, check.Equals, "POST")
+		c.Assert(r.URL.Path, check.Equals, "/v1/checks")
+		var body struct {
+			Action string
+			Checks []string
+		}
+		c.Assert(json.NewDecoder(r.Body).Decode(&body), check.IsNil)
+		c.Check(body.Checks, check.DeepEquals, []string{"chk1"})
+		actions = append(actions, body.Action)
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": true}`)
+	})
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"check", "pause", "chk1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	rest, err = cli.Parser(cli.Client()).ParseArgs([]string{"check", "resume", "chk1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(actions, check.DeepEquals, []string{"pause", "resume"})
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestCheckInvalidAction(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"check", "stop", "chk1"})
+	c.Assert(err, check.ErrorMatches, `invalid action "stop" \(must be "run", "pause", or "resume"\)`)
+}
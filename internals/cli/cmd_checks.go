@@ -16,6 +16,8 @@ package cli
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/canonical/go-flags"
 
@@ -24,14 +26,17 @@ import (
 
 type cmdChecks struct {
 	clientMixin
+	timeMixin
 	Level      string `long:"level"`
+	History    bool   `long:"history"`
 	Positional struct {
 		Checks []string `positional-arg-name:"<check>"`
 	} `positional-args:"yes"`
 }
 
 var checksDescs = map[string]string{
-	"level": `Check level to filter for ("alive" or "ready")`,
+	"level":   `Check level to filter for ("alive" or "ready")`,
+	"history": `Show the results of each check's most recent runs`,
 }
 
 var shortChecksHelp = "Query the status of configured health checks"
@@ -39,6 +44,9 @@ var longChecksHelp = `
 The checks command lists status information about the configured health
 checks, optionally filtered by level and check names provided as positional
 arguments.
+
+With --history, it shows the time, duration and result of the most recent
+runs of each check instead, oldest first.
 `
 
 func (cmd *cmdChecks) Execute(args []string) error {
@@ -63,6 +71,11 @@ func (cmd *cmdChecks) Execute(args []string) error {
 		return nil
 	}
 
+	if cmd.History {
+		cmd.writeHistory(checks)
+		return nil
+	}
+
 	w := tabWriter()
 	defer w.Flush()
 
@@ -73,11 +86,53 @@ func (cmd *cmdChecks) Execute(args []string) error {
 		if level == client.UnsetLevel {
 			level = "-"
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\n", check.Name, level, check.Status, check.Failures, check.Threshold)
+		var notes []string
+		if check.Paused {
+			notes = append(notes, "paused")
+		}
+		if check.Starting {
+			notes = append(notes, "starting")
+		}
+		if check.Successes > 0 {
+			notes = append(notes, fmt.Sprintf("recovering %d/%d", check.Successes, check.SuccessThreshold))
+		}
+		status := string(check.Status)
+		if len(notes) > 0 {
+			status += " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\n", check.Name, level, status, check.Failures, check.Threshold)
 	}
 	return nil
 }
 
+func (cmd *cmdChecks) writeHistory(checks []*client.CheckInfo) {
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, "Check\tTime\tDuration\tResult")
+
+	for _, check := range checks {
+		for _, result := range check.History {
+			outcome := "ok"
+			if !result.Success {
+				// Only show the first line of (possibly long) errors.
+				outcome = "error: " + strings.SplitN(result.Error, "\n", 2)[0]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.Name, cmd.fmtTime(result.Time),
+				fmtCheckDuration(result.Duration), outcome)
+		}
+	}
+}
+
+// fmtCheckDuration formats a check's duration with a precision that suits
+// its size: checks usually take anything from microseconds to seconds.
+func fmtCheckDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
 func init() {
-	addCommand("checks", shortChecksHelp, longChecksHelp, func() flags.Commander { return &cmdChecks{} }, checksDescs, nil)
+	addCommand("checks", shortChecksHelp, longChecksHelp, func() flags.Commander { return &cmdChecks{} }, merge(checksDescs, timeDescs), nil)
 }
@@ -35,7 +35,10 @@ func (s *PebbleSuite) TestChecks(c *check.C) {
     "result": [
 		{"name": "chk1", "status": "up", "threshold": 3},
 		{"name": "chk2", "status": "down", "failures": 1, "threshold": 1},
-		{"name": "chk3", "level": "alive", "status": "down", "failures": 42, "threshold": 3}
+		{"name": "chk3", "level": "alive", "status": "down", "failures": 42, "threshold": 3},
+		{"name": "chk4", "status": "up", "threshold": 3, "paused": true},
+		{"name": "chk5", "status": "up", "threshold": 3, "paused": true, "starting": true},
+		{"name": "chk6", "status": "down", "threshold": 3, "successes": 1, "success-threshold": 2}
 	]
 }`)
 	})
@@ -43,10 +46,44 @@ func (s *PebbleSuite) TestChecks(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(rest, check.HasLen, 0)
 	c.Check(s.Stdout(), check.Equals, `
-Check  Level  Status  Failures
-chk1   -      up      0/3
-chk2   -      down    1/1
-chk3   alive  down    42/3
+Check  Level  Status                 Failures
+chk1   -      up                     0/3
+chk2   -      down                   1/1
+chk3   alive  down                   42/3
+chk4   -      up (paused)            0/3
+chk5   -      up (paused, starting)  0/3
+chk6   -      down (recovering 1/2)  0/3
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestChecksHistory(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, check.Equals, "GET")
+		c.Assert(r.URL.Path, check.Equals, "/v1/checks")
+		fmt.Fprint(w, `{
+    "type": "sync",
+    "status-code": 200,
+    "result": [
+		{"name": "chk1", "status": "up", "threshold": 3, "history": [
+			{"time": "2023-04-05T06:07:08Z", "duration": 1234567, "success": true},
+			{"time": "2023-04-05T06:07:18Z", "duration": 3000000000, "success": false, "error": "exec check timed out"}
+		]},
+		{"name": "chk2", "status": "up", "threshold": 3},
+		{"name": "chk3", "status": "down", "failures": 1, "threshold": 1, "history": [
+			{"time": "2023-04-05T06:07:09Z", "duration": 12345, "success": false, "error": "non-20x status\nmore details"}
+		]}
+	]
+}`)
+	})
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"checks", "--history", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `
+Check  Time                  Duration  Result
+chk1   2023-04-05T06:07:08Z  1ms       ok
+chk1   2023-04-05T06:07:18Z  3s        error: exec check timed out
+chk3   2023-04-05T06:07:09Z  12µs      error: non-20x status
 `[1:])
 	c.Check(s.Stderr(), check.Equals, "")
 }
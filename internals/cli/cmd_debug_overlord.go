@@ -0,0 +1,105 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canonical/go-flags"
+)
+
+var shortDebugOverlordHelp = "Show the daemon's task runner and ensure loop internals"
+var longDebugOverlordHelp = `
+The overlord command shows the tasks the daemon's task runner is currently
+running, the task kinds it knows how to run, and how long the most recent
+run of the ensure loop took. Only the state managers that took a noticeable
+time to ensure are listed individually.
+`
+
+type cmdDebugOverlord struct {
+	clientMixin
+}
+
+type debugTask struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Summary string `json:"summary"`
+	Status  string `json:"status"`
+	Change  string `json:"change"`
+}
+
+// debugSpan is a timing span, as marshalled by the timing package.
+type debugSpan struct {
+	Label string      `json:"label"`
+	Depth int         `json:"depth"`
+	Spans []debugSpan `json:"spans"`
+	A     uint64      `json:"a"`
+	B     uint64      `json:"b"`
+}
+
+type debugOverlord struct {
+	RunningTasks  []debugTask `json:"running-tasks"`
+	TaskKinds     []string    `json:"task-kinds"`
+	EnsureTimings *debugSpan  `json:"ensure-timings"`
+}
+
+func init() {
+	addDebugCommand("overlord", shortDebugOverlordHelp, longDebugOverlordHelp, func() flags.Commander { return &cmdDebugOverlord{} }, nil, nil)
+}
+
+func (cmd *cmdDebugOverlord) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var info debugOverlord
+	if err := cmd.client.DebugGet("overlord", &info, nil); err != nil {
+		return err
+	}
+
+	if len(info.RunningTasks) == 0 {
+		fmt.Fprintln(Stdout, "No running tasks.")
+	} else {
+		w := tabWriter()
+		fmt.Fprintf(w, "ID\tChange\tKind\tStatus\tSummary\n")
+		for _, t := range info.RunningTasks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.ID, dashIfEmpty(t.Change), dashIfEmpty(t.Kind), dashIfEmpty(t.Status), t.Summary)
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintf(Stdout, "\nTask kinds: %s\n", strings.Join(info.TaskKinds, ", "))
+
+	if info.EnsureTimings == nil {
+		fmt.Fprintln(Stdout, "\nThe ensure loop hasn't run yet.")
+		return nil
+	}
+	timings := info.EnsureTimings
+	fmt.Fprintf(Stdout, "\nLast ensure took %v\n", time.Duration(timings.B-timings.A))
+	for _, span := range timings.Spans {
+		indent := strings.Repeat("  ", span.Depth)
+		fmt.Fprintf(Stdout, "%s%s: %v\n", indent, span.Label, time.Duration(span.B-span.A))
+	}
+	return nil
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
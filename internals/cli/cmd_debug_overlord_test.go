@@ -0,0 +1,80 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestDebugOverlord(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v1/debug")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{"action": {"overlord"}})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": {
+			"running-tasks": [
+				{"id": "3", "kind": "start", "summary": "Start service \"srv1\"", "status": "Doing", "change": "2"},
+				{"id": "7"}
+			],
+			"task-kinds": ["exec", "start", "stop"],
+			"ensure-timings": {
+				"tags": {"ensure": "state-engine"},
+				"base": 1700000000,
+				"b": 12000000,
+				"spans": [{"label": "*servstate.ServiceManager", "depth": 1, "a": 1000000, "b": 11000000}]
+			}
+		}}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"debug", "overlord"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `
+ID   Change  Kind   Status  Summary
+3    2       start  Doing   Start service "srv1"
+7    -       -      -       
+
+Task kinds: exec, start, stop
+
+Last ensure took 12ms
+  *servstate.ServiceManager: 10ms
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestDebugOverlordIdle(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": {
+			"running-tasks": [],
+			"task-kinds": ["exec"]
+		}}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"debug", "overlord"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `
+No running tasks.
+
+Task kinds: exec
+
+The ensure loop hasn't run yet.
+`[1:])
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/canonical/go-flags"
+)
+
+var shortDebugStateHelp = "Show the daemon's state"
+var longDebugStateHelp = `
+The state command prints the daemon's state as indented JSON. With
+--section, only that part of the state is printed: "changes", "tasks",
+"warnings", "notices", or "data" (the custom data set by managers).
+`
+
+type cmdDebugState struct {
+	clientMixin
+	Section string `long:"section" choice:"changes" choice:"tasks" choice:"warnings" choice:"notices" choice:"data"`
+}
+
+var shortDebugCheckStateHelp = "Check the consistency of the daemon's state"
+var longDebugCheckStateHelp = `
+The check-state command checks that the changes, tasks and lanes in the
+daemon's state refer to each other consistently, and prints any problems
+found.
+`
+
+type cmdDebugCheckState struct {
+	clientMixin
+}
+
+var shortDebugRemoveChangeHelp = "Forcibly remove a change from the daemon's state"
+var longDebugRemoveChangeHelp = `
+The remove-change command removes a change and all its tasks from the
+daemon's state, whatever their status. It is meant for getting rid of
+changes that are stuck. Tasks of the change that are running are
+stopped first; if they don't stop within 10 seconds, the change is not
+removed.
+`
+
+type cmdDebugRemoveChange struct {
+	clientMixin
+	Positional struct {
+		ID string `positional-arg-name:"<change-id>" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addDebugCommand("state", shortDebugStateHelp, longDebugStateHelp, func() flags.Commander { return &cmdDebugState{} }, map[string]string{
+		"section": "Only show this section of the state",
+	}, nil)
+	addDebugCommand("check-state", shortDebugCheckStateHelp, longDebugCheckStateHelp, func() flags.Commander { return &cmdDebugCheckState{} }, nil, nil)
+	addDebugCommand("remove-change", shortDebugRemoveChangeHelp, longDebugRemoveChangeHelp, func() flags.Commander { return &cmdDebugRemoveChange{} }, nil, nil)
+}
+
+func (cmd *cmdDebugState) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var params map[string]string
+	if cmd.Section != "" {
+		params = map[string]string{"section": cmd.Section}
+	}
+	var result json.RawMessage
+	if err := cmd.client.DebugGet("state", &result, params); err != nil {
+		return err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, result, "", "  "); err != nil {
+		return fmt.Errorf("cannot format state: %v", err)
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(Stdout)
+	return err
+}
+
+func (cmd *cmdDebugCheckState) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var problems []string
+	if err := cmd.client.DebugGet("check-state", &problems, nil); err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Fprintln(Stdout, "No problems found.")
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(Stdout, problem)
+	}
+	return fmt.Errorf("found %d problem(s) in state", len(problems))
+}
+
+func (cmd *cmdDebugRemoveChange) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	params := map[string]string{"change-id": cmd.Positional.ID}
+	if err := cmd.client.DebugPost("remove-change", params, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, "Removed change %s.\n", cmd.Positional.ID)
+	return nil
+}
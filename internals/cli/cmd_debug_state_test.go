@@ -0,0 +1,111 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestDebugState(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v1/debug")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{"action": {"state"}, "section": {"data"}})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": {"foo":"bar","patch-level":1}}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"debug", "state", "--section", "data"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `
+{
+  "foo": "bar",
+  "patch-level": 1
+}
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestDebugStateInvalidSection(c *check.C) {
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"debug", "state", "--section", "foo"})
+	c.Assert(err, check.ErrorMatches, "Invalid value `foo' for option `--section'.*")
+}
+
+func (s *PebbleSuite) TestDebugCheckState(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v1/debug")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{"action": {"check-state"}})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"debug", "check-state"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "No problems found.\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestDebugCheckStateProblems(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": [
+			"change 1 refers to missing task 9",
+			"task 2 waits for missing task 7"
+		]}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"debug", "check-state"})
+	c.Assert(err, check.ErrorMatches, `found 2 problem\(s\) in state`)
+	c.Check(s.Stdout(), check.Equals, `
+change 1 refers to missing task 9
+task 2 waits for missing task 7
+`[1:])
+}
+
+func (s *PebbleSuite) TestDebugRemoveChange(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/debug")
+		body := DecodedRequestBody(c, r)
+		c.Check(body, check.DeepEquals, map[string]interface{}{
+			"action": "remove-change",
+			"params": map[string]interface{}{"change-id": "42"},
+		})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": null}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"debug", "remove-change", "42"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "Removed change 42.\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestDebugRemoveChangeNotFound(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprint(w, `{"type": "error", "status-code": 404, "result": {"message": "cannot find change with id \"42\""}}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"debug", "remove-change", "42"})
+	c.Assert(err, check.ErrorMatches, `cannot find change with id "42"`)
+	c.Check(s.Stdout(), check.Equals, "")
+}
@@ -182,7 +182,7 @@ func (s *PebbleSuite) TestEnterExecCommandHelpOption(c *C) {
 	// see cmd/pebble/cmd_exec.go:163
 	c.Check(s.Stderr(), Equals, "")
 	stdout := s.Stdout()
-	c.Check(stdout, Matches, "^(?s)Usage:\n  pebble exec \\[exec-OPTIONS\\] <command>\n.*")
+	c.Check(stdout, Matches, "^(?s)Usage:\n  pebble exec \\[exec-OPTIONS\\] \\[<command>\\]\n.*")
 	c.Check(stdout, Matches, "(?s).*\\bThe exec command runs a remote command and waits for it to finish\\..*")
 	c.Check(exitCode, Equals, 0)
 }
@@ -212,7 +212,7 @@ func (s *PebbleSuite) TestEnterHelpCommandExecArg(c *C) {
 	// see cmd/pebble/cmd_exec.go:163
 	c.Check(s.Stderr(), Equals, "")
 	stdout := s.Stdout()
-	c.Check(stdout, Matches, "^(?s)Usage:\n  pebble exec \\[exec-OPTIONS\\] <command>\n.*")
+	c.Check(stdout, Matches, "^(?s)Usage:\n  pebble exec \\[exec-OPTIONS\\] \\[<command>\\]\n.*")
 	c.Check(stdout, Matches, "(?s).*\\bThe exec command runs a remote command and waits for it to finish\\..*")
 	c.Check(exitCode, Equals, 0)
 }
@@ -15,10 +15,13 @@
 package cli
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,6 +35,7 @@ import (
 
 type cmdExec struct {
 	clientMixin
+	timeMixin
 	WorkingDir     string        `short:"w"`
 	Env            []string      `long:"env"`
 	UserID         *int          `long:"uid"`
@@ -44,8 +48,18 @@ type cmdExec struct {
 	NoTerminal     bool          `short:"T"`
 	Interactive    bool          `short:"i"`
 	NonInteractive bool          `short:"I"`
+	Detach         bool          `long:"detach"`
+	Attach         string        `long:"attach"`
+	List           bool          `long:"list"`
+	Kill           string        `long:"kill"`
+	Replay         string        `long:"replay"`
+	Nice           int           `long:"nice"`
+	IONice         string        `long:"ionice"`
+	Rlimits        []string      `long:"rlimit"`
+	Memory         string        `long:"memory"`
+	CPUs           float64       `long:"cpus"`
 	Positional     struct {
-		Command string `positional-arg-name:"<command>" required:"1"`
+		Command string `positional-arg-name:"<command>"`
 	} `positional-args:"yes"`
 }
 
@@ -62,6 +76,16 @@ var execDescs = map[string]string{
 	"T":       "Disable remote pseudo-terminal allocation",
 	"i":       "Interactive mode: connect stdin to the pseudo-terminal (default if stdin and stdout are TTYs)",
 	"I":       "Disable interactive mode and use a pipe for stdin",
+	"detach":  "Run command in the background and print its task ID",
+	"attach":  "Attach to the detached command with this task ID and wait for it to finish",
+	"list":    "List running commands instead of running one",
+	"kill":    "Kill the running command with this task ID",
+	"replay":  "Replay the recorded terminal session with this task ID",
+	"nice":    "Scheduling priority (niceness) to run command with, from -20 to 19",
+	"ionice":  "I/O scheduling class and priority (in 'class[:priority]' format, for example 'best-effort:7' or 'idle')",
+	"rlimit":  "Resource limit to set (in 'name=value' format, for example 'nofile=1024' or 'core=unlimited')",
+	"memory":  "Memory limit for the command (for example '512M' or '2G')",
+	"cpus":    "CPU limit for the command in number of CPUs (for example 0.5)",
 }
 
 var shortExecHelp = "Execute a remote command and wait for it to finish"
@@ -74,6 +98,24 @@ To avoid confusion, exec options may be separated from the command and its
 arguments using "--", for example:
 
 pebble exec --timeout 10s -- echo -n foo bar
+
+With --detach, the command runs in the background and its output is buffered
+by the server, which prints the task ID and exits. Use --attach with that task
+ID to print the output so far, follow any new output, and exit with the
+command's exit code. Detaching from the command (for example, with Ctrl+C)
+doesn't stop it.
+
+Use --list to show the commands that are running (including their task IDs),
+and --kill with a task ID to kill one of them.
+
+If the server was started with --record-exec, sessions that use a terminal
+are recorded. Use --replay with a task ID to play back what was shown in the
+terminal, with its original timing (though long pauses are shortened).
+
+The --nice, --ionice, and --rlimit options lower the priority of (or limit)
+commands that would otherwise compete with services for resources. The
+--memory and --cpus options cap the command's memory and CPU usage using a
+cgroup, which requires the server to be running with cgroup v2.
 `
 
 func (cmd *cmdExec) Execute(args []string) error {
@@ -83,24 +125,50 @@ func (cmd *cmdExec) Execute(args []string) error {
 	if cmd.Interactive && cmd.NonInteractive {
 		return errors.New("cannot use -i and -I at the same time")
 	}
+	if cmd.List {
+		if cmd.Positional.Command != "" {
+			return errors.New("cannot specify a command with --list")
+		}
+		return cmd.list()
+	}
+	if cmd.Kill != "" {
+		if cmd.Positional.Command != "" {
+			return errors.New("cannot specify a command with --kill")
+		}
+		return cmd.client.KillExecution(cmd.Kill)
+	}
+	if cmd.Replay != "" {
+		if cmd.Positional.Command != "" {
+			return errors.New("cannot specify a command with --replay")
+		}
+		return cmd.replay()
+	}
+	if cmd.Attach != "" {
+		if cmd.Positional.Command != "" {
+			return errors.New("cannot specify a command with --attach")
+		}
+		return cmd.attach()
+	}
+	if cmd.Positional.Command == "" {
+		return errors.New("must specify command")
+	}
+	if cmd.Detach {
+		if cmd.Terminal || cmd.Interactive {
+			return errors.New("cannot use --detach with -t or -i")
+		}
+		return cmd.detach(args)
+	}
 
 	command := append([]string{cmd.Positional.Command}, args...)
 	logger.Debugf("Executing command %q", command)
 
 	// Set up environment variables.
-	env := make(map[string]string)
+	env := execEnvironment(cmd.Env)
 	term, ok := os.LookupEnv("TERM")
 	if ok {
-		env["TERM"] = term
-	}
-	for _, kv := range cmd.Env {
-		parts := strings.SplitN(kv, "=", 2)
-		key := parts[0]
-		value := ""
-		if len(parts) == 2 {
-			value = parts[1]
+		if _, set := env["TERM"]; !set {
+			env["TERM"] = term
 		}
-		env[key] = value
 	}
 
 	// Specify Terminal=true if -t is given, or if stdout is a TTY.
@@ -162,6 +230,10 @@ func (cmd *cmdExec) Execute(args []string) error {
 		Stdout:         Stdout,
 		Stderr:         Stderr,
 	}
+	err := cmd.setResourceOptions(opts)
+	if err != nil {
+		return err
+	}
 
 	// If stdout and stderr both refer to the same file or device (e.g.,
 	// "/dev/pts/1"), combine stderr into stdout on the server.
@@ -211,6 +283,154 @@ func (cmd *cmdExec) Execute(args []string) error {
 	}
 }
 
+// detach starts the command in detached mode and prints its task ID.
+func (cmd *cmdExec) detach(args []string) error {
+	command := append([]string{cmd.Positional.Command}, args...)
+	logger.Debugf("Executing detached command %q", command)
+
+	opts := &client.ExecOptions{
+		Command:        command,
+		ServiceContext: cmd.Context,
+		Environment:    execEnvironment(cmd.Env),
+		WorkingDir:     cmd.WorkingDir,
+		Timeout:        cmd.Timeout,
+		UserID:         cmd.UserID,
+		User:           cmd.User,
+		GroupID:        cmd.GroupID,
+		Group:          cmd.Group,
+		Detached:       true,
+	}
+	err := cmd.setResourceOptions(opts)
+	if err != nil {
+		return err
+	}
+	process, err := cmd.client.Exec(opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(Stdout, process.TaskID())
+	return nil
+}
+
+// list prints a table of the running commands.
+func (cmd *cmdExec) list() error {
+	executions, err := cmd.client.Executions()
+	if err != nil {
+		return err
+	}
+	if len(executions) == 0 {
+		fmt.Fprintln(Stderr, "No running commands.")
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, "ID\tPID\tUser\tStarted\tStreams\tCommand")
+	for _, e := range executions {
+		user := e.User
+		if user == "" {
+			user = strconv.Itoa(e.UserID)
+		}
+		streams := strings.Join(e.Streams, ",")
+		if e.Detached {
+			streams = "detached"
+			if len(e.Streams) > 0 {
+				streams += "," + strings.Join(e.Streams, ",")
+			}
+		} else if streams == "" {
+			streams = "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			e.TaskID, e.PID, user, cmd.fmtTime(e.StartTime), streams, strings.Join(e.Command, " "))
+	}
+	return nil
+}
+
+// replayMaxIdle is the longest pause between terminal output when replaying
+// a session.
+const replayMaxIdle = 2 * time.Second
+
+var replaySleep = time.Sleep
+
+// replay plays back the terminal output of a recorded session.
+func (cmd *cmdExec) replay() error {
+	reader, err := cmd.client.ExecRecording(cmd.Replay)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// The recording is in asciicast v2 format: a header line, followed by
+	// one [time, type, data] event per line.
+	decoder := json.NewDecoder(reader)
+	var header struct {
+		Version int `json:"version"`
+	}
+	err = decoder.Decode(&header)
+	if err != nil {
+		return fmt.Errorf("cannot decode recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("cannot replay recording with version %d", header.Version)
+	}
+
+	var last float64
+	for {
+		var event []interface{}
+		err := decoder.Decode(&event)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot decode recording event: %w", err)
+		}
+		if len(event) != 3 {
+			return fmt.Errorf("invalid recording event %v", event)
+		}
+		t, ok1 := event[0].(float64)
+		kind, ok2 := event[1].(string)
+		data, ok3 := event[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			return fmt.Errorf("invalid recording event %v", event)
+		}
+		if kind != "o" {
+			continue // only replay output
+		}
+		delay := time.Duration((t - last) * float64(time.Second))
+		if delay > replayMaxIdle {
+			delay = replayMaxIdle
+		}
+		if delay > 0 {
+			replaySleep(delay)
+		}
+		last = t
+		fmt.Fprint(Stdout, data)
+	}
+}
+
+// attach attaches to a detached command, printing its output and waiting
+// for it to finish.
+func (cmd *cmdExec) attach() error {
+	process, err := cmd.client.ExecAttach(&client.ExecAttachOptions{
+		TaskID: cmd.Attach,
+		Stdout: Stdout,
+	})
+	if err != nil {
+		return err
+	}
+	err = process.Wait()
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *client.ExitError:
+		logger.Debugf("Process exited with code %d", e.ExitCode())
+		panic(&exitStatus{e.ExitCode()})
+	default:
+		return err
+	}
+}
+
 func execControlHandler(process *client.ExecProcess, terminal bool, stop <-chan struct{}, sighup chan<- struct{}) {
 	ch := make(chan os.Signal, 10)
 	signal.Notify(ch,
@@ -266,8 +486,85 @@ func execControlHandler(process *client.ExecProcess, terminal bool, stop <-chan
 	}
 }
 
+// setResourceOptions sets the resource control options from the flags.
+func (cmd *cmdExec) setResourceOptions(opts *client.ExecOptions) error {
+	opts.Nice = cmd.Nice
+	if cmd.IONice != "" {
+		parts := strings.SplitN(cmd.IONice, ":", 2)
+		opts.IOClass = parts[0]
+		if len(parts) == 2 {
+			priority, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return fmt.Errorf("invalid I/O priority %q", parts[1])
+			}
+			opts.IOPriority = &priority
+		}
+	}
+	for _, kv := range cmd.Rlimits {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid resource limit %q (must be in 'name=value' format)", kv)
+		}
+		if opts.Rlimits == nil {
+			opts.Rlimits = make(map[string]string)
+		}
+		opts.Rlimits[parts[0]] = parts[1]
+	}
+	if cmd.Memory != "" {
+		memory, err := parseByteSize(cmd.Memory)
+		if err != nil {
+			return err
+		}
+		opts.MemoryLimit = memory
+	}
+	opts.CPULimit = cmd.CPUs
+	return nil
+}
+
+// parseByteSize parses a size like "512M" or "2G" (with binary multiples)
+// into a number of bytes.
+func parseByteSize(s string) (int64, error) {
+	multiplier := int64(1)
+	number := s
+	if len(s) > 0 {
+		switch s[len(s)-1] {
+		case 'K', 'k':
+			multiplier = 1 << 10
+		case 'M', 'm':
+			multiplier = 1 << 20
+		case 'G', 'g':
+			multiplier = 1 << 30
+		case 'T', 't':
+			multiplier = 1 << 40
+		}
+		if multiplier != 1 {
+			number = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (must be a number of bytes, optionally followed by K, M, G, or T)", s)
+	}
+	return n * multiplier, nil
+}
+
+// execEnvironment converts a list of "FOO=bar" strings into a map.
+func execEnvironment(kvs []string) map[string]string {
+	env := make(map[string]string)
+	for _, kv := range kvs {
+		parts := strings.SplitN(kv, "=", 2)
+		key := parts[0]
+		value := ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+		env[key] = value
+	}
+	return env
+}
+
 func init() {
-	info := addCommand("exec", shortExecHelp, longExecHelp, func() flags.Commander { return &cmdExec{} }, execDescs, nil)
+	info := addCommand("exec", shortExecHelp, longExecHelp, func() flags.Commander { return &cmdExec{} }, merge(execDescs, timeDescs), nil)
 	info.extra = func(cmd *flags.Command) {
 		cmd.PassAfterNonOption = true
 	}
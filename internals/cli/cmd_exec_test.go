@@ -0,0 +1,123 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestExecList(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, check.Equals, "GET")
+		c.Assert(r.URL.Path, check.Equals, "/v1/exec")
+		fmt.Fprint(w, `{
+    "type": "sync",
+    "status-code": 200,
+    "result": [
+		{"task-id": "1", "command": ["sleep", "100"], "user-id": 0, "user": "root", "start-time": "2023-03-09T10:00:00Z", "pid": 42, "detached": true, "streams": []},
+		{"task-id": "3", "command": ["bash"], "user-id": 1000, "start-time": "2023-03-09T11:00:00Z", "pid": 43, "terminal": true, "streams": ["control", "stdio"]}
+	]
+}`)
+	})
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"exec", "--list", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `
+ID   PID  User  Started               Streams        Command
+1    42   root  2023-03-09T10:00:00Z  detached       sleep 100
+3    43   1000  2023-03-09T11:00:00Z  control,stdio  bash
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestExecListNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, check.Equals, "GET")
+		c.Assert(r.URL.Path, check.Equals, "/v1/exec")
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"exec", "--list"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No running commands.\n")
+}
+
+func (s *PebbleSuite) TestExecKill(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, check.Equals, "POST")
+		c.Assert(r.URL.Path, check.Equals, "/v1/exec/42")
+		var body map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		c.Assert(err, check.IsNil)
+		c.Check(body, check.DeepEquals, map[string]interface{}{"action": "kill"})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": true}`)
+	})
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"exec", "--kill", "42"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestExecListWithCommand(c *check.C) {
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"exec", "--list", "echo"})
+	c.Assert(err, check.ErrorMatches, "cannot specify a command with --list")
+}
+
+func (s *PebbleSuite) TestExecReplay(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, check.Equals, "GET")
+		c.Assert(r.URL.Path, check.Equals, "/v1/exec/42/recording")
+		fmt.Fprint(w, `{"version": 2, "width": 80, "height": 24, "timestamp": 1678356000}
+[0.5, "o", "$ "]
+[1.25, "i", "ls\r"]
+[1.5, "o", "ls\r\n"]
+[1.75, "r", "100x30"]
+[60, "o", "foo\r\n"]
+`)
+	})
+	var sleeps []time.Duration
+	restore := cli.FakeReplaySleep(func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	})
+	defer restore()
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"exec", "--replay", "42"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "$ ls\r\nfoo\r\n")
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(sleeps, check.DeepEquals, []time.Duration{
+		500 * time.Millisecond,
+		time.Second,
+		2 * time.Second, // long pauses are shortened
+	})
+}
+
+func (s *PebbleSuite) TestExecReplayBadVersion(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"version": 1}`)
+	})
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"exec", "--replay", "42"})
+	c.Assert(err, check.ErrorMatches, "cannot replay recording with version 1")
+}
@@ -176,7 +176,7 @@ var helpCategories = []helpCategory{{
 }, {
 	Label:       "Services",
 	Description: "manage services",
-	Commands:    []string{"services", "logs", "checks", "start", "restart", "signal", "stop", "replan"},
+	Commands:    []string{"services", "logs", "checks", "check", "start", "restart", "signal", "stop", "replan"},
 }, {
 	Label:       "Files",
 	Description: "work with files and execute commands",
@@ -184,11 +184,15 @@ var helpCategories = []helpCategory{{
 }, {
 	Label:       "Changes",
 	Description: "manage changes and their tasks",
-	Commands:    []string{"changes", "tasks"},
+	Commands:    []string{"changes", "tasks", "abort", "retry", "prune"},
 }, {
 	Label:       "Warnings",
 	Description: "manage warnings",
-	Commands:    []string{"warnings", "okay"},
+	Commands:    []string{"warnings", "okay", "warn"},
+}, {
+	Label:       "Notices",
+	Description: "manage notices",
+	Commands:    []string{"notices", "notify"},
 }}
 
 var (
@@ -0,0 +1,90 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/canonical/go-flags"
+
+	"github.com/canonical/pebble/client"
+)
+
+var shortNoticesHelp = "List notices"
+var longNoticesHelp = `
+The notices command lists notices, ordered by the time they last repeated.
+With --timeout, it waits up to that long for matching notices to occur if
+there are none yet.
+`
+
+type cmdNotices struct {
+	clientMixin
+	timeMixin
+	Types   []string      `long:"type"`
+	Keys    []string      `long:"key"`
+	Timeout time.Duration `long:"timeout"`
+}
+
+func init() {
+	addCommand("notices", shortNoticesHelp, longNoticesHelp, func() flags.Commander { return &cmdNotices{} }, merge(timeDescs, map[string]string{
+		"type":    "Only list notices of this type (can be repeated)",
+		"key":     "Only list notices with this key (can be repeated)",
+		"timeout": "Wait up to this long for notices to occur",
+	}), nil)
+}
+
+func (cmd *cmdNotices) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	opts := client.NoticesOptions{
+		Keys: cmd.Keys,
+	}
+	for _, t := range cmd.Types {
+		opts.Types = append(opts.Types, client.NoticeType(t))
+	}
+
+	var notices []*client.Notice
+	var err error
+	if cmd.Timeout != 0 {
+		notices, err = cmd.client.WaitNotices(&opts, cmd.Timeout)
+	} else {
+		notices, err = cmd.client.Notices(&opts)
+	}
+	if err != nil {
+		return err
+	}
+	if len(notices) == 0 {
+		fmt.Fprintln(Stderr, "No matching notices.")
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, "ID\tType\tKey\tFirst\tRepeated\tOccurrences")
+	for _, notice := range notices {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n",
+			notice.ID,
+			notice.Type,
+			notice.Key,
+			cmd.fmtTime(notice.FirstOccurred),
+			cmd.fmtTime(notice.LastRepeated),
+			notice.Occurrences)
+	}
+	return nil
+}
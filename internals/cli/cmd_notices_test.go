@@ -0,0 +1,81 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestNotices(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v1/notices")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+			"types": {"custom"},
+			"keys":  {"example.com/a,example.com/b"},
+		})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": [{
+			"id": "1",
+			"type": "custom",
+			"key": "example.com/a",
+			"first-occurred": "2023-09-05T15:43:00Z",
+			"last-occurred": "2023-09-05T17:43:00Z",
+			"last-repeated": "2023-09-05T16:43:00Z",
+			"occurrences": 3,
+			"expire-after": "168h0m0s"
+		}, {
+			"id": "2",
+			"type": "custom",
+			"key": "example.com/b",
+			"first-occurred": "2023-09-06T15:43:00Z",
+			"last-occurred": "2023-09-06T15:43:00Z",
+			"last-repeated": "2023-09-06T15:43:00Z",
+			"occurrences": 1,
+			"expire-after": "168h0m0s"
+		}]}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{
+		"notices", "--abs-time", "--type", "custom", "--key", "example.com/a", "--key", "example.com/b"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `
+ID   Type    Key            First                 Repeated              Occurrences
+1    custom  example.com/a  2023-09-05T15:43:00Z  2023-09-05T16:43:00Z  3
+2    custom  example.com/b  2023-09-06T15:43:00Z  2023-09-06T15:43:00Z  1
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestNoticesTimeout(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v1/notices")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{"timeout": {"1s"}})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"notices", "--timeout", "1s"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No matching notices.\n")
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canonical/go-flags"
+
+	"github.com/canonical/pebble/client"
+)
+
+var shortNotifyHelp = "Record a custom notice"
+var longNotifyHelp = `
+The notify command records a custom notice with the specified key and optional
+data fields. The key must be in the form "domain.com/key", for example:
+
+pebble notify example.com/config-changed path=/etc/app.conf
+
+If a notice with the same key already exists, its occurrence count and
+last-occurred time are updated rather than a new notice being recorded.
+`
+
+type cmdNotify struct {
+	clientMixin
+	RepeatAfter time.Duration `long:"repeat-after"`
+	ExpireAfter time.Duration `long:"expire-after"`
+	Positional  struct {
+		Key  string   `positional-arg-name:"<key>" required:"1"`
+		Data []string `positional-arg-name:"<name=value>"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addCommand("notify", shortNotifyHelp, longNotifyHelp, func() flags.Commander { return &cmdNotify{} }, map[string]string{
+		"repeat-after": "Only repeat the notice if it occurs this long after it was last repeated",
+		"expire-after": "Expire the notice this long after it last occurred (default 168h)",
+	}, nil)
+}
+
+func (cmd *cmdNotify) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var data map[string]string
+	for _, kv := range cmd.Positional.Data {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("data must be in the form name=value, not %q", kv)
+		}
+		if data == nil {
+			data = make(map[string]string)
+		}
+		data[parts[0]] = parts[1]
+	}
+
+	opts := client.NotifyOptions{
+		Key:         cmd.Positional.Key,
+		Data:        data,
+		RepeatAfter: cmd.RepeatAfter,
+		ExpireAfter: cmd.ExpireAfter,
+	}
+	noticeID, err := cmd.client.Notify(&opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, "Recorded notice %s\n", noticeID)
+	return nil
+}
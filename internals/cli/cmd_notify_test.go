@@ -0,0 +1,65 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestNotify(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		body := DecodedRequestBody(c, r)
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/notices")
+		c.Check(body, check.DeepEquals, map[string]interface{}{
+			"action":       "add",
+			"type":         "custom",
+			"key":          "example.com/config-changed",
+			"repeat-after": "1h0m0s",
+			"expire-after": "2h0m0s",
+			"data":         map[string]interface{}{"path": "/etc/a=b.conf", "empty": ""},
+		})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": {"id": "42"}}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{
+		"notify", "--repeat-after", "1h", "--expire-after", "2h", "example.com/config-changed", "path=/etc/a=b.conf", "empty="})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "Recorded notice 42\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestNotifyInvalidData(c *check.C) {
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"notify", "example.com/foo", "bar"})
+	c.Assert(err, check.ErrorMatches, `data must be in the form name=value, not "bar"`)
+
+	_, err = cli.Parser(cli.Client()).ParseArgs([]string{"notify", "example.com/foo", "=bar"})
+	c.Assert(err, check.ErrorMatches, `data must be in the form name=value, not "=bar"`)
+}
+
+func (s *PebbleSuite) TestNotifyError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type": "error", "status-code": 400, "result": {"message": "custom notice key must be in the form \"domain.com/key\", not \"foo\""}}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"notify", "foo"})
+	c.Assert(err, check.ErrorMatches, `custom notice key must be in the form "domain.com/key", not "foo"`)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/canonical/go-flags"
+)
+
+var shortPruneHelp = "Prune old changes"
+var longPruneHelp = `
+The prune command removes old changes, warnings and notices from the daemon's
+state now, rather than waiting for the next periodic prune. Which changes are
+old is set by the --prune-wait, --prune-kind and --prune-max-changes options
+to "pebble run", or its --prune-config file. It also removes old exec
+recordings.
+
+Pruning requires admin access.
+`
+
+type cmdPrune struct {
+	clientMixin
+}
+
+func init() {
+	addCommand("prune", shortPruneHelp, longPruneHelp, func() flags.Commander { return &cmdPrune{} }, nil, nil)
+}
+
+func (cmd *cmdPrune) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	pruned, err := cmd.client.Prune()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, "Pruned %d change(s)\n", pruned)
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"github.com/canonical/go-flags"
+)
+
+var shortRetryHelp = "Retry a failed change"
+var longRetryHelp = `
+The retry command runs the tasks of a change that failed or was aborted
+again, in a new change, and waits for the new change to finish. Only
+service changes (such as start, stop, and replan) can be retried.
+`
+
+type cmdRetry struct {
+	waitMixin
+	Positional struct {
+		ID string `positional-arg-name:"<change-id>" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addCommand("retry", shortRetryHelp, longRetryHelp, func() flags.Commander { return &cmdRetry{} }, waitDescs, nil)
+}
+
+func (cmd *cmdRetry) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	changeID, err := cmd.client.Retry(cmd.Positional.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := cmd.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestRetry(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/changes/43" {
+			c.Check(r.Method, check.Equals, "GET")
+			fmt.Fprint(w, `{
+	"type": "sync",
+	"result": {
+		"id": "43",
+		"kind": "start",
+		"summary": "...",
+		"status": "Done",
+		"ready": true,
+		"spawn-time": "2016-04-21T01:02:03Z",
+		"ready-time": "2016-04-21T01:02:04Z",
+		"tasks": []
+	}
+}`)
+			return
+		}
+
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/changes/42")
+		body := DecodedRequestBody(c, r)
+		c.Check(body, check.DeepEquals, map[string]interface{}{
+			"action": "retry",
+		})
+		fmt.Fprint(w, `{"type": "async", "status-code": 202, "change": "43"}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"retry", "42"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestRetryNoWait(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/changes/42")
+		fmt.Fprint(w, `{"type": "async", "status-code": 202, "change": "43"}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"retry", "42", "--no-wait"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "43\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestRetryFails(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type": "error", "result": {"message": "cannot retry change 42 with status Done"}}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"retry", "42"})
+	c.Assert(err, check.ErrorMatches, "cannot retry change 42 with status Done")
+}
@@ -15,19 +15,26 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/canonical/go-flags"
+	"gopkg.in/yaml.v3"
 
 	"github.com/canonical/pebble/client"
 	"github.com/canonical/pebble/cmd"
 	"github.com/canonical/pebble/internals/daemon"
 	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/overlord"
+	"github.com/canonical/pebble/internals/overlord/cmdstate"
 	"github.com/canonical/pebble/internals/systemd"
 )
 
@@ -44,19 +51,41 @@ in the service plan. For example:
 `
 
 type sharedRunEnterOpts struct {
-	CreateDirs bool       `long:"create-dirs"`
-	Hold       bool       `long:"hold"`
-	HTTP       string     `long:"http"`
-	Verbose    bool       `short:"v" long:"verbose"`
-	Args       [][]string `long:"args" terminator:";"`
+	CreateDirs          bool           `long:"create-dirs"`
+	Hold                bool           `long:"hold"`
+	HTTP                string         `long:"http"`
+	Health              string         `long:"health"`
+	RecordExec          bool           `long:"record-exec"`
+	PruneConfig         string         `long:"prune-config"`
+	PruneInterval       *time.Duration `long:"prune-interval"`
+	PruneWait           *time.Duration `long:"prune-wait"`
+	AbortWait           *time.Duration `long:"abort-wait"`
+	PruneMaxChanges     *int           `long:"prune-max-changes"`
+	PruneKinds          []string       `long:"prune-kind"`
+	PruneRecordingsWait *time.Duration `long:"prune-recordings-wait"`
+	PruneMaxRecordings  *int           `long:"prune-max-recordings"`
+	StateBackend        string         `long:"state-backend" default:"snapshot" choice:"snapshot" choice:"journal"`
+	Verbose             bool           `short:"v" long:"verbose"`
+	Args                [][]string     `long:"args" terminator:";"`
 }
 
 var sharedRunEnterOptsHelp = map[string]string{
-	"create-dirs": "Create pebble directory on startup if it doesn't exist",
-	"hold":        "Do not start default services automatically",
-	"http":        `Start HTTP API listening on this address (e.g., ":4000")`,
-	"verbose":     "Log all output from services to stdout",
-	"args":        `Provide additional arguments to a service`,
+	"create-dirs":           "Create pebble directory on startup if it doesn't exist",
+	"hold":                  "Do not start default services automatically",
+	"http":                  `Start HTTP API listening on this address (e.g., ":4000")`,
+	"health":                `Start health listener (/livez, /readyz, /checks/<name>) on this address (e.g., ":8081")`,
+	"record-exec":           "Record exec sessions that use a terminal (see \"pebble exec --replay\")",
+	"prune-config":          "Read prune options from this YAML file; command line options override it",
+	"prune-interval":        "How often to prune old changes (default 10m)",
+	"prune-wait":            "How long to keep changes after they're ready (default 24h)",
+	"abort-wait":            "How long to wait before aborting changes that aren't ready (default 168h)",
+	"prune-max-changes":     "Maximum number of ready changes to keep (default 500)",
+	"prune-kind":            "How long to keep changes of a kind, in the form <kind>=<duration> (can be repeated)",
+	"prune-recordings-wait": "How long to keep exec recordings (default 720h)",
+	"prune-max-recordings":  "Maximum number of exec recordings to keep (default 100)",
+	"state-backend":         "How to persist state: rewrite a snapshot on every change, or append to a journal",
+	"verbose":               "Log all output from services to stdout",
+	"args":                  `Provide additional arguments to a service`,
 }
 
 type cmdRun struct {
@@ -90,6 +119,11 @@ func (rcmd *cmdRun) run(ready chan<- func()) {
 			// This exit code must be in system'd SuccessExitStatus.
 			panic(&exitStatus{42})
 		}
+		if exitErr, ok := err.(*daemon.ExitError); ok {
+			// Exit requested by a check action, so not an error either.
+			fmt.Fprintf(os.Stdout, "%v\n", err)
+			panic(&exitStatus{exitErr.Code})
+		}
 		fmt.Fprintf(os.Stderr, "cannot run pebble: %v\n", err)
 		panic(&exitStatus{1})
 	}
@@ -149,6 +183,22 @@ func runDaemon(rcmd *cmdRun, ch chan os.Signal, ready chan<- func()) error {
 		dopts.ServiceOutput = os.Stdout
 	}
 	dopts.HTTPAddress = rcmd.HTTP
+	dopts.HealthAddress = rcmd.Health
+	dopts.RecordExec = rcmd.RecordExec
+	prune, err := pruneOptions(&rcmd.sharedRunEnterOpts)
+	if err != nil {
+		return err
+	}
+	dopts.Prune = prune
+	dopts.StateBackend = rcmd.StateBackend
+
+	// Do this before any services are started, so only Pebble itself is
+	// moved to a new cgroup, if needed.
+	if leaf, err := cmdstate.PrepareCgroup(); err != nil {
+		logger.Noticef("Cannot prepare cgroup for exec memory and CPU limits: %v", err)
+	} else if leaf != "" {
+		logger.Noticef("Moved Pebble into cgroup %q so that exec commands can have memory and CPU limits.", leaf)
+	}
 
 	d, err := daemon.New(&dopts)
 	if err != nil {
@@ -237,6 +287,116 @@ out:
 	return d.Stop(ch)
 }
 
+// pruneConfig is the format of the file given with --prune-config. Fields
+// that aren't set keep their defaults.
+type pruneConfig struct {
+	Interval       *string           `yaml:"interval"`
+	Wait           *string           `yaml:"wait"`
+	AbortWait      *string           `yaml:"abort-wait"`
+	MaxChanges     *int              `yaml:"max-changes"`
+	Kinds          map[string]string `yaml:"kinds"`
+	RecordingsWait *string           `yaml:"recordings-wait"`
+	MaxRecordings  *int              `yaml:"max-recordings"`
+}
+
+// readPruneConfig reads the prune options from the given YAML file.
+func readPruneConfig(path string) (overlord.PruneOptions, error) {
+	var prune overlord.PruneOptions
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return prune, fmt.Errorf("cannot read prune config: %w", err)
+	}
+	var config pruneConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && err != io.EOF {
+		return prune, fmt.Errorf("cannot parse prune config %q: %w", path, err)
+	}
+
+	durations := []struct {
+		name  string
+		value *string
+		field **time.Duration
+	}{
+		{"interval", config.Interval, &prune.Interval},
+		{"wait", config.Wait, &prune.Wait},
+		{"abort-wait", config.AbortWait, &prune.AbortWait},
+		{"recordings-wait", config.RecordingsWait, &prune.RecordingsWait},
+	}
+	for _, d := range durations {
+		if d.value == nil {
+			continue
+		}
+		value, err := time.ParseDuration(*d.value)
+		if err != nil {
+			return prune, fmt.Errorf("invalid prune config %s %q", d.name, *d.value)
+		}
+		*d.field = &value
+	}
+	prune.MaxChanges = config.MaxChanges
+	prune.MaxRecordings = config.MaxRecordings
+	for kind, value := range config.Kinds {
+		wait, err := time.ParseDuration(value)
+		if err != nil {
+			return prune, fmt.Errorf("invalid prune config duration %q for kind %q", value, kind)
+		}
+		if prune.KindWaits == nil {
+			prune.KindWaits = make(map[string]time.Duration)
+		}
+		prune.KindWaits[kind] = wait
+	}
+	return prune, nil
+}
+
+// pruneOptions returns the daemon's prune options from the --prune-config
+// file, if any, overridden by the other command line options.
+func pruneOptions(opts *sharedRunEnterOpts) (overlord.PruneOptions, error) {
+	var prune overlord.PruneOptions
+	if opts.PruneConfig != "" {
+		var err error
+		prune, err = readPruneConfig(opts.PruneConfig)
+		if err != nil {
+			return overlord.PruneOptions{}, err
+		}
+	}
+	if opts.PruneInterval != nil {
+		prune.Interval = opts.PruneInterval
+	}
+	if opts.PruneWait != nil {
+		prune.Wait = opts.PruneWait
+	}
+	if opts.AbortWait != nil {
+		prune.AbortWait = opts.AbortWait
+	}
+	if opts.PruneMaxChanges != nil {
+		prune.MaxChanges = opts.PruneMaxChanges
+	}
+	if opts.PruneRecordingsWait != nil {
+		prune.RecordingsWait = opts.PruneRecordingsWait
+	}
+	if opts.PruneMaxRecordings != nil {
+		prune.MaxRecordings = opts.PruneMaxRecordings
+	}
+	for _, kindWait := range opts.PruneKinds {
+		parts := strings.SplitN(kindWait, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return overlord.PruneOptions{}, fmt.Errorf("--prune-kind must be in the form <kind>=<duration>, not %q", kindWait)
+		}
+		wait, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return overlord.PruneOptions{}, fmt.Errorf("invalid --prune-kind duration %q", parts[1])
+		}
+		if prune.KindWaits == nil {
+			prune.KindWaits = make(map[string]time.Duration)
+		}
+		prune.KindWaits[parts[0]] = wait
+	}
+	if err := prune.Validate(); err != nil {
+		return overlord.PruneOptions{}, err
+	}
+	return prune, nil
+}
+
 // convert args from [][]string type to map[string][]string
 // and check for empty or duplicated --args usage
 func convertArgs(args [][]string) (map[string][]string, error) {
@@ -0,0 +1,112 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestPruneOptionsDefault(c *check.C) {
+	prune, err := cli.ParsePruneOptions(nil)
+	c.Assert(err, check.IsNil)
+	c.Check(prune.Interval, check.IsNil)
+	c.Check(prune.Wait, check.IsNil)
+	c.Check(prune.AbortWait, check.IsNil)
+	c.Check(prune.MaxChanges, check.IsNil)
+	c.Check(prune.KindWaits, check.IsNil)
+	c.Check(prune.RecordingsWait, check.IsNil)
+	c.Check(prune.MaxRecordings, check.IsNil)
+}
+
+func (s *PebbleSuite) TestPruneOptionsFlags(c *check.C) {
+	prune, err := cli.ParsePruneOptions([]string{
+		"--prune-interval", "1m", "--prune-wait", "0", "--abort-wait", "2h",
+		"--prune-max-changes", "0", "--prune-kind", "exec=1h",
+		"--prune-recordings-wait", "3h", "--prune-max-recordings", "0",
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(*prune.Interval, check.Equals, time.Minute)
+	c.Check(*prune.Wait, check.Equals, time.Duration(0))
+	c.Check(*prune.AbortWait, check.Equals, 2*time.Hour)
+	c.Check(*prune.MaxChanges, check.Equals, 0)
+	c.Check(prune.KindWaits, check.DeepEquals, map[string]time.Duration{"exec": time.Hour})
+	c.Check(*prune.RecordingsWait, check.Equals, 3*time.Hour)
+	c.Check(*prune.MaxRecordings, check.Equals, 0)
+}
+
+func (s *PebbleSuite) TestPruneOptionsConfig(c *check.C) {
+	path := filepath.Join(c.MkDir(), "prune.yaml")
+	err := ioutil.WriteFile(path, []byte(`
+interval: 5m
+wait: 48h
+max-changes: 0
+kinds:
+    exec: 1h
+    start: 168h
+max-recordings: 10
+`), 0644)
+	c.Assert(err, check.IsNil)
+
+	// Command line options override the file.
+	prune, err := cli.ParsePruneOptions([]string{
+		"--prune-config", path, "--prune-wait", "12h", "--prune-kind", "exec=2h",
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(*prune.Interval, check.Equals, 5*time.Minute)
+	c.Check(*prune.Wait, check.Equals, 12*time.Hour)
+	c.Check(prune.AbortWait, check.IsNil)
+	c.Check(*prune.MaxChanges, check.Equals, 0)
+	c.Check(prune.KindWaits, check.DeepEquals, map[string]time.Duration{
+		"exec":  2 * time.Hour,
+		"start": 168 * time.Hour,
+	})
+	c.Check(prune.RecordingsWait, check.IsNil)
+	c.Check(*prune.MaxRecordings, check.Equals, 10)
+}
+
+func (s *PebbleSuite) TestPruneOptionsErrors(c *check.C) {
+	dir := c.MkDir()
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(content), 0644)
+		c.Assert(err, check.IsNil)
+		return path
+	}
+
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--prune-interval", "0"}, "prune interval must be positive"},
+		{[]string{"--prune-wait", "-1h"}, "prune durations must not be negative"},
+		{[]string{"--prune-max-recordings", "-1"}, "prune limits must not be negative"},
+		{[]string{"--prune-kind", "exec"}, `--prune-kind must be in the form <kind>=<duration>, not "exec"`},
+		{[]string{"--prune-kind", "exec=foo"}, `invalid --prune-kind duration "foo"`},
+		{[]string{"--prune-kind", "exec=-1h"}, `prune duration for "exec" changes must not be negative`},
+		{[]string{"--prune-config", filepath.Join(dir, "missing.yaml")}, "cannot read prune config: .*"},
+		{[]string{"--prune-config", writeConfig("unknown.yaml", "foo: bar")}, `(?s)cannot parse prune config ".*": .*field foo not found.*`},
+		{[]string{"--prune-config", writeConfig("wait.yaml", "wait: foo")}, `invalid prune config wait "foo"`},
+		{[]string{"--prune-config", writeConfig("kinds.yaml", "kinds: {exec: foo}")}, `invalid prune config duration "foo" for kind "exec"`},
+	} {
+		_, err := cli.ParsePruneOptions(test.args)
+		c.Check(err, check.ErrorMatches, test.err, check.Commentf("%v", test.args))
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"errors"
+	"time"
+
+	"github.com/canonical/go-flags"
+
+	"github.com/canonical/pebble/client"
+)
+
+var shortWarnHelp = "Add a warning"
+var longWarnHelp = `
+The warn command adds a warning with the given message, to be listed by
+'pebble warnings'. If a warning with the same message already exists, it is
+recorded as having happened again.
+
+With --delete, the warning with the given message is deleted instead.
+
+Adding and deleting warnings requires admin access.
+`
+
+type cmdWarn struct {
+	clientMixin
+	RepeatAfter time.Duration `long:"repeat-after"`
+	ExpireAfter time.Duration `long:"expire-after"`
+	Delete      bool          `long:"delete"`
+	Positional  struct {
+		Message string `positional-arg-name:"<message>" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addCommand("warn", shortWarnHelp, longWarnHelp, func() flags.Commander { return &cmdWarn{} }, map[string]string{
+		"repeat-after": "Show the warning again this long after it's acknowledged (default 24h)",
+		"expire-after": "Forget the warning this long after it last happened (default 672h)",
+		"delete":       "Delete the warning instead of adding it",
+	}, nil)
+}
+
+func (cmd *cmdWarn) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	if cmd.Delete {
+		if cmd.RepeatAfter != 0 || cmd.ExpireAfter != 0 {
+			return errors.New("cannot use --repeat-after or --expire-after with --delete")
+		}
+		return cmd.client.DeleteWarning(cmd.Positional.Message)
+	}
+
+	return cmd.client.AddWarning(cmd.Positional.Message, &client.AddWarningOptions{
+		RepeatAfter: cmd.RepeatAfter,
+		ExpireAfter: cmd.ExpireAfter,
+	})
+}
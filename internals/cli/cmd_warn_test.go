@@ -0,0 +1,88 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/cli"
+)
+
+func (s *PebbleSuite) TestWarn(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/warnings")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":       "add",
+			"message":      "disk is full",
+			"repeat-after": "1h0m0s",
+			"expire-after": "48h0m0s",
+		})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": null}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"warn", "--repeat-after", "1h", "--expire-after", "48h", "disk is full"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestWarnDefaults(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":  "add",
+			"message": "disk is full",
+		})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": null}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"warn", "disk is full"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *PebbleSuite) TestWarnDelete(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/warnings")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":  "delete",
+			"message": "disk is full",
+		})
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": null}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"warn", "--delete", "disk is full"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
+func (s *PebbleSuite) TestWarnDeleteWithDurations(c *check.C) {
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"warn", "--delete", "--repeat-after", "1h", "disk is full"})
+	c.Assert(err, check.ErrorMatches, "cannot use --repeat-after or --expire-after with --delete")
+}
+
+func (s *PebbleSuite) TestWarnError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprint(w, `{"type": "error", "status-code": 400, "result": {"message": "cannot add warning: malformed warning message"}}`)
+	})
+
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"warn", " foo "})
+	c.Assert(err, check.ErrorMatches, "cannot add warning: malformed warning message")
+}
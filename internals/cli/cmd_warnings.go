@@ -35,11 +35,18 @@ type cmdWarnings struct {
 	clientMixin
 	timeMixin
 	unicodeMixin
-	All     bool `long:"all"`
-	Verbose bool `long:"verbose"`
+	All     bool   `long:"all"`
+	Verbose bool   `long:"verbose"`
+	Text    string `long:"text"`
+	Since   string `long:"since"`
 }
 
-type cmdOkay struct{ clientMixin }
+type cmdOkay struct {
+	clientMixin
+	Positional struct {
+		Message string `positional-arg-name:"<message>"`
+	} `positional-args:"yes"`
+}
 
 var shortWarningsHelp = "List warnings"
 var longWarningsHelp = `
@@ -58,14 +65,22 @@ The okay command acknowledges the warnings listed with 'pebble warnings'.
 
 Once acknowledged, a warning won't appear again unless it reoccurs and
 sufficient time has passed.
+
+If a message is given, only the warning with that exact message is
+acknowledged, whether or not it has been listed.
 `
 
 func init() {
 	addCommand("warnings", shortWarningsHelp, longWarningsHelp, func() flags.Commander { return &cmdWarnings{} }, merge(timeDescs, unicodeDescs, map[string]string{
 		"all":     "Show all warnings",
 		"verbose": "Show more information",
+		"text":    "Only show warnings whose message contains this text",
+		"since":   "Only show warnings last added after this time (RFC3339, or a duration ago such as 1h)",
 	}), nil)
-	addCommand("okay", shortOkayHelp, longOkayHelp, func() flags.Commander { return &cmdOkay{} }, nil, nil)
+	addCommand("okay", shortOkayHelp, longOkayHelp, func() flags.Commander { return &cmdOkay{} }, nil, []argDesc{{
+		name: "<message>",
+		desc: "Only acknowledge the warning with this message",
+	}})
 }
 
 func (cmd *cmdWarnings) Execute(args []string) error {
@@ -74,7 +89,18 @@ func (cmd *cmdWarnings) Execute(args []string) error {
 	}
 	now := time.Now()
 
-	warnings, err := cmd.client.Warnings(client.WarningsOptions{All: cmd.All})
+	opts := client.WarningsOptions{
+		All:  cmd.All,
+		Text: cmd.Text,
+	}
+	if cmd.Since != "" {
+		since, err := parseTimeOrAgo(cmd.Since, now)
+		if err != nil {
+			return fmt.Errorf("invalid --since value: %v", err)
+		}
+		opts.Since = since
+	}
+	warnings, err := cmd.client.Warnings(opts)
 	if err != nil {
 		return err
 	}
@@ -148,6 +174,10 @@ func (cmd *cmdOkay) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 
+	if cmd.Positional.Message != "" {
+		return cmd.client.OkayWarning(cmd.Positional.Message)
+	}
+
 	last, err := lastWarningTimestamp()
 	if err != nil {
 		return err
@@ -126,6 +126,29 @@ warning: |
 `[1:])
 }
 
+func (s *warningSuite) TestWarningsFilters(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v1/warnings")
+		query := r.URL.Query()
+		c.Check(query.Get("text"), check.Equals, "disk")
+		since, err := time.Parse(time.RFC3339Nano, query.Get("since"))
+		c.Assert(err, check.IsNil)
+		c.Check(time.Since(since) > time.Hour, check.Equals, true)
+		c.Check(time.Since(since) < 2*time.Hour, check.Equals, true)
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"warnings", "--text", "disk", "--since", "1h"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "No warnings.\n")
+}
+
+func (s *warningSuite) TestWarningsInvalidSince(c *check.C) {
+	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"warnings", "--since", "yesterday"})
+	c.Assert(err, check.ErrorMatches, `invalid --since value: "yesterday" is not an RFC3339 time or a duration`)
+}
+
 func (s *warningSuite) TestVerboseWarnings(c *check.C) {
 	s.RedirectClientToTestServer(mkWarningsFakeHandler(c, twoWarnings))
 
@@ -189,6 +212,22 @@ func (s *warningSuite) TestOkay(c *check.C) {
 	c.Check(s.Stdout(), check.Equals, "")
 }
 
+func (s *warningSuite) TestOkayMessage(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/warnings")
+		c.Assert(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{"action": "okay", "message": "hello world number one"})
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": 1}`)
+	})
+
+	// No need to have listed the warnings first.
+	rest, err := cli.Parser(cli.Client()).ParseArgs([]string{"okay", "hello world number one"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
 func (s *warningSuite) TestOkayBeforeWarnings(c *check.C) {
 	_, err := cli.Parser(cli.Client()).ParseArgs([]string{"okay"})
 	c.Assert(err, check.ErrorMatches, "you must have looked at the warnings before acknowledging them. Try 'pebble warnings'.")
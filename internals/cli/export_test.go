@@ -16,8 +16,12 @@ package cli
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/canonical/go-flags"
 
 	"github.com/canonical/pebble/client"
+	"github.com/canonical/pebble/internals/overlord"
 )
 
 var RunMain = Run
@@ -45,6 +49,16 @@ var (
 	GetEnvPaths = getEnvPaths
 )
 
+// ParsePruneOptions returns the prune options for the given "pebble run"
+// arguments.
+func ParsePruneOptions(args []string) (overlord.PruneOptions, error) {
+	var opts sharedRunEnterOpts
+	if _, err := flags.ParseArgs(&opts, args); err != nil {
+		return overlord.PruneOptions{}, err
+	}
+	return pruneOptions(&opts)
+}
+
 func FakeIsStdoutTTY(t bool) (restore func()) {
 	oldIsStdoutTTY := isStdoutTTY
 	isStdoutTTY = t
@@ -53,6 +67,14 @@ func FakeIsStdoutTTY(t bool) (restore func()) {
 	}
 }
 
+func FakeReplaySleep(f func(time.Duration)) (restore func()) {
+	oldReplaySleep := replaySleep
+	replaySleep = f
+	return func() {
+		replaySleep = oldReplaySleep
+	}
+}
+
 func FakeIsStdinTTY(t bool) (restore func()) {
 	oldIsStdinTTY := isStdinTTY
 	isStdinTTY = t
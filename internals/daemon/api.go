@@ -37,7 +37,16 @@ var api = []*Command{{
 	Path:   "/v1/warnings",
 	UserOK: true,
 	GET:    v1GetWarnings,
-	POST:   v1AckWarnings,
+	POST:   v1PostWarnings,
+}, {
+	Path:      "/v1/audit",
+	AdminOnly: true,
+	GET:       v1GetAudit,
+}, {
+	Path:      "/v1/debug",
+	AdminOnly: true,
+	GET:       v1GetDebug,
+	POST:      v1PostDebug,
 }, {
 	Path:   "/v1/changes",
 	UserOK: true,
@@ -51,6 +60,10 @@ var api = []*Command{{
 	Path:   "/v1/changes/{id}/wait",
 	UserOK: true,
 	GET:    v1GetChangeWait,
+}, {
+	Path:      "/v1/prune",
+	AdminOnly: true,
+	POST:      v1PostPrune,
 }, {
 	Path:   "/v1/services",
 	UserOK: true,
@@ -81,7 +94,17 @@ var api = []*Command{{
 }, {
 	Path:   "/v1/exec",
 	UserOK: true,
+	GET:    v1GetExec,
 	POST:   v1PostExec,
+}, {
+	Path:   "/v1/exec/{task-id}",
+	UserOK: true,
+	GET:    v1GetExecTask,
+	POST:   v1PostExecTask,
+}, {
+	Path:      "/v1/exec/{task-id}/recording",
+	AdminOnly: true,
+	GET:       v1GetExecRecording,
 }, {
 	Path:   "/v1/tasks/{task-id}/websocket/{websocket-id}",
 	UserOK: true,
@@ -94,6 +117,20 @@ var api = []*Command{{
 	Path:   "/v1/checks",
 	UserOK: true,
 	GET:    v1GetChecks,
+	POST:   v1PostChecks,
+}, {
+	Path:   "/v1/events",
+	UserOK: true,
+	GET:    v1GetEvents,
+}, {
+	Path:   "/v1/notices",
+	UserOK: true,
+	GET:    v1GetNotices,
+	POST:   v1PostNotices,
+}, {
+	Path:   "/v1/notices/{id}",
+	UserOK: true,
+	GET:    v1GetNotice,
 }}
 
 var (
@@ -0,0 +1,40 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func v1GetAudit(c *Command, r *http.Request, _ *userState) Response {
+	var limit int
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return statusBadRequest("invalid limit %q", s)
+		}
+		limit = n
+	}
+
+	records, err := c.d.auditLog.records(limit)
+	if err != nil {
+		return statusInternalError("cannot read audit log: %v", err)
+	}
+	if records == nil {
+		records = []*auditRecord{}
+	}
+	return SyncResponse(records)
+}
@@ -16,9 +16,15 @@ package daemon
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/canonical/x-go/strutil"
+
 	"github.com/canonical/pebble/internals/logger"
 	"github.com/canonical/pebble/internals/overlord/state"
 )
@@ -35,6 +41,8 @@ type changeInfo struct {
 	SpawnTime time.Time  `json:"spawn-time,omitempty"`
 	ReadyTime *time.Time `json:"ready-time,omitempty"`
 
+	RetryOf string `json:"retry-of,omitempty"`
+
 	Data map[string]*json.RawMessage `json:"data,omitempty"`
 }
 
@@ -49,6 +57,9 @@ type taskInfo struct {
 	SpawnTime time.Time  `json:"spawn-time,omitempty"`
 	ReadyTime *time.Time `json:"ready-time,omitempty"`
 
+	Retries       int        `json:"retries,omitempty"`
+	LastRetryTime *time.Time `json:"last-retry-time,omitempty"`
+
 	Data map[string]*json.RawMessage `json:"data,omitempty"`
 }
 
@@ -76,6 +87,10 @@ func change2changeInfo(chg *state.Change) *changeInfo {
 	if err := chg.Err(); err != nil {
 		chgInfo.Err = err.Error()
 	}
+	var retryOf string
+	if chg.Get("retry-of", &retryOf) == nil {
+		chgInfo.RetryOf = retryOf
+	}
 
 	tasks := chg.Tasks()
 	taskInfos := make([]*taskInfo, len(tasks))
@@ -94,11 +109,16 @@ func change2changeInfo(chg *state.Change) *changeInfo {
 				Total: total,
 			},
 			SpawnTime: t.SpawnTime(),
+			Retries:   t.Retries(),
 		}
 		readyTime := t.ReadyTime()
 		if !readyTime.IsZero() {
 			taskInfo.ReadyTime = &readyTime
 		}
+		lastRetryTime := t.LastRetryTime()
+		if !lastRetryTime.IsZero() {
+			taskInfo.LastRetryTime = &lastRetryTime
+		}
 		var data map[string]*json.RawMessage
 		if t.Get("api-data", &data) == nil {
 			taskInfo.Data = data
@@ -115,31 +135,38 @@ func change2changeInfo(chg *state.Change) *changeInfo {
 	return chgInfo
 }
 
+// changeStatuses are the statuses changes can be filtered by.
+var changeStatuses = []state.Status{
+	state.DoStatus,
+	state.DoingStatus,
+	state.DoneStatus,
+	state.AbortStatus,
+	state.UndoStatus,
+	state.UndoingStatus,
+	state.UndoneStatus,
+	state.HoldStatus,
+	state.ErrorStatus,
+}
+
 func v1GetChanges(c *Command, r *http.Request, _ *userState) Response {
 	query := r.URL.Query()
 	qselect := query.Get("select")
 	if qselect == "" {
 		qselect = "in-progress"
 	}
-	var filter func(*state.Change) bool
+	var filters []func(*state.Change) bool
 	switch qselect {
 	case "all":
-		filter = func(*state.Change) bool { return true }
 	case "in-progress":
-		filter = func(chg *state.Change) bool { return !chg.Status().Ready() }
+		filters = append(filters, func(chg *state.Change) bool { return !chg.Status().Ready() })
 	case "ready":
-		filter = func(chg *state.Change) bool { return chg.Status().Ready() }
+		filters = append(filters, func(chg *state.Change) bool { return chg.Status().Ready() })
 	default:
 		return statusBadRequest("select should be one of: all,in-progress,ready")
 	}
 
 	if wantedName := query.Get("for"); wantedName != "" {
-		outerFilter := filter
-		filter = func(chg *state.Change) bool {
-			if !outerFilter(chg) {
-				return false
-			}
-
+		filters = append(filters, func(chg *state.Change) bool {
 			var serviceNames []string
 			if err := chg.Get("service-names", &serviceNames); err != nil {
 				logger.Noticef("Cannot get service-name for change %v", chg.ID())
@@ -153,23 +180,153 @@ func v1GetChanges(c *Command, r *http.Request, _ *userState) Response {
 			}
 
 			return false
+		})
+	}
+
+	if kinds := strutil.MultiCommaSeparatedList(query["kinds"]); len(kinds) > 0 {
+		filters = append(filters, func(chg *state.Change) bool {
+			return strutil.ListContains(kinds, chg.Kind())
+		})
+	}
+
+	if names := strutil.MultiCommaSeparatedList(query["statuses"]); len(names) > 0 {
+		statuses := make(map[state.Status]bool)
+		for _, name := range names {
+			status, ok := parseChangeStatus(name)
+			if !ok {
+				return statusBadRequest("invalid status %q", name)
+			}
+			statuses[status] = true
+		}
+		filters = append(filters, func(chg *state.Change) bool {
+			return statuses[chg.Status()]
+		})
+	}
+
+	if taskKinds := strutil.MultiCommaSeparatedList(query["task-kinds"]); len(taskKinds) > 0 {
+		filters = append(filters, func(chg *state.Change) bool {
+			for _, t := range chg.Tasks() {
+				if strutil.ListContains(taskKinds, t.Kind()) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+
+	timeFilters := []struct {
+		param string
+		time  func(*state.Change) time.Time
+		since bool
+	}{
+		{"spawn-since", (*state.Change).SpawnTime, true},
+		{"spawn-until", (*state.Change).SpawnTime, false},
+		{"ready-since", (*state.Change).ReadyTime, true},
+		{"ready-until", (*state.Change).ReadyTime, false},
+	}
+	for _, tf := range timeFilters {
+		value := query.Get(tf.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return statusBadRequest("invalid %s timestamp %q: %v", tf.param, value, err)
 		}
+		changeTime, since := tf.time, tf.since
+		filters = append(filters, func(chg *state.Change) bool {
+			ct := changeTime(chg)
+			if ct.IsZero() {
+				return false // for example, not ready yet
+			}
+			if since {
+				return !ct.Before(t)
+			}
+			return !ct.After(t)
+		})
+	}
+
+	limit, err := parseNonNegative(query.Get("limit"))
+	if err != nil {
+		return statusBadRequest("invalid limit %q", query.Get("limit"))
+	}
+	offset, err := parseNonNegative(query.Get("offset"))
+	if err != nil {
+		return statusBadRequest("invalid offset %q", query.Get("offset"))
 	}
 
 	state := c.d.overlord.State()
 	state.Lock()
 	defer state.Unlock()
 	chgs := state.Changes()
+	sortChanges(chgs)
 	chgInfos := make([]*changeInfo, 0, len(chgs))
-	for _, chg := range chgs {
-		if !filter(chg) {
+	skipped := 0
+	// Pagination starts from the newest change, so that a limit returns the
+	// most recent changes, but the results are still returned oldest first.
+outer:
+	for i := len(chgs) - 1; i >= 0; i-- {
+		chg := chgs[i]
+		for _, filter := range filters {
+			if !filter(chg) {
+				continue outer
+			}
+		}
+		if skipped < offset {
+			skipped++
 			continue
 		}
+		if limit > 0 && len(chgInfos) >= limit {
+			break
+		}
 		chgInfos = append(chgInfos, change2changeInfo(chg))
 	}
+	for i, j := 0, len(chgInfos)-1; i < j; i, j = i+1, j-1 {
+		chgInfos[i], chgInfos[j] = chgInfos[j], chgInfos[i]
+	}
 	return SyncResponse(chgInfos)
 }
 
+// parseChangeStatus parses a status name like "Error" (case-insensitively).
+func parseChangeStatus(name string) (state.Status, bool) {
+	for _, status := range changeStatuses {
+		if strings.EqualFold(name, status.String()) {
+			return status, true
+		}
+	}
+	return state.DefaultStatus, false
+}
+
+// parseNonNegative parses a non-negative integer query parameter, where ""
+// means zero.
+func parseNonNegative(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return n, nil
+}
+
+// sortChanges sorts changes by spawn time, and then by ID, so that the
+// results can be paginated.
+func sortChanges(chgs []*state.Change) {
+	sort.Slice(chgs, func(i, j int) bool {
+		ti, tj := chgs[i].SpawnTime(), chgs[j].SpawnTime()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		idi, _ := strconv.Atoi(chgs[i].ID())
+		idj, _ := strconv.Atoi(chgs[j].ID())
+		return idi < idj
+	})
+}
+
 func v1GetChange(c *Command, r *http.Request, _ *userState) Response {
 	changeID := muxVars(r)["id"]
 	st := c.d.overlord.State()
@@ -235,7 +392,8 @@ func v1PostChange(c *Command, r *http.Request, _ *userState) Response {
 	}
 
 	var reqData struct {
-		Action string `json:"action"`
+		Action  string   `json:"action"`
+		TaskIDs []string `json:"task-ids"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -243,19 +401,74 @@ func v1PostChange(c *Command, r *http.Request, _ *userState) Response {
 		return statusBadRequest("cannot decode data from request body: %v", err)
 	}
 
-	if reqData.Action != "abort" {
-		return statusBadRequest("change action %q is unsupported", reqData.Action)
-	}
+	switch reqData.Action {
+	case "abort":
+		if len(reqData.TaskIDs) > 0 {
+			return abortTasks(state, chg, reqData.TaskIDs)
+		}
+
+		if chg.Status().Ready() {
+			return statusBadRequest("cannot abort change %s with nothing pending", chID)
+		}
 
-	if chg.Status().Ready() {
-		return statusBadRequest("cannot abort change %s with nothing pending", chID)
+		// flag the change
+		chg.Abort()
+
+		// actually ask to proceed with the abort
+		stateEnsureBefore(state, 0)
+
+		return SyncResponse(change2changeInfo(chg))
+
+	case "retry":
+		if len(reqData.TaskIDs) > 0 {
+			return statusBadRequest("cannot retry individual tasks")
+		}
+
+		if !retryableChangeKinds[chg.Kind()] {
+			return statusBadRequest("cannot retry %q change %s", chg.Kind(), chID)
+		}
+		retry, err := chg.Retry()
+		if err != nil {
+			return statusBadRequest("%v", err)
+		}
+		stateEnsureBefore(state, 0)
+
+		return AsyncResponse(nil, retry.ID())
+
+	default:
+		return statusBadRequest("change action %q is unsupported", reqData.Action)
 	}
+}
 
-	// flag the change
-	chg.Abort()
+// retryableChangeKinds are the kinds of change that can be retried. Their
+// tasks can simply be run again, unlike (for example) exec tasks, which wait
+// for a client to connect to them.
+var retryableChangeKinds = map[string]bool{
+	"autostart": true,
+	"replan":    true,
+	"restart":   true,
+	"start":     true,
+	"stop":      true,
+}
 
-	// actually ask to proceed with the abort
-	stateEnsureBefore(state, 0)
+// abortTasks aborts the given tasks of a change, and the tasks that wait for
+// them.
+func abortTasks(st *state.State, chg *state.Change, taskIDs []string) Response {
+	tasks := make([]*state.Task, 0, len(taskIDs))
+	for _, taskID := range taskIDs {
+		task := st.Task(taskID)
+		if task == nil || task.Change() != chg {
+			return statusBadRequest("cannot find task %q in change %s", taskID, chg.ID())
+		}
+		if task.Status().Ready() {
+			return statusBadRequest("cannot abort task %s with nothing pending", taskID)
+		}
+		tasks = append(tasks, task)
+	}
+	for _, task := range tasks {
+		task.Abort()
+	}
+	stateEnsureBefore(st, 0)
 
 	return SyncResponse(change2changeInfo(chg))
 }
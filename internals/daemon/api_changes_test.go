@@ -191,6 +191,78 @@ func (s *apiSuite) TestStateChangesForServiceName(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *apiSuite) getChangeKinds(c *check.C, query string) []string {
+	req, err := http.NewRequest("GET", "/v1/changes?"+query, nil)
+	c.Assert(err, check.IsNil)
+	rsp := v1GetChanges(apiCmd("/v1/changes"), req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Assert(rsp.Result, check.FitsTypeOf, []*changeInfo(nil))
+	var kinds []string
+	for _, info := range rsp.Result.([]*changeInfo) {
+		kinds = append(kinds, info.Kind)
+	}
+	return kinds
+}
+
+func (s *apiSuite) TestStateChangesFilters(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	restore := state.FakeTime(time.Date(2016, 04, 21, 1, 2, 3, 0, time.UTC))
+	setupChanges(st)
+	restore()
+	restore = state.FakeTime(time.Date(2016, 04, 22, 1, 2, 3, 0, time.UTC))
+	chg3 := st.NewChange("restart", "restart...")
+	t4 := st.NewTask("stop", "1...")
+	chg3.AddTask(t4)
+	t4.SetStatus(state.DoneStatus)
+	restore()
+	st.Unlock()
+
+	c.Check(s.getChangeKinds(c, "select=all"), check.DeepEquals, []string{"install", "remove", "restart"})
+	c.Check(s.getChangeKinds(c, "select=all&kinds=remove,restart"), check.DeepEquals, []string{"remove", "restart"})
+	c.Check(s.getChangeKinds(c, "select=all&kinds=remove&kinds=install"), check.DeepEquals, []string{"install", "remove"})
+	c.Check(s.getChangeKinds(c, "select=all&statuses=error,done"), check.DeepEquals, []string{"remove", "restart"})
+	c.Check(s.getChangeKinds(c, "select=all&statuses=Do"), check.DeepEquals, []string{"install"})
+	c.Check(s.getChangeKinds(c, "select=all&task-kinds=activate,stop"), check.DeepEquals, []string{"install", "restart"})
+	c.Check(s.getChangeKinds(c, "select=all&spawn-since=2016-04-22T00:00:00Z"), check.DeepEquals, []string{"restart"})
+	c.Check(s.getChangeKinds(c, "select=all&spawn-until=2016-04-21T01:02:03Z"), check.DeepEquals, []string{"install", "remove"})
+	c.Check(s.getChangeKinds(c, "select=all&ready-since=2016-04-21T00:00:00Z"), check.DeepEquals, []string{"remove", "restart"})
+	c.Check(s.getChangeKinds(c, "select=all&ready-until=2016-04-21T12:00:00Z"), check.DeepEquals, []string{"remove"})
+	c.Check(s.getChangeKinds(c, "select=ready&kinds=install"), check.IsNil)
+
+	// Pagination is applied after filtering, starting from the newest change,
+	// but results are still oldest first.
+	c.Check(s.getChangeKinds(c, "select=all&limit=2"), check.DeepEquals, []string{"remove", "restart"})
+	c.Check(s.getChangeKinds(c, "select=all&limit=2&offset=2"), check.DeepEquals, []string{"install"})
+	c.Check(s.getChangeKinds(c, "select=all&offset=1"), check.DeepEquals, []string{"install", "remove"})
+	c.Check(s.getChangeKinds(c, "select=ready&limit=1&offset=1"), check.DeepEquals, []string{"remove"})
+	c.Check(s.getChangeKinds(c, "select=all&offset=5"), check.IsNil)
+}
+
+func (s *apiSuite) TestStateChangesInvalidParams(c *check.C) {
+	s.daemon(c)
+
+	for _, test := range []struct {
+		query string
+		error string
+	}{
+		{"select=foo", `select should be one of: all,in-progress,ready`},
+		{"statuses=done,bad", `invalid status "bad"`},
+		{"spawn-since=yesterday", `invalid spawn-since timestamp "yesterday": .*`},
+		{"ready-until=2016-04-21", `invalid ready-until timestamp "2016-04-21": .*`},
+		{"limit=x", `invalid limit "x"`},
+		{"limit=-1", `invalid limit "-1"`},
+		{"offset=-5", `invalid offset "-5"`},
+	} {
+		req, err := http.NewRequest("GET", "/v1/changes?"+test.query, nil)
+		c.Assert(err, check.IsNil)
+		rsp := v1GetChanges(apiCmd("/v1/changes"), req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, 400, check.Commentf("query %q", test.query))
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, test.error, check.Commentf("query %q", test.query))
+	}
+}
+
 func (s *apiSuite) TestStateChange(c *check.C) {
 	restore := state.FakeTime(time.Date(2016, 04, 21, 1, 2, 3, 0, time.UTC))
 	defer restore()
@@ -371,6 +443,118 @@ func (s *apiSuite) TestStateChangeAbortIsReady(c *check.C) {
 	})
 }
 
+func (s *apiSuite) postChange(c *check.C, id, body string) *resp {
+	s.vars = map[string]string{"id": id}
+	req, err := http.NewRequest("POST", "/v1/changes/"+id, bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	return v1PostChange(apiCmd("/v1/changes/{id}"), req, nil).(*resp)
+}
+
+func (s *apiSuite) TestStateChangeAbortTasks(c *check.C) {
+	soon := 0
+	restore := FakeStateEnsureBefore(func(st *state.State, d time.Duration) {
+		soon++
+	})
+	defer restore()
+
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	ids := setupChanges(st)
+	st.Task(ids[3]).WaitFor(st.Task(ids[2]))
+	st.Unlock()
+
+	rsp := s.postChange(c, ids[0], fmt.Sprintf(`{"action": "abort", "task-ids": ["%s"]}`, ids[2]))
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(soon, check.Equals, 1)
+	info := rsp.Result.(*changeInfo)
+	c.Assert(info.Tasks, check.HasLen, 2)
+	c.Check(info.Tasks[0].Status, check.Equals, "Hold")
+	c.Check(info.Tasks[1].Status, check.Equals, "Hold")
+
+	rsp = s.postChange(c, ids[0], fmt.Sprintf(`{"action": "abort", "task-ids": ["%s"]}`, ids[2]))
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals,
+		fmt.Sprintf("cannot abort task %s with nothing pending", ids[2]))
+
+	// Tasks must be in the given change.
+	rsp = s.postChange(c, ids[0], fmt.Sprintf(`{"action": "abort", "task-ids": ["%s"]}`, ids[4]))
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals,
+		fmt.Sprintf("cannot find task %q in change %s", ids[4], ids[0]))
+}
+
+func (s *apiSuite) TestStateChangeRetry(c *check.C) {
+	restore := FakeStateEnsureBefore(func(st *state.State, d time.Duration) {})
+	defer restore()
+
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	ids := setupChanges(st)
+	chg := st.NewChange("stop", "Stop service \"svc1\"")
+	t := st.NewTask("stop", "Stop service \"svc1\"")
+	chg.AddTask(t)
+	t.SetStatus(state.ErrorStatus)
+	st.Unlock()
+
+	rsp := s.postChange(c, chg.ID(), `{"action": "retry"}`)
+	c.Assert(rsp.Status, check.Equals, 202)
+	c.Check(rsp.Type, check.Equals, ResponseTypeAsync)
+	c.Check(rsp.Change, check.Not(check.Equals), "")
+
+	st.Lock()
+	retry := change2changeInfo(st.Change(rsp.Change))
+	st.Unlock()
+	c.Check(retry.Kind, check.Equals, "stop")
+	c.Check(retry.Status, check.Equals, "Do")
+	c.Check(retry.RetryOf, check.Equals, chg.ID())
+	c.Assert(retry.Tasks, check.HasLen, 1)
+	c.Check(retry.Tasks[0].Kind, check.Equals, "stop")
+	c.Check(retry.Tasks[0].Retries, check.Equals, 1)
+	c.Check(retry.Tasks[0].LastRetryTime, check.NotNil)
+
+	// Changes that haven't failed can't be retried.
+	st.Lock()
+	chg.SetStatus(state.DoingStatus)
+	st.Unlock()
+	rsp = s.postChange(c, chg.ID(), `{"action": "retry"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals,
+		fmt.Sprintf("cannot retry change %s with status Doing", chg.ID()))
+
+	rsp = s.postChange(c, chg.ID(), fmt.Sprintf(`{"action": "retry", "task-ids": ["%s"]}`, ids[4]))
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot retry individual tasks")
+
+	rsp = s.postChange(c, ids[1], `{"action": "foo"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `change action "foo" is unsupported`)
+}
+
+func (s *apiSuite) TestStateChangeRetryKind(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	ids := setupChanges(st)
+	chg := st.NewChange("exec", "Execute command \"foo\"")
+	t := st.NewTask("exec", "exec command \"foo\"")
+	chg.AddTask(t)
+	t.SetStatus(state.ErrorStatus)
+	st.Unlock()
+
+	// Only changes whose tasks can simply be run again can be retried.
+	rsp := s.postChange(c, chg.ID(), `{"action": "retry"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals,
+		fmt.Sprintf(`cannot retry "exec" change %s`, chg.ID()))
+
+	rsp = s.postChange(c, ids[1], `{"action": "retry"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals,
+		fmt.Sprintf(`cannot retry "remove" change %s`, ids[1]))
+}
+
 func (s *apiSuite) TestWaitChangeNotFound(c *check.C) {
 	s.daemon(c)
 	req, err := http.NewRequest("GET", "/v1/changes/x/wait", nil)
@@ -15,7 +15,12 @@
 package daemon
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/canonical/x-go/strutil"
 
@@ -23,11 +28,24 @@ import (
 )
 
 type checkInfo struct {
-	Name      string `json:"name"`
-	Level     string `json:"level,omitempty"`
-	Status    string `json:"status"`
-	Failures  int    `json:"failures,omitempty"`
-	Threshold int    `json:"threshold"`
+	Name             string `json:"name"`
+	Level            string `json:"level,omitempty"`
+	Status           string `json:"status"`
+	Failures         int    `json:"failures,omitempty"`
+	Threshold        int    `json:"threshold"`
+	Successes        int    `json:"successes,omitempty"`
+	SuccessThreshold int    `json:"success-threshold"`
+	Starting         bool   `json:"starting,omitempty"`
+	Paused           bool   `json:"paused,omitempty"`
+
+	History []checkResult `json:"history,omitempty"`
+}
+
+type checkResult struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
 }
 
 func v1GetChecks(c *Command, r *http.Request, _ *userState) Response {
@@ -58,9 +76,93 @@ func v1GetChecks(c *Command, r *http.Request, _ *userState) Response {
 				Status:    string(check.Status),
 				Failures:  check.Failures,
 				Threshold: check.Threshold,
+				Paused:    check.Paused,
+
+				Successes:        check.Successes,
+				SuccessThreshold: check.SuccessThreshold,
+				Starting:         check.Starting,
+			}
+			for _, result := range check.History {
+				info.History = append(info.History, checkResult{
+					Time:     result.Time,
+					Duration: result.Duration,
+					Success:  result.Success,
+					Error:    result.Error,
+				})
 			}
 			infos = append(infos, info)
 		}
 	}
 	return SyncResponse(infos)
 }
+
+type checkRunResult struct {
+	Name string `json:"name"`
+	checkResult
+}
+
+func v1PostChecks(c *Command, r *http.Request, _ *userState) Response {
+	var payload struct {
+		Action string   `json:"action"`
+		Checks []string `json:"checks"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&payload); err != nil {
+		return statusBadRequest("cannot decode data from request body: %v", err)
+	}
+	if len(payload.Checks) == 0 {
+		return statusBadRequest("no checks to %s provided", payload.Action)
+	}
+
+	checkMgr := c.d.overlord.CheckManager()
+	var err error
+	switch payload.Action {
+	case "run":
+		results := make([]checkRunResult, len(payload.Checks))
+		errs := make([]error, len(payload.Checks))
+		var wg sync.WaitGroup
+		for i, name := range payload.Checks {
+			wg.Add(1)
+			go func(i int, name string) {
+				defer wg.Done()
+				result, err := checkMgr.RunCheck(r.Context(), name)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				results[i] = checkRunResult{
+					Name: name,
+					checkResult: checkResult{
+						Time:     result.Time,
+						Duration: result.Duration,
+						Success:  result.Success,
+						Error:    result.Error,
+					},
+				}
+			}(i, name)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if errors.Is(err, os.ErrNotExist) {
+				return statusNotFound("%v", err)
+			}
+			if err != nil {
+				return statusInternalError("%v", err)
+			}
+		}
+		return SyncResponse(results)
+	case "pause":
+		err = checkMgr.PauseChecks(payload.Checks)
+	case "resume":
+		err = checkMgr.ResumeChecks(payload.Checks)
+	default:
+		return statusBadRequest("invalid action %q", payload.Action)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return statusNotFound("%v", err)
+	}
+	if err != nil {
+		return statusInternalError("%v", err)
+	}
+	return SyncResponse(true)
+}
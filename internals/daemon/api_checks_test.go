@@ -18,6 +18,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -59,9 +61,9 @@ checks:
 	err = json.Unmarshal(rec.Body.Bytes(), &body)
 	c.Check(err, IsNil)
 	c.Check(body["result"], DeepEquals, []interface{}{
-		map[string]interface{}{"name": "chk1", "status": "up", "level": "ready", "threshold": 3.0},
-		map[string]interface{}{"name": "chk2", "status": "up", "level": "alive", "threshold": 3.0},
-		map[string]interface{}{"name": "chk3", "status": "up", "threshold": 3.0},
+		map[string]interface{}{"name": "chk1", "status": "up", "level": "ready", "threshold": 3.0, "success-threshold": 1.0},
+		map[string]interface{}{"name": "chk2", "status": "up", "level": "alive", "threshold": 3.0, "success-threshold": 1.0},
+		map[string]interface{}{"name": "chk3", "status": "up", "threshold": 3.0, "success-threshold": 1.0},
 	})
 
 	// Request with names filter
@@ -76,8 +78,8 @@ checks:
 	err = json.Unmarshal(rec.Body.Bytes(), &body)
 	c.Check(err, IsNil)
 	c.Check(body["result"], DeepEquals, []interface{}{
-		map[string]interface{}{"name": "chk1", "status": "up", "level": "ready", "threshold": 3.0},
-		map[string]interface{}{"name": "chk3", "status": "up", "threshold": 3.0},
+		map[string]interface{}{"name": "chk1", "status": "up", "level": "ready", "threshold": 3.0, "success-threshold": 1.0},
+		map[string]interface{}{"name": "chk3", "status": "up", "threshold": 3.0, "success-threshold": 1.0},
 	})
 
 	// Request with names filter (comma-separated values)
@@ -92,8 +94,8 @@ checks:
 	err = json.Unmarshal(rec.Body.Bytes(), &body)
 	c.Check(err, IsNil)
 	c.Check(body["result"], DeepEquals, []interface{}{
-		map[string]interface{}{"name": "chk1", "status": "up", "level": "ready", "threshold": 3.0},
-		map[string]interface{}{"name": "chk3", "status": "up", "threshold": 3.0},
+		map[string]interface{}{"name": "chk1", "status": "up", "level": "ready", "threshold": 3.0, "success-threshold": 1.0},
+		map[string]interface{}{"name": "chk3", "status": "up", "threshold": 3.0, "success-threshold": 1.0},
 	})
 
 	// Request with level filter
@@ -108,7 +110,7 @@ checks:
 	err = json.Unmarshal(rec.Body.Bytes(), &body)
 	c.Check(err, IsNil)
 	c.Check(body["result"], DeepEquals, []interface{}{
-		map[string]interface{}{"name": "chk2", "status": "up", "level": "alive", "threshold": 3.0},
+		map[string]interface{}{"name": "chk2", "status": "up", "level": "alive", "threshold": 3.0, "success-threshold": 1.0},
 	})
 
 	// Request with names and level filters
@@ -123,10 +125,158 @@ checks:
 	err = json.Unmarshal(rec.Body.Bytes(), &body)
 	c.Check(err, IsNil)
 	c.Check(body["result"], DeepEquals, []interface{}{
-		map[string]interface{}{"name": "chk1", "status": "up", "level": "ready", "threshold": 3.0},
+		map[string]interface{}{"name": "chk1", "status": "up", "level": "ready", "threshold": 3.0, "success-threshold": 1.0},
 	})
 }
 
+func (s *apiSuite) TestChecksGetHistory(c *C) {
+	writeTestLayer(s.pebbleDir, `
+checks:
+    chk1:
+        override: replace
+        period: 10ms
+        timeout: 5ms
+        tcp:
+            port: 1
+`)
+	s.daemon(c)
+	_, err := s.d.overlord.ServiceManager().Plan() // ensure plan is loaded
+	c.Assert(err, IsNil)
+
+	// Wait for the check to have run at least once
+	for i := 0; ; i++ {
+		if i >= 1000 {
+			c.Fatalf("timed out waiting for check to run")
+		}
+		checks, err := s.d.overlord.CheckManager().Checks()
+		c.Assert(err, IsNil)
+		if len(checks) == 1 && len(checks[0].History) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	req, err := http.NewRequest("GET", "/v1/checks", nil)
+	c.Assert(err, IsNil)
+	rsp := v1GetChecks(apiCmd("/v1/checks"), req, nil).(*resp)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+	var body struct {
+		Result []struct {
+			Name    string
+			History []map[string]interface{}
+		}
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Assert(err, IsNil)
+	c.Assert(body.Result, HasLen, 1)
+	c.Assert(len(body.Result[0].History) > 0, Equals, true)
+	result := body.Result[0].History[0]
+	c.Check(result["success"], Equals, false)
+	c.Check(result["error"], Matches, ".*connection refused")
+	c.Check(result["time"], FitsTypeOf, "")
+	c.Check(result["duration"], FitsTypeOf, 0.0)
+}
+
+func (s *apiSuite) postChecks(c *C, body string) (*resp, map[string]interface{}) {
+	req, err := http.NewRequest("POST", "/v1/checks", strings.NewReader(body))
+	c.Assert(err, IsNil)
+	rsp := v1PostChecks(apiCmd("/v1/checks"), req, nil).(*resp)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, rsp.Status)
+	var result map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &result)
+	c.Assert(err, IsNil)
+	return rsp, result
+}
+
+func (s *apiSuite) TestChecksPost(c *C) {
+	writeTestLayer(s.pebbleDir, `
+checks:
+    chk1:
+        override: replace
+        period: 1h
+        timeout: 1s
+        tcp:
+            port: 1
+    chk2:
+        override: replace
+        period: 1h
+        timeout: 1s
+        tcp:
+            port: 1
+`)
+	s.daemon(c)
+	_, err := s.d.overlord.ServiceManager().Plan() // ensure plan is loaded
+	c.Assert(err, IsNil)
+
+	// Run checks immediately
+	rsp, body := s.postChecks(c, `{"action": "run", "checks": ["chk2", "chk1"]}`)
+	c.Assert(rsp.Status, Equals, 200)
+	results := body["result"].([]interface{})
+	c.Assert(results, HasLen, 2)
+	for i, name := range []string{"chk2", "chk1"} {
+		result := results[i].(map[string]interface{})
+		c.Check(result["name"], Equals, name)
+		c.Check(result["success"], Equals, false)
+		c.Check(result["error"], Matches, ".*connection refused")
+	}
+	checks, err := s.d.overlord.CheckManager().Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Failures, Equals, 1)
+
+	// Pause and resume
+	rsp, _ = s.postChecks(c, `{"action": "pause", "checks": ["chk1"]}`)
+	c.Assert(rsp.Status, Equals, 200)
+	checks, err = s.d.overlord.CheckManager().Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Paused, Equals, true)
+	c.Check(checks[1].Paused, Equals, false)
+
+	req, err := http.NewRequest("GET", "/v1/checks?names=chk1", nil)
+	c.Assert(err, IsNil)
+	getRsp := v1GetChecks(apiCmd("/v1/checks"), req, nil).(*resp)
+	c.Check(getRsp.Result.([]checkInfo)[0].Paused, Equals, true)
+
+	rsp, _ = s.postChecks(c, `{"action": "resume", "checks": ["chk1"]}`)
+	c.Assert(rsp.Status, Equals, 200)
+	checks, err = s.d.overlord.CheckManager().Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Paused, Equals, false)
+}
+
+func (s *apiSuite) TestChecksPostErrors(c *C) {
+	writeTestLayer(s.pebbleDir, `
+checks:
+    chk1:
+        override: replace
+        tcp:
+            port: 1
+`)
+	s.daemon(c)
+	_, err := s.d.overlord.ServiceManager().Plan() // ensure plan is loaded
+	c.Assert(err, IsNil)
+
+	for _, test := range []struct {
+		body    string
+		status  int
+		message string
+	}{
+		{`{"action": "run", "checks": ["chk1", "nope"]}`, 404, `cannot find check "nope": file does not exist`},
+		{`{"action": "pause", "checks": ["nope"]}`, 404, `cannot find check "nope": file does not exist`},
+		{`{"action": "resume", "checks": []}`, 400, "no checks to resume provided"},
+		{`{"action": "foo", "checks": ["chk1"]}`, 400, `invalid action "foo"`},
+		{`{"action": }`, 400, "cannot decode data from request body: .*"},
+	} {
+		c.Logf("body %s", test.body)
+		rsp, body := s.postChecks(c, test.body)
+		c.Check(rsp.Status, Equals, test.status)
+		c.Check(body["result"].(map[string]interface{})["message"], Matches, test.message)
+	}
+}
+
 func (s *apiSuite) TestChecksGetInvalidLevel(c *C) {
 	s.daemon(c)
 	_, err := s.d.overlord.ServiceManager().Plan() // ensure plan is loaded
@@ -0,0 +1,158 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/canonical/pebble/internals/timing"
+)
+
+type debugTaskInfo struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Change  string `json:"change,omitempty"`
+}
+
+type debugOverlordInfo struct {
+	RunningTasks  []*debugTaskInfo `json:"running-tasks"`
+	TaskKinds     []string         `json:"task-kinds"`
+	EnsureTimings *timing.Span     `json:"ensure-timings,omitempty"`
+}
+
+func v1GetDebug(c *Command, r *http.Request, _ *userState) Response {
+	query := r.URL.Query()
+	switch action := query.Get("action"); action {
+	case "state":
+		return getDebugState(c, query.Get("section"))
+	case "check-state":
+		st := c.d.overlord.State()
+		st.Lock()
+		problems := st.CheckIntegrity()
+		st.Unlock()
+		if problems == nil {
+			problems = []string{}
+		}
+		return SyncResponse(problems)
+	case "overlord":
+		return getDebugOverlord(c)
+	default:
+		return statusBadRequest("unknown debug action: %q", action)
+	}
+}
+
+func getDebugState(c *Command, section string) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	data, err := st.MarshalJSON()
+	st.Unlock()
+	if err != nil {
+		return statusInternalError("cannot marshal state: %v", err)
+	}
+	if section == "" {
+		return SyncResponse(json.RawMessage(data))
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return statusInternalError("cannot unmarshal state: %v", err)
+	}
+	switch section {
+	case "changes", "tasks", "warnings", "notices", "data":
+	default:
+		return statusBadRequest("invalid state section %q", section)
+	}
+	result, ok := sections[section]
+	if !ok {
+		// Warnings and notices are omitted from the state when empty.
+		result = json.RawMessage("null")
+	}
+	return SyncResponse(result)
+}
+
+func getDebugOverlord(c *Command) Response {
+	runner := c.d.overlord.TaskRunner()
+	info := &debugOverlordInfo{
+		RunningTasks:  []*debugTaskInfo{},
+		TaskKinds:     runner.KnownTaskKinds(),
+		EnsureTimings: c.d.overlord.StateEngine().LastEnsureTimings(),
+	}
+	// Get the running tasks before locking the state, as the task runner
+	// locks the state while holding its own lock.
+	running := runner.Running()
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	for _, id := range running {
+		taskInfo := &debugTaskInfo{ID: id}
+		if t := st.Task(id); t != nil {
+			taskInfo.Kind = t.Kind()
+			taskInfo.Summary = t.Summary()
+			taskInfo.Status = t.Status().String()
+			if chg := t.Change(); chg != nil {
+				taskInfo.Change = chg.ID()
+			}
+		}
+		info.RunningTasks = append(info.RunningTasks, taskInfo)
+	}
+	return SyncResponse(info)
+}
+
+func v1PostDebug(c *Command, r *http.Request, _ *userState) Response {
+	var payload struct {
+		Action string          `json:"action"`
+		Params json.RawMessage `json:"params"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&payload); err != nil {
+		return statusBadRequest("cannot decode request body: %v", err)
+	}
+
+	switch payload.Action {
+	case "remove-change":
+		var params struct {
+			ChangeID string `json:"change-id"`
+		}
+		if len(payload.Params) > 0 {
+			if err := json.Unmarshal(payload.Params, &params); err != nil {
+				return statusBadRequest("cannot decode params: %v", err)
+			}
+		}
+		if params.ChangeID == "" {
+			return statusBadRequest("change-id is required")
+		}
+
+		st := c.d.overlord.State()
+		st.Lock()
+		chg := st.Change(params.ChangeID)
+		st.Unlock()
+		if chg == nil {
+			return statusNotFound("cannot find change with id %q", params.ChangeID)
+		}
+		// The task runner stops the change's running tasks first, so
+		// that they don't finish against a change that's gone.
+		err := c.d.overlord.TaskRunner().RemoveChange(params.ChangeID)
+		if err != nil {
+			return statusInternalError("%v", err)
+		}
+		return SyncResponse(nil)
+	default:
+		return statusBadRequest("unknown debug action: %q", payload.Action)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/canonical/pebble/internals/overlord/state"
+)
+
+func (s *apiSuite) getDebug(c *C, query string) *resp {
+	req, err := http.NewRequest("GET", "/v1/debug?"+query, nil)
+	c.Assert(err, IsNil)
+	return v1GetDebug(apiCmd("/v1/debug"), req, nil).(*resp)
+}
+
+func (s *apiSuite) postDebug(c *C, body string) *resp {
+	req, err := http.NewRequest("POST", "/v1/debug", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	return v1PostDebug(apiCmd("/v1/debug"), req, nil).(*resp)
+}
+
+func (s *apiSuite) TestDebugState(c *C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	st.Set("foo", "bar")
+	chg := st.NewChange("kind", "summary")
+	chg.AddTask(st.NewTask("task-kind", "task summary"))
+	st.Unlock()
+
+	rsp := s.getDebug(c, "action=state")
+	c.Assert(rsp.Status, Equals, 200)
+	var all map[string]json.RawMessage
+	c.Assert(json.Unmarshal(rsp.Result.(json.RawMessage), &all), IsNil)
+	c.Check(all["changes"], NotNil)
+	c.Check(all["tasks"], NotNil)
+	c.Check(all["data"], NotNil)
+
+	rsp = s.getDebug(c, "action=state&section=data")
+	c.Assert(rsp.Status, Equals, 200)
+	var data map[string]interface{}
+	c.Assert(json.Unmarshal(rsp.Result.(json.RawMessage), &data), IsNil)
+	c.Check(data["foo"], Equals, "bar")
+
+	rsp = s.getDebug(c, "action=state&section=tasks")
+	c.Assert(rsp.Status, Equals, 200)
+	var tasks map[string]map[string]interface{}
+	c.Assert(json.Unmarshal(rsp.Result.(json.RawMessage), &tasks), IsNil)
+	c.Assert(tasks, HasLen, 1)
+	for _, t := range tasks {
+		c.Check(t["kind"], Equals, "task-kind")
+		c.Check(t["change"], Equals, chg.ID())
+	}
+
+	rsp = s.getDebug(c, "action=state&section=warnings")
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(string(rsp.Result.(json.RawMessage)), Equals, "null")
+
+	rsp = s.getDebug(c, "action=state&section=foo")
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, Equals, `invalid state section "foo"`)
+}
+
+func (s *apiSuite) TestDebugCheckState(c *C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.NewChange("kind", "summary")
+	chg.AddTask(st.NewTask("task-kind", "task summary"))
+	st.Unlock()
+
+	rsp := s.getDebug(c, "action=check-state")
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, []string{})
+}
+
+func (s *apiSuite) TestDebugOverlord(c *C) {
+	s.daemon(c)
+
+	rsp := s.getDebug(c, "action=overlord")
+	c.Assert(rsp.Status, Equals, 200)
+	info := rsp.Result.(*debugOverlordInfo)
+	c.Check(info.RunningTasks, HasLen, 0)
+	c.Check(info.TaskKinds, Not(HasLen), 0)
+	c.Check(info.EnsureTimings, IsNil)
+}
+
+func (s *apiSuite) TestDebugUnknownAction(c *C) {
+	s.daemon(c)
+
+	rsp := s.getDebug(c, "action=foo")
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, Equals, `unknown debug action: "foo"`)
+
+	rsp = s.postDebug(c, `{"action": "foo"}`)
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, Equals, `unknown debug action: "foo"`)
+}
+
+func (s *apiSuite) TestDebugRemoveChange(c *C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.NewChange("kind", "summary")
+	t := st.NewTask("task-kind", "task summary")
+	chg.AddTask(t)
+	st.Unlock()
+
+	rsp := s.postDebug(c, `{"action": "remove-change", "params": {"change-id": "`+chg.ID()+`"}}`)
+	c.Assert(rsp.Status, Equals, 200)
+
+	st.Lock()
+	c.Check(st.Change(chg.ID()), IsNil)
+	c.Check(st.Task(t.ID()), IsNil)
+	st.Unlock()
+
+	rsp = s.postDebug(c, `{"action": "remove-change", "params": {"change-id": "`+chg.ID()+`"}}`)
+	c.Check(rsp.Status, Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, Equals, `cannot find change with id "`+chg.ID()+`"`)
+
+	rsp = s.postDebug(c, `{"action": "remove-change"}`)
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, Equals, "change-id is required")
+}
+
+func (s *apiSuite) TestDebugRemoveChangeRunning(c *C) {
+	d := s.daemon(c)
+	runner := d.overlord.TaskRunner()
+	started := make(chan bool)
+	runner.AddHandler("fail-on-stop", func(t *state.Task, tb *tomb.Tomb) error {
+		started <- true
+		<-tb.Dying()
+		return errors.New("stopped")
+	}, nil)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.NewChange("kind", "summary")
+	t := st.NewTask("fail-on-stop", "task summary")
+	chg.AddTask(t)
+	st.EnsureBefore(0)
+	st.Unlock()
+	<-started
+
+	rsp := s.postDebug(c, `{"action": "remove-change", "params": {"change-id": "`+chg.ID()+`"}}`)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(runner.Running(), HasLen, 0)
+
+	st.Lock()
+	c.Check(st.Change(chg.ID()), IsNil)
+	c.Check(st.Task(t.ID()), IsNil)
+	st.Unlock()
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/canonical/x-go/strutil"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/overlord/eventstate"
+)
+
+var eventTypes = []eventstate.Type{
+	eventstate.TypeService,
+	eventstate.TypeCheck,
+	eventstate.TypeChange,
+	eventstate.TypeTask,
+	eventstate.TypeWarning,
+	eventstate.TypePlan,
+}
+
+func v1GetEvents(c *Command, r *http.Request, _ *userState) Response {
+	query := r.URL.Query()
+
+	var filter eventstate.Filter
+	for _, t := range strutil.MultiCommaSeparatedList(query["types"]) {
+		if !validEventType(eventstate.Type(t)) {
+			return statusBadRequest("invalid event type %q", t)
+		}
+		filter.Types = append(filter.Types, eventstate.Type(t))
+	}
+	filter.Names = strutil.MultiCommaSeparatedList(query["names"])
+
+	return eventsResponse{
+		eventMgr: c.d.overlord.EventManager(),
+		filter:   filter,
+	}
+}
+
+func validEventType(t eventstate.Type) bool {
+	for _, typ := range eventTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// eventsResponse is a Response implementation that streams events as they
+// are published, in JSON Lines format, until the request is cancelled.
+type eventsResponse struct {
+	eventMgr *eventstate.EventManager
+	filter   eventstate.Filter
+}
+
+func (r eventsResponse) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	subscriber := r.eventMgr.Subscribe(r.filter)
+	defer subscriber.Close()
+
+	// Send the headers straight away, so the client knows it's subscribed.
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flushWriter(w)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for {
+		event, ok := subscriber.Next(req.Context())
+		if !ok {
+			return
+		}
+		err := encoder.Encode(newJSONEvent(event))
+		if err != nil {
+			logger.Noticef("Cannot write events: %v", err)
+			return
+		}
+		flushWriter(w)
+	}
+}
+
+// Each event is written as a JSON object followed by a newline (JSON Lines):
+//
+// {"time":"2023-04-23T01:28:52.660Z","type":"service","name":"redis","old":"starting","new":"running"}
+// {"time":"2023-04-23T01:28:53.798Z","type":"check","name":"online","old":"up","new":"down"}
+type jsonEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Name    string    `json:"name,omitempty"`
+	Old     string    `json:"old,omitempty"`
+	New     string    `json:"new,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+func newJSONEvent(event eventstate.Event) *jsonEvent {
+	return &jsonEvent{
+		Time:    event.Time,
+		Type:    string(event.Type),
+		Name:    event.Name,
+		Old:     event.Old,
+		New:     event.New,
+		Message: event.Message,
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/overlord/eventstate"
+)
+
+func (s *apiSuite) TestEvents(c *C) {
+	s.daemon(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", "/v1/events?types=service,warning", nil)
+	c.Assert(err, IsNil)
+	rsp := v1GetEvents(apiCmd("/v1/events"), req, nil).(eventsResponse)
+	rec := &followRecorder{logChan: make(chan string)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rsp.ServeHTTP(rec, req)
+	}()
+
+	// Headers are flushed as soon as the client is subscribed.
+	c.Assert(<-rec.logChan, Equals, "")
+	c.Check(rec.status, Equals, http.StatusOK)
+	c.Check(rec.Header().Get("Content-Type"), Equals, "application/x-ndjson")
+
+	// Events that don't match the filter aren't sent.
+	eventMgr := s.d.overlord.EventManager()
+	eventMgr.Publish(eventstate.Event{Type: eventstate.TypeCheck, Name: "chk1", Old: "up", New: "down"})
+	eventMgr.Publish(eventstate.Event{
+		Time: time.Date(2023, 4, 23, 1, 28, 52, 0, time.UTC),
+		Type: eventstate.TypeService,
+		Name: "svc1",
+		Old:  "starting",
+		New:  "running",
+	})
+	c.Check(<-rec.logChan, Equals, `{"time":"2023-04-23T01:28:52Z","type":"service","name":"svc1","old":"starting","new":"running"}`+"\n")
+
+	// Warnings are published by the overlord.
+	st := s.d.overlord.State()
+	st.Lock()
+	st.Warnf("something happened")
+	st.Unlock()
+	var event map[string]interface{}
+	err = json.Unmarshal([]byte(<-rec.logChan), &event)
+	c.Assert(err, IsNil)
+	delete(event, "time")
+	c.Check(event, DeepEquals, map[string]interface{}{
+		"type":    "warning",
+		"message": "something happened",
+	})
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		c.Fatalf("timed out waiting for events response to finish")
+	}
+}
+
+func (s *apiSuite) TestEventsInvalidType(c *C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v1/events?types=service,foo", nil)
+	c.Assert(err, IsNil)
+	rsp := v1GetEvents(apiCmd("/v1/events"), req, nil).(*resp)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, IsNil)
+	c.Check(body["result"], DeepEquals, map[string]interface{}{
+		"message": `invalid event type "foo"`,
+	})
+}
@@ -16,11 +16,17 @@ package daemon
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"os/exec"
+	"os/user"
+	"strconv"
 	"time"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/canonical/pebble/internals/osutil"
 	"github.com/canonical/pebble/internals/overlord/cmdstate"
 	"github.com/canonical/pebble/internals/overlord/state"
@@ -42,6 +48,13 @@ type execPayload struct {
 	SplitStderr    bool              `json:"split-stderr"`
 	Width          int               `json:"width"`
 	Height         int               `json:"height"`
+	Detached       bool              `json:"detached"`
+	Nice           int               `json:"nice"`
+	IOClass        string            `json:"io-class"`
+	IOPriority     *int              `json:"io-priority"`
+	Rlimits        map[string]string `json:"rlimits"`
+	MemoryLimit    int64             `json:"memory-limit"`
+	CPULimit       float64           `json:"cpu-limit"`
 }
 
 func v1PostExec(c *Command, req *http.Request, _ *userState) Response {
@@ -63,6 +76,27 @@ func v1PostExec(c *Command, req *http.Request, _ *userState) Response {
 		}
 	}
 
+	resources := cmdstate.ExecResources{
+		Nice:        payload.Nice,
+		IOClass:     payload.IOClass,
+		IOPriority:  payload.IOPriority,
+		MemoryLimit: payload.MemoryLimit,
+		CPULimit:    payload.CPULimit,
+	}
+	if len(payload.Rlimits) > 0 {
+		resources.Rlimits = make(map[string]uint64, len(payload.Rlimits))
+		for name, value := range payload.Rlimits {
+			limit, err := parseRlimit(value)
+			if err != nil {
+				return statusBadRequest("invalid resource limit %q: %v", name, err)
+			}
+			resources.Rlimits[name] = limit
+		}
+	}
+	if err := resources.Validate(); err != nil {
+		return statusBadRequest("%v", err)
+	}
+
 	// Check up-front that the executable exists.
 	_, err := exec.LookPath(payload.Command[0])
 	if err != nil {
@@ -121,6 +155,8 @@ func v1PostExec(c *Command, req *http.Request, _ *userState) Response {
 		SplitStderr: payload.SplitStderr,
 		Width:       payload.Width,
 		Height:      payload.Height,
+		Detached:    payload.Detached,
+		Resources:   resources,
 	}
 	task, metadata, err := cmdstate.Exec(st, args)
 	if err != nil {
@@ -140,3 +176,154 @@ func v1PostExec(c *Command, req *http.Request, _ *userState) Response {
 	}
 	return AsyncResponse(result, change.ID())
 }
+
+// parseRlimit parses a resource limit value, which is either a non-negative
+// integer or "unlimited".
+func parseRlimit(value string) (uint64, error) {
+	if value == "unlimited" {
+		return unix.RLIM_INFINITY, nil
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf(`must be a non-negative integer or "unlimited"`)
+	}
+	return limit, nil
+}
+
+type execInfo struct {
+	TaskID    string    `json:"task-id"`
+	ChangeID  string    `json:"change-id,omitempty"`
+	Command   []string  `json:"command,omitempty"`
+	UserID    int       `json:"user-id"`
+	User      string    `json:"user,omitempty"`
+	StartTime time.Time `json:"start-time"`
+	PID       int       `json:"pid"`
+	Detached  bool      `json:"detached,omitempty"`
+	Terminal  bool      `json:"terminal,omitempty"`
+	Streams   []string  `json:"streams"`
+}
+
+func v1GetExec(c *Command, r *http.Request, _ *userState) Response {
+	executions := c.d.overlord.CommandManager().Executions()
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	userCache := make(map[int]string)
+	infos := []execInfo{} // if no executions, return [] instead of null
+	for _, e := range executions {
+		infos = append(infos, newExecInfo(st, e, userCache))
+	}
+	return SyncResponse(infos)
+}
+
+// newExecInfo builds the API representation of a running command. It must
+// be called with the state lock held.
+func newExecInfo(st *state.State, e *cmdstate.ExecutionInfo, userCache map[int]string) execInfo {
+	info := execInfo{
+		TaskID:    e.TaskID,
+		Command:   e.Command,
+		UserID:    os.Getuid(),
+		StartTime: e.StartTime,
+		PID:       e.PID,
+		Detached:  e.Detached,
+		Terminal:  e.Terminal,
+		Streams:   e.Streams,
+	}
+	if e.UserID != nil {
+		info.UserID = *e.UserID
+	}
+	if task := st.Task(e.TaskID); task != nil && task.Change() != nil {
+		info.ChangeID = task.Change().ID()
+	}
+	if info.Streams == nil {
+		info.Streams = []string{}
+	}
+
+	// Look up user names (cache per API call for efficiency).
+	info.User = userCache[info.UserID]
+	if info.User == "" {
+		u, err := user.LookupId(strconv.Itoa(info.UserID))
+		if err == nil {
+			info.User = u.Username
+			userCache[info.UserID] = u.Username
+		}
+	}
+	return info
+}
+
+// v1GetExecTask returns information about a single exec task, including the
+// ID of its change. Only the task and change IDs are included once the
+// command has finished.
+func v1GetExecTask(c *Command, r *http.Request, _ *userState) Response {
+	taskID := muxVars(r)["task-id"]
+	executions := c.d.overlord.CommandManager().Executions()
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	task := st.Task(taskID)
+	if task == nil || task.Kind() != "exec" {
+		return statusNotFound("cannot find exec task %q", taskID)
+	}
+	for _, e := range executions {
+		if e.TaskID == taskID {
+			return SyncResponse(newExecInfo(st, e, make(map[int]string)))
+		}
+	}
+	info := execInfo{TaskID: taskID, Streams: []string{}}
+	if task.Change() != nil {
+		info.ChangeID = task.Change().ID()
+	}
+	return SyncResponse(info)
+}
+
+func v1PostExecTask(c *Command, r *http.Request, _ *userState) Response {
+	taskID := muxVars(r)["task-id"]
+
+	var payload struct {
+		Action string `json:"action"`
+		Signal string `json:"signal"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&payload); err != nil {
+		return statusBadRequest("cannot decode request body: %v", err)
+	}
+
+	switch payload.Action {
+	case "signal":
+		if unix.SignalNum(payload.Signal) == 0 {
+			return statusBadRequest("invalid signal name %q", payload.Signal)
+		}
+	case "kill":
+		if payload.Signal != "" {
+			return statusBadRequest("kill accepts no signal")
+		}
+		payload.Signal = "SIGKILL"
+	default:
+		return statusBadRequest("action %q is unsupported", payload.Action)
+	}
+
+	err := c.d.overlord.CommandManager().SignalExecution(taskID, payload.Signal)
+	if errors.Is(err, os.ErrNotExist) {
+		return statusNotFound("%v", err)
+	}
+	if err != nil {
+		return statusInternalError("%v", err)
+	}
+	return SyncResponse(true)
+}
+
+func v1GetExecRecording(c *Command, r *http.Request, _ *userState) Response {
+	taskID := muxVars(r)["task-id"]
+	path, err := c.d.overlord.CommandManager().RecordingPath(taskID)
+	if errors.Is(err, os.ErrNotExist) {
+		return statusNotFound("%v", err)
+	}
+	if err != nil {
+		return statusInternalError("%v", err)
+	}
+	return fileResponse(path)
+}
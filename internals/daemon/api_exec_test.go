@@ -348,6 +348,251 @@ func (s *execSuite) TestStreaming(c *C) {
 	c.Check(err, IsNil)
 }
 
+func (s *execSuite) TestDetached(c *C) {
+	process, err := s.client.Exec(&client.ExecOptions{
+		Command:  []string{"/bin/sh", "-c", "echo OUT; echo ERR >&2; exit 3"},
+		Detached: true,
+	})
+	c.Assert(err, IsNil)
+
+	// Wait for the command to finish before attaching: the output is still
+	// available afterwards.
+	change, err := s.client.WaitChange(process.ChangeID(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(change.Err, Equals, "")
+
+	outBuf := &bytes.Buffer{}
+	attached, err := s.client.ExecAttach(&client.ExecAttachOptions{
+		TaskID: process.TaskID(),
+		Stdout: outBuf,
+	})
+	c.Assert(err, IsNil)
+	err = attached.Wait()
+	exitError, ok := err.(*client.ExitError)
+	c.Assert(ok, Equals, true, Commentf("expected *client.ExitError, got %T", err))
+	c.Check(exitError.ExitCode(), Equals, 3)
+	c.Check(outBuf.String(), Equals, "OUT\nERR\n")
+}
+
+func (s *execSuite) TestDetachedReattach(c *C) {
+	stdinCh := make(chan []byte)
+	process, err := s.client.Exec(&client.ExecOptions{
+		Command:  []string{"/bin/sh", "-c", "echo started; trap 'echo stopping; exit 0' TERM; while true; do sleep 0.01; done"},
+		Stdin:    channelReader{stdinCh}, // ignored
+		Detached: true,
+	})
+	c.Assert(err, IsNil)
+
+	// Attach and detach without affecting the command.
+	outputCh := make(chan []byte, 10)
+	_, err = s.client.ExecAttach(&client.ExecAttachOptions{
+		TaskID: process.TaskID(),
+		Stdout: channelWriter{outputCh},
+	})
+	c.Assert(err, IsNil)
+	select {
+	case b := <-outputCh:
+		c.Check(string(b), Equals, "started\n")
+	case <-time.After(5 * time.Second):
+		c.Fatalf("timed out waiting for output")
+	}
+
+	// Attach again, and stop the command with a signal.
+	outBuf := &bytes.Buffer{}
+	second, err := s.client.ExecAttach(&client.ExecAttachOptions{
+		TaskID: process.TaskID(),
+		Stdout: outBuf,
+	})
+	c.Assert(err, IsNil)
+	err = second.SendSignal("SIGTERM")
+	c.Assert(err, IsNil)
+	err = second.Wait()
+	c.Check(err, IsNil)
+	c.Check(outBuf.String(), Equals, "started\nstopping\n")
+}
+
+func (s *execSuite) TestDetachedTerminal(c *C) {
+	httpResp, execResp := execRequest(c, &client.ExecOptions{
+		Command:  []string{"echo", "foo"},
+		Terminal: true,
+		Detached: true,
+	})
+	c.Check(httpResp.StatusCode, Equals, http.StatusInternalServerError)
+	c.Check(execResp.Result["message"], Equals, "cannot call exec: cannot use detached mode with a terminal")
+}
+
+func (s *execSuite) TestListAndSignal(c *C) {
+	executions, err := s.client.Executions()
+	c.Assert(err, IsNil)
+	c.Check(executions, HasLen, 0)
+
+	process, err := s.client.Exec(&client.ExecOptions{
+		Command:  []string{"sleep", "10"},
+		Detached: true,
+	})
+	c.Assert(err, IsNil)
+
+	// Wait till the command has started.
+	for i := 0; ; i++ {
+		executions, err = s.client.Executions()
+		c.Assert(err, IsNil)
+		if len(executions) > 0 || i >= 100 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(executions, HasLen, 1)
+	e := executions[0]
+	c.Check(e.TaskID, Equals, process.TaskID())
+	c.Check(e.ChangeID, Equals, process.ChangeID())
+	c.Check(e.Command, DeepEquals, []string{"sleep", "10"})
+	c.Check(e.UserID, Equals, os.Getuid())
+	c.Check(e.PID, Not(Equals), 0)
+	c.Check(e.Detached, Equals, true)
+	c.Check(e.Streams, DeepEquals, []string{})
+	c.Check(time.Since(e.StartTime) < time.Minute, Equals, true)
+
+	execution, err := s.client.Execution(process.TaskID())
+	c.Assert(err, IsNil)
+	c.Check(execution, DeepEquals, e)
+
+	err = s.client.SignalExecution(&client.SignalExecutionOptions{
+		TaskID: process.TaskID(),
+		Signal: "SIGFOO",
+	})
+	c.Check(err, ErrorMatches, `invalid signal name "SIGFOO"`)
+
+	err = s.client.KillExecution(process.TaskID())
+	c.Assert(err, IsNil)
+	_, err = s.client.WaitChange(process.ChangeID(), nil)
+	c.Assert(err, IsNil)
+
+	executions, err = s.client.Executions()
+	c.Assert(err, IsNil)
+	c.Check(executions, HasLen, 0)
+
+	// The task can still be looked up once the command has finished.
+	execution, err = s.client.Execution(process.TaskID())
+	c.Assert(err, IsNil)
+	c.Check(execution, DeepEquals, &client.ExecutionInfo{
+		TaskID:   process.TaskID(),
+		ChangeID: process.ChangeID(),
+		Streams:  []string{},
+	})
+
+	_, err = s.client.Execution("42")
+	c.Check(err, ErrorMatches, `cannot find exec task "42"`)
+
+	err = s.client.SignalExecution(&client.SignalExecutionOptions{
+		TaskID: process.TaskID(),
+		Signal: "SIGTERM",
+	})
+	c.Check(err, ErrorMatches, fmt.Sprintf(`cannot find running command in task %q: .*`, process.TaskID()))
+}
+
+func (s *execSuite) TestRecording(c *C) {
+	s.daemon.overlord.CommandManager().SetRecording(true)
+
+	process, err := s.client.Exec(&client.ExecOptions{
+		Command:  []string{"echo", "hello"},
+		Terminal: true,
+		Width:    100,
+		Height:   30,
+		Stdin:    strings.NewReader(""),
+		Stdout:   ioutil.Discard,
+	})
+	c.Assert(err, IsNil)
+	err = process.Wait()
+	c.Assert(err, IsNil)
+
+	reader, err := s.client.ExecRecording(process.TaskID())
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	c.Assert(len(lines) >= 2, Equals, true, Commentf("%q", data))
+	var header map[string]interface{}
+	err = json.Unmarshal([]byte(lines[0]), &header)
+	c.Assert(err, IsNil)
+	c.Check(header["version"], Equals, float64(2))
+	c.Check(header["width"], Equals, float64(100))
+	c.Check(header["height"], Equals, float64(30))
+	c.Check(header["command"], Equals, "echo hello")
+	var output string
+	for _, line := range lines[1:] {
+		var event []interface{}
+		err = json.Unmarshal([]byte(line), &event)
+		c.Assert(err, IsNil)
+		if event[1] == "o" {
+			output += event[2].(string)
+		}
+	}
+	c.Check(output, Equals, "hello\r\n")
+}
+
+func (s *execSuite) TestRecordingNotFound(c *C) {
+	// Recording isn't enabled by default.
+	process, err := s.client.Exec(&client.ExecOptions{
+		Command:  []string{"echo", "hello"},
+		Terminal: true,
+		Stdin:    strings.NewReader(""),
+		Stdout:   ioutil.Discard,
+	})
+	c.Assert(err, IsNil)
+	err = process.Wait()
+	c.Assert(err, IsNil)
+
+	_, err = s.client.ExecRecording(process.TaskID())
+	c.Check(err, ErrorMatches, fmt.Sprintf(`cannot find recording for task %q: file does not exist`, process.TaskID()))
+}
+
+func (s *execSuite) TestResourceControls(c *C) {
+	stdout, stderr, waitErr := s.exec(c, "", &client.ExecOptions{
+		Command: []string{"/bin/sh", "-c", "nice; ionice -p $$"},
+		Nice:    5,
+		IOClass: "idle",
+	})
+	c.Check(waitErr, IsNil)
+	c.Check(stdout, Equals, "5\nidle\n")
+	c.Check(stderr, Equals, "")
+}
+
+func (s *execSuite) TestResourceControlsInvalid(c *C) {
+	priority := 9
+	tests := []struct {
+		opts  client.ExecOptions
+		error string
+	}{{
+		opts:  client.ExecOptions{Nice: 20},
+		error: "nice value must be between -20 and 19, not 20",
+	}, {
+		opts:  client.ExecOptions{IOClass: "fast"},
+		error: `I/O class must be "realtime", "best-effort", or "idle", not "fast"`,
+	}, {
+		opts:  client.ExecOptions{IOClass: "idle", IOPriority: &priority},
+		error: `I/O priority requires I/O class "realtime" or "best-effort"`,
+	}, {
+		opts:  client.ExecOptions{IOClass: "best-effort", IOPriority: &priority},
+		error: "I/O priority must be between 0 and 7, not 9",
+	}, {
+		opts:  client.ExecOptions{Rlimits: map[string]string{"nofile": "lots"}},
+		error: `invalid resource limit "nofile": must be a non-negative integer or "unlimited"`,
+	}, {
+		opts:  client.ExecOptions{Rlimits: map[string]string{"files": "10"}},
+		error: `invalid resource limit "files" \(must be one of: .*\)`,
+	}, {
+		opts:  client.ExecOptions{MemoryLimit: -1},
+		error: "memory limit must not be negative",
+	}}
+	for _, test := range tests {
+		test.opts.Command = []string{"echo", "foo"}
+		httpResp, execResp := execRequest(c, &test.opts)
+		c.Check(httpResp.StatusCode, Equals, http.StatusBadRequest)
+		c.Check(execResp.Result["message"], Matches, test.error)
+	}
+}
+
 type channelReader struct {
 	ch chan []byte
 }
@@ -427,6 +672,13 @@ func execRequest(c *C, opts *client.ExecOptions) (*http.Response, execResponse)
 		SplitStderr: opts.Stderr != nil,
 		Width:       opts.Width,
 		Height:      opts.Height,
+		Detached:    opts.Detached,
+		Nice:        opts.Nice,
+		IOClass:     opts.IOClass,
+		IOPriority:  opts.IOPriority,
+		Rlimits:     opts.Rlimits,
+		MemoryLimit: opts.MemoryLimit,
+		CPULimit:    opts.CPULimit,
 	}
 	requestBody, err := json.Marshal(&payload)
 	c.Assert(err, IsNil)
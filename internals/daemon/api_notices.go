@@ -0,0 +1,152 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/canonical/x-go/strutil"
+
+	"github.com/canonical/pebble/internals/overlord/state"
+)
+
+type addedNotice struct {
+	ID string `json:"id"`
+}
+
+func v1GetNotices(c *Command, r *http.Request, _ *userState) Response {
+	query := r.URL.Query()
+
+	filter := &state.NoticeFilter{}
+	for _, t := range strutil.MultiCommaSeparatedList(query["types"]) {
+		noticeType := state.NoticeType(t)
+		if noticeType != state.CustomNotice {
+			return statusBadRequest("invalid notice type %q", t)
+		}
+		filter.Types = append(filter.Types, noticeType)
+	}
+	filter.Keys = strutil.MultiCommaSeparatedList(query["keys"])
+
+	if after := query.Get("after"); after != "" {
+		var err error
+		filter.After, err = time.Parse(time.RFC3339, after)
+		if err != nil {
+			return statusBadRequest("invalid after timestamp %q: %v", after, err)
+		}
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var notices []*state.Notice
+	if timeoutStr := query.Get("timeout"); timeoutStr != "" {
+		// Timeout specified, wait till notices match the filter or the
+		// timeout occurs, whichever is first.
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			return statusBadRequest("invalid timeout %q: %v", timeoutStr, err)
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		notices, err = st.WaitNotices(ctx, filter)
+		if errors.Is(err, context.Canceled) {
+			return statusInternalError("request cancelled")
+		}
+		// Otherwise the timeout elapsed, so return no notices.
+	} else {
+		notices = st.Notices(filter)
+	}
+
+	if len(notices) == 0 {
+		// no need to confuse the issue
+		return SyncResponse([]*state.Notice{})
+	}
+	return SyncResponse(notices)
+}
+
+func v1GetNotice(c *Command, r *http.Request, _ *userState) Response {
+	noticeID := muxVars(r)["id"]
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	notice := st.Notice(noticeID)
+	if notice == nil {
+		return statusNotFound("cannot find notice with id %q", noticeID)
+	}
+	return SyncResponse(notice)
+}
+
+func v1PostNotices(c *Command, r *http.Request, _ *userState) Response {
+	defer r.Body.Close()
+	var payload struct {
+		Action      string            `json:"action"`
+		Type        string            `json:"type"`
+		Key         string            `json:"key"`
+		RepeatAfter string            `json:"repeat-after"`
+		ExpireAfter string            `json:"expire-after"`
+		Data        map[string]string `json:"data"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&payload); err != nil {
+		return statusBadRequest("cannot decode request body into notice operation: %v", err)
+	}
+	if payload.Action != "add" {
+		return statusBadRequest("invalid notice action %q", payload.Action)
+	}
+
+	noticeType := state.NoticeType(payload.Type)
+	if noticeType != state.CustomNotice {
+		return statusBadRequest(`invalid notice type %q (can only add "custom" notices)`, payload.Type)
+	}
+	if err := state.ValidateNotice(noticeType, payload.Key); err != nil {
+		return statusBadRequest("%v", err)
+	}
+
+	var repeatAfter time.Duration
+	if payload.RepeatAfter != "" {
+		var err error
+		repeatAfter, err = time.ParseDuration(payload.RepeatAfter)
+		if err != nil {
+			return statusBadRequest("invalid repeat-after %q: %v", payload.RepeatAfter, err)
+		}
+	}
+
+	var expireAfter time.Duration
+	if payload.ExpireAfter != "" {
+		var err error
+		expireAfter, err = time.ParseDuration(payload.ExpireAfter)
+		if err != nil {
+			return statusBadRequest("invalid expire-after %q: %v", payload.ExpireAfter, err)
+		}
+		if expireAfter < 0 {
+			return statusBadRequest("expire-after must not be negative")
+		}
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	noticeID := st.AddNotice(noticeType, payload.Key, &state.AddNoticeOptions{
+		Data:        payload.Data,
+		RepeatAfter: repeatAfter,
+		ExpireAfter: expireAfter,
+	})
+	return SyncResponse(addedNotice{ID: noticeID})
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/overlord/state"
+)
+
+func (s *apiSuite) TestNoticesFilter(c *C) {
+	s.daemon(c)
+
+	st := s.d.overlord.State()
+	st.Lock()
+	st.AddNotice(state.CustomNotice, "example.com/foo", nil)
+	time.Sleep(time.Microsecond) // ensure there's time between the occurrences
+	st.AddNotice(state.CustomNotice, "example.com/bar", &state.AddNoticeOptions{
+		Data: map[string]string{"k": "v"},
+	})
+	st.Unlock()
+
+	notices := s.getNotices(c, "")
+	c.Assert(notices, HasLen, 2)
+	c.Check(notices[0]["key"], Equals, "example.com/foo")
+	c.Check(notices[1]["key"], Equals, "example.com/bar")
+
+	notices = s.getNotices(c, "?types=custom&keys=example.com/bar")
+	c.Assert(notices, HasLen, 1)
+	n := notices[0]
+	firstOccurred, err := time.Parse(time.RFC3339, n["first-occurred"].(string))
+	c.Assert(err, IsNil)
+	delete(n, "first-occurred")
+	delete(n, "last-occurred")
+	delete(n, "last-repeated")
+	c.Check(n, DeepEquals, map[string]interface{}{
+		"id":           "2",
+		"type":         "custom",
+		"key":          "example.com/bar",
+		"occurrences":  1.0,
+		"last-data":    map[string]interface{}{"k": "v"},
+		"expire-after": "168h0m0s",
+	})
+
+	// Only notices repeated after the given time are returned.
+	after := firstOccurred.Add(-time.Nanosecond).Format(time.RFC3339Nano)
+	notices = s.getNotices(c, "?after="+url.QueryEscape(after))
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0]["key"], Equals, "example.com/bar")
+
+	notices = s.getNotices(c, "?keys=example.com/baz")
+	c.Check(notices, HasLen, 0)
+}
+
+func (s *apiSuite) TestNoticesWait(c *C) {
+	s.daemon(c)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		st := s.d.overlord.State()
+		st.Lock()
+		st.AddNotice(state.CustomNotice, "example.com/foo", nil)
+		st.Unlock()
+	}()
+
+	notices := s.getNotices(c, "?timeout=10s")
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0]["key"], Equals, "example.com/foo")
+}
+
+func (s *apiSuite) TestNoticesWaitTimeout(c *C) {
+	s.daemon(c)
+
+	notices := s.getNotices(c, "?timeout=10ms")
+	c.Check(notices, HasLen, 0)
+}
+
+func (s *apiSuite) TestNoticesInvalidParams(c *C) {
+	s.daemon(c)
+
+	for _, test := range []struct {
+		query   string
+		message string
+	}{
+		{"?types=foo", `invalid notice type "foo"`},
+		{"?after=foo", `invalid after timestamp "foo": .*`},
+		{"?timeout=foo", `invalid timeout "foo": .*`},
+	} {
+		req, err := http.NewRequest("GET", "/v1/notices"+test.query, nil)
+		c.Assert(err, IsNil)
+		rsp := v1GetNotices(apiCmd("/v1/notices"), req, nil).(*resp)
+		c.Check(rsp.Status, Equals, 400, Commentf("query %q", test.query))
+		c.Check(rsp.Result.(*errorResult).Message, Matches, test.message)
+	}
+}
+
+func (s *apiSuite) TestNotice(c *C) {
+	s.daemon(c)
+
+	st := s.d.overlord.State()
+	st.Lock()
+	id := st.AddNotice(state.CustomNotice, "example.com/foo", nil)
+	st.Unlock()
+
+	s.vars = map[string]string{"id": id}
+	req, err := http.NewRequest("GET", "/v1/notices/"+id, nil)
+	c.Assert(err, IsNil)
+	rsp := v1GetNotice(apiCmd("/v1/notices/{id}"), req, nil).(*resp)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(rsp.Result.(*state.Notice).Key(), Equals, "example.com/foo")
+
+	s.vars = map[string]string{"id": "1234"}
+	req, err = http.NewRequest("GET", "/v1/notices/1234", nil)
+	c.Assert(err, IsNil)
+	rsp = v1GetNotice(apiCmd("/v1/notices/{id}"), req, nil).(*resp)
+	c.Check(rsp.Status, Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, Equals, `cannot find notice with id "1234"`)
+}
+
+func (s *apiSuite) TestAddNotice(c *C) {
+	s.daemon(c)
+
+	body := `{"action": "add", "type": "custom", "key": "example.com/foo", "repeat-after": "1h", "expire-after": "2h", "data": {"k": "v"}}`
+	req, err := http.NewRequest("POST", "/v1/notices", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	rsp := v1PostNotices(apiCmd("/v1/notices"), req, nil).(*resp)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, addedNotice{ID: "1"})
+
+	st := s.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	notices := st.Notices(nil)
+	c.Assert(notices, HasLen, 1)
+	buf, err := json.Marshal(notices[0])
+	c.Assert(err, IsNil)
+	var n map[string]interface{}
+	c.Assert(json.Unmarshal(buf, &n), IsNil)
+	c.Check(n["key"], Equals, "example.com/foo")
+	c.Check(n["repeat-after"], Equals, "1h0m0s")
+	c.Check(n["expire-after"], Equals, "2h0m0s")
+	c.Check(n["last-data"], DeepEquals, map[string]interface{}{"k": "v"})
+}
+
+func (s *apiSuite) TestAddNoticeInvalid(c *C) {
+	s.daemon(c)
+
+	for _, test := range []struct {
+		body    string
+		message string
+	}{
+		{`@`, `cannot decode request body into notice operation: .*`},
+		{`{"action": "foo"}`, `invalid notice action "foo"`},
+		{`{"action": "add", "type": "foo"}`, `invalid notice type "foo" \(can only add "custom" notices\)`},
+		{`{"action": "add", "type": "custom", "key": "foo"}`, `custom notice key must be in the form "domain.com/key", not "foo"`},
+		{`{"action": "add", "type": "custom", "key": "a.b/c", "repeat-after": "x"}`, `invalid repeat-after "x": .*`},
+		{`{"action": "add", "type": "custom", "key": "a.b/c", "expire-after": "x"}`, `invalid expire-after "x": .*`},
+		{`{"action": "add", "type": "custom", "key": "a.b/c", "expire-after": "-1h"}`, `expire-after must not be negative`},
+	} {
+		req, err := http.NewRequest("POST", "/v1/notices", bytes.NewBufferString(test.body))
+		c.Assert(err, IsNil)
+		rsp := v1PostNotices(apiCmd("/v1/notices"), req, nil).(*resp)
+		c.Check(rsp.Status, Equals, 400, Commentf("body %s", test.body))
+		c.Check(rsp.Result.(*errorResult).Message, Matches, test.message)
+	}
+}
+
+func (s *apiSuite) getNotices(c *C, query string) []map[string]interface{} {
+	req, err := http.NewRequest("GET", "/v1/notices"+query, nil)
+	c.Assert(err, IsNil)
+	rsp := v1GetNotices(apiCmd("/v1/notices"), req, nil).(*resp)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+	c.Assert(rec.Code, Equals, 200)
+
+	var body struct {
+		Result []map[string]interface{} `json:"result"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Assert(err, IsNil)
+	return body.Result
+}
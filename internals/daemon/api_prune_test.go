@@ -0,0 +1,52 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/overlord/state"
+)
+
+func (s *apiSuite) TestPrune(c *C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	restore := state.FakeTime(time.Now().Add(-48 * time.Hour))
+	old := st.NewChange("old", "...")
+	t1 := st.NewTask("foo", "...")
+	old.AddTask(t1)
+	t1.SetStatus(state.DoneStatus)
+	restore()
+	recent := st.NewChange("recent", "...")
+	t2 := st.NewTask("foo", "...")
+	recent.AddTask(t2)
+	t2.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	req, err := http.NewRequest("POST", "/v1/prune", nil)
+	c.Assert(err, IsNil)
+	rsp := v1PostPrune(apiCmd("/v1/prune"), req, nil).(*resp)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, pruneResult{PrunedChanges: 1})
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Change(old.ID()), IsNil)
+	c.Check(st.Change(recent.ID()), NotNil)
+}
@@ -17,30 +17,85 @@ package daemon
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/canonical/pebble/internals/overlord/state"
 )
 
-func v1AckWarnings(c *Command, r *http.Request, _ *userState) Response {
+func v1PostWarnings(c *Command, r *http.Request, _ *userState) Response {
 	defer r.Body.Close()
 	var op struct {
-		Action    string    `json:"action"`
-		Timestamp time.Time `json:"timestamp"`
+		Action      string    `json:"action"`
+		Timestamp   time.Time `json:"timestamp"`
+		Message     string    `json:"message"`
+		RepeatAfter string    `json:"repeat-after"`
+		ExpireAfter string    `json:"expire-after"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&op); err != nil {
 		return statusBadRequest("cannot decode request body into warnings operation: %v", err)
 	}
-	if op.Action != "okay" {
-		return statusBadRequest("unknown warning action %q", op.Action)
+
+	// Only admins may add or delete warnings, whatever access the command
+	// allows for other actions.
+	if (op.Action == "add" || op.Action == "delete") && !isAdmin(r) {
+		return statusForbidden("cannot %s warnings: admin access required", op.Action)
 	}
-	st := c.d.overlord.State()
-	st.Lock()
-	defer st.Unlock()
-	n := stateOkayWarnings(st, op.Timestamp)
 
-	return SyncResponse(n)
+	switch op.Action {
+	case "okay":
+		st := c.d.overlord.State()
+		st.Lock()
+		defer st.Unlock()
+		if op.Message != "" {
+			if !st.OkayWarning(op.Message) {
+				return statusNotFound("cannot find warning %q", op.Message)
+			}
+			return SyncResponse(1)
+		}
+		n := stateOkayWarnings(st, op.Timestamp)
+		return SyncResponse(n)
+
+	case "add":
+		var opts state.WarnOptions
+		if op.RepeatAfter != "" {
+			d, err := time.ParseDuration(op.RepeatAfter)
+			if err != nil {
+				return statusBadRequest("invalid repeat-after %q: %v", op.RepeatAfter, err)
+			}
+			opts.RepeatAfter = d
+		}
+		if op.ExpireAfter != "" {
+			d, err := time.ParseDuration(op.ExpireAfter)
+			if err != nil {
+				return statusBadRequest("invalid expire-after %q: %v", op.ExpireAfter, err)
+			}
+			opts.ExpireAfter = d
+		}
+		st := c.d.overlord.State()
+		st.Lock()
+		defer st.Unlock()
+		if err := st.Warn(op.Message, &opts); err != nil {
+			return statusBadRequest("cannot add warning: %v", err)
+		}
+		return SyncResponse(nil)
+
+	case "delete":
+		if op.Message == "" {
+			return statusBadRequest("must specify the message of the warning to delete")
+		}
+		st := c.d.overlord.State()
+		st.Lock()
+		defer st.Unlock()
+		if !st.RemoveWarning(op.Message) {
+			return statusNotFound("cannot find warning %q", op.Message)
+		}
+		return SyncResponse(nil)
+
+	default:
+		return statusBadRequest("unknown warning action %q", op.Action)
+	}
 }
 
 func v1GetWarnings(c *Command, r *http.Request, _ *userState) Response {
@@ -56,6 +111,16 @@ func v1GetWarnings(c *Command, r *http.Request, _ *userState) Response {
 		return statusBadRequest("invalid select parameter: %q", sel)
 	}
 
+	text := query.Get("text")
+	var since time.Time
+	if s := query.Get("since"); s != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return statusBadRequest("invalid since parameter: %q", s)
+		}
+	}
+
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
@@ -66,6 +131,9 @@ func v1GetWarnings(c *Command, r *http.Request, _ *userState) Response {
 	} else {
 		ws, _ = statePendingWarnings(st)
 	}
+	if text != "" || !since.IsZero() {
+		ws = filterWarnings(ws, text, since)
+	}
 	if len(ws) == 0 {
 		// no need to confuse the issue
 		return SyncResponse([]state.Warning{})
@@ -73,3 +141,20 @@ func v1GetWarnings(c *Command, r *http.Request, _ *userState) Response {
 
 	return SyncResponse(ws)
 }
+
+// filterWarnings returns the warnings whose message contains text (ignoring
+// case), and that were last added after since, if those are set.
+func filterWarnings(ws []*state.Warning, text string, since time.Time) []*state.Warning {
+	text = strings.ToLower(text)
+	var filtered []*state.Warning
+	for _, w := range ws {
+		if text != "" && !strings.Contains(strings.ToLower(w.String()), text) {
+			continue
+		}
+		if !since.IsZero() && !w.LastAdded().After(since) {
+			continue
+		}
+		filtered = append(filtered, w)
+	}
+	return filtered
+}
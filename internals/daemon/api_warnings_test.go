@@ -16,11 +16,14 @@ package daemon
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/canonical/pebble/internals/osutil/sys"
 	"github.com/canonical/pebble/internals/overlord/state"
 
 	"gopkg.in/check.v1"
@@ -82,3 +85,169 @@ func (s *apiSuite) TestAckWarnings(c *check.C) {
 	c.Check(calls, check.Equals, "ok")
 	c.Check(result, check.DeepEquals, 0)
 }
+
+func (s *apiSuite) postWarnings(c *check.C, body string) *resp {
+	return s.postWarningsAs(c, 0, body)
+}
+
+func (s *apiSuite) postWarningsAs(c *check.C, uid uint32, body string) *resp {
+	req, err := http.NewRequest("POST", "/v1/warnings", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=%d;socket=;", uid)
+	return v1PostWarnings(apiCmd("/v1/warnings"), req, nil).(*resp)
+}
+
+func (s *apiSuite) TestWarningsAdminOnly(c *check.C) {
+	s.daemon(c)
+	oldGetuid := sysGetuid
+	sysGetuid = func() sys.UserID { return 0 }
+	defer func() { sysGetuid = oldGetuid }()
+
+	rsp := s.postWarningsAs(c, 1000, `{"action": "add", "message": "foo"}`)
+	c.Check(rsp.Status, check.Equals, 403)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot add warnings: admin access required")
+
+	rsp = s.postWarningsAs(c, 1000, `{"action": "delete", "message": "foo"}`)
+	c.Check(rsp.Status, check.Equals, 403)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot delete warnings: admin access required")
+
+	rsp = s.postWarningsAs(c, 1000, `{"action": "okay", "timestamp": "2006-01-02T15:04:05Z"}`)
+	c.Check(rsp.Status, check.Equals, 200)
+}
+
+func (s *apiSuite) getWarnings(c *check.C, query string) *resp {
+	req, err := http.NewRequest("GET", "/v1/warnings?"+query, nil)
+	c.Assert(err, check.IsNil)
+	return v1GetWarnings(apiCmd("/v1/warnings"), req, nil).(*resp)
+}
+
+func warningMessages(c *check.C, result interface{}) []string {
+	data, err := json.Marshal(result)
+	c.Assert(err, check.IsNil)
+	var ws []struct {
+		Message string `json:"message"`
+	}
+	c.Assert(json.Unmarshal(data, &ws), check.IsNil)
+	messages := []string{}
+	for _, w := range ws {
+		messages = append(messages, w.Message)
+	}
+	return messages
+}
+
+func (s *apiSuite) TestAddWarning(c *check.C) {
+	d := s.daemon(c)
+
+	rsp := s.postWarnings(c, `{"action": "add", "message": "disk is full", "repeat-after": "1h", "expire-after": "48h"}`)
+	c.Assert(rsp.Status, check.Equals, 200)
+
+	st := d.overlord.State()
+	st.Lock()
+	ws := st.AllWarnings()
+	st.Unlock()
+	c.Assert(ws, check.HasLen, 1)
+	data, err := json.Marshal(ws[0])
+	c.Assert(err, check.IsNil)
+	var w map[string]interface{}
+	c.Assert(json.Unmarshal(data, &w), check.IsNil)
+	c.Check(w["message"], check.Equals, "disk is full")
+	c.Check(w["repeat-after"], check.Equals, "1h0m0s")
+	c.Check(w["expire-after"], check.Equals, "48h0m0s")
+
+	rsp = s.postWarnings(c, `{"action": "add", "message": ""}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot add warning: warning has no message")
+
+	rsp = s.postWarnings(c, `{"action": "add", "message": "foo", "repeat-after": "x"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Matches, `invalid repeat-after "x": .*`)
+
+	rsp = s.postWarnings(c, `{"action": "add", "message": "foo", "expire-after": "-1h"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot add warning: warning expire-after must not be negative")
+}
+
+func (s *apiSuite) TestOkayWarningByMessage(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	st.Warnf("number one")
+	st.Warnf("number two")
+	st.Unlock()
+
+	rsp := s.postWarnings(c, `{"action": "okay", "message": "number one"}`)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.Equals, 1)
+
+	rsp = s.getWarnings(c, "")
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(warningMessages(c, rsp.Result), check.DeepEquals, []string{"number two"})
+
+	rsp = s.postWarnings(c, `{"action": "okay", "message": "number three"}`)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot find warning "number three"`)
+}
+
+func (s *apiSuite) TestDeleteWarning(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	st.Warnf("number one")
+	st.Warnf("number two")
+	st.Unlock()
+
+	rsp := s.postWarnings(c, `{"action": "delete", "message": "number one"}`)
+	c.Assert(rsp.Status, check.Equals, 200)
+
+	rsp = s.getWarnings(c, "select=all")
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(warningMessages(c, rsp.Result), check.DeepEquals, []string{"number two"})
+
+	rsp = s.postWarnings(c, `{"action": "delete", "message": "number one"}`)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot find warning "number one"`)
+
+	rsp = s.postWarnings(c, `{"action": "delete"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "must specify the message of the warning to delete")
+}
+
+func (s *apiSuite) TestFilterWarnings(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	st.Warnf("Disk one is full")
+	st.Unlock()
+	between := time.Now()
+	st.Lock()
+	st.Warnf("disk two is full")
+	st.Warnf("network is down")
+	st.Unlock()
+
+	rsp := s.getWarnings(c, "text=DISK")
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(warningMessages(c, rsp.Result), check.DeepEquals, []string{"Disk one is full", "disk two is full"})
+
+	rsp = s.getWarnings(c, "select=all&since="+url.QueryEscape(between.Format(time.RFC3339Nano)))
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(warningMessages(c, rsp.Result), check.DeepEquals, []string{"disk two is full", "network is down"})
+
+	rsp = s.getWarnings(c, "text=disk&since="+url.QueryEscape(between.Format(time.RFC3339Nano)))
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(warningMessages(c, rsp.Result), check.DeepEquals, []string{"disk two is full"})
+
+	rsp = s.getWarnings(c, "text=foo")
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []state.Warning{})
+
+	rsp = s.getWarnings(c, "since=yesterday")
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `invalid since parameter: "yesterday"`)
+}
+
+func (s *apiSuite) TestUnknownWarningAction(c *check.C) {
+	s.daemon(c)
+	rsp := s.postWarnings(c, `{"action": "foo"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `unknown warning action "foo"`)
+}
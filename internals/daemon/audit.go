@@ -0,0 +1,398 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/canonical/pebble/internals/logger"
+)
+
+// maxAuditBody is the maximum number of bytes of a request body captured
+// for the audit log. Larger bodies aren't recorded.
+const maxAuditBody = 64 * 1024
+
+// maxAuditRecord is the maximum length of a line in the audit log. Records
+// that would be longer are shortened when they're written.
+const maxAuditRecord = 2 * maxAuditBody
+
+// maxAuditPath is the length request paths are cut to in records that are
+// too long.
+const maxAuditPath = 4096
+
+// auditRecord is one line of the audit log.
+type auditRecord struct {
+	Time   time.Time       `json:"time"`
+	UID    *uint32         `json:"uid,omitempty"`
+	PID    *int32          `json:"pid,omitempty"`
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  url.Values      `json:"query,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Status int             `json:"status,omitempty"`
+	Change string          `json:"change,omitempty"`
+
+	// Truncated is true if the record was too long to log in full, in
+	// which case the query and parameters are omitted.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// maxAuditLogSize is the size the audit log can grow to before it's rotated,
+// and auditLogFiles is the number of rotated logs that are kept.
+const (
+	maxAuditLogSize = 10 * 1024 * 1024
+	auditLogFiles   = 5
+)
+
+// auditLog is an append-only JSON-lines log of mutating API requests.
+//
+// When the log would grow beyond maxSize, it's renamed to path+".1" (and
+// any older rotated logs to ".2" and so on, up to maxFiles of them), and a
+// new log is started. If maxSize is zero, the log is never rotated.
+type auditLog struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+}
+
+func (l *auditLog) append(record *auditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if len(data) >= maxAuditRecord {
+		// The query (and, once escaped, the parameters) can be arbitrarily
+		// long, so drop them to keep the line readable by records.
+		shortened := *record
+		shortened.Query = nil
+		shortened.Params = nil
+		if len(shortened.Path) > maxAuditPath {
+			shortened.Path = shortened.Path[:maxAuditPath]
+		}
+		shortened.Truncated = true
+		data, err = json.Marshal(&shortened)
+		if err != nil {
+			return err
+		}
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.rotate(int64(len(data))); err != nil {
+		return fmt.Errorf("cannot rotate audit log: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// rotate rotates the log if writing n more bytes to it would make it larger
+// than maxSize. It must be called with l.mu held.
+func (l *auditLog) rotate(n int64) error {
+	if l.maxSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 || info.Size()+n <= l.maxSize {
+		return nil
+	}
+	if l.maxFiles <= 0 {
+		return os.Remove(l.path)
+	}
+	// Renaming over the oldest rotated log removes it.
+	for i := l.maxFiles; i > 0; i-- {
+		err := os.Rename(l.rotatedPath(i-1), l.rotatedPath(i))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotatedPath returns the path of the i'th most recent rotated log, or of
+// the current log if i is zero.
+func (l *auditLog) rotatedPath(i int) string {
+	if i == 0 {
+		return l.path
+	}
+	return l.path + "." + strconv.Itoa(i)
+}
+
+// records returns the records in the audit log, including any rotated logs,
+// oldest first. If limit is greater than zero, only the last limit records
+// are returned.
+func (l *auditLog) records(limit int) ([]*auditRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var records []*auditRecord
+	for i := l.maxFiles; i >= 0; i-- {
+		var err error
+		records, err = readAuditRecords(l.rotatedPath(i), records, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// readAuditRecords appends the records in the given log file to records,
+// keeping only the last limit of them if limit is greater than zero.
+func readAuditRecords(path string, records []*auditRecord, limit int) ([]*auditRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReaderSize(f, maxAuditRecord)
+	for {
+		line, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Skip over a line that's too long (perhaps written by an
+			// older version), rather than failing to read the whole log.
+			for err == bufio.ErrBufferFull {
+				_, err = reader.ReadSlice('\n')
+			}
+			logger.Noticef("Cannot decode audit log record: line too long")
+			line = nil
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record auditRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				logger.Noticef("Cannot decode audit log record: %v", err)
+			} else {
+				records = append(records, &record)
+				if limit > 0 && len(records) > limit {
+					records = records[1:]
+				}
+			}
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
+
+// auditBody wraps a request body, capturing the start of it as the handler
+// reads it.
+type auditBody struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxAuditBody - b.buf.Len(); n > room {
+		b.buf.Write(p[:room])
+		b.truncated = true
+	} else {
+		b.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// params returns the request parameters captured from the body, or nil if
+// they can't be recorded. For multipart requests (file writes), only the
+// "request" metadata part is recorded, not the file contents.
+func (b *auditBody) params(contentType string) json.RawMessage {
+	mediaType, mediaParams, _ := mime.ParseMediaType(contentType)
+	data := b.buf.Bytes()
+	if mediaType == "multipart/form-data" {
+		mr := multipart.NewReader(bytes.NewReader(data), mediaParams["boundary"])
+		part, err := mr.NextPart()
+		if err != nil || part.FormName() != "request" {
+			return nil
+		}
+		data, err = ioutil.ReadAll(part)
+		if err != nil {
+			return nil
+		}
+	} else if b.truncated {
+		return nil
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, data); err != nil {
+		return nil
+	}
+	return redactParams(compacted.Bytes())
+}
+
+// auditRedacted replaces credentials in the parameters recorded in the
+// audit log.
+const auditRedacted = "*****"
+
+// auditSecretMaps are the parameter names (at any depth) whose values are
+// maps that may hold credentials, such as exec and service environment
+// variables, and HTTP headers. Only their keys are recorded.
+var auditSecretMaps = map[string]bool{
+	"environment": true,
+	"headers":     true,
+}
+
+// redactParams returns the JSON parameters with any credentials replaced:
+// the values of auditSecretMaps, and passwords in URLs. The
+// layer YAML of a "layer" parameter is redacted the same way. The parameters
+// are returned unchanged if there's nothing to redact.
+func redactParams(params json.RawMessage) json.RawMessage {
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return params
+	}
+	if !redactValue(value) {
+		return params
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// redactValue redacts the decoded JSON or YAML value in place, and reports
+// whether anything was redacted.
+func redactValue(value interface{}) bool {
+	redacted := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			if m, ok := v.(map[string]interface{}); ok && auditSecretMaps[key] {
+				for k := range m {
+					m[k] = auditRedacted
+					redacted = true
+				}
+				continue
+			}
+			if s, ok := v.(string); ok {
+				switch key {
+				case "url":
+					if r := redactURL(s); r != s {
+						value[key] = r
+						redacted = true
+					}
+				case "layer":
+					if r := redactLayer(s); r != s {
+						value[key] = r
+						redacted = true
+					}
+				}
+				continue
+			}
+			if redactValue(v) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, v := range value {
+			if redactValue(v) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// redactURL returns the URL with any password replaced by "xxxxx", as
+// url.URL.Redacted does (auditRedacted would be escaped).
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return u.String()
+}
+
+// redactLayer returns the layer YAML with any credentials replaced. Layers
+// that can't be parsed are replaced entirely, as they may still contain
+// credentials.
+func redactLayer(s string) string {
+	var layer interface{}
+	if err := yaml.Unmarshal([]byte(s), &layer); err != nil {
+		return auditRedacted
+	}
+	if !redactValue(layer) {
+		return s
+	}
+	data, err := yaml.Marshal(layer)
+	if err != nil {
+		return auditRedacted
+	}
+	return string(data)
+}
+
+// newAuditRecord returns an audit record for the request and the response
+// the handler returned for it.
+func newAuditRecord(r *http.Request, body *auditBody, rsp Response) *auditRecord {
+	record := &auditRecord{
+		Time:   time.Now().UTC(),
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Params: body.params(r.Header.Get("Content-Type")),
+	}
+	if pid, uid, _, err := ucrednetGet(r.RemoteAddr); err == nil {
+		record.PID = &pid
+		record.UID = &uid
+	}
+	if query := r.URL.Query(); len(query) > 0 {
+		record.Query = query
+	}
+	if rsp, ok := rsp.(*resp); ok {
+		record.Status = rsp.Status
+		record.Change = rsp.Change
+	}
+	return record
+}
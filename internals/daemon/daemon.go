@@ -25,6 +25,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
@@ -69,6 +70,24 @@ type Options struct {
 	// server is not started.
 	HTTPAddress string
 
+	// HealthAddress is the address for the health listener, which serves
+	// only the /livez, /readyz, and /checks/<name> probe endpoints, for
+	// example ":8081". If not set, the health listener is not started.
+	HealthAddress string
+
+	// RecordExec enables recording of exec sessions that use a terminal.
+	// Recordings are stored in the "recordings" subdirectory of Dir.
+	RecordExec bool
+
+	// Prune configures how often and how aggressively old changes and exec
+	// recordings are pruned. Nil fields use the defaults.
+	Prune overlord.PruneOptions
+
+	// StateBackend selects how state is persisted to disk, one of
+	// overlord.StateBackendSnapshot (the default) or
+	// overlord.StateBackendJournal.
+	StateBackend string
+
 	// ServiceOuput is an optional io.Writer for the service log output, if set, all services
 	// log output will be written to the writer.
 	ServiceOutput io.Writer
@@ -82,16 +101,20 @@ type Daemon struct {
 	normalSocketPath    string
 	untrustedSocketPath string
 	httpAddress         string
+	healthAddress       string
 	overlord            *overlord.Overlord
 	state               *state.State
 	generalListener     net.Listener
 	untrustedListener   net.Listener
 	httpListener        net.Listener
+	healthListener      net.Listener
+	healthServe         *http.Server
 	connTracker         *connTracker
 	serve               *http.Server
 	tomb                tomb.Tomb
 	router              *mux.Router
 	standbyOpinions     *standby.StandbyOpinions
+	auditLog            *auditLog
 
 	// set to remember we need to restart the system
 	restartSystem bool
@@ -100,6 +123,9 @@ type Daemon struct {
 	// prevents systemd from restarting it
 	restartSocket bool
 
+	// set to the exit code requested by HandleExit
+	exitCode int
+
 	// degradedErr is set when the daemon is in degraded mode
 	degradedErr error
 
@@ -211,6 +237,13 @@ func (c *Command) canAccess(r *http.Request, user *userState) accessResult {
 	return accessUnauthorized
 }
 
+// isAdmin reports whether the request comes from root or the user the
+// daemon runs as, which canAccess requires for admin access.
+func isAdmin(r *http.Request) bool {
+	_, uid, _, err := ucrednetGet(r.RemoteAddr)
+	return err == nil && (uid == 0 || sys.UserID(uid) == sysGetuid())
+}
+
 func userFromRequest(state interface{}, r *http.Request) (*userState, error) {
 	return nil, nil
 }
@@ -257,7 +290,21 @@ func (c *Command) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if rspf != nil {
+		var body *auditBody
+		if r.Method != "GET" && c.d.auditLog != nil {
+			// Record mutating requests in the audit log.
+			if r.Body == nil {
+				r.Body = http.NoBody
+			}
+			body = &auditBody{ReadCloser: r.Body}
+			r.Body = body
+		}
 		rsp = rspf(c, r, user)
+		if body != nil {
+			if err := c.d.auditLog.append(newAuditRecord(r, body, rsp)); err != nil {
+				logger.Noticef("Cannot write audit log record: %v", err)
+			}
+		}
 	}
 
 	if rsp, ok := rsp.(*resp); ok {
@@ -381,6 +428,15 @@ func (d *Daemon) Init() error {
 		logger.Noticef("HTTP API server listening on %q.", d.httpAddress)
 	}
 
+	if d.healthAddress != "" {
+		listener, err := net.Listen("tcp", d.healthAddress)
+		if err != nil {
+			return fmt.Errorf("cannot listen on %q: %v", d.healthAddress, err)
+		}
+		d.healthListener = listener
+		logger.Noticef("Health server listening on %q.", d.healthAddress)
+	}
+
 	logger.Noticef("Started daemon.")
 	return nil
 }
@@ -503,6 +559,19 @@ func (d *Daemon) Start() {
 		})
 	}
 
+	if d.healthListener != nil {
+		// The health listener has its own server, as it serves the probe
+		// endpoints instead of the API.
+		d.healthServe = &http.Server{Handler: d.healthHandler()}
+		d.tomb.Go(func() error {
+			err := d.healthServe.Serve(d.healthListener)
+			if err != http.ErrServerClosed && d.tomb.Err() == tomb.ErrStillAlive {
+				return err
+			}
+			return nil
+		})
+	}
+
 	// notify systemd that we are ready
 	systemdSdNotify("READY=1")
 }
@@ -533,6 +602,26 @@ func (d *Daemon) HandleRestart(t restart.RestartType) {
 	d.tomb.Kill(nil)
 }
 
+// HandleExit is like HandleRestart with restart.RestartDaemon, but once the
+// daemon is stopped, Stop returns an *ExitError with the given exit code
+// (unless it's zero).
+func (d *Daemon) HandleExit(code int) {
+	d.mu.Lock()
+	d.exitCode = code
+	d.mu.Unlock()
+	d.tomb.Kill(nil)
+}
+
+// ExitError is returned by Stop when the daemon was asked to exit with a
+// non-zero exit code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("daemon exit requested with code %d", e.Code)
+}
+
 var (
 	rebootNoticeWait       = 3 * time.Second
 	rebootWaitTimeout      = 10 * time.Minute
@@ -579,6 +668,10 @@ func (d *Daemon) Stop(sigCh chan<- os.Signal) error {
 		d.httpListener.Close()
 	}
 
+	if d.healthListener != nil {
+		d.healthListener.Close()
+	}
+
 	if restartSystem {
 		// give time to polling clients to notice restart
 		time.Sleep(rebootNoticeWait)
@@ -589,6 +682,9 @@ func (d *Daemon) Stop(sigCh chan<- os.Signal) error {
 	// called.
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	d.tomb.Kill(d.serve.Shutdown(ctx))
+	if d.healthServe != nil {
+		d.tomb.Kill(d.healthServe.Shutdown(ctx))
+	}
 	cancel()
 
 	if !restartSystem {
@@ -632,6 +728,13 @@ func (d *Daemon) Stop(sigCh chan<- os.Signal) error {
 		return ErrRestartSocket
 	}
 
+	d.mu.Lock()
+	exitCode := d.exitCode
+	d.mu.Unlock()
+	if exitCode != 0 {
+		return &ExitError{Code: exitCode}
+	}
+
 	return nil
 }
 
@@ -788,9 +891,20 @@ func New(opts *Options) (*Daemon, error) {
 		normalSocketPath:    opts.SocketPath,
 		untrustedSocketPath: opts.SocketPath + ".untrusted",
 		httpAddress:         opts.HTTPAddress,
-	}
-
-	ovld, err := overlord.New(opts.Dir, d, opts.ServiceOutput)
+		healthAddress:       opts.HealthAddress,
+		auditLog: &auditLog{
+			path:     filepath.Join(opts.Dir, ".pebble.audit"),
+			maxSize:  maxAuditLogSize,
+			maxFiles: auditLogFiles,
+		},
+	}
+
+	ovld, err := overlord.NewWithOptions(overlord.Options{
+		PebbleDir:      opts.Dir,
+		RestartHandler: d,
+		ServiceOutput:  opts.ServiceOutput,
+		StateBackend:   opts.StateBackend,
+	})
 	if err == errExpectedReboot {
 		// we proceed without overlord until we reach Stop
 		// where we will schedule and wait again for a system restart.
@@ -804,6 +918,10 @@ func New(opts *Options) (*Daemon, error) {
 	}
 	d.overlord = ovld
 	d.state = ovld.State()
+	ovld.CommandManager().SetRecording(opts.RecordExec)
+	if err := ovld.SetPruneOptions(opts.Prune); err != nil {
+		return nil, err
+	}
 	return d, nil
 }
 
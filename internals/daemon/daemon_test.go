@@ -52,6 +52,7 @@ type daemonSuite struct {
 	pebbleDir       string
 	socketPath      string
 	httpAddress     string
+	healthAddress   string
 	statePath       string
 	authorized      bool
 	err             error
@@ -75,13 +76,15 @@ func (s *daemonSuite) TearDownTest(c *check.C) {
 	s.notified = nil
 	s.authorized = false
 	s.err = nil
+	s.healthAddress = ""
 }
 
 func (s *daemonSuite) newDaemon(c *check.C) *Daemon {
 	d, err := New(&Options{
-		Dir:         s.pebbleDir,
-		SocketPath:  s.socketPath,
-		HTTPAddress: s.httpAddress,
+		Dir:           s.pebbleDir,
+		SocketPath:    s.socketPath,
+		HTTPAddress:   s.httpAddress,
+		HealthAddress: s.healthAddress,
 	})
 	c.Assert(err, check.IsNil)
 	d.addRoutes()
@@ -960,6 +963,23 @@ func (s *daemonSuite) TestRestartIntoSocketModeNoNewChanges(c *check.C) {
 	c.Check(d.restartSocket, check.Equals, true)
 }
 
+func (s *daemonSuite) TestHandleExit(c *check.C) {
+	d := s.newDaemon(c)
+	makeDaemonListeners(c, d)
+
+	d.Start()
+	d.HandleExit(3)
+
+	select {
+	case <-d.Dying():
+	case <-time.After(15 * time.Second):
+		c.Errorf("daemon did not stop after 15s")
+	}
+	err := d.Stop(nil)
+	c.Check(err, check.DeepEquals, &ExitError{Code: 3})
+	c.Check(err, check.ErrorMatches, "daemon exit requested with code 3")
+}
+
 func (s *daemonSuite) TestRestartIntoSocketModePendingChanges(c *check.C) {
 	os.Setenv("NOTIFY_SOCKET", c.MkDir())
 	defer os.Setenv("NOTIFY_SOCKET", "")
@@ -1090,6 +1110,31 @@ func (s *daemonSuite) TestHTTPAPI(c *check.C) {
 	c.Assert(err, ErrorMatches, ".* connection refused")
 }
 
+func (s *daemonSuite) TestHealthListener(c *check.C) {
+	s.healthAddress = ":0"
+	d := s.newDaemon(c)
+	d.Init()
+	d.Start()
+	port := d.healthListener.Addr().(*net.TCPAddr).Port
+
+	response, err := http.Get(fmt.Sprintf("http://localhost:%d/livez", port))
+	c.Assert(err, IsNil)
+	body, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Check(response.StatusCode, Equals, http.StatusOK)
+	c.Check(string(body), Equals, "ok\n")
+
+	// The API isn't served by the health listener.
+	response, err = http.Get(fmt.Sprintf("http://localhost:%d/v1/health", port))
+	c.Assert(err, IsNil)
+	c.Check(response.StatusCode, Equals, http.StatusNotFound)
+
+	err = d.Stop(nil)
+	c.Assert(err, IsNil)
+	_, err = http.Get(fmt.Sprintf("http://localhost:%d/livez", port))
+	c.Assert(err, ErrorMatches, ".* connection refused")
+}
+
 func (s *daemonSuite) TestStopRunning(c *C) {
 	// Start the daemon.
 	writeTestLayer(s.pebbleDir, `
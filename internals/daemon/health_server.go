@@ -0,0 +1,123 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/overlord/checkstate"
+	"github.com/canonical/pebble/internals/plan"
+)
+
+// healthHandler returns the handler for the health listener, which serves
+// Kubernetes-style probe endpoints without the rest of the API:
+//
+//	/livez          healthy if all "alive" checks are up
+//	/readyz         healthy if all "ready" and "alive" checks are up
+//	/checks/<name>  healthy if the named check is up
+//
+// Healthy responses have status 200 and body "ok", unhealthy ones status 502
+// and the list of checks. Add "?verbose" for the list of checks either way,
+// including the last error of failing checks (which isn't shown otherwise,
+// as the listener doesn't require authentication).
+func (d *Daemon) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		d.serveHealth(w, r, "livez", false, func(check *checkstate.CheckInfo) bool {
+			return check.Level == plan.AliveLevel
+		})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		d.serveHealth(w, r, "readyz", false, func(check *checkstate.CheckInfo) bool {
+			return check.Level == plan.AliveLevel || check.Level == plan.ReadyLevel
+		})
+	})
+	mux.HandleFunc("/checks/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/checks/")
+		d.serveHealth(w, r, "check "+name, true, func(check *checkstate.CheckInfo) bool {
+			return check.Name == name
+		})
+	})
+	return mux
+}
+
+// serveHealth writes the health of the checks that match. If mustExist is
+// true, it responds with 404 Not Found if there are no such checks.
+func (d *Daemon) serveHealth(w http.ResponseWriter, r *http.Request, what string, mustExist bool, match func(*checkstate.CheckInfo) bool) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	checks, err := getChecks(d.overlord)
+	if err != nil {
+		logger.Noticef("Cannot fetch checks: %v", err.Error())
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	_, verbose := r.URL.Query()["verbose"]
+	var buf bytes.Buffer
+	healthy := true
+	found := false
+	for _, check := range checks {
+		if !match(check) {
+			continue
+		}
+		found = true
+		if check.Status == checkstate.CheckStatusUp {
+			fmt.Fprintf(&buf, "[+]%s ok\n", check.Name)
+			continue
+		}
+		healthy = false
+		if verbose && check.LastError != "" {
+			fmt.Fprintf(&buf, "[-]%s failed: %s\n", check.Name, firstLine(check.LastError))
+		} else {
+			fmt.Fprintf(&buf, "[-]%s failed\n", check.Name)
+		}
+	}
+	if !found && mustExist {
+		http.Error(w, what+" not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if healthy {
+		if !verbose {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, "ok\n")
+			return
+		}
+		fmt.Fprintf(&buf, "%s passed\n", what)
+		w.WriteHeader(http.StatusOK)
+	} else {
+		fmt.Fprintf(&buf, "%s failed\n", what)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	w.Write(buf.Bytes())
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
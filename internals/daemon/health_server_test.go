@@ -0,0 +1,134 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package daemon
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/overlord"
+	"github.com/canonical/pebble/internals/overlord/checkstate"
+	"github.com/canonical/pebble/internals/plan"
+)
+
+var _ = Suite(&healthServerSuite{})
+
+type healthServerSuite struct{}
+
+func (s *healthServerSuite) fakeChecks(checks ...*checkstate.CheckInfo) (restore func()) {
+	return FakeGetChecks(func(o *overlord.Overlord) ([]*checkstate.CheckInfo, error) {
+		return checks, nil
+	})
+}
+
+func serveHealthServer(c *C, method, url string) (int, string) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(method, url, nil)
+	c.Assert(err, IsNil)
+	(&Daemon{}).healthHandler().ServeHTTP(recorder, request)
+	return recorder.Code, recorder.Body.String()
+}
+
+func (s *healthServerSuite) TestHealthy(c *C) {
+	restore := s.fakeChecks(
+		&checkstate.CheckInfo{Name: "chk1", Level: plan.AliveLevel, Status: checkstate.CheckStatusUp},
+		&checkstate.CheckInfo{Name: "chk2", Level: plan.ReadyLevel, Status: checkstate.CheckStatusUp},
+		&checkstate.CheckInfo{Name: "chk3", Status: checkstate.CheckStatusDown},
+	)
+	defer restore()
+
+	for _, path := range []string{"/livez", "/readyz", "/checks/chk1"} {
+		status, body := serveHealthServer(c, "GET", path)
+		c.Check(status, Equals, http.StatusOK, Commentf(path))
+		c.Check(body, Equals, "ok\n", Commentf(path))
+	}
+
+	status, body := serveHealthServer(c, "GET", "/readyz?verbose")
+	c.Check(status, Equals, http.StatusOK)
+	c.Check(body, Equals, `
+[+]chk1 ok
+[+]chk2 ok
+readyz passed
+`[1:])
+}
+
+func (s *healthServerSuite) TestUnhealthy(c *C) {
+	restore := s.fakeChecks(
+		&checkstate.CheckInfo{Name: "chk1", Level: plan.AliveLevel, Status: checkstate.CheckStatusUp},
+		&checkstate.CheckInfo{
+			Name:      "chk2",
+			Level:     plan.ReadyLevel,
+			Status:    checkstate.CheckStatusDown,
+			LastError: "exit status 1\nmore details",
+		},
+	)
+	defer restore()
+
+	status, body := serveHealthServer(c, "GET", "/livez")
+	c.Check(status, Equals, http.StatusOK)
+	c.Check(body, Equals, "ok\n")
+
+	// Errors are only included in verbose output.
+	status, body = serveHealthServer(c, "GET", "/readyz")
+	c.Check(status, Equals, http.StatusBadGateway)
+	c.Check(body, Equals, `
+[+]chk1 ok
+[-]chk2 failed
+readyz failed
+`[1:])
+
+	status, body = serveHealthServer(c, "GET", "/checks/chk2?verbose")
+	c.Check(status, Equals, http.StatusBadGateway)
+	c.Check(body, Equals, `
+[-]chk2 failed: exit status 1
+check chk2 failed
+`[1:])
+}
+
+func (s *healthServerSuite) TestCheckNotFound(c *C) {
+	restore := s.fakeChecks()
+	defer restore()
+
+	status, body := serveHealthServer(c, "GET", "/checks/nochk")
+	c.Check(status, Equals, http.StatusNotFound)
+	c.Check(body, Equals, "check nochk not found\n")
+
+	// No checks at a level is healthy.
+	status, body = serveHealthServer(c, "GET", "/livez")
+	c.Check(status, Equals, http.StatusOK)
+	c.Check(body, Equals, "ok\n")
+}
+
+func (s *healthServerSuite) TestErrors(c *C) {
+	restore := s.fakeChecks()
+	defer restore()
+
+	status, _ := serveHealthServer(c, "POST", "/livez")
+	c.Check(status, Equals, http.StatusMethodNotAllowed)
+
+	status, _ = serveHealthServer(c, "GET", "/v1/health")
+	c.Check(status, Equals, http.StatusNotFound)
+
+	restore = FakeGetChecks(func(o *overlord.Overlord) ([]*checkstate.CheckInfo, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+	status, body := serveHealthServer(c, "GET", "/readyz")
+	c.Check(status, Equals, http.StatusInternalServerError)
+	c.Check(body, Equals, "internal server error\n")
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package overlord
+
+import (
+	"fmt"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/overlord/restart"
+	"github.com/canonical/pebble/internals/overlord/servstate"
+	"github.com/canonical/pebble/internals/overlord/state"
+)
+
+// checkActionHandler performs the check actions (see checkstate.ActionHandler)
+// that need the state, the service manager, or the restart handler.
+type checkActionHandler struct {
+	state          *state.State
+	serviceMgr     *servstate.ServiceManager
+	restartHandler restart.Handler
+}
+
+func (h *checkActionHandler) Warnf(format string, args ...interface{}) {
+	h.state.Lock()
+	defer h.state.Unlock()
+	h.state.Warnf(format, args...)
+}
+
+func (h *checkActionHandler) SendSignal(services []string, signal string) error {
+	return h.serviceMgr.SendSignal(services, signal)
+}
+
+func (h *checkActionHandler) Shutdown(exitCode int) {
+	switch handler := h.restartHandler.(type) {
+	case nil:
+		logger.Noticef("Cannot shut down: no restart handler")
+	case restart.ExitHandler:
+		handler.HandleExit(exitCode)
+	default:
+		handler.HandleRestart(restart.RestartDaemon)
+	}
+}
+
+// serviceHealth returns the error for the check of a service with "health"
+// set, given the service's status. Only a service that has failed (and is
+// in backoff or has given up) is unhealthy: one that's inactive because it
+// hasn't been started yet, or has been stopped deliberately, isn't failing.
+func serviceHealth(name string, status servstate.ServiceStatus) error {
+	switch status {
+	case servstate.StatusBackoff, servstate.StatusError:
+		return fmt.Errorf("service %q is %s", name, status)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package checkstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/canonical/x-go/strutil/shlex"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/osutil"
+	"github.com/canonical/pebble/internals/plan"
+	"github.com/canonical/pebble/internals/reaper"
+	"github.com/canonical/pebble/internals/servicelog"
+)
+
+// ActionHandler performs the check actions that need other parts of Pebble.
+type ActionHandler interface {
+	// Warnf adds a Pebble warning.
+	Warnf(format string, args ...interface{})
+
+	// SendSignal sends the named signal to the given services.
+	SendSignal(services []string, signal string) error
+
+	// Shutdown makes the daemon exit with the given exit code.
+	Shutdown(exitCode int)
+}
+
+// SetActionHandler sets the handler used for check actions that add a
+// warning, send a signal, or shut down the daemon. Without a handler, those
+// actions fail (and the failure is logged).
+func (m *CheckManager) SetActionHandler(h ActionHandler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.actionHandler = h
+}
+
+// checkEvent is the event that triggers a check's actions.
+type checkEvent string
+
+const (
+	checkEventFailure  checkEvent = "failure"
+	checkEventRecovery checkEvent = "recovery"
+)
+
+// actionTimeout is how long an exec action may run before it's killed.
+var actionTimeout = time.Minute
+
+// runActions performs the check's actions for the given event in order,
+// logging any that fail.
+func (m *CheckManager) runActions(config *plan.Check, event checkEvent) {
+	actions := config.OnFailure
+	if event == checkEventRecovery {
+		actions = config.OnRecovery
+	}
+	if len(actions) == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	handler := m.actionHandler
+	m.mutex.Unlock()
+
+	for _, action := range actions {
+		err := runAction(handler, config.Name, event, action)
+		if err != nil {
+			logger.Noticef("Check %q on-%s action failed: %v", config.Name, event, err)
+		}
+	}
+}
+
+func runAction(handler ActionHandler, name string, event checkEvent, action *plan.CheckAction) error {
+	if action.Exec != "" {
+		return runExecAction(name, event, action.Exec)
+	}
+	if handler == nil {
+		return errors.New("no action handler")
+	}
+	switch {
+	case action.Warn != "":
+		handler.Warnf("%s", action.Warn)
+	case action.Signal != "":
+		return handler.SendSignal([]string{action.Service}, action.Signal)
+	case action.Shutdown != nil:
+		logger.Noticef("Check %q on-%s action is shutdown, triggering server exit (code %d)",
+			name, event, *action.Shutdown)
+		handler.Shutdown(*action.Shutdown)
+	}
+	return nil
+}
+
+// runExecAction runs an exec action's command, with PEBBLE_CHECK and
+// PEBBLE_CHECK_EVENT added to the daemon's environment.
+func runExecAction(name string, event checkEvent, command string) error {
+	args, err := shlex.Split(command)
+	if err != nil {
+		return fmt.Errorf("cannot parse command: %v", err)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("cannot run empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+
+	environment := osutil.Environ()
+	environment["PEBBLE_CHECK"] = name
+	environment["PEBBLE_CHECK_EVENT"] = string(event)
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = make([]string, 0, len(environment))
+	for k, v := range environment {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	// Keep the last few lines of output to log on error.
+	ringBuffer := servicelog.NewRingBuffer(maxErrorBytes)
+	defer ringBuffer.Close()
+	cmd.Stdout = ringBuffer
+	cmd.Stderr = ringBuffer
+	err = reaper.StartCommand(cmd)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Check %q on-%s action: running %q (PID %d)", name, event, command, cmd.Process.Pid)
+
+	exitCode, err := reaper.WaitCommand(cmd)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("command timed out")
+	}
+	if err == nil && exitCode > 0 {
+		err = fmt.Errorf("exit status %d", exitCode)
+	}
+	if err != nil {
+		output, linesErr := servicelog.LastLines(ringBuffer, maxErrorLines, "    ", false)
+		if linesErr == nil && output != "" {
+			err = fmt.Errorf("%v; output:\n%s", err, output)
+		}
+		return err
+	}
+	return nil
+}
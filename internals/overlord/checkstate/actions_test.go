@@ -0,0 +1,141 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package checkstate
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/plan"
+)
+
+type fakeActionHandler struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (h *fakeActionHandler) Warnf(format string, args ...interface{}) {
+	h.record("warn " + fmt.Sprintf(format, args...))
+}
+
+func (h *fakeActionHandler) SendSignal(services []string, signal string) error {
+	h.record(fmt.Sprintf("signal %s %v", signal, services))
+	return nil
+}
+
+func (h *fakeActionHandler) Shutdown(exitCode int) {
+	h.record(fmt.Sprintf("shutdown %d", exitCode))
+}
+
+func (h *fakeActionHandler) record(call string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.calls = append(h.calls, call)
+}
+
+func (h *fakeActionHandler) waitCalls(c *C, n int) []string {
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(time.Millisecond) {
+		h.mutex.Lock()
+		calls := append([]string(nil), h.calls...)
+		h.mutex.Unlock()
+		if len(calls) >= n {
+			return calls
+		}
+	}
+	c.Fatalf("timed out waiting for %d action calls", n)
+	return nil
+}
+
+func (s *ManagerSuite) TestActions(c *C) {
+	dir := c.MkDir()
+	testPath := filepath.Join(dir, "test")
+	err := ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	outputPath := filepath.Join(dir, "output")
+	execAction := &plan.CheckAction{
+		Exec: fmt.Sprintf(`/bin/sh -c 'echo $PEBBLE_CHECK $PEBBLE_CHECK_EVENT >>%s'`, outputPath),
+	}
+	exitCode := 3
+
+	mgr := NewManager()
+	handler := &fakeActionHandler{}
+	mgr.SetActionHandler(handler)
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": {
+				Name:      "chk1",
+				Period:    plan.OptionalDuration{Value: time.Hour},
+				Timeout:   plan.OptionalDuration{Value: time.Second},
+				Threshold: 2,
+				Exec: &plan.ExecCheck{
+					Command: fmt.Sprintf(`/bin/sh -c '[ ! -f %s ]'`, testPath),
+				},
+				OnFailure: []*plan.CheckAction{
+					execAction,
+					{Warn: "chk1 is down"},
+					{Signal: "SIGHUP", Service: "svc1"},
+					{Shutdown: &exitCode},
+				},
+				OnRecovery: []*plan.CheckAction{
+					execAction,
+					{Warn: "chk1 is up"},
+				},
+			},
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	// Actions aren't run until the failure threshold is hit.
+	_, err = mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	time.Sleep(20 * time.Millisecond)
+	c.Check(handler.waitCalls(c, 0), HasLen, 0)
+
+	_, err = mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	calls := handler.waitCalls(c, 3)
+	c.Check(calls, DeepEquals, []string{
+		"warn chk1 is down",
+		"signal SIGHUP [svc1]",
+		"shutdown 3",
+	})
+	output, err := ioutil.ReadFile(outputPath)
+	c.Assert(err, IsNil)
+	c.Check(string(output), Equals, "chk1 failure\n")
+
+	// Recovery actions are run once the check is up again.
+	err = os.Remove(testPath)
+	c.Assert(err, IsNil)
+	_, err = mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	calls = handler.waitCalls(c, 4)
+	c.Check(calls[3], Equals, "warn chk1 is up")
+	output, err = ioutil.ReadFile(outputPath)
+	c.Assert(err, IsNil)
+	c.Check(string(output), Equals, "chk1 failure\nchk1 recovery\n")
+}
+
+func (s *ManagerSuite) TestExecActionEmptyCommand(c *C) {
+	// Validation rejects these, but they mustn't panic if they get through.
+	err := runExecAction("chk1", checkEventFailure, "   ")
+	c.Assert(err, ErrorMatches, "cannot run empty command")
+}
@@ -15,22 +15,34 @@
 package checkstate
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/canonical/x-go/strutil/quantity"
 	"github.com/canonical/x-go/strutil/shlex"
+	"golang.org/x/net/http2"
 
 	"github.com/canonical/pebble/internals/logger"
 	"github.com/canonical/pebble/internals/osutil"
+	"github.com/canonical/pebble/internals/plan"
 	"github.com/canonical/pebble/internals/reaper"
 	"github.com/canonical/pebble/internals/servicelog"
 )
@@ -38,19 +50,62 @@ import (
 const (
 	maxErrorBytes = 10 * 1024
 	maxErrorLines = 20
+
+	// Maximum amount of response body to read when matching the body of an
+	// HTTP check's response.
+	maxMatchBytes = 1024 * 1024
 )
 
-// httpChecker is a checker that ensures an HTTP GET at a specified URL returns 20x.
+// httpChecker is a checker that ensures an HTTP request to a specified URL
+// returns an expected status (20x by default), and optionally that the
+// response body matches a regexp.
 type httpChecker struct {
-	name    string
-	url     string
-	headers map[string]string
+	name           string
+	url            string
+	socket         string // if set, make the request over this unix socket
+	method         string
+	headers        map[string]string
+	body           string
+	expectedStatus []int
+	bodyMatch      *regexp.Regexp
+	tlsCA          string
+	tlsCert        string
+	tlsKey         string
+	tlsSkipVerify  bool
 }
 
 func (c *httpChecker) check(ctx context.Context) error {
-	logger.Debugf("Check %q (http): requesting %q", c.name, c.url)
-	client := &http.Client{}
-	request, err := http.NewRequestWithContext(ctx, "GET", c.url, nil)
+	method := c.method
+	if method == "" {
+		method = "GET"
+	}
+	if c.socket != "" {
+		logger.Debugf("Check %q (http): requesting %s %q over socket %q", c.name, method, c.url, c.socket)
+	} else {
+		logger.Debugf("Check %q (http): requesting %s %q", c.name, method, c.url)
+	}
+	transport, err := c.transport()
+	if err != nil {
+		return err
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: transport,
+		// Don't follow redirects, so that a redirect status can be expected
+		// (and isn't mistaken for the status of the page redirected to).
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var body io.Reader
+	if c.body != "" {
+		body = strings.NewReader(c.body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.url, body)
+	if err != nil {
+		return err
+	}
 	for k, v := range c.headers {
 		request.Header.Set(k, v)
 	}
@@ -61,28 +116,138 @@ func (c *httpChecker) check(ctx context.Context) error {
 	}
 	defer response.Body.Close()
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
+	if !c.statusOK(response.StatusCode) {
 		// Include first few lines of response body in error details
 		output, err := ioutil.ReadAll(io.LimitReader(response.Body, maxErrorBytes))
-		details := ""
-		if err != nil {
-			details = fmt.Sprintf("cannot read response body: %v", err)
-		} else {
-			lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-			if len(lines) > maxErrorLines {
-				lines = lines[:maxErrorLines+1]
-				lines[maxErrorLines] = "(...)"
-			}
-			details = strings.Join(lines, "\n")
+		message := fmt.Sprintf("received non-20x status code %d", response.StatusCode)
+		if len(c.expectedStatus) > 0 {
+			message = fmt.Sprintf("received unexpected status code %d", response.StatusCode)
 		}
 		return &detailsError{
-			error:   fmt.Errorf("received non-20x status code %d", response.StatusCode),
-			details: details,
+			error:   errors.New(message),
+			details: bodyDetails(output, err),
+		}
+	}
+
+	if c.bodyMatch != nil {
+		output, err := ioutil.ReadAll(io.LimitReader(response.Body, maxMatchBytes))
+		if err != nil {
+			return fmt.Errorf("cannot read response body: %w", err)
+		}
+		if !c.bodyMatch.Match(output) {
+			if len(output) > maxErrorBytes {
+				output = output[:maxErrorBytes]
+			}
+			return &detailsError{
+				error:   fmt.Errorf("response body does not match %q", c.bodyMatch.String()),
+				details: bodyDetails(output, nil),
+			}
 		}
 	}
 	return nil
 }
 
+// statusOK reports whether the given HTTP status code is healthy.
+func (c *httpChecker) statusOK(status int) bool {
+	if len(c.expectedStatus) == 0 {
+		return status >= 200 && status <= 299
+	}
+	for _, expected := range c.expectedStatus {
+		if status == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// transport returns the HTTP transport for a single check. The TLS files are
+// loaded each time so that updated certificates are picked up.
+func (c *httpChecker) transport() (*http.Transport, error) {
+	transport := &http.Transport{DisableKeepAlives: true}
+	if c.socket != "" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", c.socket)
+		}
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.tlsSkipVerify}
+	if c.tlsCA != "" {
+		pem, err := ioutil.ReadFile(c.tlsCA)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("cannot find certificates in CA file %q", c.tlsCA)
+		}
+	}
+	if c.tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.tlsCert, c.tlsKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// bodyDetails returns the first few lines of a response body, for use in
+// error details.
+func bodyDetails(output []byte, err error) string {
+	if err != nil {
+		return fmt.Sprintf("cannot read response body: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) > maxErrorLines {
+		lines = lines[:maxErrorLines+1]
+		lines[maxErrorLines] = "(...)"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// compositeChecker is a checker that aggregates the status of other checks:
+// it succeeds if all of them are up, or in "any" mode, if any of them are.
+type compositeChecker struct {
+	name    string
+	checks  []string
+	any     bool
+	manager *CheckManager
+}
+
+func (c *compositeChecker) check(ctx context.Context) error {
+	var down []string
+	for _, name := range c.checks {
+		if c.manager.isDown(name) {
+			down = append(down, name)
+		}
+	}
+	if len(down) == 0 || (c.any && len(down) < len(c.checks)) {
+		return nil
+	}
+	if len(down) == 1 {
+		return fmt.Errorf("check %q is down", down[0])
+	}
+	return fmt.Errorf("checks are down: %s", strings.Join(down, ", "))
+}
+
+// serviceChecker is a checker that ensures a service is running, for
+// services with "health" set.
+type serviceChecker struct {
+	name    string
+	manager *CheckManager
+}
+
+func (c *serviceChecker) check(ctx context.Context) error {
+	serviceStatus := c.manager.serviceStatusFunc()
+	if serviceStatus == nil {
+		return errors.New("cannot look up service status")
+	}
+	return serviceStatus(c.name)
+}
+
 // tcpChecker is a checker that ensures a TCP port is open.
 type tcpChecker struct {
 	name string
@@ -192,6 +357,225 @@ func (c *execChecker) check(ctx context.Context) error {
 	return nil
 }
 
+// grpcChecker is a checker that ensures a gRPC server's standard health
+// service (grpc.health.v1.Health/Check) reports that the service is serving.
+type grpcChecker struct {
+	name          string
+	address       string
+	service       string
+	tls           bool
+	tlsSkipVerify bool
+	metadata      map[string]string
+}
+
+// Health check serving status values, from the grpc.health.v1 protocol.
+var grpcServingStatuses = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+const grpcServing = 1
+
+// Status codes, from https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+var grpcStatusCodes = map[string]string{
+	"1":  "CANCELLED",
+	"2":  "UNKNOWN",
+	"3":  "INVALID_ARGUMENT",
+	"4":  "DEADLINE_EXCEEDED",
+	"5":  "NOT_FOUND",
+	"6":  "ALREADY_EXISTS",
+	"7":  "PERMISSION_DENIED",
+	"8":  "RESOURCE_EXHAUSTED",
+	"9":  "FAILED_PRECONDITION",
+	"10": "ABORTED",
+	"11": "OUT_OF_RANGE",
+	"12": "UNIMPLEMENTED",
+	"13": "INTERNAL",
+	"14": "UNAVAILABLE",
+	"15": "DATA_LOSS",
+	"16": "UNAUTHENTICATED",
+}
+
+func (c *grpcChecker) check(ctx context.Context) error {
+	logger.Debugf("Check %q (grpc): checking service %q at %q", c.name, c.service, c.address)
+
+	// gRPC runs over HTTP/2, which is used without TLS ("h2c") unless TLS is
+	// enabled.
+	transport := &http2.Transport{}
+	scheme := "http"
+	if c.tls {
+		scheme = "https"
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: c.tlsSkipVerify}
+	} else {
+		transport.AllowHTTP = true
+		transport.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	defer transport.CloseIdleConnections()
+
+	body := grpcFrame(grpcEncodeString(1, c.service))
+	requestURL := scheme + "://" + c.address + "/grpc.health.v1.Health/Check"
+	request, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range c.metadata {
+		request.Header.Set(k, v)
+	}
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("TE", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline).Milliseconds()
+		if timeout < 1 {
+			timeout = 1
+		}
+		request.Header.Set("grpc-timeout", strconv.FormatInt(timeout, 10)+"m")
+	}
+
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("received non-200 HTTP status code %d", response.StatusCode)
+	}
+	message, err := ioutil.ReadAll(io.LimitReader(response.Body, maxErrorBytes))
+	if err != nil {
+		return fmt.Errorf("cannot read response: %w", err)
+	}
+
+	// The status is sent in the trailers, or in the headers if the response
+	// has no body.
+	status := response.Trailer.Get("grpc-status")
+	statusMessage := response.Trailer.Get("grpc-message")
+	if status == "" {
+		status = response.Header.Get("grpc-status")
+		statusMessage = response.Header.Get("grpc-message")
+	}
+	if status == "" {
+		return fmt.Errorf("response has no gRPC status")
+	}
+	if status != "0" {
+		name, ok := grpcStatusCodes[status]
+		if !ok {
+			name = "code " + status
+		}
+		err := fmt.Errorf("received gRPC status %s", name)
+		if statusMessage != "" {
+			decoded, decodeErr := url.PathUnescape(statusMessage)
+			if decodeErr == nil {
+				statusMessage = decoded
+			}
+			return &detailsError{error: err, details: statusMessage}
+		}
+		return err
+	}
+
+	payload, err := grpcUnframe(message)
+	if err != nil {
+		return err
+	}
+	servingStatus, err := grpcDecodeVarint(1, payload)
+	if err != nil {
+		return fmt.Errorf("cannot decode response: %w", err)
+	}
+	if servingStatus != grpcServing {
+		name, ok := grpcServingStatuses[servingStatus]
+		if !ok {
+			name = strconv.FormatUint(servingStatus, 10)
+		}
+		return fmt.Errorf("service status is %s", name)
+	}
+	return nil
+}
+
+// grpcFrame returns the gRPC length-prefixed message frame for a message
+// (which isn't compressed).
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// grpcUnframe returns the message in a gRPC length-prefixed message frame.
+func grpcUnframe(frame []byte) ([]byte, error) {
+	if len(frame) < 5 {
+		return nil, fmt.Errorf("response message too short")
+	}
+	if frame[0] != 0 {
+		return nil, fmt.Errorf("cannot decode compressed response message")
+	}
+	length := binary.BigEndian.Uint32(frame[1:5])
+	if uint32(len(frame)-5) < length {
+		return nil, fmt.Errorf("response message truncated")
+	}
+	return frame[5 : 5+length], nil
+}
+
+// The health check request and response messages are simple enough to
+// encode and decode by hand. The request has a single string field (the
+// service name), and the response a single enum field (the status).
+
+// grpcEncodeString encodes a Protocol Buffers string field. Empty strings
+// aren't encoded, as that's the default value.
+func grpcEncodeString(field int, value string) []byte {
+	if value == "" {
+		return nil
+	}
+	b := make([]byte, 2*binary.MaxVarintLen64, 2*binary.MaxVarintLen64+len(value))
+	n := binary.PutUvarint(b, uint64(field)<<3|2) // wire type 2: length-delimited
+	n += binary.PutUvarint(b[n:], uint64(len(value)))
+	return append(b[:n], value...)
+}
+
+// grpcDecodeVarint decodes the varint field with the given number from a
+// Protocol Buffers message, returning zero (the default) if it's not present.
+func grpcDecodeVarint(field int, message []byte) (uint64, error) {
+	var value uint64
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return 0, fmt.Errorf("invalid field key")
+		}
+		message = message[n:]
+		switch key & 7 {
+		case 0: // varint
+			v, n := binary.Uvarint(message)
+			if n <= 0 {
+				return 0, fmt.Errorf("invalid varint")
+			}
+			message = message[n:]
+			if key>>3 == uint64(field) {
+				value = v // last value wins
+			}
+		case 1: // 64-bit
+			if len(message) < 8 {
+				return 0, fmt.Errorf("truncated field")
+			}
+			message = message[8:]
+		case 2: // length-delimited
+			length, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < length {
+				return 0, fmt.Errorf("truncated field")
+			}
+			message = message[n+int(length):]
+		case 5: // 32-bit
+			if len(message) < 4 {
+				return 0, fmt.Errorf("truncated field")
+			}
+			message = message[4:]
+		default:
+			return 0, fmt.Errorf("unsupported wire type %d", key&7)
+		}
+	}
+	return value, nil
+}
+
 type detailsError struct {
 	error
 	details string
@@ -200,3 +584,288 @@ type detailsError struct {
 func (e *detailsError) Details() string {
 	return e.details
 }
+
+// procPath is where the proc filesystem is mounted (a variable for tests).
+var procPath = "/proc"
+
+// cgroupRoot is where the cgroup v2 filesystem is mounted (a variable for
+// tests).
+var cgroupRoot = "/sys/fs/cgroup"
+
+// diskChecker is a checker that ensures the filesystem containing a path
+// has enough free space and inodes.
+type diskChecker struct {
+	name          string
+	path          string
+	minFree       plan.OptionalAmount
+	minFreeInodes plan.OptionalAmount
+}
+
+func (c *diskChecker) check(ctx context.Context) error {
+	logger.Debugf("Check %q (disk): checking free space on %q", c.name, c.path)
+	var st syscall.Statfs_t
+	err := syscall.Statfs(c.path, &st)
+	if err != nil {
+		return fmt.Errorf("cannot get filesystem status: %w", err)
+	}
+	if c.minFree.IsSet {
+		free := float64(st.Bavail) * float64(st.Bsize)
+		total := float64(st.Blocks) * float64(st.Bsize)
+		if !amountAtLeast(free, total, c.minFree) {
+			return fmt.Errorf("only %sB free on %s (minimum %s)",
+				quantity.FormatAmount(uint64(free), -1), c.path, formatAmount(c.minFree, "B"))
+		}
+	}
+	// Some filesystems (such as btrfs) don't have a fixed number of inodes.
+	if c.minFreeInodes.IsSet && st.Files > 0 {
+		free := float64(st.Ffree)
+		if !amountAtLeast(free, float64(st.Files), c.minFreeInodes) {
+			return fmt.Errorf("only %d inodes free on %s (minimum %s)",
+				st.Ffree, c.path, formatAmount(c.minFreeInodes, ""))
+		}
+	}
+	return nil
+}
+
+// amountAtLeast reports whether value (out of total) is at least min.
+func amountAtLeast(value, total float64, min plan.OptionalAmount) bool {
+	if min.Percent {
+		return total > 0 && value*100/total >= min.Value
+	}
+	return value >= min.Value
+}
+
+// amountExceeds reports whether value (out of total) is more than max.
+func amountExceeds(value, total float64, max plan.OptionalAmount) bool {
+	if max.Percent {
+		return total > 0 && value*100/total > max.Value
+	}
+	return value > max.Value
+}
+
+func formatAmount(amount plan.OptionalAmount, unit string) string {
+	if amount.Percent {
+		return amount.String()
+	}
+	return quantity.FormatAmount(uint64(amount.Value), -1) + unit
+}
+
+// fileChecker is a checker that ensures a file exists, and optionally that
+// it was modified recently.
+type fileChecker struct {
+	name   string
+	path   string
+	maxAge time.Duration
+}
+
+func (c *fileChecker) check(ctx context.Context) error {
+	logger.Debugf("Check %q (file): checking %q", c.name, c.path)
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return err
+	}
+	if c.maxAge > 0 {
+		age := time.Since(info.ModTime())
+		if age > c.maxAge {
+			return fmt.Errorf("%s last modified %s ago (maximum %s)",
+				c.path, age.Round(time.Second), c.maxAge)
+		}
+	}
+	return nil
+}
+
+// processChecker is a checker that ensures a process is running, found by
+// name or by the PID in a PID file.
+type processChecker struct {
+	name        string
+	processName string
+	pidFile     string
+}
+
+func (c *processChecker) check(ctx context.Context) error {
+	logger.Debugf("Check %q (process): looking for process %q", c.name, c.processName+c.pidFile)
+	if c.pidFile != "" {
+		data, err := ioutil.ReadFile(c.pidFile)
+		if err != nil {
+			return fmt.Errorf("cannot read PID file: %w", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || pid <= 0 {
+			return fmt.Errorf("invalid PID in %s", c.pidFile)
+		}
+		// Signal 0 only checks whether the process exists.
+		err = syscall.Kill(pid, 0)
+		if err != nil && err != syscall.EPERM {
+			return fmt.Errorf("process %d from %s is not running", pid, c.pidFile)
+		}
+		return nil
+	}
+
+	entries, err := ioutil.ReadDir(procPath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		if processNameMatches(filepath.Join(procPath, entry.Name()), c.processName) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no process named %q is running", c.processName)
+}
+
+// processNameMatches reports whether the process whose /proc directory is
+// dir is called name, either by its command name, or by the base name of
+// its first argument (as the command name is truncated to 15 bytes).
+func processNameMatches(dir, name string) bool {
+	comm, err := ioutil.ReadFile(filepath.Join(dir, "comm"))
+	if err == nil && strings.TrimSuffix(string(comm), "\n") == name {
+		return true
+	}
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil || len(cmdline) == 0 {
+		return false
+	}
+	if i := bytes.IndexByte(cmdline, 0); i >= 0 {
+		cmdline = cmdline[:i]
+	}
+	return filepath.Base(string(cmdline)) == name
+}
+
+// resourcesChecker is a checker that ensures the system's memory use and
+// load average are below the configured thresholds.
+//
+// If Pebble's cgroup (or one of its ancestors) has a cgroup v2 memory limit,
+// as in a container, memory use is measured against the nearest such limit.
+// Otherwise, and always for the load average, host-wide figures are used.
+type resourcesChecker struct {
+	name      string
+	maxMemory plan.OptionalAmount
+	maxLoad   plan.OptionalFloat
+}
+
+func (c *resourcesChecker) check(ctx context.Context) error {
+	logger.Debugf("Check %q (resources): checking memory use and load average", c.name)
+	if c.maxMemory.IsSet {
+		used, total, err := readMemoryUsage()
+		if err != nil {
+			return err
+		}
+		if amountExceeds(used, total, c.maxMemory) {
+			return fmt.Errorf("%sB memory used (maximum %s)",
+				quantity.FormatAmount(uint64(used), -1), formatAmount(c.maxMemory, "B"))
+		}
+	}
+	if c.maxLoad.IsSet {
+		data, err := ioutil.ReadFile(filepath.Join(procPath, "loadavg"))
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) == 0 {
+			return fmt.Errorf("invalid load average %q", data)
+		}
+		load, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return fmt.Errorf("invalid load average %q", fields[0])
+		}
+		if load > c.maxLoad.Value {
+			return fmt.Errorf("load average %.2f (maximum %g)", load, c.maxLoad.Value)
+		}
+	}
+	return nil
+}
+
+// readMemoryUsage returns the memory used and the total memory in bytes,
+// from Pebble's cgroup if it has a memory limit, or from /proc/meminfo.
+func readMemoryUsage() (used, total float64, err error) {
+	used, total, ok, err := readCgroupMemory()
+	if err != nil || ok {
+		return used, total, err
+	}
+	total, available, err := readMemInfo()
+	if err != nil {
+		return 0, 0, err
+	}
+	return total - available, total, nil
+}
+
+// readCgroupMemory returns the current memory use and the memory limit of
+// the nearest cgroup v2 cgroup, starting with Pebble's own and walking up
+// the hierarchy, that has a memory limit. It returns ok false if cgroup v2
+// isn't available or no such cgroup is found.
+func readCgroupMemory() (used, limit float64, ok bool, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(procPath, "self", "cgroup"))
+	if err != nil {
+		return 0, 0, false, nil
+	}
+	var dir string
+	for _, line := range strings.Split(string(data), "\n") {
+		// The cgroup v2 (unified hierarchy) entry is "0::/path".
+		if strings.HasPrefix(line, "0::") {
+			dir = filepath.Join(cgroupRoot, line[len("0::"):])
+			break
+		}
+	}
+	if dir == "" {
+		return 0, 0, false, nil
+	}
+	root := filepath.Clean(cgroupRoot)
+	for ; strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "memory.max"))
+		if err == nil && strings.TrimSpace(string(data)) != "max" {
+			limit, err = strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+			if err != nil {
+				return 0, 0, false, fmt.Errorf("invalid cgroup memory limit %q", data)
+			}
+			data, err = ioutil.ReadFile(filepath.Join(dir, "memory.current"))
+			if err != nil {
+				return 0, 0, false, err
+			}
+			used, err = strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+			if err != nil {
+				return 0, 0, false, fmt.Errorf("invalid cgroup memory usage %q", data)
+			}
+			return used, limit, true, nil
+		}
+		if dir == root {
+			break
+		}
+	}
+	return 0, 0, false, nil
+}
+
+// readMemInfo returns the total and available memory in bytes, as reported
+// by /proc/meminfo.
+func readMemInfo() (total, available float64, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(procPath, "meminfo"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var foundTotal, foundAvailable bool
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 2 && fields[2] == "kB" {
+			value *= 1024
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total, foundTotal = value, true
+		case "MemAvailable:":
+			available, foundAvailable = value, true
+		}
+	}
+	if !foundTotal || !foundAvailable {
+		return 0, 0, errors.New("cannot find memory usage in meminfo")
+	}
+	return total, available, nil
+}
@@ -17,16 +17,26 @@ package checkstate
 import (
 	"bytes"
 	"context"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/user"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	. "gopkg.in/check.v1"
 
+	"github.com/canonical/pebble/internals/plan"
 	"github.com/canonical/pebble/internals/reaper"
 )
 
@@ -113,6 +123,119 @@ func (s *CheckersSuite) TestHTTP(c *C) {
 	c.Assert(err, ErrorMatches, ".* connection refused")
 }
 
+func (s *CheckersSuite) TestHTTPOptions(c *C) {
+	var method, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		data, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		body = string(data)
+		switch r.URL.Path {
+		case "/login":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/status":
+			fmt.Fprint(w, `{"status": "ok", "version": 2}`)
+		case "/old":
+			http.Redirect(w, r, "/status", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	// Method and body are sent
+	chk := &httpChecker{url: server.URL, method: "POST", body: `{"ping": true}`}
+	err := chk.check(context.Background())
+	c.Assert(err, IsNil)
+	c.Check(method, Equals, "POST")
+	c.Check(body, Equals, `{"ping": true}`)
+
+	// Expected status list replaces the default of 20x
+	chk = &httpChecker{url: server.URL + "/login", expectedStatus: []int{200, 401}}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+	c.Check(method, Equals, "GET")
+	chk = &httpChecker{url: server.URL, expectedStatus: []int{401}}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "received unexpected status code 200")
+
+	// Redirects aren't followed
+	chk = &httpChecker{url: server.URL + "/old", expectedStatus: []int{301}}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+	chk = &httpChecker{url: server.URL + "/old"}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "received non-20x status code 301")
+
+	// Body must match
+	chk = &httpChecker{url: server.URL + "/status", bodyMatch: regexp.MustCompile(`"status": ?"ok"`)}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+	chk = &httpChecker{url: server.URL + "/status", bodyMatch: regexp.MustCompile(`"version": 3`)}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `response body does not match "\\"version\\": 3"`)
+	detailsErr, ok := err.(*detailsError)
+	c.Assert(ok, Equals, true)
+	c.Assert(detailsErr.Details(), Equals, `{"status": "ok", "version": 2}`)
+}
+
+func (s *CheckersSuite) TestHTTPUnixSocket(c *C) {
+	socket := filepath.Join(c.MkDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	c.Assert(err, IsNil)
+	var path string
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.RequestURI()
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	chk := &httpChecker{url: "http://localhost/health?full=1", socket: socket}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+	c.Check(path, Equals, "/health?full=1")
+
+	chk = &httpChecker{url: "http://localhost/bad", socket: socket}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "received non-20x status code 503")
+
+	chk = &httpChecker{url: "http://localhost/", socket: socket + ".missing"}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, ".* no such file or directory")
+}
+
+func (s *CheckersSuite) TestHTTPTLS(c *C) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Test server's certificate isn't trusted by default
+	chk := &httpChecker{url: server.URL}
+	err := chk.check(context.Background())
+	c.Assert(err, ErrorMatches, ".*certificate.*")
+
+	chk = &httpChecker{url: server.URL, tlsSkipVerify: true}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	// Pinning the test server's certificate as the CA works
+	caPath := filepath.Join(c.MkDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	err = ioutil.WriteFile(caPath, caPEM, 0644)
+	c.Assert(err, IsNil)
+	chk = &httpChecker{url: server.URL, tlsCA: caPath}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	// Errors loading TLS files are check errors
+	chk = &httpChecker{url: server.URL, tlsCA: caPath + ".missing"}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "cannot read CA certificate: .*")
+	chk = &httpChecker{url: server.URL, tlsCert: caPath, tlsKey: caPath}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "cannot load client certificate: .*")
+}
+
 func (s *CheckersSuite) TestTCP(c *C) {
 	listener, err := net.Listen("tcp", "localhost:")
 	c.Assert(err, IsNil)
@@ -148,6 +271,143 @@ func (s *CheckersSuite) TestTCP(c *C) {
 	c.Assert(err, ErrorMatches, ".* connection refused")
 }
 
+// grpcHealthHandler is a fake gRPC health service. It responds based on the
+// requested service name.
+func grpcHealthHandler(c *C, requests *[]*http.Request) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/grpc.health.v1.Health/Check")
+		c.Check(r.Header.Get("Content-Type"), Equals, "application/grpc")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		message, err := grpcUnframe(body)
+		c.Assert(err, IsNil)
+		var service string
+		if len(message) > 0 {
+			c.Assert(message[0], Equals, byte(1<<3|2))
+			service = string(message[2 : 2+message[1]])
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		var status byte
+		switch service {
+		case "":
+			status = 1 // SERVING
+		case "not-serving":
+			status = 2 // NOT_SERVING
+		case "unknown":
+			// Trailers-only response, as sent for errors.
+			w.Header().Set("grpc-status", "5")
+			w.Header().Set("grpc-message", "unknown%20service")
+			return
+		case "bad-response":
+			w.Write([]byte("junk"))
+			w.Header().Set(http.TrailerPrefix+"grpc-status", "0")
+			return
+		default:
+			status = 1
+		}
+		w.Write(grpcFrame([]byte{1 << 3, status}))
+		w.Header().Set(http.TrailerPrefix+"grpc-status", "0")
+	})
+}
+
+func (s *CheckersSuite) TestGRPC(c *C) {
+	var requests []*http.Request
+	server := httptest.NewServer(h2c.NewHandler(grpcHealthHandler(c, &requests), &http2.Server{}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	// Serving service is healthy, and metadata is sent through
+	chk := &grpcChecker{
+		address:  address,
+		metadata: map[string]string{"Authorization": "Bearer token"},
+	}
+	err := chk.check(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(requests, HasLen, 1)
+	c.Check(requests[0].ProtoMajor, Equals, 2)
+	c.Check(requests[0].Header.Get("Authorization"), Equals, "Bearer token")
+
+	// Named service is sent in request
+	chk = &grpcChecker{address: address, service: "foo.Foo"}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	// Not serving returns error
+	chk = &grpcChecker{address: address, service: "not-serving"}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "service status is NOT_SERVING")
+
+	// Error status returns error, with message in details
+	chk = &grpcChecker{address: address, service: "unknown"}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "received gRPC status NOT_FOUND")
+	detailsErr, ok := err.(*detailsError)
+	c.Assert(ok, Equals, true)
+	c.Assert(detailsErr.Details(), Equals, "unknown service")
+
+	// Invalid response returns error
+	chk = &grpcChecker{address: address, service: "bad-response"}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "response message too short")
+
+	// Cancelled context returns error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	chk = &grpcChecker{address: address}
+	err = chk.check(ctx)
+	c.Assert(err, ErrorMatches, ".*cancel(ed|led).*")
+
+	// After server closed, should get a network dial error
+	server.Close()
+	chk = &grpcChecker{address: address}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, ".*connection refused")
+}
+
+func (s *CheckersSuite) TestGRPCTLS(c *C) {
+	var requests []*http.Request
+	server := httptest.NewUnstartedServer(grpcHealthHandler(c, &requests))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	// Test server's certificate isn't trusted
+	chk := &grpcChecker{address: address, tls: true}
+	err := chk.check(context.Background())
+	c.Assert(err, ErrorMatches, ".*certificate.*")
+
+	chk = &grpcChecker{address: address, tls: true, tlsSkipVerify: true}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(requests, HasLen, 1)
+	c.Check(requests[0].ProtoMajor, Equals, 2)
+}
+
+func (s *CheckersSuite) TestGRPCDecodeVarint(c *C) {
+	// Other fields (of all wire types) are skipped.
+	message := []byte{
+		2<<3 | 0, 0x96, 0x01, // field 2 varint 150
+		3<<3 | 1, 1, 2, 3, 4, 5, 6, 7, 8, // field 3 64-bit
+		4<<3 | 2, 2, 'h', 'i', // field 4 length-delimited
+		5<<3 | 5, 1, 2, 3, 4, // field 5 32-bit
+		1<<3 | 0, 2, // field 1 varint 2
+	}
+	value, err := grpcDecodeVarint(1, message)
+	c.Assert(err, IsNil)
+	c.Check(value, Equals, uint64(2))
+
+	value, err = grpcDecodeVarint(1, nil)
+	c.Assert(err, IsNil)
+	c.Check(value, Equals, uint64(0))
+
+	_, err = grpcDecodeVarint(1, []byte{4<<3 | 2, 10, 'x'})
+	c.Check(err, ErrorMatches, "truncated field")
+}
+
 func (s *CheckersSuite) TestExec(c *C) {
 	err := reaper.Start()
 	c.Assert(err, IsNil)
@@ -252,3 +512,192 @@ func (s *CheckersSuite) TestExec(c *C) {
 	c.Assert(ok, Equals, true)
 	c.Assert(detailsErr.Details(), Equals, currentUser.Username)
 }
+
+func (s *CheckersSuite) TestDisk(c *C) {
+	dir := c.MkDir()
+
+	chk := &diskChecker{path: dir, minFree: plan.OptionalAmount{Value: 1, IsSet: true}}
+	err := chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	chk = &diskChecker{path: dir, minFree: plan.OptionalAmount{Value: 0, Percent: true, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	// No filesystem has an exabyte free.
+	chk = &diskChecker{path: dir, minFree: plan.OptionalAmount{Value: 1e18, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `only .*B free on `+regexp.QuoteMeta(dir)+` \(minimum 1.00EB\)`)
+
+	chk = &diskChecker{path: filepath.Join(dir, "missing"), minFree: plan.OptionalAmount{Value: 1, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "cannot get filesystem status: no such file or directory")
+}
+
+func (s *CheckersSuite) TestFile(c *C) {
+	path := filepath.Join(c.MkDir(), "heartbeat")
+
+	chk := &fileChecker{path: path}
+	err := chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "stat .*heartbeat: no such file or directory")
+
+	err = ioutil.WriteFile(path, nil, 0o644)
+	c.Assert(err, IsNil)
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	chk = &fileChecker{path: path, maxAge: time.Minute}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	old := time.Now().Add(-time.Hour)
+	err = os.Chtimes(path, old, old)
+	c.Assert(err, IsNil)
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `.*heartbeat last modified 1h0m[0-9]+s ago \(maximum 1m0s\)`)
+}
+
+func (s *CheckersSuite) TestProcessByName(c *C) {
+	restore := fakeProcPath(c.MkDir())
+	defer restore()
+	writeProcFile(c, "1/comm", "init\n")
+	writeProcFile(c, "1/cmdline", "/sbin/init\x00splash\x00")
+	writeProcFile(c, "42/comm", "a-very-long-pro\n")
+	writeProcFile(c, "42/cmdline", "/usr/bin/a-very-long-process-name\x00--flag\x00")
+	writeProcFile(c, "self/comm", "self\n")
+
+	for _, name := range []string{"init", "a-very-long-process-name"} {
+		chk := &processChecker{processName: name}
+		err := chk.check(context.Background())
+		c.Check(err, IsNil, Commentf(name))
+	}
+
+	chk := &processChecker{processName: "self"}
+	err := chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `no process named "self" is running`)
+}
+
+func (s *CheckersSuite) TestProcessByPIDFile(c *C) {
+	pidFile := filepath.Join(c.MkDir(), "test.pid")
+
+	chk := &processChecker{pidFile: pidFile}
+	err := chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "cannot read PID file: .* no such file or directory")
+
+	err = ioutil.WriteFile(pidFile, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0o644)
+	c.Assert(err, IsNil)
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	err = ioutil.WriteFile(pidFile, []byte("bad"), 0o644)
+	c.Assert(err, IsNil)
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "invalid PID in .*test.pid")
+
+	// Find a PID that isn't in use.
+	pid := 1 << 22
+	for ; syscall.Kill(pid, 0) != syscall.ESRCH; pid-- {
+	}
+	err = ioutil.WriteFile(pidFile, []byte(strconv.Itoa(pid)), 0o644)
+	c.Assert(err, IsNil)
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, fmt.Sprintf("process %d from .*test.pid is not running", pid))
+}
+
+func (s *CheckersSuite) TestResources(c *C) {
+	restore := fakeProcPath(c.MkDir())
+	defer restore()
+	writeProcFile(c, "meminfo", `
+MemTotal:        2000000 kB
+MemFree:          500000 kB
+MemAvailable:     800000 kB
+`[1:])
+	writeProcFile(c, "loadavg", "1.50 1.20 0.90 2/300 12345\n")
+
+	// 1200000 kB (60%) of memory is used.
+	chk := &resourcesChecker{
+		maxMemory: plan.OptionalAmount{Value: 70, Percent: true, IsSet: true},
+		maxLoad:   plan.OptionalFloat{Value: 2, IsSet: true},
+	}
+	err := chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	chk = &resourcesChecker{maxMemory: plan.OptionalAmount{Value: 50, Percent: true, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `1.23GB memory used \(maximum 50%\)`)
+
+	chk = &resourcesChecker{maxMemory: plan.OptionalAmount{Value: 1e9, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `1.23GB memory used \(maximum 1.00GB\)`)
+
+	chk = &resourcesChecker{maxLoad: plan.OptionalFloat{Value: 1.5, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	chk = &resourcesChecker{maxLoad: plan.OptionalFloat{Value: 1, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `load average 1.50 \(maximum 1\)`)
+
+	writeProcFile(c, "meminfo", "MemTotal: 2000000 kB\n")
+	chk = &resourcesChecker{maxMemory: plan.OptionalAmount{Value: 50, Percent: true, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, "cannot find memory usage in meminfo")
+}
+
+func (s *CheckersSuite) TestResourcesCgroup(c *C) {
+	restore := fakeProcPath(c.MkDir())
+	defer restore()
+	root := c.MkDir()
+	oldRoot := cgroupRoot
+	cgroupRoot = root
+	defer func() { cgroupRoot = oldRoot }()
+
+	writeProcFile(c, "meminfo", `
+MemTotal:        2000000 kB
+MemAvailable:     800000 kB
+`[1:])
+	writeProcFile(c, "self/cgroup", "0::/container/pebble-daemon\n")
+	writeCgroupFile := func(name, content string) {
+		path := filepath.Join(root, name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0o755), IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(content), 0o644), IsNil)
+	}
+	// Pebble's own cgroup has no limit, so its parent's limit is used.
+	writeCgroupFile("container/pebble-daemon/memory.max", "max\n")
+	writeCgroupFile("container/pebble-daemon/memory.current", "100000000\n")
+	writeCgroupFile("container/memory.max", "2000000000\n")
+	writeCgroupFile("container/memory.current", "1200000000\n")
+
+	chk := &resourcesChecker{maxMemory: plan.OptionalAmount{Value: 70, Percent: true, IsSet: true}}
+	err := chk.check(context.Background())
+	c.Assert(err, IsNil)
+
+	chk = &resourcesChecker{maxMemory: plan.OptionalAmount{Value: 50, Percent: true, IsSet: true}}
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `1.20GB memory used \(maximum 50%\)`)
+
+	// Without a limit anywhere, host-wide figures are used.
+	writeCgroupFile("container/memory.max", "max\n")
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `1.23GB memory used \(maximum 50%\)`)
+
+	writeCgroupFile("container/memory.max", "foo\n")
+	err = chk.check(context.Background())
+	c.Assert(err, ErrorMatches, `invalid cgroup memory limit "foo\\n"`)
+}
+
+func fakeProcPath(path string) (restore func()) {
+	old := procPath
+	procPath = path
+	return func() {
+		procPath = old
+	}
+}
+
+func writeProcFile(c *C, name, content string) {
+	path := filepath.Join(procPath, name)
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(path, []byte(content), 0o644)
+	c.Assert(err, IsNil)
+}
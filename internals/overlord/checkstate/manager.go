@@ -16,6 +16,10 @@ package checkstate
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
 	"sort"
 	"sync"
 	"time"
@@ -27,14 +31,35 @@ import (
 // CheckManager starts and manages the health checks.
 type CheckManager struct {
 	mutex           sync.Mutex
-	wg              sync.WaitGroup
 	checks          map[string]*checkData
 	failureHandlers []FailureFunc
+	statusHandlers  []StatusFunc
+	actionHandler   ActionHandler
+
+	// Running checks look up the status of other checks (that they require,
+	// or that a composite check aggregates) in statuses, which is the same
+	// as checks but protected by its own mutex, as PlanChanged holds mutex
+	// while waiting for checks to stop. The same goes for serviceStatus.
+	statusMutex   sync.Mutex
+	statuses      map[string]*checkData
+	serviceStatus ServiceStatusFunc
 }
 
 // FailureFunc is the type of function called when a failure action is triggered.
 type FailureFunc func(name string)
 
+// ServiceStatusFunc is the type of function called by the checks generated
+// for services with "health" set. It returns an error if the named service
+// isn't running.
+type ServiceStatusFunc func(name string) error
+
+// serviceCheckPeriod is how often the checks generated for services look up
+// the service's status.
+var serviceCheckPeriod = time.Second
+
+// StatusFunc is the type of function called when a check goes up or down.
+type StatusFunc func(name string, status CheckStatus)
+
 // NewManager creates a new check manager.
 func NewManager() *CheckManager {
 	return &CheckManager{}
@@ -46,44 +71,142 @@ func (m *CheckManager) NotifyCheckFailed(f FailureFunc) {
 	m.failureHandlers = append(m.failureHandlers, f)
 }
 
+// NotifyCheckStatusChanged adds f to the list of functions that are called
+// whenever a check goes down (hits its failure threshold) or up again.
+func (m *CheckManager) NotifyCheckStatusChanged(f StatusFunc) {
+	m.statusHandlers = append(m.statusHandlers, f)
+}
+
+// SetServiceStatusFunc sets the function used by the checks generated for
+// services with "health" set. Without it, those checks fail.
+func (m *CheckManager) SetServiceStatusFunc(f ServiceStatusFunc) {
+	m.statusMutex.Lock()
+	defer m.statusMutex.Unlock()
+	m.serviceStatus = f
+}
+
 // PlanChanged handles updates to the plan (server configuration),
 // stopping the previous checks and starting the new ones as required.
 func (m *CheckManager) PlanChanged(p *plan.Plan) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	configs := checkConfigs(p)
+
+	// Checks whose configuration hasn't changed keep running, so that their
+	// state and history is kept.
+	checks := make(map[string]*checkData, len(configs))
+	var stopping []*checkData
+	for name, check := range m.checks {
+		config, ok := configs[name]
+		if ok && config.Equal(check.config) && reflect.DeepEqual(m.newChecker(config, p), check.checker) {
+			checks[name] = check
+			continue
+		}
+		stopping = append(stopping, check)
+	}
+
 	logger.Debugf("Configuring check manager (stopping %d, starting %d)",
-		len(m.checks), len(p.Checks))
+		len(stopping), len(configs)-len(checks))
 
-	// First stop existing checks.
-	for _, check := range m.checks {
+	// First stop the checks that are removed or changed.
+	for _, check := range stopping {
 		check.cancel()
 	}
 	// Wait for all context cancellations to propagate and allow
 	// each goroutine to cleanly exit.
-	m.wg.Wait()
-
-	// Set the size of the next wait group
-	m.wg.Add(len(p.Checks))
+	for _, check := range stopping {
+		<-check.done
+	}
 
-	// Then configure and start new checks.
-	checks := make(map[string]*checkData, len(p.Checks))
-	for name, config := range p.Checks {
+	// Then configure new checks, and start them once all the checks they
+	// may look up are in place.
+	var starting []*checkData
+	for name, config := range configs {
+		if _, ok := checks[name]; ok {
+			continue
+		}
 		ctx, cancel := context.WithCancel(context.Background())
 		check := &checkData{
 			config:  config,
-			checker: newChecker(config, p),
+			checker: m.newChecker(config, p),
 			ctx:     ctx,
 			cancel:  cancel,
+			done:    make(chan struct{}),
+			runs:    make(chan chan CheckResult),
 			action:  m.callFailureHandlers,
+			actions: m.runActions,
+			isDown:  m.isDown,
+			changed: m.callStatusHandlers,
+		}
+		if config.StartPeriod.Value > 0 {
+			check.startEnd = time.Now().Add(config.StartPeriod.Value)
+		}
+		if old, ok := m.checks[name]; ok {
+			// A changed check stays paused.
+			check.paused = old.isPaused()
 		}
 		checks[name] = check
+		starting = append(starting, check)
+	}
+	m.checks = checks
+
+	m.statusMutex.Lock()
+	m.statuses = checks
+	m.statusMutex.Unlock()
+
+	for _, check := range starting {
+		check := check
 		go func() {
-			defer m.wg.Done()
+			defer close(check.done)
 			check.loop()
 		}()
 	}
-	m.checks = checks
+}
+
+// checkConfigs returns the plan's checks, along with the checks generated
+// for services with "health" set, which have the service's name.
+func checkConfigs(p *plan.Plan) map[string]*plan.Check {
+	configs := make(map[string]*plan.Check, len(p.Checks))
+	for name, config := range p.Checks {
+		configs[name] = config
+	}
+	for name, service := range p.Services {
+		if service.Health == plan.UnsetLevel {
+			continue
+		}
+		configs[name] = &plan.Check{
+			Name:             name,
+			Level:            service.Health,
+			Period:           plan.OptionalDuration{Value: serviceCheckPeriod},
+			Timeout:          plan.OptionalDuration{Value: serviceCheckPeriod},
+			Threshold:        1,
+			SuccessThreshold: 1,
+		}
+	}
+	return configs
+}
+
+// serviceStatusFunc returns the function used by the checks generated for
+// services. Like isDown, it's safe to call from running checks.
+func (m *CheckManager) serviceStatusFunc() ServiceStatusFunc {
+	m.statusMutex.Lock()
+	defer m.statusMutex.Unlock()
+	return m.serviceStatus
+}
+
+// isDown reports whether the named check is down. It doesn't use m.mutex, so
+// it's safe to call from running checks.
+func (m *CheckManager) isDown(name string) bool {
+	m.statusMutex.Lock()
+	check, ok := m.statuses[name]
+	m.statusMutex.Unlock()
+	if !ok {
+		return false
+	}
+	check.mutex.Lock()
+	defer check.mutex.Unlock()
+	return check.down
 }
 
 func (m *CheckManager) callFailureHandlers(name string) {
@@ -92,15 +215,38 @@ func (m *CheckManager) callFailureHandlers(name string) {
 	}
 }
 
+func (m *CheckManager) callStatusHandlers(name string, status CheckStatus) {
+	for _, f := range m.statusHandlers {
+		f(name, status)
+	}
+}
+
 // newChecker creates a new checker of the configured type.
-func newChecker(config *plan.Check, p *plan.Plan) checker {
+func (m *CheckManager) newChecker(config *plan.Check, p *plan.Plan) checker {
 	switch {
 	case config.HTTP != nil:
-		return &httpChecker{
-			name:    config.Name,
-			url:     config.HTTP.URL,
-			headers: config.HTTP.Headers,
+		// The plan has already validated the URL and body-match regexp.
+		socket, url, err := config.HTTP.ParseURL()
+		if err != nil {
+			url = config.HTTP.URL
+		}
+		chk := &httpChecker{
+			name:           config.Name,
+			url:            url,
+			socket:         socket,
+			method:         config.HTTP.Method,
+			headers:        config.HTTP.Headers,
+			body:           config.HTTP.Body,
+			expectedStatus: config.HTTP.ExpectedStatus,
+			tlsCA:          config.HTTP.TLSCA,
+			tlsCert:        config.HTTP.TLSCert,
+			tlsKey:         config.HTTP.TLSKey,
+			tlsSkipVerify:  config.HTTP.TLSSkipVerify != nil && *config.HTTP.TLSSkipVerify,
 		}
+		if config.HTTP.BodyMatch != "" {
+			chk.bodyMatch, _ = regexp.Compile(config.HTTP.BodyMatch)
+		}
+		return chk
 
 	case config.TCP != nil:
 		return &tcpChecker{
@@ -134,6 +280,60 @@ func newChecker(config *plan.Check, p *plan.Plan) checker {
 			workingDir:  merged.WorkingDir,
 		}
 
+	case config.GRPC != nil:
+		return &grpcChecker{
+			name:          config.Name,
+			address:       config.GRPC.Address,
+			service:       config.GRPC.Service,
+			tls:           config.GRPC.TLS != nil && *config.GRPC.TLS,
+			tlsSkipVerify: config.GRPC.TLSSkipVerify != nil && *config.GRPC.TLSSkipVerify,
+			metadata:      config.GRPC.Metadata,
+		}
+
+	case config.Composite != nil:
+		return &compositeChecker{
+			name:    config.Name,
+			checks:  config.Composite.Checks,
+			any:     config.Composite.Mode == plan.CompositeAny,
+			manager: m,
+		}
+
+	case config.Disk != nil:
+		return &diskChecker{
+			name:          config.Name,
+			path:          config.Disk.Path,
+			minFree:       config.Disk.MinFree,
+			minFreeInodes: config.Disk.MinFreeInodes,
+		}
+
+	case config.File != nil:
+		return &fileChecker{
+			name:   config.Name,
+			path:   config.File.Path,
+			maxAge: config.File.MaxAge.Value,
+		}
+
+	case config.Process != nil:
+		return &processChecker{
+			name:        config.Name,
+			processName: config.Process.Name,
+			pidFile:     config.Process.PIDFile,
+		}
+
+	case config.Resources != nil:
+		return &resourcesChecker{
+			name:      config.Name,
+			maxMemory: config.Resources.MaxMemory,
+			maxLoad:   config.Resources.MaxLoad,
+		}
+
+	case p != nil && p.Services[config.Name] != nil && p.Services[config.Name].Health != plan.UnsetLevel:
+		// This check was generated for a service with "health" set.
+		return &serviceChecker{
+			name:    config.Name,
+			manager: m,
+		}
+
 	default:
 		// This has already been checked when parsing the config.
 		panic("internal error: invalid check config")
@@ -157,6 +357,68 @@ func (m *CheckManager) Checks() ([]*CheckInfo, error) {
 	return infos, nil
 }
 
+// RunCheck runs the named check immediately (whether or not it's paused),
+// and returns its result once it completes. It returns an error wrapping
+// os.ErrNotExist if there's no such check.
+func (m *CheckManager) RunCheck(ctx context.Context, name string) (*CheckResult, error) {
+	m.mutex.Lock()
+	check, ok := m.checks[name]
+	m.mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("cannot find check %q: %w", name, os.ErrNotExist)
+	}
+
+	reply := make(chan CheckResult, 1)
+	select {
+	case check.runs <- reply:
+	case <-check.ctx.Done():
+		return nil, fmt.Errorf("check %q was stopped", name)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case result := <-reply:
+		return &result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// PauseChecks stops the named checks from running periodically till they're
+// resumed, for example during maintenance. A paused check doesn't trigger
+// its failure action. It returns an error wrapping os.ErrNotExist (and
+// pauses none of them) if any of the checks don't exist.
+func (m *CheckManager) PauseChecks(names []string) error {
+	return m.setPaused(names, true)
+}
+
+// ResumeChecks resumes running the named checks periodically. It returns
+// an error wrapping os.ErrNotExist (and resumes none of them) if any of the
+// checks don't exist.
+func (m *CheckManager) ResumeChecks(names []string) error {
+	return m.setPaused(names, false)
+}
+
+func (m *CheckManager) setPaused(names []string, paused bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, name := range names {
+		if _, ok := m.checks[name]; !ok {
+			return fmt.Errorf("cannot find check %q: %w", name, os.ErrNotExist)
+		}
+	}
+	for _, name := range names {
+		m.checks[name].setPaused(paused)
+		if paused {
+			logger.Noticef("Check %q paused", name)
+		} else {
+			logger.Noticef("Check %q resumed", name)
+		}
+	}
+	return nil
+}
+
 // CheckInfo provides status information about a single check.
 type CheckInfo struct {
 	Name         string
@@ -166,8 +428,30 @@ type CheckInfo struct {
 	Threshold    int
 	LastError    string
 	ErrorDetails string
+	History      []CheckResult
+	Paused       bool
+
+	// Successes is the number of successes in a row while the check is
+	// down, and SuccessThreshold the number needed for it to be up again.
+	Successes        int
+	SuccessThreshold int
+
+	// Starting is true while the check is in its start period, during
+	// which failures aren't counted.
+	Starting bool
+}
+
+// CheckResult records the result of a single run of a check.
+type CheckResult struct {
+	Time     time.Time
+	Duration time.Duration
+	Success  bool
+	Error    string
 }
 
+// maxHistory is the number of recent results kept for each check.
+const maxHistory = 20
+
 type CheckStatus string
 
 const (
@@ -181,12 +465,22 @@ type checkData struct {
 	checker checker
 	ctx     context.Context
 	cancel  context.CancelFunc
+	done    chan struct{}
+	runs    chan chan CheckResult
 	action  FailureFunc
+	actions func(config *plan.Check, event checkEvent)
+	isDown  func(name string) bool
+	changed StatusFunc
 
 	mutex     sync.Mutex
 	failures  int
+	successes int
+	down      bool
 	actionRan bool
 	lastErr   error
+	history   []CheckResult // oldest first
+	paused    bool
+	startEnd  time.Time // zero if there's no start period, or it has ended
 }
 
 type checker interface {
@@ -196,18 +490,37 @@ type checker interface {
 func (c *checkData) loop() {
 	logger.Debugf("Check %q starting with period %v", c.config.Name, c.config.Period.Value)
 
+	if c.config.InitialDelay.Value > 0 {
+		if !c.initialDelay() {
+			return
+		}
+		// Run the check as soon as the delay is over, then periodically.
+		if !c.isPaused() && !c.blocked() {
+			c.runCheck()
+			if c.ctx.Err() != nil {
+				return
+			}
+		}
+	}
+
 	ticker := time.NewTicker(c.config.Period.Value)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
+			if c.isPaused() || c.blocked() {
+				continue
+			}
 			c.runCheck()
 			if c.ctx.Err() != nil {
 				// Don't re-run check in edge case where period is short and
 				// in-flight check was cancelled.
 				return
 			}
+		case reply := <-c.runs:
+			// Manual run requested by RunCheck.
+			reply <- c.runCheck()
 		case <-c.ctx.Done():
 			logger.Debugf("Check %q stopped: %v", c.config.Name, c.ctx.Err())
 			return
@@ -215,41 +528,150 @@ func (c *checkData) loop() {
 	}
 }
 
-func (c *checkData) runCheck() {
+// initialDelay waits for the check's initial delay, still serving manual runs
+// in the meantime. It returns false if the check was stopped.
+func (c *checkData) initialDelay() bool {
+	logger.Debugf("Check %q waiting for initial delay %v", c.config.Name, c.config.InitialDelay.Value)
+
+	timer := time.NewTimer(c.config.InitialDelay.Value)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case reply := <-c.runs:
+			reply <- c.runCheck()
+		case <-c.ctx.Done():
+			logger.Debugf("Check %q stopped: %v", c.config.Name, c.ctx.Err())
+			return false
+		}
+	}
+}
+
+func (c *checkData) runCheck() CheckResult {
 	// Run the check with a timeout.
 	ctx, cancel := context.WithTimeout(c.ctx, c.config.Timeout.Value)
 	defer cancel()
+	start := time.Now()
 	err := c.checker.check(ctx)
+	result := CheckResult{Time: start, Duration: time.Since(start), Success: err == nil}
 
 	// Lock while we update state, as the manager may access these too.
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
 	if err == nil {
-		// Successful check
+		// Successful check, which also ends the start period.
+		c.addResult(result)
 		c.lastErr = nil
 		c.failures = 0
-		c.actionRan = false
-		return
+		c.startEnd = time.Time{}
+		if !c.down {
+			c.actionRan = false
+			return result
+		}
+		c.successes++
+		if c.successes >= c.successThreshold() {
+			logger.Noticef("Check %q succeeded %d times in a row, check is up",
+				c.config.Name, c.successes)
+			c.down = false
+			c.successes = 0
+			c.changed(c.config.Name, CheckStatusUp)
+			if c.actionRan {
+				go c.actions(c.config, checkEventRecovery)
+			}
+			c.actionRan = false
+		}
+		return result
 	}
 
+	result.Error = err.Error()
 	if ctx.Err() == context.Canceled {
 		// Check was stopped, don't trigger failure action.
 		logger.Debugf("Check %q canceled in flight", c.config.Name)
-		return
+		return result
 	}
 
-	// Track failure, run failure action if "failures" threshold was hit.
+	c.addResult(result)
 	c.lastErr = err
+	c.successes = 0
+	if c.starting() {
+		logger.Noticef("Check %q failure during start period (not counted): %v",
+			c.config.Name, err)
+		return result
+	}
+
+	// Track failure, run failure action if "failures" threshold was hit.
 	c.failures++
+	if c.failures >= c.config.Threshold && !c.down {
+		c.down = true
+		c.changed(c.config.Name, CheckStatusDown)
+	}
 	logger.Noticef("Check %q failure %d (threshold %d): %v",
 		c.config.Name, c.failures, c.config.Threshold, err)
+	if c.paused {
+		// Failure action is triggered by the first failing run after the
+		// check is resumed.
+		return result
+	}
 	if !c.actionRan && c.failures >= c.config.Threshold {
 		logger.Noticef("Check %q failure threshold %d hit, triggering action",
 			c.config.Name, c.config.Threshold)
 		c.action(c.config.Name)
+		go c.actions(c.config, checkEventFailure)
 		c.actionRan = true
 	}
+	return result
+}
+
+// blocked reports whether any of the checks this check requires are down, in
+// which case it's not run periodically.
+func (c *checkData) blocked() bool {
+	for _, name := range c.config.Requires {
+		if c.isDown(name) {
+			logger.Debugf("Check %q not run: required check %q is down", c.config.Name, name)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checkData) isPaused() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.paused
+}
+
+func (c *checkData) setPaused(paused bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.paused = paused
+}
+
+// starting reports whether the check is in its start period. The caller
+// must hold c.mutex.
+func (c *checkData) starting() bool {
+	return !c.startEnd.IsZero() && time.Now().Before(c.startEnd)
+}
+
+// successThreshold returns the number of successes in a row needed for a
+// check that's down to be up again.
+func (c *checkData) successThreshold() int {
+	if c.config.SuccessThreshold < 1 {
+		return 1
+	}
+	return c.config.SuccessThreshold
+}
+
+// addResult appends a result to the check's history, discarding the oldest
+// result if the history is full. The caller must hold c.mutex.
+func (c *checkData) addResult(result CheckResult) {
+	if len(c.history) >= maxHistory {
+		copy(c.history, c.history[1:])
+		c.history = c.history[:len(c.history)-1]
+	}
+	c.history = append(c.history, result)
 }
 
 // info returns user-facing check information for use in Checks (and tests).
@@ -258,13 +680,18 @@ func (c *checkData) info() *CheckInfo {
 	defer c.mutex.Unlock()
 
 	info := &CheckInfo{
-		Name:      c.config.Name,
-		Level:     c.config.Level,
-		Status:    CheckStatusUp,
-		Failures:  c.failures,
-		Threshold: c.config.Threshold,
+		Name:             c.config.Name,
+		Level:            c.config.Level,
+		Status:           CheckStatusUp,
+		Failures:         c.failures,
+		Threshold:        c.config.Threshold,
+		History:          append([]CheckResult(nil), c.history...),
+		Paused:           c.paused,
+		Successes:        c.successes,
+		SuccessThreshold: c.config.SuccessThreshold,
+		Starting:         c.starting(),
 	}
-	if c.failures >= c.config.Threshold {
+	if c.down {
 		info.Status = CheckStatusDown
 	}
 	if c.lastErr != nil {
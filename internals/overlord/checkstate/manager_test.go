@@ -15,10 +15,13 @@
 package checkstate
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -209,6 +212,13 @@ func (s *ManagerSuite) TestFailures(c *C) {
 	mgr.NotifyCheckFailed(func(name string) {
 		failureName = name
 	})
+	var statusMutex sync.Mutex
+	var statuses []string
+	mgr.NotifyCheckStatusChanged(func(name string, status CheckStatus) {
+		statusMutex.Lock()
+		defer statusMutex.Unlock()
+		statuses = append(statuses, name+" "+string(status))
+	})
 	testPath := c.MkDir() + "/test"
 	err := ioutil.WriteFile(testPath, nil, 0o644)
 	c.Assert(err, IsNil)
@@ -265,6 +275,578 @@ func (s *ManagerSuite) TestFailures(c *C) {
 	c.Assert(check.Threshold, Equals, 3)
 	c.Assert(check.LastError, Equals, "")
 	c.Assert(failureName, Equals, "")
+
+	// Status handlers are only called when the check goes down or up.
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+	c.Assert(statuses, DeepEquals, []string{"chk1 down", "chk1 up"})
+}
+
+func (s *ManagerSuite) TestHistory(c *C) {
+	mgr := NewManager()
+	testPath := c.MkDir() + "/test"
+	err := ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": {
+				Name:      "chk1",
+				Period:    plan.OptionalDuration{Value: 20 * time.Millisecond},
+				Timeout:   plan.OptionalDuration{Value: 100 * time.Millisecond},
+				Threshold: 3,
+				Exec: &plan.ExecCheck{
+					Command: fmt.Sprintf(`/bin/sh -c '[ ! -f %s ]'`, testPath),
+				},
+			},
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	start := time.Now()
+	check := waitCheck(c, mgr, "chk1", func(check *CheckInfo) bool {
+		return check.Failures == 1
+	})
+	c.Assert(check.History, HasLen, 1)
+	result := check.History[0]
+	c.Check(result.Success, Equals, false)
+	c.Check(result.Error, Equals, "exit status 1")
+	c.Check(result.Time.After(start), Equals, true)
+	c.Check(result.Duration > 0, Equals, true)
+
+	err = os.Remove(testPath)
+	c.Assert(err, IsNil)
+	check = waitCheck(c, mgr, "chk1", func(check *CheckInfo) bool {
+		return check.Status == CheckStatusUp && check.Failures == 0
+	})
+	last := check.History[len(check.History)-1]
+	c.Check(last.Success, Equals, true)
+	c.Check(last.Error, Equals, "")
+	c.Check(last.Time.After(result.Time), Equals, true)
+}
+
+func (s *ManagerSuite) TestHistoryLimit(c *C) {
+	check := &checkData{}
+	for i := 0; i < maxHistory+5; i++ {
+		check.addResult(CheckResult{Error: strconv.Itoa(i)})
+	}
+	c.Assert(check.history, HasLen, maxHistory)
+	c.Check(check.history[0].Error, Equals, "5")
+	c.Check(check.history[maxHistory-1].Error, Equals, strconv.Itoa(maxHistory+4))
+}
+
+func (s *ManagerSuite) TestPlanChangedKeepsState(c *C) {
+	mgr := NewManager()
+	failingPlan := func(threshold int) *plan.Plan {
+		return &plan.Plan{
+			Checks: map[string]*plan.Check{
+				"chk1": {
+					Name:      "chk1",
+					Period:    plan.OptionalDuration{Value: 20 * time.Millisecond},
+					Timeout:   plan.OptionalDuration{Value: 100 * time.Millisecond},
+					Threshold: threshold,
+					Exec:      &plan.ExecCheck{Command: "/bin/sh -c 'exit 1'"},
+				},
+			},
+		}
+	}
+	mgr.PlanChanged(failingPlan(10))
+	defer stopChecks(c, mgr)
+	waitCheck(c, mgr, "chk1", func(check *CheckInfo) bool {
+		return check.Failures >= 2
+	})
+
+	// Unchanged check keeps running, along with its state, even when other
+	// checks are added.
+	p := failingPlan(10)
+	p.Checks["chk2"] = &plan.Check{
+		Name:      "chk2",
+		Period:    plan.OptionalDuration{Value: time.Second},
+		Threshold: 3,
+		Exec:      &plan.ExecCheck{Command: "echo chk2"},
+	}
+	mgr.PlanChanged(p)
+	checks, err := mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Assert(checks, HasLen, 2)
+	c.Check(checks[0].Failures >= 2, Equals, true)
+	c.Check(len(checks[0].History) >= 2, Equals, true)
+
+	// Changed check is restarted with fresh state.
+	mgr.PlanChanged(failingPlan(5))
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Assert(checks, HasLen, 1)
+	c.Check(checks[0].Threshold, Equals, 5)
+	c.Check(checks[0].Failures < 2, Equals, true)
+}
+
+func (s *ManagerSuite) TestRunCheck(c *C) {
+	mgr := NewManager()
+	testPath := c.MkDir() + "/test"
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": {
+				Name:      "chk1",
+				Period:    plan.OptionalDuration{Value: time.Hour},
+				Timeout:   plan.OptionalDuration{Value: time.Second},
+				Threshold: 3,
+				Exec: &plan.ExecCheck{
+					Command: fmt.Sprintf(`/bin/sh -c '[ -f %s ]'`, testPath),
+				},
+			},
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	result, err := mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	c.Check(result.Success, Equals, false)
+	c.Check(result.Error, Equals, "exit status 1")
+
+	err = ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	result, err = mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	c.Check(result.Success, Equals, true)
+	c.Check(result.Error, Equals, "")
+
+	// Manual runs are recorded like periodic ones.
+	checks, err := mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Assert(checks[0].History, HasLen, 2)
+	c.Check(checks[0].History[0].Success, Equals, false)
+	c.Check(checks[0].History[1].Success, Equals, true)
+
+	_, err = mgr.RunCheck(context.Background(), "nonexistent")
+	c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+	c.Check(err, ErrorMatches, `cannot find check "nonexistent": file does not exist`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = mgr.RunCheck(ctx, "chk1")
+	c.Check(err, Equals, context.Canceled)
+}
+
+func (s *ManagerSuite) TestPauseResume(c *C) {
+	mgr := NewManager()
+	failureName := ""
+	mgr.NotifyCheckFailed(func(name string) {
+		failureName = name
+	})
+	failingPlan := func(threshold int) *plan.Plan {
+		return &plan.Plan{
+			Checks: map[string]*plan.Check{
+				"chk1": {
+					Name:      "chk1",
+					Period:    plan.OptionalDuration{Value: 10 * time.Millisecond},
+					Timeout:   plan.OptionalDuration{Value: 5 * time.Millisecond},
+					Threshold: threshold,
+					TCP:       &plan.TCPCheck{Port: 1},
+				},
+			},
+		}
+	}
+	mgr.PlanChanged(failingPlan(1))
+	defer stopChecks(c, mgr)
+
+	err := mgr.PauseChecks([]string{"chk1", "nonexistent"})
+	c.Assert(errors.Is(err, os.ErrNotExist), Equals, true)
+	err = mgr.PauseChecks([]string{"chk1"})
+	c.Assert(err, IsNil)
+
+	// Paused check doesn't run periodically.
+	checks, err := mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Paused, Equals, true)
+	failures := checks[0].Failures
+	time.Sleep(50 * time.Millisecond)
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Failures, Equals, failures)
+
+	// It can still be run manually, but doesn't trigger the failure action.
+	failureName = ""
+	result, err := mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	c.Check(result.Success, Equals, false)
+	c.Check(failureName, Equals, "")
+
+	// Changed check stays paused.
+	mgr.PlanChanged(failingPlan(2))
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Paused, Equals, true)
+
+	// Resumed check runs again, and triggers the failure action.
+	err = mgr.ResumeChecks([]string{"chk1"})
+	c.Assert(err, IsNil)
+	check := waitCheck(c, mgr, "chk1", func(check *CheckInfo) bool {
+		return check.Failures >= 2
+	})
+	c.Check(check.Paused, Equals, false)
+	c.Check(failureName, Equals, "chk1")
+}
+
+func (s *ManagerSuite) TestSuccessThreshold(c *C) {
+	mgr := NewManager()
+	failures := 0
+	mgr.NotifyCheckFailed(func(name string) {
+		failures++
+	})
+	testPath := c.MkDir() + "/test"
+	err := ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": {
+				Name:             "chk1",
+				Period:           plan.OptionalDuration{Value: time.Hour},
+				Timeout:          plan.OptionalDuration{Value: time.Second},
+				Threshold:        1,
+				SuccessThreshold: 2,
+				Exec: &plan.ExecCheck{
+					Command: fmt.Sprintf(`/bin/sh -c '[ ! -f %s ]'`, testPath),
+				},
+			},
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	runCheck := func() *CheckInfo {
+		_, err := mgr.RunCheck(context.Background(), "chk1")
+		c.Assert(err, IsNil)
+		checks, err := mgr.Checks()
+		c.Assert(err, IsNil)
+		return checks[0]
+	}
+
+	check := runCheck()
+	c.Check(check.Status, Equals, CheckStatusDown)
+	c.Check(check.SuccessThreshold, Equals, 2)
+	c.Check(failures, Equals, 1)
+
+	// A single success isn't enough for the check to be up again.
+	err = os.Remove(testPath)
+	c.Assert(err, IsNil)
+	check = runCheck()
+	c.Check(check.Status, Equals, CheckStatusDown)
+	c.Check(check.Failures, Equals, 0)
+	c.Check(check.Successes, Equals, 1)
+
+	// A failure in between resets the successes, but doesn't trigger the
+	// failure action again.
+	err = ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	check = runCheck()
+	c.Check(check.Status, Equals, CheckStatusDown)
+	c.Check(check.Successes, Equals, 0)
+	c.Check(failures, Equals, 1)
+
+	err = os.Remove(testPath)
+	c.Assert(err, IsNil)
+	check = runCheck()
+	c.Check(check.Status, Equals, CheckStatusDown)
+	c.Check(check.Successes, Equals, 1)
+	check = runCheck()
+	c.Check(check.Status, Equals, CheckStatusUp)
+	c.Check(check.Successes, Equals, 0)
+
+	// Once it's up, the next failure triggers the action again.
+	err = ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	check = runCheck()
+	c.Check(check.Status, Equals, CheckStatusDown)
+	c.Check(failures, Equals, 2)
+}
+
+func (s *ManagerSuite) TestStartPeriod(c *C) {
+	mgr := NewManager()
+	failureName := ""
+	mgr.NotifyCheckFailed(func(name string) {
+		failureName = name
+	})
+	testPath := c.MkDir() + "/test"
+	err := ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": {
+				Name:        "chk1",
+				Period:      plan.OptionalDuration{Value: time.Hour},
+				Timeout:     plan.OptionalDuration{Value: time.Second},
+				Threshold:   1,
+				StartPeriod: plan.OptionalDuration{Value: time.Hour, IsSet: true},
+				Exec: &plan.ExecCheck{
+					Command: fmt.Sprintf(`/bin/sh -c '[ ! -f %s ]'`, testPath),
+				},
+			},
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	checks, err := mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Starting, Equals, true)
+
+	// Failures during the start period are recorded but not counted.
+	result, err := mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	c.Check(result.Success, Equals, false)
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Status, Equals, CheckStatusUp)
+	c.Check(checks[0].Failures, Equals, 0)
+	c.Check(checks[0].Starting, Equals, true)
+	c.Check(checks[0].LastError, Equals, "exit status 1")
+	c.Check(checks[0].History, HasLen, 1)
+	c.Check(failureName, Equals, "")
+
+	// The first success ends the start period.
+	err = os.Remove(testPath)
+	c.Assert(err, IsNil)
+	_, err = mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Starting, Equals, false)
+
+	err = ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	_, err = mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].Status, Equals, CheckStatusDown)
+	c.Check(checks[0].Failures, Equals, 1)
+	c.Check(failureName, Equals, "chk1")
+}
+
+func (s *ManagerSuite) TestInitialDelay(c *C) {
+	mgr := NewManager()
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": {
+				Name:         "chk1",
+				Period:       plan.OptionalDuration{Value: 10 * time.Millisecond},
+				Timeout:      plan.OptionalDuration{Value: time.Second},
+				Threshold:    3,
+				InitialDelay: plan.OptionalDuration{Value: time.Hour, IsSet: true},
+				Exec:         &plan.ExecCheck{Command: "/bin/true"},
+			},
+			"chk2": {
+				Name:         "chk2",
+				Period:       plan.OptionalDuration{Value: time.Hour},
+				Timeout:      plan.OptionalDuration{Value: time.Second},
+				Threshold:    3,
+				InitialDelay: plan.OptionalDuration{Value: 10 * time.Millisecond, IsSet: true},
+				Exec:         &plan.ExecCheck{Command: "/bin/true"},
+			},
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	// The second check runs as soon as its initial delay is over.
+	waitCheck(c, mgr, "chk2", func(check *CheckInfo) bool {
+		return len(check.History) == 1
+	})
+
+	// The first check doesn't run periodically during its initial delay,
+	// but can still be run manually.
+	checks, err := mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].History, HasLen, 0)
+	result, err := mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	c.Check(result.Success, Equals, true)
+}
+
+func (s *ManagerSuite) TestComposite(c *C) {
+	testPath := c.MkDir() + "/test"
+	err := ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	check := func(name string, composite *plan.CompositeCheck) *plan.Check {
+		config := &plan.Check{
+			Name:      name,
+			Period:    plan.OptionalDuration{Value: time.Hour},
+			Timeout:   plan.OptionalDuration{Value: time.Second},
+			Threshold: 1,
+			Composite: composite,
+		}
+		if composite == nil {
+			config.Exec = &plan.ExecCheck{Command: "/bin/true"}
+		}
+		return config
+	}
+	chk1 := check("chk1", nil)
+	chk1.Exec.Command = fmt.Sprintf(`/bin/sh -c '[ ! -f %s ]'`, testPath)
+	mgr := NewManager()
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": chk1,
+			"chk2": check("chk2", nil),
+			"all":  check("all", &plan.CompositeCheck{Checks: []string{"chk1", "chk2"}}),
+			"any": check("any", &plan.CompositeCheck{
+				Checks: []string{"chk1", "chk2"},
+				Mode:   plan.CompositeAny,
+			}),
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	runCheck := func(name string) *CheckResult {
+		result, err := mgr.RunCheck(context.Background(), name)
+		c.Assert(err, IsNil)
+		return result
+	}
+
+	// Both checks are up to start with.
+	c.Check(runCheck("all").Success, Equals, true)
+	c.Check(runCheck("any").Success, Equals, true)
+
+	c.Check(runCheck("chk1").Success, Equals, false)
+	result := runCheck("all")
+	c.Check(result.Success, Equals, false)
+	c.Check(result.Error, Equals, `check "chk1" is down`)
+	c.Check(runCheck("any").Success, Equals, true)
+
+	err = os.Remove(testPath)
+	c.Assert(err, IsNil)
+	c.Check(runCheck("chk1").Success, Equals, true)
+	c.Check(runCheck("all").Success, Equals, true)
+
+	// A composite check isn't restarted if the plan changes elsewhere.
+	checks, err := mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Assert(checks[0].Name, Equals, "all")
+	c.Check(checks[0].History, HasLen, 3)
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": chk1,
+			"chk2": check("chk2", nil),
+			"all":  check("all", &plan.CompositeCheck{Checks: []string{"chk1", "chk2"}}),
+		},
+	})
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[0].History, HasLen, 3)
+}
+
+func (s *ManagerSuite) TestRequires(c *C) {
+	testPath := c.MkDir() + "/test"
+	mgr := NewManager()
+	mgr.PlanChanged(&plan.Plan{
+		Checks: map[string]*plan.Check{
+			"chk1": {
+				Name:      "chk1",
+				Period:    plan.OptionalDuration{Value: time.Hour},
+				Timeout:   plan.OptionalDuration{Value: time.Second},
+				Threshold: 1,
+				Exec: &plan.ExecCheck{
+					Command: fmt.Sprintf(`/bin/sh -c '[ ! -f %s ]'`, testPath),
+				},
+			},
+			"chk2": {
+				Name:      "chk2",
+				Period:    plan.OptionalDuration{Value: 10 * time.Millisecond},
+				Timeout:   plan.OptionalDuration{Value: 5 * time.Millisecond},
+				Threshold: 3,
+				Requires:  []string{"chk1"},
+				TCP:       &plan.TCPCheck{Port: 1},
+			},
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	// The check runs while the check it requires is up.
+	waitCheck(c, mgr, "chk2", func(check *CheckInfo) bool {
+		return len(check.History) > 0
+	})
+
+	// But not periodically while it's down.
+	err := ioutil.WriteFile(testPath, nil, 0o644)
+	c.Assert(err, IsNil)
+	result, err := mgr.RunCheck(context.Background(), "chk1")
+	c.Assert(err, IsNil)
+	c.Check(result.Success, Equals, false)
+	time.Sleep(20 * time.Millisecond)
+	checks, err := mgr.Checks()
+	c.Assert(err, IsNil)
+	runs := len(checks[1].History)
+	time.Sleep(50 * time.Millisecond)
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks[1].History, HasLen, runs)
+
+	// It can still be run manually.
+	_, err = mgr.RunCheck(context.Background(), "chk2")
+	c.Assert(err, IsNil)
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	if runs < maxHistory {
+		c.Check(checks[1].History, HasLen, runs+1)
+	}
+}
+
+func (s *ManagerSuite) TestServiceHealth(c *C) {
+	old := serviceCheckPeriod
+	serviceCheckPeriod = 10 * time.Millisecond
+	defer func() {
+		serviceCheckPeriod = old
+	}()
+
+	var mutex sync.Mutex
+	running := true
+	mgr := NewManager()
+	mgr.SetServiceStatusFunc(func(name string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !running {
+			return fmt.Errorf("service %q is backoff", name)
+		}
+		return nil
+	})
+	mgr.PlanChanged(&plan.Plan{
+		Services: map[string]*plan.Service{
+			"svc1": {Name: "svc1", Command: "cmd", Health: plan.AliveLevel},
+			"svc2": {Name: "svc2", Command: "cmd"},
+		},
+	})
+	defer stopChecks(c, mgr)
+
+	// Only services with "health" set have a check.
+	check := waitCheck(c, mgr, "svc1", func(check *CheckInfo) bool {
+		return len(check.History) > 0
+	})
+	c.Check(check.Level, Equals, plan.AliveLevel)
+	c.Check(check.Status, Equals, CheckStatusUp)
+	checks, err := mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks, HasLen, 1)
+
+	mutex.Lock()
+	running = false
+	mutex.Unlock()
+	check = waitCheck(c, mgr, "svc1", func(check *CheckInfo) bool {
+		return check.Status == CheckStatusDown
+	})
+	c.Check(check.LastError, Equals, `service "svc1" is backoff`)
+
+	mutex.Lock()
+	running = true
+	mutex.Unlock()
+	waitCheck(c, mgr, "svc1", func(check *CheckInfo) bool {
+		return check.Status == CheckStatusUp
+	})
+
+	// The check is removed along with the service's "health" setting.
+	mgr.PlanChanged(&plan.Plan{
+		Services: map[string]*plan.Service{
+			"svc1": {Name: "svc1", Command: "cmd"},
+		},
+	})
+	checks, err = mgr.Checks()
+	c.Assert(err, IsNil)
+	c.Check(checks, HasLen, 0)
 }
 
 // waitCheck is a time based approach to wait for a checker run to complete.
@@ -296,7 +878,7 @@ func waitCheck(c *C, mgr *CheckManager, name string, f func(check *CheckInfo) bo
 }
 
 func (s *CheckersSuite) TestNewChecker(c *C) {
-	chk := newChecker(&plan.Check{
+	chk := NewManager().newChecker(&plan.Check{
 		Name: "http",
 		HTTP: &plan.HTTPCheck{
 			URL:     "https://example.com/foo",
@@ -309,7 +891,24 @@ func (s *CheckersSuite) TestNewChecker(c *C) {
 	c.Check(http.url, Equals, "https://example.com/foo")
 	c.Check(http.headers, DeepEquals, map[string]string{"k": "v"})
 
-	chk = newChecker(&plan.Check{
+	chk = NewManager().newChecker(&plan.Check{
+		Name: "http-unix",
+		HTTP: &plan.HTTPCheck{
+			URL:            "http+unix://%2Frun%2Fapp.sock/health",
+			Method:         "HEAD",
+			ExpectedStatus: []int{204},
+			BodyMatch:      "^ok$",
+		},
+	}, nil)
+	http, ok = chk.(*httpChecker)
+	c.Assert(ok, Equals, true)
+	c.Check(http.url, Equals, "http://localhost/health")
+	c.Check(http.socket, Equals, "/run/app.sock")
+	c.Check(http.method, Equals, "HEAD")
+	c.Check(http.expectedStatus, DeepEquals, []int{204})
+	c.Check(http.bodyMatch.String(), Equals, "^ok$")
+
+	chk = NewManager().newChecker(&plan.Check{
 		Name: "tcp",
 		TCP: &plan.TCPCheck{
 			Port: 80,
@@ -323,7 +922,7 @@ func (s *CheckersSuite) TestNewChecker(c *C) {
 	c.Check(tcp.host, Equals, "localhost")
 
 	userID, groupID := 100, 200
-	chk = newChecker(&plan.Check{
+	chk = NewManager().newChecker(&plan.Check{
 		Name: "exec",
 		Exec: &plan.ExecCheck{
 			Command:     "sleep 1",
@@ -348,7 +947,7 @@ func (s *CheckersSuite) TestNewChecker(c *C) {
 
 func (s *CheckersSuite) TestExecContextNoOverride(c *C) {
 	svcUserID, svcGroupID := 10, 20
-	chk := newChecker(&plan.Check{
+	chk := NewManager().newChecker(&plan.Check{
 		Name: "exec",
 		Exec: &plan.ExecCheck{
 			Command:        "sleep 1",
@@ -379,7 +978,7 @@ func (s *CheckersSuite) TestExecContextNoOverride(c *C) {
 func (s *CheckersSuite) TestExecContextOverride(c *C) {
 	userID, groupID := 100, 200
 	svcUserID, svcGroupID := 10, 20
-	chk := newChecker(&plan.Check{
+	chk := NewManager().newChecker(&plan.Check{
 		Name: "exec",
 		Exec: &plan.ExecCheck{
 			Command:        "sleep 1",
@@ -0,0 +1,189 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmdstate
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/overlord/state"
+	"github.com/canonical/pebble/internals/reaper"
+	"github.com/canonical/pebble/internals/wsutil"
+)
+
+var (
+	// detachedOutputBytes is the size of the buffer holding the (combined
+	// stdout and stderr) output of a detached command.
+	detachedOutputBytes = 1024 * 1024
+
+	// detachedKeepTime is how long a detached execution is kept after its
+	// command finishes, so that clients can still attach to read its output.
+	detachedKeepTime = 10 * time.Minute
+)
+
+// doDetached runs the command in detached mode, writing its output to the
+// execution's output buffer rather than to websockets.
+func (e *execution) doDetached(ctx context.Context, task *state.Task) error {
+	// Closing the buffer tells attached clients the output is complete.
+	defer e.output.Close()
+
+	cgroup, err := e.resources.newCgroup(task.ID())
+	if err != nil {
+		return err
+	}
+	defer cgroup.remove()
+
+	if e.timeout != 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	// Detached commands have no stdin (it's connected to /dev/null).
+	cmd := e.newCmd(ctx)
+	cmd.Stdout = e.output
+	cmd.Stderr = e.output
+
+	err = e.resources.startCommand(cmd, cgroup)
+	exitCode := -1
+	if err == nil {
+		e.setPID(cmd.Process.Pid)
+		exitCode, err = reaper.WaitCommand(cmd)
+		e.setPID(0)
+	}
+	return e.finish(ctx, task, exitCode, err)
+}
+
+// setPID records the PID of the running command, along with its start
+// time. A PID of zero means the command is no longer running.
+func (e *execution) setPID(pid int) {
+	e.pidMutex.Lock()
+	defer e.pidMutex.Unlock()
+	e.pid = pid
+	if pid != 0 {
+		e.startTime = time.Now()
+	}
+}
+
+func (e *execution) getPID() int {
+	e.pidMutex.Lock()
+	defer e.pidMutex.Unlock()
+	return e.pid
+}
+
+// attach connects a client to a detached execution. Unlike with regular
+// executions, clients may connect to the "control" and "stdio" websockets
+// any number of times, and disconnecting doesn't affect the command.
+func (e *execution) attach(r *http.Request, w http.ResponseWriter, execID, id string) error {
+	if id != wsControl && id != wsStdio {
+		return os.ErrNotExist
+	}
+
+	conn, err := websocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Exec %s: client attached to %q websocket", execID, id)
+
+	e.websocketsLock.Lock()
+	e.attached[id]++
+	e.websocketsLock.Unlock()
+
+	go func() {
+		defer func() {
+			e.websocketsLock.Lock()
+			e.attached[id]--
+			e.websocketsLock.Unlock()
+		}()
+		switch id {
+		case wsControl:
+			e.detachedControlLoop(execID, conn)
+		case wsStdio:
+			e.sendOutput(execID, conn)
+		}
+	}()
+	return nil
+}
+
+// sendOutput sends the buffered output of a detached command to the
+// websocket, followed by any new output till the command finishes.
+func (e *execution) sendOutput(execID string, conn *websocket.Conn) {
+	defer conn.Close()
+
+	// Stop following the output if the client goes away. Anything the client
+	// sends is discarded, as detached commands don't have any input.
+	disconnected := make(chan struct{})
+	go func() {
+		for {
+			_, _, err := conn.NextReader()
+			if err != nil {
+				close(disconnected)
+				return
+			}
+		}
+	}()
+
+	reader, writer := io.Pipe()
+	go func() {
+		it := e.output.TailIterator()
+		defer it.Close()
+		for it.Next(disconnected) {
+			_, err := it.WriteTo(writer)
+			if err != nil && err != io.EOF {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		writer.Close()
+	}()
+
+	<-wsutil.WebsocketSendStream(conn, reader, -1)
+	reader.Close()
+	logger.Debugf("Exec %s: finished sending output", execID)
+}
+
+// detachedControlLoop handles control commands sent by a client attached
+// to a detached execution.
+func (e *execution) detachedControlLoop(execID string, conn *websocket.Conn) {
+	defer conn.Close()
+
+	for {
+		mt, r, err := conn.NextReader()
+		if err != nil || mt == websocket.CloseMessage {
+			return
+		}
+
+		var command execCommand
+		err = json.NewDecoder(r).Decode(&command)
+		if err != nil {
+			logger.Noticef("Exec %s: cannot decode control websocket command: %v", execID, err)
+			continue
+		}
+
+		pid := e.getPID()
+		if pid == 0 {
+			logger.Noticef("Exec %s: cannot handle control command %q: command not running", execID, command.Command)
+			continue
+		}
+		e.handleControlCommand(execID, pid, -1, &command)
+	}
+}
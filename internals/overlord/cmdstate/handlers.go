@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"sort"
 	"sync"
 	"syscall"
 	"time"
@@ -36,6 +37,7 @@ import (
 	"github.com/canonical/pebble/internals/overlord/state"
 	"github.com/canonical/pebble/internals/ptyutil"
 	"github.com/canonical/pebble/internals/reaper"
+	"github.com/canonical/pebble/internals/servicelog"
 	"github.com/canonical/pebble/internals/wsutil"
 )
 
@@ -61,6 +63,28 @@ type execution struct {
 	userID      *int
 	groupID     *int
 	workingDir  string
+	detached    bool
+	resources   ExecResources
+
+	// Process ID and start time of the running command (zero if the
+	// command isn't running).
+	pidMutex  sync.Mutex
+	pid       int
+	startTime time.Time
+
+	// Only used for detached executions. The timer removes the execution
+	// a while after the command finishes (it's guarded by the manager's
+	// executionsMutex).
+	output      *servicelog.RingBuffer
+	removeTimer *time.Timer
+
+	// Number of clients attached to each websocket of a detached execution.
+	attached map[string]int
+
+	// Path to record the terminal session to, if recording is enabled, and
+	// the recorder writing it (nil if not recording).
+	recordingPath string
+	recorder      *recorder
 
 	websockets       map[string]*websocket.Conn
 	websocketsLock   sync.Mutex
@@ -91,16 +115,29 @@ func (m *CommandManager) doExec(task *state.Task, tomb *tomb.Tomb) error {
 		userID:           setup.UserID,
 		groupID:          setup.GroupID,
 		workingDir:       setup.WorkingDir,
+		detached:         setup.Detached,
+		resources:        setup.Resources,
 		websockets:       make(map[string]*websocket.Conn),
 		ioConnected:      make(chan struct{}),
 		controlConnected: make(chan struct{}),
 	}
 
-	// Populate the websockets map (with nil connections until connected).
-	e.websockets[wsControl] = nil
-	e.websockets[wsStdio] = nil
-	if e.splitStderr {
-		e.websockets[wsStderr] = nil
+	if e.terminal && m.recordingEnabled() {
+		e.recordingPath = m.recordingPath(task.ID())
+	}
+
+	if e.detached {
+		// Detached executions send output to a buffer, and clients can
+		// attach to it (as many times as they like) via the websockets.
+		e.output = servicelog.NewRingBuffer(detachedOutputBytes)
+		e.attached = make(map[string]int)
+	} else {
+		// Populate the websockets map (with nil connections until connected).
+		e.websockets[wsControl] = nil
+		e.websockets[wsStdio] = nil
+		if e.splitStderr {
+			e.websockets[wsStderr] = nil
+		}
 	}
 
 	// Store the execution object on the manager (for Connect).
@@ -109,13 +146,18 @@ func (m *CommandManager) doExec(task *state.Task, tomb *tomb.Tomb) error {
 	m.executionsMutex.Unlock()
 	m.executionsCond.Broadcast() // signal that Connects can start happening
 	defer func() {
-		m.executionsMutex.Lock()
-		delete(m.executions, task.ID())
-		m.executionsMutex.Unlock()
+		if e.detached {
+			m.keepExecution(task.ID(), e)
+			return
+		}
+		m.removeExecution(task.ID())
 	}()
 
 	// Run the command! Killing the tomb will terminate the command.
 	ctx := tomb.Context(context.Background())
+	if e.detached {
+		return e.doDetached(ctx, task)
+	}
 	return e.do(ctx, task)
 }
 
@@ -155,6 +197,30 @@ func (e *execution) connect(r *http.Request, w http.ResponseWriter, id string) e
 	return nil
 }
 
+// streams returns the sorted names of the websockets that clients are
+// connected to.
+func (e *execution) streams() []string {
+	e.websocketsLock.Lock()
+	defer e.websocketsLock.Unlock()
+
+	var names []string
+	if e.detached {
+		for name, n := range e.attached {
+			if n > 0 {
+				names = append(names, name)
+			}
+		}
+	} else {
+		for name, conn := range e.websockets {
+			if conn != nil {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (e *execution) getWebsocket(key string) *websocket.Conn {
 	e.websocketsLock.Lock()
 	defer e.websocketsLock.Unlock()
@@ -187,6 +253,12 @@ func (e *execution) do(ctx context.Context, task *state.Task) error {
 		return err
 	}
 
+	cgroup, err := e.resources.newCgroup(task.ID())
+	if err != nil {
+		return err
+	}
+	defer cgroup.remove()
+
 	// Files/pipes to close before and after waiting for output to be finished sending.
 	var beforeClosers []io.Closer
 	var afterClosers []io.Closer
@@ -232,10 +304,24 @@ func (e *execution) do(ctx context.Context, task *state.Task) error {
 			}
 		}
 
+		// Record the session (before the control loop starts, so resizes
+		// are recorded too).
+		if e.recordingPath != "" {
+			e.recorder, err = newRecorder(e.recordingPath, e)
+			if err != nil {
+				return err
+			}
+			afterClosers = append(afterClosers, e.recorder)
+		}
+
 		go e.controlLoop(task.ID(), pidCh, stopControl, int(master.Fd()))
 
 		// Start goroutine to mirror PTY output to "stdio" websocket.
 		ioConn := e.getWebsocket(wsStdio)
+		var ioWriter wsutil.MessageWriter = ioConn
+		if e.recorder != nil {
+			ioWriter = recordingWriter{ioConn, e.recorder}
+		}
 		wgOutputSent.Add(1)
 		go func() {
 			defer wgOutputSent.Done()
@@ -243,14 +329,18 @@ func (e *execution) do(ctx context.Context, task *state.Task) error {
 			logger.Debugf("Exec %s: started mirroring websocket", task.ID())
 			defer logger.Debugf("Exec %s: finished mirroring websocket", task.ID())
 
-			wsutil.MirrorToWebsocket(ioConn, master, childDead, int(master.Fd()))
+			wsutil.MirrorToWebsocket(ioWriter, master, childDead, int(master.Fd()))
 		}()
 
 		if e.interactive {
 			// Interactive: start goroutine to receive stdin from "stdio"
 			// websocket and write to the PTY.
+			var ptyWriter io.Writer = master
+			if e.recorder != nil {
+				ptyWriter = inputRecorder{master, e.recorder}
+			}
 			go func() {
-				<-wsutil.WebsocketRecvStream(master, ioConn)
+				<-wsutil.WebsocketRecvStream(ptyWriter, ioConn)
 				master.Close()
 			}()
 		} else {
@@ -327,6 +417,49 @@ func (e *execution) do(ctx context.Context, task *state.Task) error {
 		defer cancel()
 	}
 
+	cmd := e.newCmd(ctx)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// Start the command!
+	err = e.resources.startCommand(cmd, cgroup)
+	exitCode := -1
+	if err == nil {
+		// Send its PID to the control loop.
+		e.setPID(cmd.Process.Pid)
+		pidCh <- cmd.Process.Pid
+
+		// Wait for it to finish.
+		exitCode, err = reaper.WaitCommand(cmd)
+		e.setPID(0)
+	}
+
+	// Close open files and channels.
+	for _, closer := range beforeClosers {
+		_ = closer.Close()
+	}
+
+	// Close the control channel, if connected.
+	controlConn := e.getWebsocket(wsControl)
+	if controlConn != nil {
+		_ = controlConn.Close()
+	}
+
+	close(childDead)
+
+	wgOutputSent.Wait()
+
+	for _, closer := range afterClosers {
+		_ = closer.Close()
+	}
+
+	return e.finish(ctx, task, exitCode, err)
+}
+
+// newCmd creates the exec.Cmd for the execution, without its standard
+// input and output set up.
+func (e *execution) newCmd(ctx context.Context) *exec.Cmd {
 	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
 
 	// Ensure cmd.Env is not nil (does not inherit parent env). This is not
@@ -340,10 +473,6 @@ func (e *execution) do(ctx context.Context, task *state.Task) error {
 
 	cmd.Dir = e.workingDir
 
-	cmd.Stdin = stdin
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
-
 	cmd.SysProcAttr = &syscall.SysProcAttr{}
 	if e.userID != nil && e.groupID != nil {
 		isCurrent, err := osutil.IsCurrent(*e.userID, *e.groupID)
@@ -372,36 +501,12 @@ func (e *execution) do(ctx context.Context, task *state.Task) error {
 		cmd.SysProcAttr.Setctty = true
 	}
 
-	// Start the command!
-	err = reaper.StartCommand(cmd)
-	exitCode := -1
-	if err == nil {
-		// Send its PID to the control loop.
-		pidCh <- cmd.Process.Pid
-
-		// Wait for it to finish.
-		exitCode, err = reaper.WaitCommand(cmd)
-	}
-
-	// Close open files and channels.
-	for _, closer := range beforeClosers {
-		_ = closer.Close()
-	}
-
-	// Close the control channel, if connected.
-	controlConn := e.getWebsocket(wsControl)
-	if controlConn != nil {
-		_ = controlConn.Close()
-	}
-
-	close(childDead)
-
-	wgOutputSent.Wait()
-
-	for _, closer := range afterClosers {
-		_ = closer.Close()
-	}
+	return cmd
+}
 
+// finish records the exit code of the finished command on the task, and
+// returns the task's error result.
+func (e *execution) finish(ctx context.Context, task *state.Task, exitCode int, err error) error {
 	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
 		setExitCode(task, -1)
 		return fmt.Errorf("timed out after %v: %w", e.timeout, ctx.Err())
@@ -493,40 +598,44 @@ func (e *execution) controlLoop(execID string, pidCh <-chan int, stop <-chan str
 			logger.Noticef("Exec %s: cannot decode control websocket command: %v", execID, err)
 			continue
 		}
+		e.handleControlCommand(execID, pid, ptyFd, &command)
+	}
+}
 
-		switch {
-		case command.Command == "resize" && e.terminal:
-			if command.Resize == nil {
-				logger.Noticef(`Exec %s: control command "resize" requires terminal width and height`, execID)
-				continue
-			}
-			w, h := command.Resize.Width, command.Resize.Height
-			err = ptyutil.SetSize(ptyFd, w, h)
-			if err != nil {
-				logger.Noticef(`Exec %s: control command "resize" cannot set terminal size to %dx%d: %v`, execID, w, h, err)
-				continue
-			}
-			logger.Debugf(`Exec %s: PID %d terminal resized to %dx%d`, execID, pid, w, h)
-		case command.Command == "signal":
-			if command.Signal == nil {
-				logger.Noticef(`Exec %s: control command "signal" requires signal name`, execID)
-				continue
-			}
-			name := command.Signal.Name
-			sig := unix.SignalNum(name)
-			if sig == 0 {
-				logger.Noticef("Exec %s: invalid signal name %q", execID, name)
-				continue
-			}
-			logger.Debugf(`Exec %s: received control command "signal" with name %q`, execID, name)
-			err := unix.Kill(pid, sig)
-			if err != nil {
-				logger.Noticef(`Exec %s: control command "signal" cannot forward %s to PID %d: %v`, execID, name, pid, err)
-				continue
-			}
-			logger.Noticef("Exec %s: forwarded signal %s to PID %d", execID, name, pid)
-		default:
-			logger.Noticef("Exec %s: invalid control websocket command %q", execID, command.Command)
+func (e *execution) handleControlCommand(execID string, pid int, ptyFd int, command *execCommand) {
+	switch {
+	case command.Command == "resize" && e.terminal:
+		if command.Resize == nil {
+			logger.Noticef(`Exec %s: control command "resize" requires terminal width and height`, execID)
+			return
+		}
+		w, h := command.Resize.Width, command.Resize.Height
+		err := ptyutil.SetSize(ptyFd, w, h)
+		if err != nil {
+			logger.Noticef(`Exec %s: control command "resize" cannot set terminal size to %dx%d: %v`, execID, w, h, err)
+			return
+		}
+		logger.Debugf(`Exec %s: PID %d terminal resized to %dx%d`, execID, pid, w, h)
+		e.recorder.resize(w, h)
+	case command.Command == "signal":
+		if command.Signal == nil {
+			logger.Noticef(`Exec %s: control command "signal" requires signal name`, execID)
+			return
+		}
+		name := command.Signal.Name
+		sig := unix.SignalNum(name)
+		if sig == 0 {
+			logger.Noticef("Exec %s: invalid signal name %q", execID, name)
+			return
+		}
+		logger.Debugf(`Exec %s: received control command "signal" with name %q`, execID, name)
+		err := unix.Kill(pid, sig)
+		if err != nil {
+			logger.Noticef(`Exec %s: control command "signal" cannot forward %s to PID %d: %v`, execID, name, pid, err)
+			return
 		}
+		logger.Noticef("Exec %s: forwarded signal %s to PID %d", execID, name, pid)
+	default:
+		logger.Noticef("Exec %s: invalid control websocket command %q", execID, command.Command)
 	}
 }
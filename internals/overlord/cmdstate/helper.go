@@ -0,0 +1,113 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmdstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Go can't run code in a new process between fork and exec, so resource
+// limits can't be set on a command before it's executed. Instead, Pebble
+// executes itself with ExecHelperName as argv[0], and its main function
+// runs RunExecHelper (the "exec helper"), which sets the limits and then
+// executes the actual command in the same process. Setting them afterwards
+// from Pebble would leave a window where the command could start other
+// processes without the limits.
+const ExecHelperName = "pebble-exec-helper"
+
+// execHelperArgs holds what the exec helper needs to execute the command.
+type execHelperArgs struct {
+	Path    string            `json:"path"`
+	Args    []string          `json:"args"`
+	Rlimits map[string]uint64 `json:"rlimits"`
+
+	// The user and group to switch to. These are set by the helper rather
+	// than when it's started, so that it can raise limits beyond Pebble's
+	// own before dropping privileges.
+	UserID  *uint32 `json:"user-id,omitempty"`
+	GroupID *uint32 `json:"group-id,omitempty"`
+}
+
+// wrapExecHelper changes the command to run via the exec helper, which sets
+// the given resource limits before executing it.
+func wrapExecHelper(cmd *exec.Cmd, rlimits map[string]uint64) error {
+	args := execHelperArgs{
+		Path:    cmd.Path,
+		Args:    cmd.Args,
+		Rlimits: rlimits,
+	}
+	if cred := cmd.SysProcAttr.Credential; cred != nil {
+		args.UserID = &cred.Uid
+		args.GroupID = &cred.Gid
+		cmd.SysProcAttr.Credential = nil
+	}
+	data, err := json.Marshal(&args)
+	if err != nil {
+		return fmt.Errorf("cannot encode exec helper arguments: %w", err)
+	}
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = []string{ExecHelperName, string(data)}
+	return nil
+}
+
+// RunExecHelper runs the exec helper, given the arguments Pebble started it
+// with (not including argv[0]). It only returns if the command couldn't be
+// executed.
+func RunExecHelper(helperArgs []string) error {
+	if len(helperArgs) != 1 {
+		return fmt.Errorf("exec helper needs exactly one argument")
+	}
+	var args execHelperArgs
+	err := json.Unmarshal([]byte(helperArgs[0]), &args)
+	if err != nil {
+		return fmt.Errorf("cannot decode exec helper arguments: %w", err)
+	}
+
+	for name, value := range args.Rlimits {
+		resource, ok := rlimitResources[name]
+		if !ok {
+			return fmt.Errorf("invalid resource limit %q", name)
+		}
+		// Use syscall.Setrlimit so the Go runtime doesn't restore its
+		// original "nofile" limit on exec.
+		limit := syscall.Rlimit{Cur: value, Max: value}
+		err := syscall.Setrlimit(resource, &limit)
+		if err != nil {
+			return fmt.Errorf("cannot set resource limit %q: %w", name, err)
+		}
+	}
+
+	if args.UserID != nil && args.GroupID != nil {
+		// Same as os/exec does for SysProcAttr.Credential.
+		err := syscall.Setgroups(nil)
+		if err != nil {
+			return fmt.Errorf("cannot set supplementary groups: %w", err)
+		}
+		err = syscall.Setgid(int(*args.GroupID))
+		if err != nil {
+			return fmt.Errorf("cannot set group ID: %w", err)
+		}
+		err = syscall.Setuid(int(*args.UserID))
+		if err != nil {
+			return fmt.Errorf("cannot set user ID: %w", err)
+		}
+	}
+
+	return syscall.Exec(args.Path, args.Args, os.Environ())
+}
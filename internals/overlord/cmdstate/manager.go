@@ -15,9 +15,18 @@
 package cmdstate
 
 import (
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/pebble/internals/logger"
 	"github.com/canonical/pebble/internals/overlord/state"
 )
 
@@ -25,13 +34,19 @@ type CommandManager struct {
 	executions      map[string]*execution
 	executionsCond  *sync.Cond
 	executionsMutex sync.Mutex
+	stopped         bool
+
+	recordingsDir string
+	recording     bool
 }
 
-// NewManager creates a new CommandManager.
-func NewManager(runner *state.TaskRunner) *CommandManager {
+// NewManager creates a new CommandManager. Terminal session recordings (if
+// enabled) are stored in the "recordings" subdirectory of pebbleDir.
+func NewManager(runner *state.TaskRunner, pebbleDir string) *CommandManager {
 	manager := &CommandManager{
 		executions:     make(map[string]*execution),
 		executionsCond: sync.NewCond(&sync.Mutex{}),
+		recordingsDir:  filepath.Join(pebbleDir, "recordings"),
 	}
 	runner.AddHandler("exec", manager.doExec, nil)
 	return manager
@@ -44,6 +59,16 @@ func (m *CommandManager) Ensure() error {
 
 // Connect upgrades the HTTP connection and connects to the given websocket.
 func (m *CommandManager) Connect(r *http.Request, w http.ResponseWriter, task *state.Task, websocketID string) error {
+	// Don't wait for the execution object if the task has already finished
+	// (and its execution has been removed), as it'll never appear.
+	st := task.State()
+	st.Lock()
+	ready := task.Status().Ready()
+	st.Unlock()
+	if ready && m.execution(task.ID()) == nil {
+		return fmt.Errorf("command in task %s has finished", task.ID())
+	}
+
 	stopWait := make(chan struct{})
 	defer func() {
 		// So waitExecution wakes up if it's stuck in Wait().
@@ -62,6 +87,9 @@ func (m *CommandManager) Connect(r *http.Request, w http.ResponseWriter, task *s
 	// Wait till the execution object is ready or the request is cancelled.
 	select {
 	case e := <-executionCh:
+		if e.detached {
+			return e.attach(r, w, task.ID(), websocketID)
+		}
 		return e.connect(r, w, websocketID)
 	case <-r.Context().Done():
 		return r.Context().Err()
@@ -79,12 +107,121 @@ func (m *CommandManager) waitExecution(taskID string, stop <-chan struct{}) *exe
 		default:
 		}
 
-		m.executionsMutex.Lock()
-		e := m.executions[taskID]
-		m.executionsMutex.Unlock()
+		e := m.execution(taskID)
 		if e != nil {
 			return e
 		}
 		m.executionsCond.Wait()
 	}
 }
+
+func (m *CommandManager) execution(taskID string) *execution {
+	m.executionsMutex.Lock()
+	defer m.executionsMutex.Unlock()
+	return m.executions[taskID]
+}
+
+func (m *CommandManager) removeExecution(taskID string) {
+	m.executionsMutex.Lock()
+	defer m.executionsMutex.Unlock()
+	delete(m.executions, taskID)
+}
+
+// keepExecution removes the finished detached execution after
+// detachedKeepTime, so that clients can still attach to read its output
+// until then. It's removed straight away if the manager has been stopped.
+func (m *CommandManager) keepExecution(taskID string, e *execution) {
+	m.executionsMutex.Lock()
+	defer m.executionsMutex.Unlock()
+	if m.stopped {
+		delete(m.executions, taskID)
+		return
+	}
+	e.removeTimer = time.AfterFunc(detachedKeepTime, func() { m.removeExecution(taskID) })
+}
+
+// Stop implements overlord.StateStopper. It stops the timers that remove
+// finished detached executions.
+func (m *CommandManager) Stop() {
+	m.executionsMutex.Lock()
+	defer m.executionsMutex.Unlock()
+	m.stopped = true
+	for _, e := range m.executions {
+		if e.removeTimer != nil {
+			e.removeTimer.Stop()
+		}
+	}
+}
+
+// ExecutionInfo holds information about a running command.
+type ExecutionInfo struct {
+	TaskID    string
+	Command   []string
+	UserID    *int
+	StartTime time.Time
+	PID       int
+	Detached  bool
+	Terminal  bool
+
+	// Streams holds the names of the websockets clients are connected to
+	// (for example "control" and "stdio"), in sorted order.
+	Streams []string
+}
+
+// Executions returns information about the commands that are currently
+// running, ordered by task ID. Commands that are still waiting for clients
+// to connect, and detached commands that have finished, aren't included.
+func (m *CommandManager) Executions() []*ExecutionInfo {
+	m.executionsMutex.Lock()
+	defer m.executionsMutex.Unlock()
+
+	var infos []*ExecutionInfo
+	for taskID, e := range m.executions {
+		e.pidMutex.Lock()
+		pid, startTime := e.pid, e.startTime
+		e.pidMutex.Unlock()
+		if pid == 0 {
+			continue
+		}
+		infos = append(infos, &ExecutionInfo{
+			TaskID:    taskID,
+			Command:   e.command,
+			UserID:    e.userID,
+			StartTime: startTime,
+			PID:       pid,
+			Detached:  e.detached,
+			Terminal:  e.terminal,
+			Streams:   e.streams(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		a, _ := strconv.Atoi(infos[i].TaskID)
+		b, _ := strconv.Atoi(infos[j].TaskID)
+		return a < b
+	})
+	return infos
+}
+
+// SignalExecution sends the named signal (for example "SIGTERM") to the
+// running command in the given task. It returns an error wrapping
+// os.ErrNotExist if there's no such running command.
+func (m *CommandManager) SignalExecution(taskID, signal string) error {
+	sig := unix.SignalNum(signal)
+	if sig == 0 {
+		return fmt.Errorf("invalid signal name %q", signal)
+	}
+	e := m.execution(taskID)
+	pid := 0
+	if e != nil {
+		pid = e.getPID()
+	}
+	if pid == 0 {
+		return fmt.Errorf("cannot find running command in task %q: %w", taskID, os.ErrNotExist)
+	}
+	err := unix.Kill(pid, sig)
+	if err != nil {
+		return fmt.Errorf("cannot send %s to PID %d: %w", signal, pid, err)
+	}
+	logger.Noticef("Exec %s: sent signal %s to PID %d", taskID, signal, pid)
+	return nil
+}
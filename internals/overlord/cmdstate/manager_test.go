@@ -0,0 +1,41 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmdstate
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type managerSuite struct{}
+
+var _ = Suite(&managerSuite{})
+
+func (s *managerSuite) TestStopDetachedTimers(c *C) {
+	m := &CommandManager{executions: make(map[string]*execution)}
+	e := &execution{}
+	m.executions["1"] = e
+	m.keepExecution("1", e)
+	c.Assert(e.removeTimer, NotNil)
+
+	m.Stop()
+	c.Check(e.removeTimer.Stop(), Equals, false) // already stopped
+
+	// Executions that finish after Stop are removed straight away.
+	e2 := &execution{}
+	m.executions["2"] = e2
+	m.keepExecution("2", e2)
+	c.Check(e2.removeTimer, IsNil)
+	c.Check(m.executions, DeepEquals, map[string]*execution{"1": e})
+}
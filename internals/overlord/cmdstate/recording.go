@@ -0,0 +1,284 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmdstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/wsutil"
+)
+
+// Terminal sessions are recorded in asciicast v2 format, which is described
+// at https://docs.asciinema.org/manual/asciicast/v2/. The file is a header
+// line followed by one line per event, each a JSON value.
+
+const recordingExt = ".cast"
+
+type recordingHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event types.
+const (
+	recordOutput = "o"
+	recordInput  = "i"
+	recordResize = "r"
+)
+
+// recorder writes a terminal session recording. Its methods are safe to
+// call on a nil *recorder (they do nothing), so callers don't need to check
+// whether the session is being recorded.
+type recorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	start   time.Time
+	partial map[string][]byte // incomplete UTF-8 sequence at end of each stream
+	stopped bool              // closed, or stopped after a write error
+}
+
+// newRecorder creates the recording file and writes its header.
+func newRecorder(path string, e *execution) (*recorder, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create recordings directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create recording: %w", err)
+	}
+	r := &recorder{
+		file:    file,
+		start:   time.Now(),
+		partial: make(map[string][]byte),
+	}
+
+	header := recordingHeader{
+		Version:   2,
+		Width:     e.width,
+		Height:    e.height,
+		Timestamp: r.start.Unix(),
+		Command:   strings.Join(e.command, " "),
+	}
+	if header.Width <= 0 || header.Height <= 0 {
+		header.Width, header.Height = 80, 24
+	}
+	if term, ok := e.environment["TERM"]; ok {
+		header.Env = map[string]string{"TERM": term}
+	}
+	err = json.NewEncoder(file).Encode(header)
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("cannot write recording: %w", err)
+	}
+	return r, nil
+}
+
+// output records data written to the terminal.
+func (r *recorder) output(data []byte) {
+	r.stream(recordOutput, data)
+}
+
+// input records data typed into the terminal.
+func (r *recorder) input(data []byte) {
+	r.stream(recordInput, data)
+}
+
+// resize records a change in the terminal's size.
+func (r *recorder) resize(width, height int) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.write(recordResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+func (r *recorder) stream(kind string, data []byte) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Event data must be valid UTF-8, so hold back any incomplete UTF-8
+	// sequence at the end of the data till the rest of it arrives.
+	data = append(r.partial[kind], data...)
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	r.partial[kind] = append([]byte(nil), data[end:]...)
+	if end > 0 {
+		r.write(kind, string(data[:end]))
+	}
+}
+
+func (r *recorder) write(kind, data string) {
+	if r.stopped {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	event := []interface{}{json.Number(strconv.FormatFloat(elapsed, 'f', 6, 64)), kind, data}
+	err := json.NewEncoder(r.file).Encode(event)
+	if err != nil {
+		// Don't interrupt the session if the recording can't be written.
+		logger.Noticef("Cannot write to recording %q: %v", r.file.Name(), err)
+		r.stopped = true
+	}
+}
+
+// Close flushes any remaining data and closes the recording file.
+func (r *recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, kind := range []string{recordOutput, recordInput} {
+		if len(r.partial[kind]) > 0 {
+			r.write(kind, string(r.partial[kind]))
+		}
+	}
+	r.stopped = true
+	return r.file.Close()
+}
+
+// recordingWriter records the binary messages sent to a websocket as
+// terminal output.
+type recordingWriter struct {
+	wsutil.MessageWriter
+	recorder *recorder
+}
+
+func (w recordingWriter) WriteMessage(messageType int, data []byte) error {
+	if messageType == websocket.BinaryMessage {
+		w.recorder.output(data)
+	}
+	return w.MessageWriter.WriteMessage(messageType, data)
+}
+
+// inputRecorder records the data written to it as terminal input.
+type inputRecorder struct {
+	io.Writer
+	recorder *recorder
+}
+
+func (w inputRecorder) Write(p []byte) (int, error) {
+	w.recorder.input(p)
+	return w.Writer.Write(p)
+}
+
+// recordingPath returns the path of the recording for the given task.
+func (m *CommandManager) recordingPath(taskID string) string {
+	return filepath.Join(m.recordingsDir, taskID+recordingExt)
+}
+
+// SetRecording enables or disables recording of terminal sessions started
+// after this call.
+func (m *CommandManager) SetRecording(enabled bool) {
+	m.executionsMutex.Lock()
+	defer m.executionsMutex.Unlock()
+	m.recording = enabled
+}
+
+func (m *CommandManager) recordingEnabled() bool {
+	m.executionsMutex.Lock()
+	defer m.executionsMutex.Unlock()
+	return m.recording
+}
+
+// RecordingPath returns the path of the session recording for the given
+// task. It returns an error wrapping os.ErrNotExist if there's no recording
+// for that task.
+func (m *CommandManager) RecordingPath(taskID string) (string, error) {
+	if _, err := strconv.ParseUint(taskID, 10, 64); err != nil {
+		return "", fmt.Errorf("cannot find recording for task %q: %w", taskID, os.ErrNotExist)
+	}
+	path := m.recordingPath(taskID)
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("cannot find recording for task %q: %w", taskID, os.ErrNotExist)
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// PruneRecordings removes session recordings older than maxAge, and then
+// the oldest recordings till there are at most maxCount left. Recordings of
+// sessions that are still running are never removed.
+func (m *CommandManager) PruneRecordings(maxAge time.Duration, maxCount int) error {
+	infos, err := ioutil.ReadDir(m.recordingsDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read recordings directory: %w", err)
+	}
+
+	var recordings []os.FileInfo
+	for _, info := range infos {
+		name := info.Name()
+		if !info.Mode().IsRegular() || !strings.HasSuffix(name, recordingExt) {
+			continue
+		}
+		if m.execution(strings.TrimSuffix(name, recordingExt)) != nil {
+			continue // still running
+		}
+		recordings = append(recordings, info)
+	}
+	// Sort from newest to oldest.
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].ModTime().After(recordings[j].ModTime())
+	})
+
+	ageLimit := time.Now().Add(-maxAge)
+	for i, info := range recordings {
+		if i < maxCount && info.ModTime().After(ageLimit) {
+			continue
+		}
+		err := os.Remove(filepath.Join(m.recordingsDir, info.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove recording: %w", err)
+		}
+	}
+	return nil
+}
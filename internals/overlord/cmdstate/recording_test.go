@@ -0,0 +1,181 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmdstate
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type recordingSuite struct {
+	dir string
+}
+
+var _ = Suite(&recordingSuite{})
+
+func (s *recordingSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+// readRecording returns the recording's header and its events (without the
+// times).
+func (s *recordingSuite) readRecording(c *C, path string) (map[string]interface{}, [][]interface{}) {
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	c.Assert(scanner.Scan(), Equals, true)
+	var header map[string]interface{}
+	err = json.Unmarshal(scanner.Bytes(), &header)
+	c.Assert(err, IsNil)
+
+	var events [][]interface{}
+	for scanner.Scan() {
+		var event []interface{}
+		err = json.Unmarshal(scanner.Bytes(), &event)
+		c.Assert(err, IsNil)
+		c.Assert(event, HasLen, 3)
+		c.Check(event[0], FitsTypeOf, float64(0))
+		events = append(events, event[1:])
+	}
+	c.Assert(scanner.Err(), IsNil)
+	return header, events
+}
+
+func (s *recordingSuite) TestRecorder(c *C) {
+	path := filepath.Join(s.dir, "recordings", "1.cast")
+	e := &execution{
+		command:     []string{"bash", "-l"},
+		environment: map[string]string{"TERM": "xterm", "FOO": "bar"},
+		width:       100,
+		height:      30,
+	}
+	r, err := newRecorder(path, e)
+	c.Assert(err, IsNil)
+	r.output([]byte("$ "))
+	r.input([]byte("ls\r"))
+	r.resize(120, 40)
+	r.output([]byte("caf\xc3")) // "é" split over two writes
+	r.output([]byte("\xa9\r\n"))
+	r.output([]byte("\xe2\x82")) // incomplete at the end
+	err = r.Close()
+	c.Assert(err, IsNil)
+
+	info, err := os.Stat(path)
+	c.Assert(err, IsNil)
+	c.Check(info.Mode().Perm(), Equals, os.FileMode(0600))
+
+	header, events := s.readRecording(c, path)
+	c.Check(header["version"], Equals, float64(2))
+	c.Check(header["width"], Equals, float64(100))
+	c.Check(header["height"], Equals, float64(30))
+	c.Check(header["command"], Equals, "bash -l")
+	c.Check(header["env"], DeepEquals, map[string]interface{}{"TERM": "xterm"})
+	c.Check(events, DeepEquals, [][]interface{}{
+		{"o", "$ "},
+		{"i", "ls\r"},
+		{"r", "120x40"},
+		{"o", "caf"},
+		{"o", "é\r\n"},
+		{"o", "\ufffd\ufffd"}, // each invalid byte is replaced
+	})
+
+	// Writes after closing are ignored.
+	r.output([]byte("more"))
+}
+
+func (s *recordingSuite) TestRecorderDefaultSize(c *C) {
+	path := filepath.Join(s.dir, "1.cast")
+	r, err := newRecorder(path, &execution{command: []string{"sh"}})
+	c.Assert(err, IsNil)
+	err = r.Close()
+	c.Assert(err, IsNil)
+
+	header, events := s.readRecording(c, path)
+	c.Check(header["width"], Equals, float64(80))
+	c.Check(header["height"], Equals, float64(24))
+	c.Check(events, HasLen, 0)
+}
+
+func (s *recordingSuite) TestNilRecorder(c *C) {
+	var r *recorder
+	r.output([]byte("foo"))
+	r.input([]byte("bar"))
+	r.resize(80, 24)
+	c.Check(r.Close(), IsNil)
+}
+
+func (s *recordingSuite) TestRecordingPath(c *C) {
+	m := &CommandManager{recordingsDir: s.dir}
+	_, err := m.RecordingPath("1")
+	c.Check(err, ErrorMatches, `cannot find recording for task "1": file does not exist`)
+	_, err = m.RecordingPath("../x")
+	c.Check(err, ErrorMatches, `cannot find recording for task "../x": file does not exist`)
+
+	err = ioutil.WriteFile(filepath.Join(s.dir, "1.cast"), nil, 0600)
+	c.Assert(err, IsNil)
+	path, err := m.RecordingPath("1")
+	c.Assert(err, IsNil)
+	c.Check(path, Equals, filepath.Join(s.dir, "1.cast"))
+}
+
+func (s *recordingSuite) TestPruneRecordings(c *C) {
+	m := &CommandManager{
+		recordingsDir: s.dir,
+		executions:    map[string]*execution{"1": {}},
+	}
+
+	// No recordings directory yet.
+	m.recordingsDir = filepath.Join(s.dir, "nonexistent")
+	err := m.PruneRecordings(time.Hour, 2)
+	c.Assert(err, IsNil)
+	m.recordingsDir = s.dir
+
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"1.cast":    48 * time.Hour, // old, but still running
+		"2.cast":    48 * time.Hour,
+		"3.cast":    3 * time.Minute,
+		"4.cast":    2 * time.Minute,
+		"5.cast":    time.Minute,
+		"other.txt": 48 * time.Hour,
+	} {
+		path := filepath.Join(s.dir, name)
+		err := ioutil.WriteFile(path, nil, 0600)
+		c.Assert(err, IsNil)
+		err = os.Chtimes(path, now.Add(-age), now.Add(-age))
+		c.Assert(err, IsNil)
+	}
+
+	err = m.PruneRecordings(time.Hour, 2)
+	c.Assert(err, IsNil)
+
+	infos, err := ioutil.ReadDir(s.dir)
+	c.Assert(err, IsNil)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	c.Check(names, DeepEquals, []string{"1.cast", "4.cast", "5.cast", "other.txt"})
+}
@@ -40,6 +40,14 @@ type ExecArgs struct {
 	SplitStderr bool
 	Width       int
 	Height      int
+
+	// Detached runs the command in the background, with output going to a
+	// buffer that clients can attach to later, rather than tying the
+	// command's lifetime to the client's websocket connections.
+	Detached bool
+
+	// Resource controls (priority, limits) to apply to the command.
+	Resources ExecResources
 }
 
 // ExecMetadata is the metadata returned from an Exec call.
@@ -62,6 +70,8 @@ type execSetup struct {
 	UserID      *int
 	GroupID     *int
 	WorkingDir  string
+	Detached    bool
+	Resources   ExecResources
 }
 
 // Exec creates a task that will execute the command with the given arguments.
@@ -69,6 +79,12 @@ func Exec(st *state.State, args *ExecArgs) (*state.Task, ExecMetadata, error) {
 	if args.Interactive && !args.Terminal {
 		return nil, ExecMetadata{}, errors.New("cannot use interactive mode without a terminal")
 	}
+	if args.Detached && args.Terminal {
+		return nil, ExecMetadata{}, errors.New("cannot use detached mode with a terminal")
+	}
+	if err := args.Resources.Validate(); err != nil {
+		return nil, ExecMetadata{}, err
+	}
 
 	// Inherit the pebble daemon environment.
 	environment := osutil.Environ()
@@ -133,6 +149,8 @@ func Exec(st *state.State, args *ExecArgs) (*state.Task, ExecMetadata, error) {
 		UserID:      args.UserID,
 		GroupID:     args.GroupID,
 		WorkingDir:  workingDir,
+		Detached:    args.Detached,
+		Resources:   args.Resources,
 	}
 	task.Set("exec-setup", &setup)
 
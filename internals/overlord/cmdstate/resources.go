@@ -0,0 +1,386 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmdstate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/reaper"
+)
+
+// ExecResources holds the resource controls applied to an executed command.
+type ExecResources struct {
+	// Scheduling priority ("niceness") from -20 (highest) to 19 (lowest).
+	// Zero leaves the priority unchanged.
+	Nice int
+
+	// I/O scheduling class: "realtime", "best-effort", or "idle". Empty
+	// leaves the class unchanged.
+	IOClass string
+
+	// Priority within the I/O scheduling class, from 0 (highest) to 7
+	// (lowest). Only valid for the "realtime" and "best-effort" classes.
+	IOPriority *int
+
+	// Resource limits keyed by name (for example "nofile"), which set both
+	// the soft and hard limit. Use unix.RLIM_INFINITY for no limit.
+	Rlimits map[string]uint64
+
+	// Memory limit in bytes, applied using a cgroup. Zero means no limit.
+	MemoryLimit int64
+
+	// CPU limit in number of CPUs (for example 0.5 for half a CPU),
+	// applied using a cgroup. Zero means no limit.
+	CPULimit float64
+}
+
+const (
+	IOClassRealtime   = "realtime"
+	IOClassBestEffort = "best-effort"
+	IOClassIdle       = "idle"
+)
+
+var ioClasses = map[string]int{
+	IOClassRealtime:   1,
+	IOClassBestEffort: 2,
+	IOClassIdle:       3,
+}
+
+var rlimitResources = map[string]int{
+	"as":      unix.RLIMIT_AS,
+	"core":    unix.RLIMIT_CORE,
+	"cpu":     unix.RLIMIT_CPU,
+	"data":    unix.RLIMIT_DATA,
+	"fsize":   unix.RLIMIT_FSIZE,
+	"memlock": unix.RLIMIT_MEMLOCK,
+	"nofile":  unix.RLIMIT_NOFILE,
+	"nproc":   unix.RLIMIT_NPROC,
+	"stack":   unix.RLIMIT_STACK,
+}
+
+// Validate checks that the resource controls are valid.
+func (r *ExecResources) Validate() error {
+	if r.Nice < -20 || r.Nice > 19 {
+		return fmt.Errorf("nice value must be between -20 and 19, not %d", r.Nice)
+	}
+	if r.IOClass != "" {
+		if _, ok := ioClasses[r.IOClass]; !ok {
+			return fmt.Errorf(`I/O class must be "realtime", "best-effort", or "idle", not %q`, r.IOClass)
+		}
+	}
+	if r.IOPriority != nil {
+		if r.IOClass != IOClassRealtime && r.IOClass != IOClassBestEffort {
+			return fmt.Errorf(`I/O priority requires I/O class "realtime" or "best-effort"`)
+		}
+		if *r.IOPriority < 0 || *r.IOPriority > 7 {
+			return fmt.Errorf("I/O priority must be between 0 and 7, not %d", *r.IOPriority)
+		}
+	}
+	for name := range r.Rlimits {
+		if _, ok := rlimitResources[name]; !ok {
+			names := make([]string, 0, len(rlimitResources))
+			for name := range rlimitResources {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("invalid resource limit %q (must be one of: %s)", name, strings.Join(names, ", "))
+		}
+	}
+	if r.MemoryLimit < 0 {
+		return fmt.Errorf("memory limit must not be negative")
+	}
+	if r.CPULimit < 0 {
+		return fmt.Errorf("CPU limit must not be negative")
+	}
+	return nil
+}
+
+func (r *ExecResources) needsCgroup() bool {
+	return r.MemoryLimit > 0 || r.CPULimit > 0
+}
+
+// startCommand starts the command with its resource controls applied.
+//
+// Nice values and I/O priorities are per-thread on Linux, and a new process
+// inherits them from the thread that forks it. So they're set on a dedicated
+// OS thread that starts the command, which means there's no window where the
+// command is running (and perhaps starting other processes) with the wrong
+// priority. The goroutine exits while still locked to the thread, so the
+// thread is discarded afterwards.
+//
+// Similarly, the command is created directly inside its cgroup (if any), and
+// resource limits are set by a helper before the command is executed (see
+// execHelper), so that nothing the command starts can escape the controls.
+func (r *ExecResources) startCommand(cmd *exec.Cmd, cgroup *execCgroup) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if cgroup != nil {
+		// Requires clone3 with CLONE_INTO_CGROUP (Linux 5.7+).
+		f, err := os.Open(cgroup.dir)
+		if err != nil {
+			return fmt.Errorf("cannot open cgroup: %w", err)
+		}
+		defer f.Close()
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	}
+	if len(r.Rlimits) > 0 {
+		err := wrapExecHelper(cmd, r.Rlimits)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Nice == 0 && r.IOClass == "" {
+		return reaper.StartCommand(cmd)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		errCh <- r.startOnThread(cmd, unix.Gettid())
+	}()
+	return <-errCh
+}
+
+func (r *ExecResources) startOnThread(cmd *exec.Cmd, tid int) error {
+	if r.Nice != 0 {
+		err := unix.Setpriority(unix.PRIO_PROCESS, tid, r.Nice)
+		if err != nil {
+			return fmt.Errorf("cannot set nice value: %w", err)
+		}
+	}
+	if r.IOClass != "" {
+		level := 0
+		if r.IOPriority != nil {
+			level = *r.IOPriority
+		} else if r.IOClass != IOClassIdle {
+			level = 4 // kernel default for the class
+		}
+		err := ioprioSet(tid, ioClasses[r.IOClass], level)
+		if err != nil {
+			return fmt.Errorf("cannot set I/O priority: %w", err)
+		}
+	}
+	return reaper.StartCommand(cmd)
+}
+
+func ioprioSet(tid, class, level int) error {
+	const (
+		ioprioWhoProcess = 1
+		ioprioClassShift = 13
+	)
+	prio := class<<ioprioClassShift | level
+	_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+var (
+	cgroupRoot     = "/sys/fs/cgroup"
+	procSelfCgroup = "/proc/self/cgroup"
+)
+
+// cpuPeriod is the cgroup CPU period (in microseconds) used for CPU limits.
+const cpuPeriod = 100000
+
+// execCgroup is a cgroup (v2) created to limit the resources of a command.
+type execCgroup struct {
+	dir string
+}
+
+// daemonCgroupName is the name of the leaf cgroup that the processes in
+// Pebble's own cgroup (Pebble itself and its services) are moved into, so
+// that controllers can be enabled for exec cgroups.
+const daemonCgroupName = "pebble-daemon"
+
+// newCgroup creates a cgroup for the execution with the given ID, as a child
+// of Pebble's own cgroup, and sets its limits. It returns nil if no limits
+// need a cgroup.
+func (r *ExecResources) newCgroup(execID string) (*execCgroup, error) {
+	if !r.needsCgroup() {
+		return nil, nil
+	}
+	parent, err := execCgroupParent()
+	if err != nil {
+		return nil, err
+	}
+
+	// Enable the controllers needed for the child cgroup. This fails if
+	// Pebble's own cgroup doesn't have these controllers available.
+	var controllers []string
+	if r.MemoryLimit > 0 {
+		controllers = append(controllers, "+memory")
+	}
+	if r.CPULimit > 0 {
+		controllers = append(controllers, "+cpu")
+	}
+	err = ioutil.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte(strings.Join(controllers, " ")), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot enable cgroup controllers: %w", err)
+	}
+
+	dir := filepath.Join(parent, "pebble-exec-"+execID)
+	err = os.Mkdir(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create cgroup: %w", err)
+	}
+	cgroup := &execCgroup{dir: dir}
+
+	if r.MemoryLimit > 0 {
+		err = cgroup.write("memory.max", strconv.FormatInt(r.MemoryLimit, 10))
+		if err != nil {
+			cgroup.remove()
+			return nil, err
+		}
+	}
+	if r.CPULimit > 0 {
+		quota := int64(r.CPULimit * cpuPeriod)
+		if quota < 1000 {
+			quota = 1000 // minimum allowed by the kernel
+		}
+		err = cgroup.write("cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod))
+		if err != nil {
+			cgroup.remove()
+			return nil, err
+		}
+	}
+	return cgroup, nil
+}
+
+// execCgroupParent returns the directory of the cgroup that exec cgroups are
+// created in: Pebble's own cgroup, or its parent if PrepareCgroup moved
+// Pebble into a leaf cgroup.
+func execCgroupParent() (string, error) {
+	dir, err := ownCgroupDir()
+	if err != nil {
+		return "", err
+	}
+	if filepath.Base(dir) == daemonCgroupName {
+		dir = filepath.Dir(dir)
+	}
+	return dir, nil
+}
+
+// PrepareCgroup gets Pebble's cgroup ready for exec commands with memory or
+// CPU limits, whose cgroups are created inside it. It's meant to be called
+// once, when the daemon starts, and returns the leaf cgroup that processes
+// were moved into, if any.
+//
+// With cgroup v2, a (non-root) cgroup can't both contain processes and have
+// controllers enabled for its children. So if Pebble's cgroup contains any
+// processes, they're moved into a "pebble-daemon" leaf cgroup, which is where
+// Pebble (and the services it starts) then run. Nothing is done if cgroup v2
+// isn't available, or if Pebble is in the root cgroup or was already moved.
+func PrepareCgroup() (string, error) {
+	dir, err := ownCgroupDir()
+	if err == errNoCgroupV2 {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if filepath.Base(dir) == daemonCgroupName || dir == filepath.Clean(cgroupRoot) {
+		// Already moved (perhaps by a previous run of Pebble), or in the
+		// root cgroup, which is exempt from the rule.
+		return "", nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return "", fmt.Errorf("cannot read cgroup processes: %w", err)
+	}
+	pids := strings.Fields(string(data))
+	if len(pids) == 0 {
+		return "", nil
+	}
+	leaf := filepath.Join(dir, daemonCgroupName)
+	err = os.Mkdir(leaf, 0755)
+	if err != nil && !os.IsExist(err) {
+		return "", fmt.Errorf("cannot create cgroup: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(leaf, "cgroup.procs"), os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return "", fmt.Errorf("cannot move processes to cgroup: %w", err)
+	}
+	defer f.Close()
+	for _, pid := range pids {
+		// The kernel only accepts one PID per write.
+		_, err := f.Write([]byte(pid + "\n"))
+		if err != nil && !errors.Is(err, unix.ESRCH) { // process may have exited
+			return "", fmt.Errorf("cannot move process %s to cgroup: %w", pid, err)
+		}
+	}
+	return leaf, nil
+}
+
+var errNoCgroupV2 = errors.New("cannot determine cgroup: cgroup v2 not available")
+
+// ownCgroupDir returns the directory of Pebble's own (cgroup v2) cgroup.
+func ownCgroupDir() (string, error) {
+	f, err := os.Open(procSelfCgroup)
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cgroup: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The cgroup v2 (unified hierarchy) entry is "0::/path".
+		line := scanner.Text()
+		if strings.HasPrefix(line, "0::") {
+			return filepath.Join(cgroupRoot, line[len("0::"):]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("cannot determine cgroup: %w", err)
+	}
+	return "", errNoCgroupV2
+}
+
+func (g *execCgroup) write(name, value string) error {
+	err := ioutil.WriteFile(filepath.Join(g.dir, name), []byte(value), 0644)
+	if err != nil {
+		return fmt.Errorf("cannot set cgroup %s: %w", name, err)
+	}
+	return nil
+}
+
+// remove removes the cgroup. It's safe to call on a nil *execCgroup.
+func (g *execCgroup) remove() {
+	if g == nil {
+		return
+	}
+	err := os.Remove(g.dir)
+	if err != nil {
+		logger.Noticef("Cannot remove cgroup %q: %v", g.dir, err)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmdstate
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/reaper"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+func TestMain(m *testing.M) {
+	// Tests that set resource limits start this test binary as the exec
+	// helper, so it needs to act like the pebble binary does.
+	if len(os.Args) > 0 && os.Args[0] == ExecHelperName {
+		err := RunExecHelper(os.Args[1:])
+		fmt.Fprintf(os.Stderr, "cannot execute command: %v\n", err)
+		os.Exit(127)
+	}
+	os.Exit(m.Run())
+}
+
+type resourcesSuite struct {
+	root string
+
+	oldCgroupRoot     string
+	oldProcSelfCgroup string
+}
+
+var _ = Suite(&resourcesSuite{})
+
+func (s *resourcesSuite) SetUpTest(c *C) {
+	s.root = c.MkDir()
+	err := os.MkdirAll(filepath.Join(s.root, "sys", "pebble.service"), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(s.root, "sys", "pebble.service", "cgroup.procs"), nil, 0644)
+	c.Assert(err, IsNil)
+	selfCgroup := filepath.Join(s.root, "cgroup")
+	err = ioutil.WriteFile(selfCgroup, []byte("0::/pebble.service\n"), 0644)
+	c.Assert(err, IsNil)
+
+	s.oldCgroupRoot, s.oldProcSelfCgroup = cgroupRoot, procSelfCgroup
+	cgroupRoot = filepath.Join(s.root, "sys")
+	procSelfCgroup = selfCgroup
+}
+
+func (s *resourcesSuite) TearDownTest(c *C) {
+	cgroupRoot, procSelfCgroup = s.oldCgroupRoot, s.oldProcSelfCgroup
+}
+
+func (s *resourcesSuite) readFile(c *C, path ...string) string {
+	b, err := ioutil.ReadFile(filepath.Join(append([]string{s.root, "sys", "pebble.service"}, path...)...))
+	c.Assert(err, IsNil)
+	return string(b)
+}
+
+func (s *resourcesSuite) TestNoCgroup(c *C) {
+	r := &ExecResources{Nice: 5}
+	cgroup, err := r.newCgroup("1")
+	c.Assert(err, IsNil)
+	c.Check(cgroup, IsNil)
+	cgroup.remove() // no-op on a nil cgroup
+}
+
+func (s *resourcesSuite) TestCgroupLimits(c *C) {
+	r := &ExecResources{MemoryLimit: 512 * 1024 * 1024, CPULimit: 0.5}
+	cgroup, err := r.newCgroup("42")
+	c.Assert(err, IsNil)
+	c.Check(cgroup.dir, Equals, filepath.Join(s.root, "sys", "pebble.service", "pebble-exec-42"))
+	c.Check(s.readFile(c, "cgroup.subtree_control"), Equals, "+memory +cpu")
+	c.Check(s.readFile(c, "pebble-exec-42", "memory.max"), Equals, "536870912")
+	c.Check(s.readFile(c, "pebble-exec-42", "cpu.max"), Equals, "50000 100000")
+
+}
+
+func (s *resourcesSuite) TestCgroupMinimumCPU(c *C) {
+	r := &ExecResources{CPULimit: 0.001}
+	cgroup, err := r.newCgroup("42")
+	c.Assert(err, IsNil)
+	c.Check(s.readFile(c, "cgroup.subtree_control"), Equals, "+cpu")
+	c.Check(s.readFile(c, "pebble-exec-42", "cpu.max"), Equals, "1000 100000")
+	_, err = os.Stat(filepath.Join(cgroup.dir, "memory.max"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *resourcesSuite) TestCgroupV1(c *C) {
+	err := ioutil.WriteFile(procSelfCgroup, []byte("12:memory:/pebble.service\n1:name=systemd:/pebble.service\n"), 0644)
+	c.Assert(err, IsNil)
+	r := &ExecResources{MemoryLimit: 1024}
+	_, err = r.newCgroup("42")
+	c.Check(err, ErrorMatches, "cannot determine cgroup: cgroup v2 not available")
+}
+
+func (s *resourcesSuite) TestCgroupControllersUnavailable(c *C) {
+	// Make writing cgroup.subtree_control fail.
+	err := os.Mkdir(filepath.Join(s.root, "sys", "pebble.service", "cgroup.subtree_control"), 0755)
+	c.Assert(err, IsNil)
+	r := &ExecResources{MemoryLimit: 1024}
+	_, err = r.newCgroup("42")
+	c.Check(err, ErrorMatches, "cannot enable cgroup controllers: .*")
+}
+
+func (s *resourcesSuite) TestCgroupAlreadyInLeaf(c *C) {
+	err := ioutil.WriteFile(procSelfCgroup, []byte("0::/pebble.service/pebble-daemon\n"), 0644)
+	c.Assert(err, IsNil)
+
+	r := &ExecResources{MemoryLimit: 1024}
+	cgroup, err := r.newCgroup("42")
+	c.Assert(err, IsNil)
+	c.Check(cgroup.dir, Equals, filepath.Join(s.root, "sys", "pebble.service", "pebble-exec-42"))
+	c.Check(s.readFile(c, "cgroup.subtree_control"), Equals, "+memory")
+}
+
+func (s *resourcesSuite) TestCgroupRoot(c *C) {
+	err := ioutil.WriteFile(procSelfCgroup, []byte("0::/\n"), 0644)
+	c.Assert(err, IsNil)
+
+	r := &ExecResources{MemoryLimit: 1024}
+	cgroup, err := r.newCgroup("42")
+	c.Assert(err, IsNil)
+	c.Check(cgroup.dir, Equals, filepath.Join(s.root, "sys", "pebble-exec-42"))
+}
+
+func (s *resourcesSuite) TestPrepareCgroup(c *C) {
+	// Pebble's own cgroup has processes in it, so they're moved into a leaf
+	// cgroup so that controllers can be enabled for exec cgroups.
+	err := ioutil.WriteFile(filepath.Join(s.root, "sys", "pebble.service", "cgroup.procs"), []byte("1\n42\n"), 0644)
+	c.Assert(err, IsNil)
+
+	leaf, err := PrepareCgroup()
+	c.Assert(err, IsNil)
+	c.Check(leaf, Equals, filepath.Join(s.root, "sys", "pebble.service", "pebble-daemon"))
+	c.Check(s.readFile(c, "pebble-daemon", "cgroup.procs"), Equals, "1\n42\n")
+}
+
+func (s *resourcesSuite) TestPrepareCgroupNotNeeded(c *C) {
+	// No processes in Pebble's cgroup.
+	leaf, err := PrepareCgroup()
+	c.Assert(err, IsNil)
+	c.Check(leaf, Equals, "")
+
+	// Already in the leaf cgroup.
+	err = ioutil.WriteFile(procSelfCgroup, []byte("0::/pebble.service/pebble-daemon\n"), 0644)
+	c.Assert(err, IsNil)
+	leaf, err = PrepareCgroup()
+	c.Assert(err, IsNil)
+	c.Check(leaf, Equals, "")
+
+	// The root cgroup's processes are never moved.
+	err = ioutil.WriteFile(procSelfCgroup, []byte("0::/\n"), 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(s.root, "sys", "cgroup.procs"), []byte("1\n"), 0644)
+	c.Assert(err, IsNil)
+	leaf, err = PrepareCgroup()
+	c.Assert(err, IsNil)
+	c.Check(leaf, Equals, "")
+	_, err = os.Stat(filepath.Join(s.root, "sys", "pebble-daemon"))
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	// No cgroup v2.
+	err = ioutil.WriteFile(procSelfCgroup, []byte("12:memory:/pebble.service\n"), 0644)
+	c.Assert(err, IsNil)
+	leaf, err = PrepareCgroup()
+	c.Assert(err, IsNil)
+	c.Check(leaf, Equals, "")
+}
+
+func (s *resourcesSuite) TestStartCommandRlimits(c *C) {
+	err := reaper.Start()
+	c.Assert(err, IsNil)
+	defer reaper.Stop()
+
+	// The limits are set before the command is executed, so they apply to
+	// the command itself rather than having to be read back from Pebble.
+	var stdout bytes.Buffer
+	cmd := exec.Command("cat", "/proc/self/limits")
+	cmd.Stdout = &stdout
+	r := &ExecResources{Rlimits: map[string]uint64{
+		"nofile": 512,
+		"core":   unix.RLIM_INFINITY,
+	}}
+	err = r.startCommand(cmd, nil)
+	c.Assert(err, IsNil)
+	exitCode, err := reaper.WaitCommand(cmd)
+	c.Assert(err, IsNil)
+	c.Assert(exitCode, Equals, 0)
+	c.Check(stdout.String(), Matches, `(?s).*Max open files +512 +512 +files.*`)
+	c.Check(stdout.String(), Matches, `(?s).*Max core file size +unlimited +unlimited +bytes.*`)
+
+	// The command gets its own arguments and environment.
+	stdout.Reset()
+	cmd = exec.Command("sh", "-c", `echo "$0 $1 $FOO"`, "zero", "one")
+	cmd.Env = []string{"FOO=bar"}
+	cmd.Stdout = &stdout
+	err = r.startCommand(cmd, nil)
+	c.Assert(err, IsNil)
+	_, err = reaper.WaitCommand(cmd)
+	c.Assert(err, IsNil)
+	c.Check(stdout.String(), Equals, "zero one bar\n")
+}
+
+func (s *resourcesSuite) TestStartCommandInvalidCgroup(c *C) {
+	cmd := exec.Command("true")
+	cgroup := &execCgroup{dir: filepath.Join(s.root, "nonexistent")}
+	r := &ExecResources{MemoryLimit: 1024}
+	err := r.startCommand(cmd, cgroup)
+	c.Check(err, ErrorMatches, "cannot open cgroup: .*")
+}
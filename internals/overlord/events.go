@@ -0,0 +1,67 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package overlord
+
+import (
+	"github.com/canonical/pebble/internals/overlord/checkstate"
+	"github.com/canonical/pebble/internals/overlord/eventstate"
+	"github.com/canonical/pebble/internals/overlord/state"
+	"github.com/canonical/pebble/internals/plan"
+)
+
+// publishEvents makes the event manager publish the transitions of services,
+// checks, changes, tasks, and warnings, and plan updates.
+func (o *Overlord) publishEvents() {
+	publish := o.eventMgr.Publish
+
+	o.serviceMgr.NotifyServiceStateChanged(func(name, old, new string) {
+		publish(eventstate.Event{Type: eventstate.TypeService, Name: name, Old: old, New: new})
+	})
+	o.serviceMgr.NotifyPlanChanged(func(p *plan.Plan) {
+		publish(eventstate.Event{Type: eventstate.TypePlan})
+	})
+	o.checkMgr.NotifyCheckStatusChanged(func(name string, status checkstate.CheckStatus) {
+		old := checkstate.CheckStatusUp
+		if status == checkstate.CheckStatusUp {
+			old = checkstate.CheckStatusDown
+		}
+		publish(eventstate.Event{Type: eventstate.TypeCheck, Name: name, Old: string(old), New: string(status)})
+	})
+
+	st := o.State()
+	st.Lock()
+	defer st.Unlock()
+	st.AddTaskStatusChangedHandler(func(t *state.Task, old, new state.Status) {
+		publish(eventstate.Event{
+			Type:    eventstate.TypeTask,
+			Name:    t.ID(),
+			Old:     old.String(),
+			New:     new.String(),
+			Message: t.Summary(),
+		})
+	})
+	st.AddChangeStatusChangedHandler(func(chg *state.Change, old, new state.Status) {
+		publish(eventstate.Event{
+			Type:    eventstate.TypeChange,
+			Name:    chg.ID(),
+			Old:     old.String(),
+			New:     new.String(),
+			Message: chg.Summary(),
+		})
+	})
+	st.AddWarningHandler(func(message string) {
+		publish(eventstate.Event{Type: eventstate.TypeWarning, Message: message})
+	})
+}
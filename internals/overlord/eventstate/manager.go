@@ -0,0 +1,205 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package eventstate publishes events about state transitions in Pebble
+// (services, checks, changes, tasks, warnings, and the plan) to subscribers.
+package eventstate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/canonical/pebble/internals/logger"
+)
+
+// Type is the type of an event.
+type Type string
+
+const (
+	// TypeService events are published when a service changes state. The
+	// event's name is the service name.
+	TypeService Type = "service"
+
+	// TypeCheck events are published when a check goes up or down. The
+	// event's name is the check name.
+	TypeCheck Type = "check"
+
+	// TypeChange events are published when a change's status changes. The
+	// event's name is the change ID, and its message the change summary.
+	TypeChange Type = "change"
+
+	// TypeTask events are published when a task's status changes. The
+	// event's name is the task ID, and its message the task summary.
+	TypeTask Type = "task"
+
+	// TypeWarning events are published when a warning is added (or added
+	// again). The event's message is the warning message.
+	TypeWarning Type = "warning"
+
+	// TypePlan events are published when the plan is updated.
+	TypePlan Type = "plan"
+)
+
+// Event is a single state transition.
+type Event struct {
+	Time    time.Time
+	Type    Type
+	Name    string
+	Old     string
+	New     string
+	Message string
+}
+
+// Filter selects the events a subscriber receives. Empty fields match all
+// events.
+type Filter struct {
+	Types []Type
+	Names []string
+}
+
+func (f *Filter) match(event *Event) bool {
+	if len(f.Types) > 0 && !containsType(f.Types, event.Type) {
+		return false
+	}
+	if len(f.Names) > 0 && !containsName(f.Names, event.Name) {
+		return false
+	}
+	return true
+}
+
+func containsType(types []Type, t Type) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// maxQueued is the maximum number of events queued for a subscriber that
+// isn't keeping up. Beyond that, the oldest events are dropped.
+const maxQueued = 1000
+
+// EventManager publishes events to subscribers.
+type EventManager struct {
+	mutex       sync.Mutex
+	subscribers map[*Subscriber]bool
+}
+
+// NewManager creates a new event manager.
+func NewManager() *EventManager {
+	return &EventManager{
+		subscribers: make(map[*Subscriber]bool),
+	}
+}
+
+// Publish queues the event for the subscribers whose filter matches it. It
+// doesn't block, so it's safe to call while holding other locks. If the
+// event's time is zero, it's set to the current time.
+func (m *EventManager) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for s := range m.subscribers {
+		if s.filter.match(&event) {
+			s.queue(event)
+		}
+	}
+}
+
+// Subscribe returns a subscriber that receives the events published from
+// now on that match filter. It must be closed when no longer needed.
+func (m *EventManager) Subscribe(filter Filter) *Subscriber {
+	s := &Subscriber{
+		manager: m,
+		filter:  filter,
+		notify:  make(chan struct{}, 1),
+	}
+	m.mutex.Lock()
+	m.subscribers[s] = true
+	m.mutex.Unlock()
+	return s
+}
+
+// Subscriber receives published events in order.
+type Subscriber struct {
+	manager *EventManager
+	filter  Filter
+	notify  chan struct{}
+
+	mutex   sync.Mutex
+	events  []Event
+	dropped int
+}
+
+func (s *Subscriber) queue(event Event) {
+	s.mutex.Lock()
+	if len(s.events) >= maxQueued {
+		s.events = s.events[1:]
+		s.dropped++
+	}
+	s.events = append(s.events, event)
+	s.mutex.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Next waits for the next event and returns it. It returns false if the
+// context is done first.
+func (s *Subscriber) Next(ctx context.Context) (Event, bool) {
+	for {
+		s.mutex.Lock()
+		if s.dropped > 0 {
+			logger.Noticef("Event subscriber not keeping up, dropped %d events", s.dropped)
+			s.dropped = 0
+		}
+		if len(s.events) > 0 {
+			event := s.events[0]
+			s.events = s.events[1:]
+			s.mutex.Unlock()
+			return event, true
+		}
+		s.mutex.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-ctx.Done():
+			return Event{}, false
+		}
+	}
+}
+
+// Close stops the subscriber receiving events.
+func (s *Subscriber) Close() {
+	s.manager.mutex.Lock()
+	delete(s.manager.subscribers, s)
+	s.manager.mutex.Unlock()
+}
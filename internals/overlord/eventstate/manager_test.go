@@ -0,0 +1,116 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eventstate_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/overlord/eventstate"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type managerSuite struct{}
+
+var _ = Suite(&managerSuite{})
+
+func (s *managerSuite) TestPublishSubscribe(c *C) {
+	mgr := eventstate.NewManager()
+
+	// Events published before subscribing aren't received.
+	mgr.Publish(eventstate.Event{Type: eventstate.TypePlan})
+
+	all := mgr.Subscribe(eventstate.Filter{})
+	defer all.Close()
+	services := mgr.Subscribe(eventstate.Filter{
+		Types: []eventstate.Type{eventstate.TypeService},
+		Names: []string{"svc1"},
+	})
+	defer services.Close()
+
+	now := time.Now()
+	mgr.Publish(eventstate.Event{Type: eventstate.TypeService, Name: "svc1", Old: "starting", New: "running"})
+	mgr.Publish(eventstate.Event{Type: eventstate.TypeService, Name: "svc2", Old: "starting", New: "running"})
+	mgr.Publish(eventstate.Event{Type: eventstate.TypeCheck, Name: "svc1", Old: "up", New: "down"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var names []string
+	for i := 0; i < 3; i++ {
+		event, ok := all.Next(ctx)
+		c.Assert(ok, Equals, true)
+		c.Check(event.Time.Before(now), Equals, false)
+		names = append(names, string(event.Type)+" "+event.Name)
+	}
+	c.Check(names, DeepEquals, []string{"service svc1", "service svc2", "check svc1"})
+
+	event, ok := services.Next(ctx)
+	c.Assert(ok, Equals, true)
+	event.Time = time.Time{}
+	c.Check(event, DeepEquals, eventstate.Event{
+		Type: eventstate.TypeService,
+		Name: "svc1",
+		Old:  "starting",
+		New:  "running",
+	})
+
+	// Next waits for the next event, until the context is done.
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shortCancel()
+	_, ok = services.Next(shortCtx)
+	c.Check(ok, Equals, false)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		mgr.Publish(eventstate.Event{Type: eventstate.TypeService, Name: "svc1", Old: "running", New: "backoff"})
+	}()
+	event, ok = services.Next(ctx)
+	c.Assert(ok, Equals, true)
+	c.Check(event.New, Equals, "backoff")
+}
+
+func (s *managerSuite) TestSlowSubscriber(c *C) {
+	mgr := eventstate.NewManager()
+	sub := mgr.Subscribe(eventstate.Filter{})
+	defer sub.Close()
+
+	// The oldest events are dropped when a subscriber doesn't keep up.
+	for i := 0; i < 1010; i++ {
+		mgr.Publish(eventstate.Event{Type: eventstate.TypeTask, Name: string(rune('a' + i%26))})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	event, ok := sub.Next(ctx)
+	c.Assert(ok, Equals, true)
+	c.Check(event.Name, Equals, string(rune('a'+10%26)))
+}
+
+func (s *managerSuite) TestClose(c *C) {
+	mgr := eventstate.NewManager()
+	sub := mgr.Subscribe(eventstate.Filter{})
+	sub.Close()
+	mgr.Publish(eventstate.Event{Type: eventstate.TypePlan})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, ok := sub.Next(ctx)
+	c.Check(ok, Equals, false)
+}
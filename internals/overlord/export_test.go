@@ -16,6 +16,9 @@ package overlord
 
 import (
 	"time"
+
+	"github.com/canonical/pebble/internals/overlord/checkstate"
+	"github.com/canonical/pebble/internals/overlord/restart"
 )
 
 // FakeEnsureInterval sets the overlord ensure interval for tests.
@@ -49,3 +52,23 @@ func FakeEnsureNext(o *Overlord, t time.Time) {
 func (o *Overlord) Engine() *StateEngine {
 	return o.stateEng
 }
+
+// NewCheckActionHandler returns the check action handler the overlord uses,
+// with the given restart handler.
+func NewCheckActionHandler(o *Overlord, restartHandler restart.Handler) checkstate.ActionHandler {
+	return &checkActionHandler{
+		state:          o.State(),
+		serviceMgr:     o.serviceMgr,
+		restartHandler: restartHandler,
+	}
+}
+
+var ServiceHealth = serviceHealth
+
+// FakeJournalMaxEntries sets the number of state journal entries after
+// which the journal is compacted, for tests.
+func FakeJournalMaxEntries(n int) (restore func()) {
+	old := journalMaxEntries
+	journalMaxEntries = n
+	return func() { journalMaxEntries = old }
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package overlord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/osutil"
+)
+
+var (
+	// journalMaxEntries is the number of journal entries after which the
+	// journal is compacted into the state snapshot.
+	journalMaxEntries = 1000
+
+	// journalMinCompactSize is the journal size in bytes below which the
+	// journal isn't compacted, even if it's larger than the snapshot.
+	journalMinCompactSize int64 = 1024 * 1024
+)
+
+// journalNestedKeys are the top-level state keys whose values are objects
+// that are journalled entry by entry, rather than as a whole.
+var journalNestedKeys = map[string]bool{
+	"data":    true,
+	"changes": true,
+	"tasks":   true,
+}
+
+// journalEntry is one line of the state journal: the state entries that
+// were set or deleted by a single checkpoint.
+type journalEntry struct {
+	Set    map[string]json.RawMessage `json:"set,omitempty"`
+	Delete []string                   `json:"delete,omitempty"`
+}
+
+// journalStateBackend is a state backend that appends the differences
+// between checkpoints to a journal, and only periodically rewrites the
+// whole state snapshot. Each journal entry is synced to disk before
+// Checkpoint returns.
+type journalStateBackend struct {
+	path         string
+	journalPath  string
+	ensureBefore func(d time.Duration)
+
+	file         *os.File
+	last         map[string]json.RawMessage
+	entries      int
+	size         int64
+	snapshotSize int64
+}
+
+func (jsb *journalStateBackend) Checkpoint(data []byte) error {
+	current, err := flattenState(data)
+	if err != nil {
+		return err
+	}
+	if jsb.last == nil {
+		// Nothing journalled against yet, start from a fresh snapshot.
+		return jsb.compact(data, current)
+	}
+	entry := diffState(jsb.last, current)
+	if len(entry.Set) == 0 && len(entry.Delete) == 0 {
+		return nil
+	}
+	// Always journal the entry before compacting, so that if we crash
+	// between writing the snapshot and truncating the journal, replaying
+	// the journal over the snapshot still ends up with the same state.
+	if err := jsb.append(entry); err != nil {
+		return err
+	}
+	jsb.last = current
+	compactSize := jsb.snapshotSize
+	if compactSize < journalMinCompactSize {
+		compactSize = journalMinCompactSize
+	}
+	if jsb.entries >= journalMaxEntries || jsb.size >= compactSize {
+		return jsb.compact(data, current)
+	}
+	return nil
+}
+
+func (jsb *journalStateBackend) EnsureBefore(d time.Duration) {
+	jsb.ensureBefore(d)
+}
+
+func (jsb *journalStateBackend) append(entry *journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if jsb.file == nil {
+		f, err := os.OpenFile(jsb.journalPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		if err := syncDir(filepath.Dir(jsb.journalPath)); err != nil {
+			f.Close()
+			return err
+		}
+		jsb.file = f
+	}
+	_, err = jsb.file.Write(line)
+	if err == nil {
+		err = jsb.file.Sync()
+	}
+	if err != nil {
+		// Drop any partial write so later entries aren't appended after
+		// a torn one.
+		jsb.file.Truncate(jsb.size)
+		return err
+	}
+	jsb.entries++
+	jsb.size += int64(len(line))
+	return nil
+}
+
+// compact writes the whole state to the snapshot and empties the journal.
+func (jsb *journalStateBackend) compact(data []byte, current map[string]json.RawMessage) error {
+	if err := osutil.AtomicWriteFile(jsb.path, data, 0600, 0); err != nil {
+		return err
+	}
+	if jsb.file != nil {
+		if err := jsb.file.Truncate(0); err != nil {
+			return err
+		}
+		if err := jsb.file.Sync(); err != nil {
+			return err
+		}
+	}
+	jsb.last = current
+	jsb.entries = 0
+	jsb.size = 0
+	jsb.snapshotSize = int64(len(data))
+	return nil
+}
+
+// recoverJournal replays the state journal, if there is one, over the
+// state snapshot, writes the result as the new snapshot and removes the
+// journal. This leaves a state file that state.ReadState can read,
+// whichever backend is used afterwards.
+func recoverJournal(statePath, journalPath string) error {
+	journal, err := ioutil.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read state journal: %v", err)
+	}
+
+	flat := make(map[string]json.RawMessage)
+	snapshot, err := ioutil.ReadFile(statePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read the state file: %v", err)
+	}
+	if err == nil {
+		flat, err = flattenState(snapshot)
+		if err != nil {
+			return fmt.Errorf("cannot read state: %v", err)
+		}
+	}
+
+	lines := bytes.Split(journal, []byte("\n"))
+	applied := 0
+	for i, line := range lines {
+		if i == len(lines)-1 {
+			// Anything after the final newline is an entry that was
+			// never completely written.
+			if len(line) > 0 {
+				logger.Noticef("Ignoring incomplete entry at end of state journal.")
+			}
+			break
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("cannot read state journal entry %d: %v", i+1, err)
+		}
+		applyJournalEntry(flat, &entry)
+		applied++
+	}
+
+	if applied > 0 {
+		data, err := unflattenState(flat)
+		if err != nil {
+			return err
+		}
+		if err := osutil.AtomicWriteFile(statePath, data, 0600, 0); err != nil {
+			return err
+		}
+		logger.Noticef("Recovered %d entries from state journal.", applied)
+	}
+	return os.Remove(journalPath)
+}
+
+// flattenState splits marshalled state into its entries, with the entries
+// of nested objects keyed as "<key>/<id>".
+func flattenState(data []byte) (map[string]json.RawMessage, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, err
+	}
+	flat := make(map[string]json.RawMessage, len(top))
+	for key, value := range top {
+		if !journalNestedKeys[key] {
+			flat[key] = value
+			continue
+		}
+		var nested map[string]json.RawMessage
+		if err := json.Unmarshal(value, &nested); err != nil {
+			return nil, err
+		}
+		for id, nestedValue := range nested {
+			flat[key+"/"+id] = nestedValue
+		}
+	}
+	return flat, nil
+}
+
+// unflattenState is the inverse of flattenState.
+func unflattenState(flat map[string]json.RawMessage) ([]byte, error) {
+	top := make(map[string]interface{})
+	for key := range journalNestedKeys {
+		top[key] = make(map[string]json.RawMessage)
+	}
+	for key, value := range flat {
+		parts := strings.SplitN(key, "/", 2)
+		if len(parts) == 2 && journalNestedKeys[parts[0]] {
+			top[parts[0]].(map[string]json.RawMessage)[parts[1]] = value
+		} else {
+			top[key] = value
+		}
+	}
+	return json.Marshal(top)
+}
+
+func diffState(old, new map[string]json.RawMessage) *journalEntry {
+	entry := &journalEntry{}
+	for key, value := range new {
+		if oldValue, ok := old[key]; ok && bytes.Equal(oldValue, value) {
+			continue
+		}
+		if entry.Set == nil {
+			entry.Set = make(map[string]json.RawMessage)
+		}
+		entry.Set[key] = value
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			entry.Delete = append(entry.Delete, key)
+		}
+	}
+	return entry
+}
+
+func applyJournalEntry(flat map[string]json.RawMessage, entry *journalEntry) {
+	for key, value := range entry.Set {
+		flat[key] = value
+	}
+	for _, key := range entry.Delete {
+		delete(flat, key)
+	}
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -27,14 +27,17 @@ import (
 	"github.com/canonical/x-go/randutil"
 	"gopkg.in/tomb.v2"
 
+	"github.com/canonical/pebble/internals/logger"
 	"github.com/canonical/pebble/internals/osutil"
 	"github.com/canonical/pebble/internals/overlord/checkstate"
 	"github.com/canonical/pebble/internals/overlord/cmdstate"
+	"github.com/canonical/pebble/internals/overlord/eventstate"
 	"github.com/canonical/pebble/internals/overlord/logstate"
 	"github.com/canonical/pebble/internals/overlord/patch"
 	"github.com/canonical/pebble/internals/overlord/restart"
 	"github.com/canonical/pebble/internals/overlord/servstate"
 	"github.com/canonical/pebble/internals/overlord/state"
+	"github.com/canonical/pebble/internals/overlord/webhookstate"
 	"github.com/canonical/pebble/internals/timing"
 )
 
@@ -46,6 +49,10 @@ var (
 
 	pruneMaxChanges = 500
 
+	// Retention limits for exec terminal session recordings.
+	pruneRecordingsWait = 24 * time.Hour * 30
+	pruneMaxRecordings  = 100
+
 	defaultCachedDownloads = 5
 )
 
@@ -63,6 +70,12 @@ type Overlord struct {
 	ensureRun   int32
 	pruneTicker *time.Ticker
 
+	// pruning
+	pruneInterval  time.Duration
+	pruneOpts      state.PruneOptions
+	recordingsWait time.Duration
+	maxRecordings  int
+
 	// managers
 	inited     bool
 	runner     *state.TaskRunner
@@ -70,15 +83,62 @@ type Overlord struct {
 	commandMgr *cmdstate.CommandManager
 	checkMgr   *checkstate.CheckManager
 	logMgr     *logstate.LogManager
+	eventMgr   *eventstate.EventManager
+	webhookMgr *webhookstate.WebhookManager
+}
+
+// State backends that can be selected with Options.StateBackend.
+const (
+	// StateBackendSnapshot rewrites the whole state file on every
+	// checkpoint. This is the default.
+	StateBackendSnapshot = "snapshot"
+
+	// StateBackendJournal appends the changes made by each checkpoint to
+	// a journal, and only periodically compacts them into the state file.
+	StateBackendJournal = "journal"
+)
+
+// Options holds the options for creating an Overlord with NewWithOptions.
+type Options struct {
+	// PebbleDir is the path to the pebble directory. It must be provided.
+	PebbleDir string
+
+	// RestartHandler is an optional restart.Handler.
+	RestartHandler restart.Handler
+
+	// ServiceOutput is an optional writer for the output of services.
+	ServiceOutput io.Writer
+
+	// StateBackend selects how state is persisted: StateBackendSnapshot
+	// (the default if empty) or StateBackendJournal.
+	StateBackend string
 }
 
 // New creates a new Overlord with all its state managers.
 // It can be provided with an optional restart.Handler.
 func New(pebbleDir string, restartHandler restart.Handler, serviceOutput io.Writer) (*Overlord, error) {
+	return NewWithOptions(Options{
+		PebbleDir:      pebbleDir,
+		RestartHandler: restartHandler,
+		ServiceOutput:  serviceOutput,
+	})
+}
+
+// NewWithOptions creates a new Overlord with all its state managers,
+// configured with the given options.
+func NewWithOptions(opts Options) (*Overlord, error) {
+	pebbleDir := opts.PebbleDir
+	restartHandler := opts.RestartHandler
+	serviceOutput := opts.ServiceOutput
+
 	o := &Overlord{
-		pebbleDir: pebbleDir,
-		loopTomb:  new(tomb.Tomb),
-		inited:    true,
+		pebbleDir:      pebbleDir,
+		loopTomb:       new(tomb.Tomb),
+		inited:         true,
+		pruneInterval:  pruneInterval,
+		pruneOpts:      defaultPruneOptions(),
+		recordingsWait: pruneRecordingsWait,
+		maxRecordings:  pruneMaxRecordings,
 	}
 
 	if !filepath.IsAbs(pebbleDir) {
@@ -88,10 +148,29 @@ func New(pebbleDir string, restartHandler restart.Handler, serviceOutput io.Writ
 		return nil, fmt.Errorf("directory %q does not exist", pebbleDir)
 	}
 	statePath := filepath.Join(pebbleDir, ".pebble.state")
+	journalPath := statePath + ".journal"
+
+	var backend state.Backend
+	switch opts.StateBackend {
+	case "", StateBackendSnapshot:
+		backend = &overlordStateBackend{
+			path:         statePath,
+			ensureBefore: o.ensureBefore,
+		}
+	case StateBackendJournal:
+		backend = &journalStateBackend{
+			path:         statePath,
+			journalPath:  journalPath,
+			ensureBefore: o.ensureBefore,
+		}
+	default:
+		return nil, fmt.Errorf("invalid state backend %q", opts.StateBackend)
+	}
 
-	backend := &overlordStateBackend{
-		path:         statePath,
-		ensureBefore: o.ensureBefore,
+	// Fold any journal left by the journal backend into the state file
+	// first, so that either backend can be used from here on.
+	if err := recoverJournal(statePath, journalPath); err != nil {
+		return nil, err
 	}
 	s, err := loadState(statePath, restartHandler, backend)
 	if err != nil {
@@ -116,7 +195,7 @@ func New(pebbleDir string, restartHandler restart.Handler, serviceOutput io.Writ
 	}
 	o.addManager(o.serviceMgr)
 
-	o.commandMgr = cmdstate.NewManager(o.runner)
+	o.commandMgr = cmdstate.NewManager(o.runner, o.pebbleDir)
 	o.addManager(o.commandMgr)
 
 	o.checkMgr = checkstate.NewManager()
@@ -130,6 +209,34 @@ func New(pebbleDir string, restartHandler restart.Handler, serviceOutput io.Writ
 	// Tell service manager about check failures.
 	o.checkMgr.NotifyCheckFailed(o.serviceMgr.CheckFailed)
 
+	// Let check actions add warnings, signal services, and shut down.
+	o.checkMgr.SetActionHandler(&checkActionHandler{
+		state:          s,
+		serviceMgr:     o.serviceMgr,
+		restartHandler: restartHandler,
+	})
+
+	// Let the checks for services with "health" set look up their status.
+	o.checkMgr.SetServiceStatusFunc(func(name string) error {
+		return serviceHealth(name, o.serviceMgr.ServiceStatus(name))
+	})
+
+	// Publish state transitions to event subscribers.
+	o.eventMgr = eventstate.NewManager()
+	o.publishEvents()
+
+	// Deliver webhooks for service exits, check transitions, and warnings.
+	o.webhookMgr = webhookstate.NewManager()
+	o.addManager(o.webhookMgr)
+	o.serviceMgr.NotifyPlanChanged(o.webhookMgr.PlanChanged)
+	o.serviceMgr.NotifyServiceExited(o.webhookMgr.ServiceExited)
+	o.checkMgr.NotifyCheckStatusChanged(func(name string, status checkstate.CheckStatus) {
+		o.webhookMgr.CheckStatusChanged(name, string(status))
+	})
+	s.Lock()
+	s.AddWarningHandler(o.webhookMgr.WarningAdded)
+	s.Unlock()
+
 	// the shared task runner should be added last!
 	o.stateEng.AddManager(o.runner)
 
@@ -215,7 +322,7 @@ func (o *Overlord) ensureTimerSetup() {
 	defer o.ensureLock.Unlock()
 	o.ensureTimer = time.NewTimer(ensureInterval)
 	o.ensureNext = time.Now().Add(ensureInterval)
-	o.pruneTicker = time.NewTicker(pruneInterval)
+	o.pruneTicker = time.NewTicker(o.pruneInterval)
 }
 
 func (o *Overlord) ensureTimerReset() time.Time {
@@ -268,15 +375,127 @@ func (o *Overlord) Loop() {
 				return nil
 			case <-o.ensureTimer.C:
 			case <-o.pruneTicker.C:
-				st := o.State()
-				st.Lock()
-				st.Prune(pruneWait, abortWait, pruneMaxChanges)
-				st.Unlock()
+				o.Prune()
 			}
 		}
 	})
 }
 
+// PruneOptions configures how often and how aggressively the overlord
+// prunes old changes from the state, and old exec recordings. Nil fields
+// leave the defaults as is, so that zero can be set explicitly.
+type PruneOptions struct {
+	// Interval is how often pruning happens. It must be positive.
+	Interval *time.Duration
+
+	// Wait is how long changes are kept after becoming ready.
+	Wait *time.Duration
+
+	// AbortWait is how long after being spawned changes that aren't
+	// ready are aborted.
+	AbortWait *time.Duration
+
+	// MaxChanges is the maximum number of ready changes kept.
+	MaxChanges *int
+
+	// KindWaits overrides Wait for changes of the given kinds, for
+	// example to keep "exec" changes for less time than others.
+	KindWaits map[string]time.Duration
+
+	// RecordingsWait is how long exec recordings are kept.
+	RecordingsWait *time.Duration
+
+	// MaxRecordings is the maximum number of exec recordings kept.
+	MaxRecordings *int
+}
+
+// Validate checks that the prune options are in range.
+func (opts *PruneOptions) Validate() error {
+	if opts.Interval != nil && *opts.Interval <= 0 {
+		return fmt.Errorf("prune interval must be positive")
+	}
+	for _, d := range []*time.Duration{opts.Wait, opts.AbortWait, opts.RecordingsWait} {
+		if d != nil && *d < 0 {
+			return fmt.Errorf("prune durations must not be negative")
+		}
+	}
+	for _, n := range []*int{opts.MaxChanges, opts.MaxRecordings} {
+		if n != nil && *n < 0 {
+			return fmt.Errorf("prune limits must not be negative")
+		}
+	}
+	for kind, d := range opts.KindWaits {
+		if d < 0 {
+			return fmt.Errorf("prune duration for %q changes must not be negative", kind)
+		}
+	}
+	return nil
+}
+
+func defaultPruneOptions() state.PruneOptions {
+	return state.PruneOptions{
+		Wait:            pruneWait,
+		AbortWait:       abortWait,
+		MaxReadyChanges: pruneMaxChanges,
+	}
+}
+
+// SetPruneOptions sets the pruning options, after checking them with
+// Validate. It must be called before Loop.
+func (o *Overlord) SetPruneOptions(opts PruneOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	o.ensureLock.Lock()
+	defer o.ensureLock.Unlock()
+	if opts.Interval != nil {
+		o.pruneInterval = *opts.Interval
+	}
+	if opts.Wait != nil {
+		o.pruneOpts.Wait = *opts.Wait
+	}
+	if opts.AbortWait != nil {
+		o.pruneOpts.AbortWait = *opts.AbortWait
+	}
+	if opts.MaxChanges != nil {
+		o.pruneOpts.MaxReadyChanges = *opts.MaxChanges
+	}
+	if opts.KindWaits != nil {
+		o.pruneOpts.KindWaits = opts.KindWaits
+	}
+	if opts.RecordingsWait != nil {
+		o.recordingsWait = *opts.RecordingsWait
+	}
+	if opts.MaxRecordings != nil {
+		o.maxRecordings = *opts.MaxRecordings
+	}
+	return nil
+}
+
+// Prune prunes old changes, warnings and notices from the state, and old
+// exec recordings. It returns the number of changes pruned.
+func (o *Overlord) Prune() int {
+	o.ensureLock.Lock()
+	opts := o.pruneOpts
+	recordingsWait, maxRecordings := o.recordingsWait, o.maxRecordings
+	o.ensureLock.Unlock()
+
+	st := o.State()
+	st.Lock()
+	before := len(st.Changes())
+	st.PruneWithOptions(&opts)
+	pruned := before - len(st.Changes())
+	st.Unlock()
+
+	if o.commandMgr != nil {
+		err := o.commandMgr.PruneRecordings(recordingsWait, maxRecordings)
+		if err != nil {
+			logger.Noticef("Cannot prune exec recordings: %v", err)
+		}
+	}
+	return pruned
+}
+
 func (o *Overlord) ensureDidRun() {
 	atomic.StoreInt32(&o.ensureRun, 1)
 }
@@ -415,6 +634,12 @@ func (o *Overlord) CheckManager() *checkstate.CheckManager {
 	return o.checkMgr
 }
 
+// EventManager returns the event manager that publishes state transitions
+// to subscribers.
+func (o *Overlord) EventManager() *eventstate.EventManager {
+	return o.eventMgr
+}
+
 // Fake creates an Overlord without any managers and with a backend
 // not using disk. Managers can be added with AddManager. For testing.
 func Fake() *Overlord {
@@ -426,8 +651,12 @@ func Fake() *Overlord {
 // testing.
 func FakeWithState(handleRestart func(restart.RestartType)) *Overlord {
 	o := &Overlord{
-		loopTomb: new(tomb.Tomb),
-		inited:   false,
+		loopTomb:       new(tomb.Tomb),
+		inited:         false,
+		pruneInterval:  pruneInterval,
+		pruneOpts:      defaultPruneOptions(),
+		recordingsWait: pruneRecordingsWait,
+		maxRecordings:  pruneMaxRecordings,
 	}
 	s := state.New(fakeBackend{o: o})
 	o.stateEng = NewStateEngine(s)
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -33,6 +34,7 @@ import (
 	"github.com/canonical/pebble/internals/overlord"
 	"github.com/canonical/pebble/internals/overlord/patch"
 	"github.com/canonical/pebble/internals/overlord/restart"
+	"github.com/canonical/pebble/internals/overlord/servstate"
 	"github.com/canonical/pebble/internals/overlord/state"
 	"github.com/canonical/pebble/internals/testutil"
 )
@@ -115,6 +117,137 @@ func (ovs *overlordSuite) TestNewWithInvalidState(c *C) {
 	c.Assert(err, ErrorMatches, "cannot read state: EOF")
 }
 
+func (ovs *overlordSuite) TestNewWithInvalidStateBackend(c *C) {
+	_, err := overlord.NewWithOptions(overlord.Options{
+		PebbleDir:    ovs.dir,
+		StateBackend: "foo",
+	})
+	c.Assert(err, ErrorMatches, `invalid state backend "foo"`)
+}
+
+func (ovs *overlordSuite) TestJournalStateBackend(c *C) {
+	journalPath := ovs.statePath + ".journal"
+
+	o, err := overlord.NewWithOptions(overlord.Options{
+		PebbleDir:    ovs.dir,
+		StateBackend: overlord.StateBackendJournal,
+	})
+	c.Assert(err, IsNil)
+
+	// The first checkpoint writes a snapshot, and the patches applied at
+	// startup are journalled after it.
+	c.Check(ovs.statePath, testutil.FilePresent)
+	journalLines := func() []string {
+		journal, err := ioutil.ReadFile(journalPath)
+		c.Assert(err, IsNil)
+		return strings.Split(strings.TrimSuffix(string(journal), "\n"), "\n")
+	}
+	c.Assert(journalLines(), HasLen, 1)
+
+	st := o.State()
+	st.Lock()
+	st.Set("foo", "bar")
+	chg := st.NewChange("kind", "summary")
+	st.Unlock()
+
+	lines := journalLines()
+	c.Assert(lines, HasLen, 2)
+	c.Check(lines[1], Matches, `{"set":{"changes/1":.*,"data/foo":"bar","last-change-id":1}}`)
+
+	// Only the entries that changed are journalled.
+	st.Lock()
+	chg.Set("baz", 42)
+	st.Unlock()
+	lines = journalLines()
+	c.Assert(lines, HasLen, 3)
+	c.Check(lines[2], Matches, `{"set":{"changes/1":{.*"baz":42.*}}}`)
+
+	// The snapshot is still the original one.
+	snapshot, err := ioutil.ReadFile(ovs.statePath)
+	c.Assert(err, IsNil)
+	c.Check(string(snapshot), Not(Matches), `.*"foo".*`)
+
+	// Starting again with the default backend recovers the journal into
+	// the snapshot.
+	o, err = overlord.New(ovs.dir, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(journalPath, testutil.FileAbsent)
+
+	st = o.State()
+	st.Lock()
+	defer st.Unlock()
+	var foo string
+	c.Assert(st.Get("foo", &foo), IsNil)
+	c.Check(foo, Equals, "bar")
+	chg = st.Change("1")
+	c.Assert(chg, NotNil)
+	var baz int
+	c.Assert(chg.Get("baz", &baz), IsNil)
+	c.Check(baz, Equals, 42)
+}
+
+func (ovs *overlordSuite) TestJournalStateBackendCompaction(c *C) {
+	restore := overlord.FakeJournalMaxEntries(3)
+	defer restore()
+	journalPath := ovs.statePath + ".journal"
+
+	o, err := overlord.NewWithOptions(overlord.Options{
+		PebbleDir:    ovs.dir,
+		StateBackend: overlord.StateBackendJournal,
+	})
+	c.Assert(err, IsNil)
+
+	st := o.State()
+	for i := 1; i <= 3; i++ {
+		st.Lock()
+		st.Set("count", i)
+		st.Unlock()
+	}
+
+	// The patches applied at startup and the first two counts filled the
+	// journal, which was compacted into the snapshot, so only the last
+	// count is left in the journal.
+	snapshot, err := ioutil.ReadFile(ovs.statePath)
+	c.Assert(err, IsNil)
+	c.Check(string(snapshot), Matches, `.*"count":2.*`)
+	journal, err := ioutil.ReadFile(journalPath)
+	c.Assert(err, IsNil)
+	c.Check(string(journal), Equals, `{"set":{"data/count":3}}`+"\n")
+}
+
+func (ovs *overlordSuite) TestNewRecoversJournal(c *C) {
+	fakeState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"patch-sublevel":%d,"patch-sublevel-last-version":%q,"some":"data","gone":true},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0,"last-lane-id":0}`, patch.Level, patch.Sublevel, cmd.Version))
+	err := ioutil.WriteFile(ovs.statePath, fakeState, 0600)
+	c.Assert(err, IsNil)
+	// The last entry was only partly written, so it's ignored.
+	journal := `{"set":{"data/some":"other"}}` + "\n" + `{"delete":["data/gone"]}` + "\n" + `{"set":{"data/some":"to`
+	err = ioutil.WriteFile(ovs.statePath+".journal", []byte(journal), 0600)
+	c.Assert(err, IsNil)
+
+	o, err := overlord.NewWithOptions(overlord.Options{
+		PebbleDir:    ovs.dir,
+		StateBackend: overlord.StateBackendJournal,
+	})
+	c.Assert(err, IsNil)
+
+	st := o.State()
+	st.Lock()
+	defer st.Unlock()
+	var some string
+	c.Assert(st.Get("some", &some), IsNil)
+	c.Check(some, Equals, "other")
+	var gone bool
+	c.Check(st.Get("gone", &gone), Equals, state.ErrNoState)
+}
+
+func (ovs *overlordSuite) TestNewWithInvalidJournal(c *C) {
+	err := ioutil.WriteFile(ovs.statePath+".journal", []byte("{}\nfoo\n"), 0600)
+	c.Assert(err, IsNil)
+
+	_, err = overlord.New(ovs.dir, nil, nil)
+	c.Assert(err, ErrorMatches, "cannot read state journal entry 2: .*")
+}
+
 func (ovs *overlordSuite) TestNewWithPatches(c *C) {
 	p := func(s *state.State) error {
 		s.Set("patched", true)
@@ -482,6 +615,103 @@ func (ovs *overlordSuite) TestEnsureLoopPruneRunsMultipleTimes(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (ovs *overlordSuite) TestPrune(c *C) {
+	o := overlord.Fake()
+	wait := 2 * time.Hour
+	err := o.SetPruneOptions(overlord.PruneOptions{
+		Wait:      &wait,
+		KindWaits: map[string]time.Duration{"exec": time.Minute},
+	})
+	c.Assert(err, IsNil)
+
+	st := o.State()
+	st.Lock()
+	restore := state.FakeTime(time.Now().Add(-time.Hour))
+	for _, kind := range []string{"exec", "start"} {
+		chg := st.NewChange(kind, "...")
+		t := st.NewTask("foo", "...")
+		chg.AddTask(t)
+		t.SetStatus(state.DoneStatus)
+	}
+	restore()
+	st.Unlock()
+
+	c.Check(o.Prune(), Equals, 1)
+	c.Check(o.Prune(), Equals, 0)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Assert(st.Changes(), HasLen, 1)
+	c.Check(st.Changes()[0].Kind(), Equals, "start")
+}
+
+func (ovs *overlordSuite) TestPruneZeroMaxChanges(c *C) {
+	o := overlord.Fake()
+	maxChanges := 0
+	err := o.SetPruneOptions(overlord.PruneOptions{MaxChanges: &maxChanges})
+	c.Assert(err, IsNil)
+
+	st := o.State()
+	st.Lock()
+	chg := st.NewChange("start", "...")
+	t := st.NewTask("foo", "...")
+	chg.AddTask(t)
+	t.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	// Zero means zero rather than the default.
+	c.Check(o.Prune(), Equals, 1)
+}
+
+func (ovs *overlordSuite) TestPruneRecordings(c *C) {
+	o, err := overlord.New(ovs.dir, nil, nil)
+	c.Assert(err, IsNil)
+	recordingsWait := time.Hour
+	maxRecordings := 1
+	err = o.SetPruneOptions(overlord.PruneOptions{
+		RecordingsWait: &recordingsWait,
+		MaxRecordings:  &maxRecordings,
+	})
+	c.Assert(err, IsNil)
+
+	dir := filepath.Join(ovs.dir, "recordings")
+	c.Assert(os.MkdirAll(dir, 0700), IsNil)
+	now := time.Now()
+	for i, age := range []time.Duration{time.Minute, 2 * time.Minute, 2 * time.Hour} {
+		path := filepath.Join(dir, fmt.Sprintf("%d.cast", i))
+		c.Assert(ioutil.WriteFile(path, nil, 0600), IsNil)
+		c.Assert(os.Chtimes(path, now.Add(-age), now.Add(-age)), IsNil)
+	}
+
+	o.Prune()
+
+	infos, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	c.Assert(infos, HasLen, 1)
+	c.Check(infos[0].Name(), Equals, "0.cast")
+}
+
+func (ovs *overlordSuite) TestSetPruneOptionsInvalid(c *C) {
+	o := overlord.Fake()
+	zero := time.Duration(0)
+	negative := -time.Second
+	negativeCount := -1
+	for _, test := range []struct {
+		opts overlord.PruneOptions
+		err  string
+	}{
+		{overlord.PruneOptions{Interval: &zero}, "prune interval must be positive"},
+		{overlord.PruneOptions{Wait: &negative}, "prune durations must not be negative"},
+		{overlord.PruneOptions{RecordingsWait: &negative}, "prune durations must not be negative"},
+		{overlord.PruneOptions{MaxChanges: &negativeCount}, "prune limits must not be negative"},
+		{overlord.PruneOptions{MaxRecordings: &negativeCount}, "prune limits must not be negative"},
+		{overlord.PruneOptions{KindWaits: map[string]time.Duration{"exec": -time.Second}}, `prune duration for "exec" changes must not be negative`},
+	} {
+		err := o.SetPruneOptions(test.opts)
+		c.Check(err, ErrorMatches, test.err)
+	}
+}
+
 func (ovs *overlordSuite) TestCheckpoint(c *C) {
 	oldUmask := syscall.Umask(0)
 	defer syscall.Umask(oldUmask)
@@ -757,6 +987,50 @@ func (rb *testRestartHandler) RebootIsMissing(_ *state.State) error {
 	return rb.rebootVerifiedErr
 }
 
+type testExitHandler struct {
+	testRestartHandler
+	exitCode int
+}
+
+func (h *testExitHandler) HandleExit(code int) {
+	h.exitCode = code
+}
+
+func (ovs *overlordSuite) TestCheckActionHandler(c *C) {
+	o, err := overlord.New(ovs.dir, nil, nil)
+	c.Assert(err, IsNil)
+
+	restartHandler := &testRestartHandler{}
+	handler := overlord.NewCheckActionHandler(o, restartHandler)
+	handler.Warnf("check %q is down", "chk1")
+	st := o.State()
+	st.Lock()
+	warnings := st.AllWarnings()
+	st.Unlock()
+	c.Assert(warnings, HasLen, 1)
+	c.Check(warnings[0].String(), Equals, `check "chk1" is down`)
+
+	err = handler.SendSignal([]string{"svc1"}, "SIGHUP")
+	c.Check(err, ErrorMatches, `cannot send signal to "svc1": service is not running`)
+
+	// Without exit code support, shutdown restarts the daemon.
+	handler.Shutdown(3)
+	c.Check(restartHandler.restartRequested, Equals, restart.RestartDaemon)
+
+	exitHandler := &testExitHandler{}
+	handler = overlord.NewCheckActionHandler(o, exitHandler)
+	handler.Shutdown(3)
+	c.Check(exitHandler.exitCode, Equals, 3)
+	c.Check(exitHandler.restartRequested, Equals, restart.RestartUnset)
+}
+
+func (ovs *overlordSuite) TestServiceHealth(c *C) {
+	c.Check(overlord.ServiceHealth("svc1", servstate.StatusActive), IsNil)
+	c.Check(overlord.ServiceHealth("svc1", servstate.StatusInactive), IsNil)
+	c.Check(overlord.ServiceHealth("svc1", servstate.StatusBackoff), ErrorMatches, `service "svc1" is backoff`)
+	c.Check(overlord.ServiceHealth("svc1", servstate.StatusError), ErrorMatches, `service "svc1" is error`)
+}
+
 func (ovs *overlordSuite) TestRequestRestartHandler(c *C) {
 	rb := &testRestartHandler{}
 
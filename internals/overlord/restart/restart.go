@@ -49,6 +49,12 @@ type Handler interface {
 	RebootIsMissing(st *state.State) error
 }
 
+// ExitHandler can optionally be implemented by a Handler that supports
+// making the daemon exit with a specific exit code.
+type ExitHandler interface {
+	HandleExit(code int)
+}
+
 // Init initializes the support for restarts requests.
 // It takes the current boot id to track and verify reboots and a
 // Handler that handles the actual requests and reacts to reboot
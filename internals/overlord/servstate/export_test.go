@@ -82,6 +82,16 @@ func FakeKillFailDelay(newKillDelay, newFailDelay time.Duration) (restore func()
 	}
 }
 
+// FakeWatchInterval changes the interval at which watched files are polled,
+// as well as the default debounce duration, for testing purposes.
+func FakeWatchInterval(interval, debounce time.Duration) (restore func()) {
+	old1, old2 := watchInterval, watchDebounceDefault
+	watchInterval, watchDebounceDefault = interval, debounce
+	return func() {
+		watchInterval, watchDebounceDefault = old1, old2
+	}
+}
+
 func FakeSetCmdCredential(f func(cmd *exec.Cmd, credential *syscall.Credential)) (restore func()) {
 	old := setCmdCredential
 	setCmdCredential = f
@@ -299,8 +299,14 @@ func (s *serviceData) transitionRestarting(state serviceState, restarting bool)
 		s.currentSince = time.Now()
 	}
 
+	old := s.state
 	s.state = state
 	s.restarting = restarting
+	if old != state {
+		for _, f := range s.manager.stateHandlers {
+			f(s.config.Name, string(old), string(state))
+		}
+	}
 }
 
 // start is called to transition from the initial state and start the service.
@@ -479,10 +485,12 @@ func (s *serviceData) exited(exitCode int) error {
 	switch s.state {
 	case stateStarting:
 		s.started <- fmt.Errorf("exited quickly with code %d", exitCode)
+		s.notifyExited(exitCode)
 		s.transition(stateExited) // not strictly necessary as doStart will return, but doesn't hurt
 
 	case stateRunning:
 		logger.Noticef("Service %q stopped unexpectedly with code %d", s.config.Name, exitCode)
+		s.notifyExited(exitCode)
 		action, onType := getAction(s.config, exitCode == 0)
 		switch action {
 		case plan.ActionIgnore:
@@ -522,6 +530,21 @@ func (s *serviceData) exited(exitCode int) error {
 	return nil
 }
 
+// notifyExited calls the exit handlers with the last few lines of service
+// output. It must be called with the services lock held.
+func (s *serviceData) notifyExited(exitCode int) {
+	if len(s.manager.exitHandlers) == 0 {
+		return
+	}
+	logs, err := servicelog.LastLines(s.logs, lastLogLines, "", true)
+	if err != nil {
+		logger.Noticef("Cannot read service %q logs: %v", s.config.Name, err)
+	}
+	for _, f := range s.manager.exitHandlers {
+		f(s.config.Name, exitCode, logs)
+	}
+}
+
 // addLastLogs adds the last few lines of service output to the task's log.
 func addLastLogs(task *state.Task, logBuffer *servicelog.RingBuffer) {
 	st := task.State()
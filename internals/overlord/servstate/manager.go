@@ -25,9 +25,12 @@ type ServiceManager struct {
 	planLock     sync.Mutex
 	plan         *plan.Plan
 	planHandlers []PlanFunc
+	watchers     map[string]*fileWatcher // protected by planLock
 
-	servicesLock sync.Mutex
-	services     map[string]*serviceData
+	servicesLock  sync.Mutex
+	services      map[string]*serviceData
+	stateHandlers []StateFunc // protected by servicesLock
+	exitHandlers  []ExitFunc  // protected by servicesLock
 
 	serviceOutput io.Writer
 	restarter     Restarter
@@ -45,6 +48,15 @@ type LogManager interface {
 // PlanFunc is the type of function used by NotifyPlanChanged.
 type PlanFunc func(p *plan.Plan)
 
+// StateFunc is the type of function used by NotifyServiceStateChanged. It's
+// called with the service's old and new state, for example "starting" and
+// "running".
+type StateFunc func(name, old, new string)
+
+// ExitFunc is the type of function used by NotifyServiceExited. It's called
+// with the service's exit code and its most recent output.
+type ExitFunc func(name string, exitCode int, lastLogs string)
+
 type Restarter interface {
 	HandleRestart(t restart.RestartType)
 }
@@ -65,6 +77,7 @@ func NewManager(s *state.State, runner *state.TaskRunner, pebbleDir string, serv
 		runner:        runner,
 		pebbleDir:     pebbleDir,
 		services:      make(map[string]*serviceData),
+		watchers:      make(map[string]*fileWatcher),
 		serviceOutput: serviceOutput,
 		restarter:     restarter,
 		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
@@ -84,6 +97,7 @@ func NewManager(s *state.State, runner *state.TaskRunner, pebbleDir string, serv
 
 // Stop implements overlord.StateStopper and stops background functions.
 func (m *ServiceManager) Stop() {
+	m.stopWatchers()
 	err := reaper.Stop()
 	if err != nil {
 		logger.Noticef("Cannot stop child process reaper: %v", err)
@@ -96,8 +110,27 @@ func (m *ServiceManager) NotifyPlanChanged(f PlanFunc) {
 	m.planHandlers = append(m.planHandlers, f)
 }
 
+// NotifyServiceStateChanged adds f to the list of functions that are called
+// whenever a service's state changes. The functions are called with the
+// services lock held, so they mustn't block or call back into the manager.
+func (m *ServiceManager) NotifyServiceStateChanged(f StateFunc) {
+	m.servicesLock.Lock()
+	defer m.servicesLock.Unlock()
+	m.stateHandlers = append(m.stateHandlers, f)
+}
+
+// NotifyServiceExited adds f to the list of functions that are called
+// whenever a service exits unexpectedly (not because it was stopped). Like
+// the state-changed functions, they're called with the services lock held.
+func (m *ServiceManager) NotifyServiceExited(f ExitFunc) {
+	m.servicesLock.Lock()
+	defer m.servicesLock.Unlock()
+	m.exitHandlers = append(m.exitHandlers, f)
+}
+
 func (m *ServiceManager) updatePlan(p *plan.Plan) {
 	m.plan = p
+	m.updateWatchers(p)
 	for _, f := range m.planHandlers {
 		f(p)
 	}
@@ -166,6 +199,7 @@ func (m *ServiceManager) updatePlanLayers(layers []*plan.Layer) error {
 		Services:   combined.Services,
 		Checks:     combined.Checks,
 		LogTargets: combined.LogTargets,
+		Webhooks:   combined.Webhooks,
 	}
 	m.updatePlan(p)
 	return nil
@@ -332,6 +366,20 @@ func (m *ServiceManager) StopTimeout() time.Duration {
 	return maxDuration + failDelay + 100*time.Millisecond
 }
 
+// ServiceStatus returns the current status of the named service. Unlike
+// Services, it doesn't acquire the plan lock, so it's safe to call from
+// functions that the plan change handlers wait on.
+func (m *ServiceManager) ServiceStatus(name string) ServiceStatus {
+	m.servicesLock.Lock()
+	defer m.servicesLock.Unlock()
+
+	s, ok := m.services[name]
+	if !ok {
+		return StatusInactive
+	}
+	return stateToStatus(s.state)
+}
+
 func stateToStatus(state serviceState) ServiceStatus {
 	switch state {
 	case stateStarting, stateRunning:
@@ -171,7 +171,6 @@ func (s *S) SetUpTest(c *C) {
 	s.stopDaemon = make(chan struct{})
 	manager, err := servstate.NewManager(s.st, s.runner, s.dir, logOutput, testRestarter{s.stopDaemon}, fakeLogManager{})
 	c.Assert(err, IsNil)
-	s.AddCleanup(manager.Stop)
 	s.manager = manager
 
 	restore := servstate.FakeOkayWait(shortOkayDelay)
@@ -181,6 +180,9 @@ func (s *S) SetUpTest(c *C) {
 }
 
 func (s *S) TearDownTest(c *C) {
+	// Stop the manager (and with it any file watchers) before the cleanups
+	// restore the faked package variables.
+	s.manager.Stop()
 	s.BaseTest.TearDownTest(c)
 }
 
@@ -969,6 +971,57 @@ func (s *S) TestServices(c *C) {
 		{Name: "test4", Current: servstate.StatusInactive, Startup: servstate.StartupDisabled},
 		{Name: "test5", Current: servstate.StatusInactive, Startup: servstate.StartupDisabled},
 	})
+
+	c.Check(s.manager.ServiceStatus("test2"), Equals, servstate.StatusActive)
+	c.Check(s.manager.ServiceStatus("test3"), Equals, servstate.StatusInactive)
+	c.Check(s.manager.ServiceStatus("nosvc"), Equals, servstate.StatusInactive)
+}
+
+func (s *S) TestNotifyServiceStateChanged(c *C) {
+	var mutex sync.Mutex
+	var transitions []string
+	s.manager.NotifyServiceStateChanged(func(name, old, new string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		transitions = append(transitions, fmt.Sprintf("%s %s %s", name, old, new))
+	})
+
+	s.startServices(c, []string{"test2"}, 1)
+	s.stopServices(c, []string{"test2"}, 1)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	c.Check(transitions, DeepEquals, []string{
+		"test2 initial starting",
+		"test2 starting running",
+		"test2 running terminating",
+		"test2 terminating stopped",
+	})
+}
+
+func (s *S) TestNotifyServiceExited(c *C) {
+	exited := make(chan string, 1)
+	s.manager.NotifyServiceExited(func(name string, exitCode int, lastLogs string) {
+		exited <- fmt.Sprintf("%s %d %q", name, exitCode, lastLogs)
+	})
+
+	layer := parseLayer(c, 0, "layer", `
+services:
+    test2:
+        override: replace
+        command: /bin/sh -c "echo exiting; sleep 0.15; exit 3"
+        on-failure: ignore
+`)
+	err := s.manager.AppendLayer(layer)
+	c.Assert(err, IsNil)
+
+	s.startServices(c, []string{"test2"}, 1)
+	select {
+	case msg := <-exited:
+		c.Check(msg, Equals, `test2 3 "exiting"`)
+	case <-time.After(10 * time.Second):
+		c.Fatalf("timed out waiting for service to exit")
+	}
 }
 
 var planLayerEnv = `
@@ -1450,6 +1503,134 @@ func (s *S) waitUntilService(c *C, service string, f func(svc *servstate.Service
 	c.Fatalf("timed out waiting for service")
 }
 
+func (s *S) TestWatchRestart(c *C) {
+	restore := servstate.FakeWatchInterval(10*time.Millisecond, 20*time.Millisecond)
+	defer restore()
+
+	configPath := filepath.Join(c.MkDir(), "config")
+	err := ioutil.WriteFile(configPath, []byte("v1"), 0644)
+	c.Assert(err, IsNil)
+	layer := parseLayer(c, 0, "layer", fmt.Sprintf(`
+services:
+    test2:
+        override: merge
+        command: /bin/sh -c "echo test2; exec sleep 10"
+        watch:
+            - %s
+`, configPath))
+	err = s.manager.AppendLayer(layer)
+	c.Assert(err, IsNil)
+
+	s.startServices(c, []string{"test2"}, 1)
+	defer s.stopServices(c, []string{"test2"}, 1)
+	s.waitUntilService(c, "test2", func(svc *servstate.ServiceInfo) bool {
+		return svc.Current == servstate.StatusActive
+	})
+	pid := s.manager.RunningCmds()["test2"].Process.Pid
+
+	err = ioutil.WriteFile(configPath, []byte("version 2"), 0644)
+	c.Assert(err, IsNil)
+
+	// Wait for the restart change to be created, and run it.
+	var chg *state.Change
+	for i := 0; chg == nil; i++ {
+		if i >= 100 {
+			c.Fatalf("timed out waiting for restart change")
+		}
+		time.Sleep(10 * time.Millisecond)
+		s.st.Lock()
+		for _, change := range s.st.Changes() {
+			if change.Kind() == "restart" {
+				chg = change
+			}
+		}
+		s.st.Unlock()
+	}
+	s.ensure(c, 2)
+
+	s.st.Lock()
+	c.Check(chg.Status(), Equals, state.DoneStatus, Commentf("Error: %v", chg.Err()))
+	c.Check(chg.Summary(), Equals, `Restart service "test2" (watched files changed)`)
+	s.st.Unlock()
+	cmds := s.manager.RunningCmds()
+	c.Assert(cmds["test2"], NotNil)
+	c.Check(cmds["test2"].Process.Pid, Not(Equals), pid)
+}
+
+func (s *S) TestWatchSignal(c *C) {
+	restore := servstate.FakeWatchInterval(10*time.Millisecond, 20*time.Millisecond)
+	defer restore()
+
+	dir := c.MkDir()
+	configPath := filepath.Join(dir, "config")
+	outputPath := filepath.Join(dir, "output")
+	layer := parseLayer(c, 0, "layer", fmt.Sprintf(`
+services:
+    test2:
+        override: merge
+        command: /bin/sh -c "trap 'echo got-usr1 >>%s' USR1; while true; do sleep 0.01; done"
+        watch:
+            - %s
+        watch-action: signal
+        watch-signal: SIGUSR1
+`, outputPath, configPath))
+	err := s.manager.AppendLayer(layer)
+	c.Assert(err, IsNil)
+
+	s.startServices(c, []string{"test2"}, 1)
+	defer s.stopServices(c, []string{"test2"}, 1)
+	s.waitUntilService(c, "test2", func(svc *servstate.ServiceInfo) bool {
+		return svc.Current == servstate.StatusActive
+	})
+
+	// Creating the watched file counts as a change.
+	err = ioutil.WriteFile(configPath, []byte("new"), 0644)
+	c.Assert(err, IsNil)
+
+	for i := 0; ; i++ {
+		if i >= 100 {
+			c.Fatalf("timed out waiting for signal to be handled")
+		}
+		b, _ := ioutil.ReadFile(outputPath)
+		if string(b) == "got-usr1\n" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The service was signalled rather than restarted.
+	s.st.Lock()
+	c.Check(s.st.Changes(), HasLen, 1)
+	s.st.Unlock()
+	svc := s.serviceByName(c, "test2")
+	c.Check(svc.Current, Equals, servstate.StatusActive)
+}
+
+func (s *S) TestWatchNotRunning(c *C) {
+	restore := servstate.FakeWatchInterval(10*time.Millisecond, 20*time.Millisecond)
+	defer restore()
+
+	configPath := filepath.Join(c.MkDir(), "config")
+	layer := parseLayer(c, 0, "layer", fmt.Sprintf(`
+services:
+    test2:
+        override: merge
+        watch:
+            - %s
+`, configPath))
+	err := s.manager.AppendLayer(layer)
+	c.Assert(err, IsNil)
+
+	err = ioutil.WriteFile(configPath, []byte("new"), 0644)
+	c.Assert(err, IsNil)
+	time.Sleep(100 * time.Millisecond)
+
+	// No change is created for a service that isn't running.
+	s.st.Lock()
+	c.Check(s.st.Changes(), HasLen, 0)
+	s.st.Unlock()
+}
+
 func (s *S) TestActionShutdown(c *C) {
 	layer := parseLayer(c, 0, "layer", `
 services:
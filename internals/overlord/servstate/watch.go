@@ -0,0 +1,247 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package servstate
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/plan"
+)
+
+var (
+	// watchInterval is how often watched files are polled for changes.
+	watchInterval = time.Second
+
+	// watchDebounceDefault is how long watched files must remain unchanged
+	// before the watch action is triggered, if the service hasn't specified
+	// its own duration.
+	watchDebounceDefault = time.Second
+)
+
+// fileWatcher polls a service's watched files for changes.
+type fileWatcher struct {
+	paths    []string
+	interval time.Duration
+	debounce time.Duration
+	stop     chan struct{}
+}
+
+// fileStamp identifies the version of a watched file at a point in time.
+type fileStamp struct {
+	exists  bool
+	inode   uint64
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.exists == other.exists && s.inode == other.inode && s.size == other.size &&
+		s.mode == other.mode && s.modTime.Equal(other.modTime)
+}
+
+func newFileWatcher(config *plan.Service) *fileWatcher {
+	debounce := watchDebounceDefault
+	if config.WatchDebounce.IsSet {
+		debounce = config.WatchDebounce.Value
+	}
+	return &fileWatcher{
+		paths:    append([]string(nil), config.Watch...),
+		interval: watchInterval,
+		debounce: debounce,
+		stop:     make(chan struct{}),
+	}
+}
+
+// matches reports whether the watcher is watching the files configured for
+// the given service, in which case it can be left running across plan updates.
+func (w *fileWatcher) matches(config *plan.Service) bool {
+	other := newFileWatcher(config)
+	if w.debounce != other.debounce || len(w.paths) != len(other.paths) {
+		return false
+	}
+	for i, path := range w.paths {
+		if path != other.paths[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// loop polls the watched files till the watcher is stopped, calling changed
+// once the files have been modified and then left alone for the debounce
+// duration.
+func (w *fileWatcher) loop(changed func()) {
+	stamps := statFiles(w.paths)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ticker.C:
+			current := statFiles(w.paths)
+			for i := range current {
+				if !current[i].equal(stamps[i]) {
+					logger.Debugf("Watched file %q changed", w.paths[i])
+					settled = time.After(w.debounce)
+				}
+			}
+			stamps = current
+		case <-settled:
+			settled = nil
+			changed()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func statFiles(paths []string) []fileStamp {
+	stamps := make([]fileStamp, len(paths))
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Treat any error as the file not being there; it will be seen
+			// as changed when it's created (or readable) again.
+			continue
+		}
+		stamps[i] = fileStamp{
+			exists:  true,
+			size:    info.Size(),
+			mode:    info.Mode(),
+			modTime: info.ModTime(),
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			// Files are often replaced atomically via rename, so include the
+			// inode in case size and modification time stay the same.
+			stamps[i].inode = uint64(stat.Ino)
+		}
+	}
+	return stamps
+}
+
+// updateWatchers starts and stops file watchers to match the services in
+// the plan. Watchers whose configuration hasn't changed are kept running.
+// It must be called with the plan lock held.
+func (m *ServiceManager) updateWatchers(p *plan.Plan) {
+	for name, w := range m.watchers {
+		config, ok := p.Services[name]
+		if ok && len(config.Watch) > 0 && w.matches(config) {
+			continue
+		}
+		close(w.stop)
+		delete(m.watchers, name)
+	}
+	for name, config := range p.Services {
+		if len(config.Watch) == 0 || m.watchers[name] != nil {
+			continue
+		}
+		w := newFileWatcher(config)
+		m.watchers[name] = w
+		serviceName := name
+		go w.loop(func() { m.watchedFilesChanged(serviceName) })
+	}
+}
+
+// stopWatchers stops all running file watchers.
+func (m *ServiceManager) stopWatchers() {
+	m.planLock.Lock()
+	defer m.planLock.Unlock()
+
+	for name, w := range m.watchers {
+		close(w.stop)
+		delete(m.watchers, name)
+	}
+}
+
+// watchedFilesChanged performs the configured watch action when a service's
+// watched files have changed. Nothing is done if the service isn't running.
+func (m *ServiceManager) watchedFilesChanged(name string) {
+	releasePlan, err := m.acquirePlan()
+	if err != nil {
+		logger.Noticef("Cannot handle watched files change for service %q: %v", name, err)
+		return
+	}
+	config, ok := m.plan.Services[name]
+	releasePlan()
+	if !ok {
+		return
+	}
+
+	m.servicesLock.Lock()
+	s := m.services[name]
+	running := s != nil && (s.state == stateStarting || s.state == stateRunning)
+	m.servicesLock.Unlock()
+	if !running {
+		logger.Debugf("Service %q watched files changed, but service is not running", name)
+		return
+	}
+
+	action := config.WatchAction
+	if action == plan.WatchActionUnset {
+		action = plan.WatchActionRestart
+	}
+	logger.Noticef("Service %q watched files changed, watch-action is %q", name, action)
+
+	switch action {
+	case plan.WatchActionReload, plan.WatchActionSignal:
+		signal := "SIGHUP"
+		if action == plan.WatchActionSignal {
+			signal = config.WatchSignal
+		}
+		err := m.SendSignal([]string{name}, signal)
+		if err != nil {
+			logger.Noticef("Cannot %s service %q: %v", action, name, err)
+		}
+
+	case plan.WatchActionRestart:
+		m.state.Lock()
+		defer m.state.Unlock()
+		err := m.restartChange(name)
+		if err != nil {
+			logger.Noticef("Cannot restart service %q: %v", name, err)
+		}
+
+	default:
+		logger.Noticef("Internal error: unexpected watch-action %q for service %q", action, name)
+	}
+}
+
+// restartChange creates a change that restarts the named service. It must
+// be called with the state lock held.
+func (m *ServiceManager) restartChange(name string) error {
+	stopTasks, err := Stop(m.state, []string{name})
+	if err != nil {
+		return err
+	}
+	startTasks, err := Start(m.state, []string{name})
+	if err != nil {
+		return err
+	}
+	startTasks.WaitAll(stopTasks)
+
+	chg := m.state.NewChange("restart", fmt.Sprintf("Restart service %q (watched files changed)", name))
+	chg.AddAll(stopTasks)
+	chg.AddAll(startTasks)
+	chg.Set("service-names", []string{name})
+	m.state.EnsureBefore(0)
+	return nil
+}
@@ -300,10 +300,29 @@ func (c *Change) Status() Status {
 // SetStatus sets the change status, overriding the default behavior (see Status method).
 func (c *Change) SetStatus(s Status) {
 	c.state.writing()
+	var old Status
+	if len(c.state.changeHandlers) > 0 {
+		old = c.Status()
+	}
 	c.status = s
 	if s.Ready() {
 		c.markReady()
 	}
+	if len(c.state.changeHandlers) > 0 {
+		c.notifyStatusChanged(old)
+	}
+}
+
+// notifyStatusChanged calls the state's change handlers if the change's
+// status is different from old.
+func (c *Change) notifyStatusChanged(old Status) {
+	new := c.Status()
+	if new == old {
+		return
+	}
+	for _, f := range c.state.changeHandlers {
+		f(c, old, new)
+	}
 }
 
 func (c *Change) markReady() {
@@ -511,6 +530,55 @@ func (c *Change) Abort() {
 	c.abortTasks(tasks, make(map[int]bool), make(map[string]bool))
 }
 
+// Retry creates a new change to run this change's tasks again, with the same
+// kinds, summaries and data, and the same dependencies between them. The new
+// change records the ID of this change as its "retry-of" data. Only changes
+// that failed or were aborted (with status Error, Undone or Hold) can be
+// retried.
+func (c *Change) Retry() (*Change, error) {
+	c.state.writing()
+	switch status := c.Status(); status {
+	case ErrorStatus, UndoneStatus, HoldStatus:
+	default:
+		return nil, fmt.Errorf("cannot retry change %s with status %s", c.id, status)
+	}
+
+	retry := c.state.NewChange(c.kind, c.summary)
+	for k, v := range c.data {
+		retry.data[k] = v
+	}
+	retry.Set("retry-of", c.id)
+
+	tasks := c.Tasks()
+	retryTasks := make(map[string]*Task, len(tasks))
+	retryLanes := make(map[int]int)
+	for _, t := range tasks {
+		rt := c.state.NewTask(t.kind, t.summary)
+		for k, v := range t.data {
+			rt.data[k] = v
+		}
+		for _, lane := range t.lanes {
+			if _, ok := retryLanes[lane]; !ok {
+				retryLanes[lane] = c.state.NewLane()
+			}
+			rt.JoinLane(retryLanes[lane])
+		}
+		rt.retries = t.retries
+		rt.recordRetry()
+		retryTasks[t.id] = rt
+	}
+	for _, t := range tasks {
+		rt := retryTasks[t.id]
+		for _, waitID := range t.waitTasks {
+			if wt, ok := retryTasks[waitID]; ok {
+				rt.WaitFor(wt)
+			}
+		}
+		retry.AddTask(rt)
+	}
+	return retry, nil
+}
+
 // AbortLanes aborts all tasks in the provided lanes and any tasks waiting on them,
 // except for tasks that are also in a healthy lane (not aborted, and not waiting
 // on aborted).
@@ -579,17 +647,7 @@ func (c *Change) abortTasks(tasks []*Task, abortedLanes map[int]bool, seenTasks
 			continue
 		}
 		seenTasks[t.id] = true
-		switch t.Status() {
-		case DoStatus:
-			// Still pending so don't even start.
-			t.SetStatus(HoldStatus)
-		case DoingStatus:
-			// In progress so stop and undo it.
-			t.SetStatus(AbortStatus)
-		case DoneStatus:
-			// Already done so undo it.
-			t.SetStatus(UndoStatus)
-		}
+		abortTask(t)
 
 		for _, lane := range t.Lanes() {
 			if !abortedLanes[lane] {
@@ -607,3 +665,18 @@ func (c *Change) abortTasks(tasks []*Task, abortedLanes map[int]bool, seenTasks
 		c.abortLanes(lanes, abortedLanes, seenTasks)
 	}
 }
+
+// abortTask flags a single task for cancellation.
+func abortTask(t *Task) {
+	switch t.Status() {
+	case DoStatus:
+		// Still pending so don't even start.
+		t.SetStatus(HoldStatus)
+	case DoingStatus:
+		// In progress so stop and undo it.
+		t.SetStatus(AbortStatus)
+	case DoneStatus:
+		// Already done so undo it.
+		t.SetStatus(UndoStatus)
+	}
+}
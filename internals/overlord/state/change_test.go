@@ -258,6 +258,41 @@ func (cs *changeSuite) TestStatusDerivedFromTasks(c *C) {
 	}
 }
 
+func (cs *changeSuite) TestStatusChangedHandlers(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	var calls []string
+	st.AddTaskStatusChangedHandler(func(t *state.Task, old, new state.Status) {
+		calls = append(calls, fmt.Sprintf("task %s %s %s", t.Summary(), old, new))
+	})
+	st.AddChangeStatusChangedHandler(func(chg *state.Change, old, new state.Status) {
+		calls = append(calls, fmt.Sprintf("change %s %s %s", chg.Summary(), old, new))
+	})
+
+	chg := st.NewChange("install", "chg")
+	t1 := st.NewTask("download", "t1")
+	t2 := st.NewTask("download", "t2")
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+
+	t1.SetStatus(state.DoingStatus)
+	t1.SetStatus(state.DoingStatus)
+	t1.SetStatus(state.DoneStatus)
+	t2.SetStatus(state.DoneStatus)
+	chg.SetStatus(state.ErrorStatus)
+	c.Check(calls, DeepEquals, []string{
+		"task t1 Do Doing",
+		"change chg Do Doing",
+		"task t1 Doing Done",
+		"change chg Doing Do",
+		"task t2 Do Done",
+		"change chg Do Done",
+		"change chg Done Error",
+	})
+}
+
 func (cs *changeSuite) TestCloseReadyOnExplicitStatus(c *C) {
 	st := state.New(nil)
 	st.Lock()
@@ -395,6 +430,7 @@ func (cs *changeSuite) TestMethodEntrance(c *C) {
 		func() { chg.AddTask(nil) },
 		func() { chg.AddAll(nil) },
 		func() { chg.UnmarshalJSON(nil) },
+		func() { chg.Retry() },
 	}
 
 	reads := []func(){
@@ -461,6 +497,79 @@ func (cs *changeSuite) TestAbort(c *C) {
 	}
 }
 
+func (cs *changeSuite) TestRetry(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("start", "Start service")
+	chg.Set("service-names", []string{"svc1"})
+	t1 := st.NewTask("start", "1...")
+	t1.Set("service-request", "svc1")
+	t2 := st.NewTask("start", "2...")
+	t2.WaitFor(t1)
+	lane := st.NewLane()
+	t2.JoinLane(lane)
+	chg.AddAll(state.NewTaskSet(t1, t2))
+	t1.SetStatus(state.ErrorStatus)
+	t2.SetStatus(state.HoldStatus)
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	now := time.Now()
+	restore := state.FakeTime(now)
+	defer restore()
+	retry, err := chg.Retry()
+	c.Assert(err, IsNil)
+	c.Check(retry.ID(), Not(Equals), chg.ID())
+	c.Check(retry.Kind(), Equals, "start")
+	c.Check(retry.Summary(), Equals, "Start service")
+	c.Check(retry.Status(), Equals, state.DoStatus)
+	var names []string
+	c.Check(retry.Get("service-names", &names), IsNil)
+	c.Check(names, DeepEquals, []string{"svc1"})
+	var retryOf string
+	c.Check(retry.Get("retry-of", &retryOf), IsNil)
+	c.Check(retryOf, Equals, chg.ID())
+
+	tasks := retry.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	c.Check(tasks[0].Summary(), Equals, "1...")
+	c.Check(tasks[1].Summary(), Equals, "2...")
+	var request string
+	c.Check(tasks[0].Get("service-request", &request), IsNil)
+	c.Check(request, Equals, "svc1")
+	c.Check(tasks[1].WaitTasks(), DeepEquals, []*state.Task{tasks[0]})
+	c.Assert(tasks[1].Lanes(), HasLen, 1)
+	c.Check(tasks[1].Lanes()[0], Not(Equals), lane)
+	for _, t := range tasks {
+		c.Check(t.Status(), Equals, state.DoStatus)
+		c.Check(t.Retries(), Equals, 1)
+		c.Check(t.LastRetryTime().Equal(now), Equals, true)
+	}
+
+	// The original change is left as is.
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Tasks(), DeepEquals, []*state.Task{t1, t2})
+}
+
+func (cs *changeSuite) TestRetryNotFailed(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("start", "...")
+	t := st.NewTask("start", "...")
+	chg.AddTask(t)
+
+	_, err := chg.Retry()
+	c.Check(err, ErrorMatches, `cannot retry change 1 with status Do`)
+
+	t.SetStatus(state.DoneStatus)
+	_, err = chg.Retry()
+	c.Check(err, ErrorMatches, `cannot retry change 1 with status Done`)
+	c.Check(st.Changes(), HasLen, 1)
+}
+
 func (cs *changeSuite) TestAbortCircular(c *C) {
 	st := state.New(nil)
 	st.Lock()
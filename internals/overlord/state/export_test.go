@@ -35,6 +35,15 @@ func FakeCheckpointRetryDelay(retryInterval, retryMaxTime time.Duration) (restor
 	}
 }
 
+// FakeRemoveChangeTimeout changes removeChangeTimeout.
+func FakeRemoveChangeTimeout(timeout time.Duration) (restore func()) {
+	old := removeChangeTimeout
+	removeChangeTimeout = timeout
+	return func() {
+		removeChangeTimeout = old
+	}
+}
+
 func FakeChangeTimes(chg *Change, spawnTime, readyTime time.Time) {
 	chg.spawnTime = spawnTime
 	chg.readyTime = readyTime
@@ -54,10 +63,6 @@ func (s *State) AddWarning(message string, lastAdded, lastShown time.Time, expir
 	}, lastAdded)
 }
 
-func (w Warning) LastAdded() time.Time {
-	return w.lastAdded
-}
-
 func (t *Task) AccumulateDoingTime(duration time.Duration) {
 	t.accumulateDoingTime(duration)
 }
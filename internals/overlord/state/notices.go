@@ -0,0 +1,389 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultNoticeExpireAfter is how long after a notice last occurred
+	// that it's dropped.
+	defaultNoticeExpireAfter = 7 * 24 * time.Hour
+)
+
+// NoticeType is the type of a notice.
+type NoticeType string
+
+const (
+	// CustomNotice is a notice recorded by a client, with a key in the
+	// form "domain.com/path".
+	CustomNotice NoticeType = "custom"
+)
+
+// customKeyRegexp is the format of the keys of custom notices, for example
+// "example.com/config-changed".
+var customKeyRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*(\.[a-z0-9]+(-[a-z0-9]+)*)+(/[a-z0-9]+(-[a-z0-9]+)*)+$`)
+
+// ValidateNotice returns an error if the notice type or key is invalid.
+func ValidateNotice(noticeType NoticeType, key string) error {
+	switch noticeType {
+	case CustomNotice:
+		if !customKeyRegexp.MatchString(key) {
+			return fmt.Errorf(`custom notice key must be in the form "domain.com/key", not %q`, key)
+		}
+	default:
+		return fmt.Errorf("invalid notice type %q", noticeType)
+	}
+	return nil
+}
+
+// Notice is a deduplicated event: when a notice with the same type and key
+// is recorded again, its occurrence count and last-occurred time are updated
+// rather than adding a new notice.
+type Notice struct {
+	// unique ID for this notice
+	id string
+	// type and key, unique together
+	noticeType NoticeType
+	key        string
+	// the time the notice first occurred, and last occurred
+	firstOccurred time.Time
+	lastOccurred  time.Time
+	// the time the notice last occurred and was "repeated", so that it's
+	// returned to clients waiting for new notices (see repeatAfter)
+	lastRepeated time.Time
+	// number of times the notice has occurred
+	occurrences int
+	// data from the last occurrence
+	lastData map[string]string
+	// if set, the notice is only repeated if it occurs this long after it
+	// was last repeated
+	repeatAfter time.Duration
+	// how long after the notice last occurred to drop it
+	expireAfter time.Duration
+}
+
+func (n *Notice) String() string {
+	return fmt.Sprintf("Notice %s (%s:%s)", n.id, n.noticeType, n.key)
+}
+
+// ID returns the notice's unique ID.
+func (n *Notice) ID() string {
+	return n.id
+}
+
+// Type returns the notice's type.
+func (n *Notice) Type() NoticeType {
+	return n.noticeType
+}
+
+// Key returns the notice's key.
+func (n *Notice) Key() string {
+	return n.key
+}
+
+// LastRepeated returns the time the notice was last repeated.
+func (n *Notice) LastRepeated() time.Time {
+	return n.lastRepeated
+}
+
+func (n *Notice) expired(now time.Time) bool {
+	return n.lastOccurred.Add(n.expireAfter).Before(now)
+}
+
+type jsonNotice struct {
+	ID            string            `json:"id"`
+	Type          string            `json:"type"`
+	Key           string            `json:"key"`
+	FirstOccurred time.Time         `json:"first-occurred"`
+	LastOccurred  time.Time         `json:"last-occurred"`
+	LastRepeated  time.Time         `json:"last-repeated"`
+	Occurrences   int               `json:"occurrences"`
+	LastData      map[string]string `json:"last-data,omitempty"`
+	RepeatAfter   string            `json:"repeat-after,omitempty"`
+	ExpireAfter   string            `json:"expire-after,omitempty"`
+}
+
+func (n *Notice) MarshalJSON() ([]byte, error) {
+	jn := jsonNotice{
+		ID:            n.id,
+		Type:          string(n.noticeType),
+		Key:           n.key,
+		FirstOccurred: n.firstOccurred,
+		LastOccurred:  n.lastOccurred,
+		LastRepeated:  n.lastRepeated,
+		Occurrences:   n.occurrences,
+		LastData:      n.lastData,
+	}
+	if n.repeatAfter != 0 {
+		jn.RepeatAfter = n.repeatAfter.String()
+	}
+	if n.expireAfter != 0 {
+		jn.ExpireAfter = n.expireAfter.String()
+	}
+	return json.Marshal(jn)
+}
+
+func (n *Notice) UnmarshalJSON(data []byte) error {
+	var jn jsonNotice
+	err := json.Unmarshal(data, &jn)
+	if err != nil {
+		return err
+	}
+	n.id = jn.ID
+	n.noticeType = NoticeType(jn.Type)
+	n.key = jn.Key
+	n.firstOccurred = jn.FirstOccurred
+	n.lastOccurred = jn.LastOccurred
+	n.lastRepeated = jn.LastRepeated
+	n.occurrences = jn.Occurrences
+	n.lastData = jn.LastData
+	if jn.RepeatAfter != "" {
+		n.repeatAfter, err = time.ParseDuration(jn.RepeatAfter)
+		if err != nil {
+			return err
+		}
+	}
+	if jn.ExpireAfter != "" {
+		n.expireAfter, err = time.ParseDuration(jn.ExpireAfter)
+		if err != nil {
+			return err
+		}
+	}
+	if n.expireAfter == 0 {
+		return errors.New("notice has no expire-after duration")
+	}
+	return nil
+}
+
+// AddNoticeOptions holds optional parameters for an AddNotice call.
+type AddNoticeOptions struct {
+	// Data is the optional key-value data for this occurrence.
+	Data map[string]string
+
+	// RepeatAfter defines how long after this notice was last repeated we
+	// should allow it to repeat. Zero means always repeat.
+	RepeatAfter time.Duration
+
+	// ExpireAfter is how long after it last occurred the notice expires.
+	// If zero, the notice keeps its existing expiry time, or the default
+	// (7 days) for a new notice.
+	ExpireAfter time.Duration
+
+	// Time, if set, overrides time.Now() as the notice occurrence time.
+	Time time.Time
+}
+
+type noticeKey struct {
+	noticeType NoticeType
+	key        string
+}
+
+// AddNotice records an occurrence of a notice with the specified type and
+// key, and returns the notice's ID. The type and key must be valid (see
+// ValidateNotice).
+func (s *State) AddNotice(noticeType NoticeType, key string, options *AddNoticeOptions) string {
+	if options == nil {
+		options = &AddNoticeOptions{}
+	}
+	s.writing()
+
+	now := options.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.UTC()
+	newOrRepeated := false
+	uniqueKey := noticeKey{noticeType, key}
+	notice, ok := s.notices[uniqueKey]
+	if !ok {
+		s.lastNoticeId++
+		notice = &Notice{
+			id:            strconv.Itoa(s.lastNoticeId),
+			noticeType:    noticeType,
+			key:           key,
+			firstOccurred: now,
+			lastRepeated:  now,
+			expireAfter:   defaultNoticeExpireAfter,
+		}
+		s.notices[uniqueKey] = notice
+		newOrRepeated = true
+	} else if options.RepeatAfter == 0 || now.After(notice.lastRepeated.Add(options.RepeatAfter)) {
+		notice.lastRepeated = now
+		newOrRepeated = true
+	}
+	notice.occurrences++
+	notice.lastOccurred = now
+	notice.lastData = options.Data
+	notice.repeatAfter = options.RepeatAfter
+	if options.ExpireAfter != 0 {
+		notice.expireAfter = options.ExpireAfter
+	}
+
+	if newOrRepeated {
+		s.noticeCond.Broadcast()
+	}
+	return notice.id
+}
+
+// NoticeFilter allows filtering notices by various fields.
+type NoticeFilter struct {
+	// Types, if not empty, includes only notices whose type is one of these.
+	Types []NoticeType
+
+	// Keys, if not empty, includes only notices whose key is one of these.
+	Keys []string
+
+	// After, if set, includes only notices that were last repeated after
+	// this time.
+	After time.Time
+}
+
+func (f *NoticeFilter) matches(n *Notice) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Types) > 0 && !noticeTypeIn(f.Types, n.noticeType) {
+		return false
+	}
+	if len(f.Keys) > 0 && !stringIn(f.Keys, n.key) {
+		return false
+	}
+	if !f.After.IsZero() && !n.lastRepeated.After(f.After) {
+		return false
+	}
+	return true
+}
+
+func noticeTypeIn(types []NoticeType, t NoticeType) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+func stringIn(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Notices returns the list of notices that match the filter (if any),
+// ordered by the last-repeated time.
+func (s *State) Notices(filter *NoticeFilter) []*Notice {
+	s.reading()
+
+	now := time.Now()
+	var notices []*Notice
+	for _, n := range s.notices {
+		if n.expired(now) || !filter.matches(n) {
+			continue
+		}
+		notices = append(notices, n)
+	}
+	sort.Slice(notices, func(i, j int) bool {
+		return notices[i].lastRepeated.Before(notices[j].lastRepeated)
+	})
+	return notices
+}
+
+// Notice returns a single notice by ID, or nil if not found.
+func (s *State) Notice(id string) *Notice {
+	s.reading()
+
+	for _, n := range s.notices {
+		if n.id == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// WaitNotices waits for notices that match the filter to exist or occur,
+// returning them as soon as there are any. It returns the context's error
+// if the context is done first. It must be called with the state locked,
+// and unlocks it while waiting.
+func (s *State) WaitNotices(ctx context.Context, filter *NoticeFilter) ([]*Notice, error) {
+	s.reading()
+
+	notices := s.Notices(filter)
+	if len(notices) > 0 {
+		return notices, nil
+	}
+
+	// Wake up the waiters when the context is done, so this one returns.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Lock()
+			s.noticeCond.Broadcast()
+			s.unlock()
+		case <-done:
+		}
+	}()
+
+	for {
+		s.noticeCond.Wait()
+		notices = s.Notices(filter)
+		if len(notices) > 0 {
+			return notices, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// flattenNotices returns the non-expired notices as a flat list, for
+// serialising. Call with the lock held.
+func (s *State) flattenNotices() []*Notice {
+	now := time.Now()
+	flat := make([]*Notice, 0, len(s.notices))
+	for _, n := range s.notices {
+		if n.expired(now) {
+			continue
+		}
+		flat = append(flat, n)
+	}
+	return flat
+}
+
+// unflattenNotices replaces the notices map with the given flat list,
+// ignoring expired notices. Call with the lock held.
+func (s *State) unflattenNotices(flat []*Notice) {
+	now := time.Now()
+	s.notices = make(map[noticeKey]*Notice, len(flat))
+	for _, n := range flat {
+		if n.expired(now) {
+			continue
+		}
+		s.notices[noticeKey{n.noticeType, n.key}] = n
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package state_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/overlord/state"
+)
+
+type noticesSuite struct{}
+
+var _ = Suite(&noticesSuite{})
+
+func (s *noticesSuite) TestValidateNotice(c *C) {
+	c.Check(state.ValidateNotice(state.CustomNotice, "example.com/foo"), IsNil)
+	c.Check(state.ValidateNotice(state.CustomNotice, "a.b/c/d-e"), IsNil)
+	for _, key := range []string{"", "foo", "example.com", "/foo", "Example.com/foo", "example.com/foo/"} {
+		c.Check(state.ValidateNotice(state.CustomNotice, key), ErrorMatches, `custom notice key must be in the form "domain.com/key", not ".*"`, Commentf("key %q", key))
+	}
+	c.Check(state.ValidateNotice("foo", "example.com/foo"), ErrorMatches, `invalid notice type "foo"`)
+}
+
+func (s *noticesSuite) TestAddNotice(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	t1 := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	id1 := st.AddNotice(state.CustomNotice, "example.com/foo", &state.AddNoticeOptions{Time: t1})
+	id2 := st.AddNotice(state.CustomNotice, "example.com/bar", &state.AddNoticeOptions{Time: t1.Add(time.Second)})
+	c.Check(id1, Equals, "1")
+	c.Check(id2, Equals, "2")
+
+	// Adding the same notice again updates it rather than adding a new one.
+	id := st.AddNotice(state.CustomNotice, "example.com/foo", &state.AddNoticeOptions{
+		Time: t1.Add(2 * time.Second),
+		Data: map[string]string{"k": "v"},
+	})
+	c.Check(id, Equals, id1)
+
+	notices := st.Notices(nil)
+	c.Assert(notices, HasLen, 2)
+	c.Check(notices[0].ID(), Equals, id2)
+	c.Check(notices[1].ID(), Equals, id1)
+
+	n := noticeToMap(c, st.Notice(id1))
+	c.Check(n, DeepEquals, map[string]interface{}{
+		"id":             "1",
+		"type":           "custom",
+		"key":            "example.com/foo",
+		"first-occurred": t1.Format(time.RFC3339),
+		"last-occurred":  t1.Add(2 * time.Second).Format(time.RFC3339),
+		"last-repeated":  t1.Add(2 * time.Second).Format(time.RFC3339),
+		"occurrences":    2.0,
+		"last-data":      map[string]interface{}{"k": "v"},
+		"expire-after":   "168h0m0s",
+	})
+	c.Check(st.Notice("3"), IsNil)
+}
+
+func (s *noticesSuite) TestRepeatAfter(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	t1 := time.Now().UTC().Truncate(time.Second).Add(-3 * time.Hour)
+	options := &state.AddNoticeOptions{Time: t1, RepeatAfter: time.Hour}
+	id := st.AddNotice(state.CustomNotice, "example.com/foo", options)
+
+	// Within repeat-after, the notice occurs but isn't repeated.
+	options.Time = t1.Add(time.Minute)
+	st.AddNotice(state.CustomNotice, "example.com/foo", options)
+	n := noticeToMap(c, st.Notice(id))
+	c.Check(n["occurrences"], Equals, 2.0)
+	c.Check(n["last-occurred"], Equals, t1.Add(time.Minute).Format(time.RFC3339))
+	c.Check(n["last-repeated"], Equals, t1.Format(time.RFC3339))
+	c.Check(n["repeat-after"], Equals, "1h0m0s")
+
+	// After repeat-after, it's repeated.
+	options.Time = t1.Add(2 * time.Hour)
+	st.AddNotice(state.CustomNotice, "example.com/foo", options)
+	n = noticeToMap(c, st.Notice(id))
+	c.Check(n["occurrences"], Equals, 3.0)
+	c.Check(n["last-repeated"], Equals, t1.Add(2*time.Hour).Format(time.RFC3339))
+}
+
+func (s *noticesSuite) TestExpireAfter(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	id := st.AddNotice(state.CustomNotice, "example.com/foo", &state.AddNoticeOptions{
+		ExpireAfter: time.Hour,
+	})
+	n := noticeToMap(c, st.Notice(id))
+	c.Check(n["expire-after"], Equals, "1h0m0s")
+
+	// A zero expire-after leaves the existing value alone.
+	st.AddNotice(state.CustomNotice, "example.com/foo", nil)
+	n = noticeToMap(c, st.Notice(id))
+	c.Check(n["occurrences"], Equals, 2.0)
+	c.Check(n["expire-after"], Equals, "1h0m0s")
+
+	// A new notice gets the default.
+	id = st.AddNotice(state.CustomNotice, "example.com/bar", nil)
+	n = noticeToMap(c, st.Notice(id))
+	c.Check(n["expire-after"], Equals, "168h0m0s")
+}
+
+func (s *noticesSuite) TestNoticesFilter(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	t1 := time.Now().Add(-time.Hour)
+	st.AddNotice(state.CustomNotice, "example.com/foo", &state.AddNoticeOptions{Time: t1})
+	st.AddNotice(state.CustomNotice, "example.com/bar", &state.AddNoticeOptions{Time: t1.Add(time.Minute)})
+
+	notices := st.Notices(&state.NoticeFilter{Keys: []string{"example.com/bar"}})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "example.com/bar")
+
+	notices = st.Notices(&state.NoticeFilter{Types: []state.NoticeType{state.CustomNotice}})
+	c.Check(notices, HasLen, 2)
+	notices = st.Notices(&state.NoticeFilter{Types: []state.NoticeType{"other"}})
+	c.Check(notices, HasLen, 0)
+
+	notices = st.Notices(&state.NoticeFilter{After: t1})
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "example.com/bar")
+}
+
+func (s *noticesSuite) TestWaitNotices(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		st.Lock()
+		st.AddNotice(state.CustomNotice, "example.com/other", nil)
+		st.AddNotice(state.CustomNotice, "example.com/foo", nil)
+		st.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	notices, err := st.WaitNotices(ctx, &state.NoticeFilter{Keys: []string{"example.com/foo"}})
+	c.Assert(err, IsNil)
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "example.com/foo")
+
+	// Existing notices are returned straight away.
+	notices, err = st.WaitNotices(ctx, nil)
+	c.Assert(err, IsNil)
+	c.Check(notices, HasLen, 2)
+}
+
+func (s *noticesSuite) TestWaitNoticesTimeout(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	notices, err := st.WaitNotices(ctx, nil)
+	c.Check(err, Equals, context.DeadlineExceeded)
+	c.Check(notices, HasLen, 0)
+}
+
+func (s *noticesSuite) TestReadStatePrune(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	st.AddNotice(state.CustomNotice, "example.com/old", &state.AddNoticeOptions{
+		Time: time.Now().Add(-8 * 24 * time.Hour),
+	})
+	id := st.AddNotice(state.CustomNotice, "example.com/new", nil)
+	buf, err := json.Marshal(st)
+	st.Unlock()
+	c.Assert(err, IsNil)
+
+	// Expired notices aren't serialised, but the last ID is kept.
+	st2, err := state.ReadState(nil, bytes.NewReader(buf))
+	c.Assert(err, IsNil)
+	st2.Lock()
+	defer st2.Unlock()
+	notices := st2.Notices(nil)
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].ID(), Equals, id)
+	c.Check(st2.AddNotice(state.CustomNotice, "example.com/another", nil), Equals, "3")
+
+	st2.AddNotice(state.CustomNotice, "example.com/expiring", &state.AddNoticeOptions{
+		Time: time.Now().Add(-8 * 24 * time.Hour),
+	})
+	st2.Prune(time.Hour, time.Hour, 100)
+	buf, err = json.Marshal(st2)
+	c.Assert(err, IsNil)
+	c.Check(bytes.Contains(buf, []byte("example.com/expiring")), Equals, false)
+}
+
+func noticeToMap(c *C, notice *state.Notice) map[string]interface{} {
+	buf, err := json.Marshal(notice)
+	c.Assert(err, IsNil)
+	var n map[string]interface{}
+	err = json.Unmarshal(buf, &n)
+	c.Assert(err, IsNil)
+	return n
+}
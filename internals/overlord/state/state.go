@@ -87,29 +87,74 @@ type State struct {
 	lastTaskId   int
 	lastChangeId int
 	lastLaneId   int
+	lastNoticeId int
 
 	backend  Backend
 	data     customData
 	changes  map[string]*Change
 	tasks    map[string]*Task
 	warnings map[string]*Warning
+	notices  map[noticeKey]*Notice
+
+	noticeCond *sync.Cond
 
 	modified bool
 
 	cache map[interface{}]interface{}
+
+	taskHandlers    []TaskStatusChangedFunc
+	changeHandlers  []ChangeStatusChangedFunc
+	warningHandlers []WarningAddedFunc
 }
 
 // New returns a new empty state.
 func New(backend Backend) *State {
-	return &State{
+	s := &State{
 		backend:  backend,
 		data:     make(customData),
 		changes:  make(map[string]*Change),
 		tasks:    make(map[string]*Task),
 		warnings: make(map[string]*Warning),
+		notices:  make(map[noticeKey]*Notice),
 		modified: true,
 		cache:    make(map[interface{}]interface{}),
 	}
+	s.noticeCond = sync.NewCond(stateLocker{s})
+	return s
+}
+
+// TaskStatusChangedFunc is the type of function called when a task's status
+// changes.
+type TaskStatusChangedFunc func(t *Task, old, new Status)
+
+// ChangeStatusChangedFunc is the type of function called when a change's
+// status changes.
+type ChangeStatusChangedFunc func(chg *Change, old, new Status)
+
+// WarningAddedFunc is the type of function called when a warning is added.
+type WarningAddedFunc func(message string)
+
+// AddTaskStatusChangedHandler adds f to the list of functions called when a
+// task's status changes. The functions are called with the state locked.
+func (s *State) AddTaskStatusChangedHandler(f TaskStatusChangedFunc) {
+	s.reading()
+	s.taskHandlers = append(s.taskHandlers, f)
+}
+
+// AddChangeStatusChangedHandler adds f to the list of functions called when
+// a change's status changes, either because it was set explicitly or
+// because one of its tasks' status changed. The functions are called with
+// the state locked.
+func (s *State) AddChangeStatusChangedHandler(f ChangeStatusChangedFunc) {
+	s.reading()
+	s.changeHandlers = append(s.changeHandlers, f)
+}
+
+// AddWarningHandler adds f to the list of functions called when a warning
+// is added (or added again). The functions are called with the state locked.
+func (s *State) AddWarningHandler(f WarningAddedFunc) {
+	s.reading()
+	s.warningHandlers = append(s.warningHandlers, f)
 }
 
 // Modified returns whether the state was modified since the last checkpoint.
@@ -141,15 +186,31 @@ func (s *State) unlock() {
 	s.mu.Unlock()
 }
 
+// stateLocker is the sync.Locker used by waiters on the state, so that
+// waiting releases the lock without checkpointing.
+type stateLocker struct {
+	s *State
+}
+
+func (l stateLocker) Lock() {
+	l.s.Lock()
+}
+
+func (l stateLocker) Unlock() {
+	l.s.unlock()
+}
+
 type marshalledState struct {
 	Data     map[string]*json.RawMessage `json:"data"`
 	Changes  map[string]*Change          `json:"changes"`
 	Tasks    map[string]*Task            `json:"tasks"`
 	Warnings []*Warning                  `json:"warnings,omitempty"`
+	Notices  []*Notice                   `json:"notices,omitempty"`
 
 	LastChangeId int `json:"last-change-id"`
 	LastTaskId   int `json:"last-task-id"`
 	LastLaneId   int `json:"last-lane-id"`
+	LastNoticeId int `json:"last-notice-id,omitempty"`
 }
 
 // MarshalJSON makes State a json.Marshaller
@@ -160,10 +221,12 @@ func (s *State) MarshalJSON() ([]byte, error) {
 		Changes:  s.changes,
 		Tasks:    s.tasks,
 		Warnings: s.flattenWarnings(),
+		Notices:  s.flattenNotices(),
 
 		LastTaskId:   s.lastTaskId,
 		LastChangeId: s.lastChangeId,
 		LastLaneId:   s.lastLaneId,
+		LastNoticeId: s.lastNoticeId,
 	})
 }
 
@@ -179,9 +242,11 @@ func (s *State) UnmarshalJSON(data []byte) error {
 	s.changes = unmarshalled.Changes
 	s.tasks = unmarshalled.Tasks
 	s.unflattenWarnings(unmarshalled.Warnings)
+	s.unflattenNotices(unmarshalled.Notices)
 	s.lastChangeId = unmarshalled.LastChangeId
 	s.lastTaskId = unmarshalled.LastTaskId
 	s.lastLaneId = unmarshalled.LastLaneId
+	s.lastNoticeId = unmarshalled.LastNoticeId
 	// backlink state again
 	for _, t := range s.tasks {
 		t.state = s
@@ -357,6 +422,22 @@ func (s *State) tasksIn(tids []string) []*Task {
 	return res
 }
 
+// PruneOptions configures how PruneWithOptions prunes the state.
+type PruneOptions struct {
+	// Wait is how long changes are kept after becoming ready.
+	Wait time.Duration
+
+	// AbortWait is how long after being spawned changes that aren't
+	// ready are aborted.
+	AbortWait time.Duration
+
+	// MaxReadyChanges is the maximum number of ready changes kept.
+	MaxReadyChanges int
+
+	// KindWaits overrides Wait for changes of the given kinds.
+	KindWaits map[string]time.Duration
+}
+
 // Prune does several cleanup tasks to the in-memory state:
 //
 //   - it removes changes that became ready for more than pruneWait and aborts
@@ -364,12 +445,27 @@ func (s *State) tasksIn(tids []string) []*Task {
 //   - it removes tasks unlinked to changes after pruneWait. When there are more
 //     changes than the limit set via "maxReadyChanges" those changes in ready
 //     state will also removed even if they are below the pruneWait duration.
-//   - it removes expired warnings.
+//   - it removes expired warnings and notices.
 func (s *State) Prune(pruneWait, abortWait time.Duration, maxReadyChanges int) {
-	now := time.Now()
-	pruneLimit := now.Add(-pruneWait)
-	abortLimit := now.Add(-abortWait)
+	s.PruneWithOptions(&PruneOptions{
+		Wait:            pruneWait,
+		AbortWait:       abortWait,
+		MaxReadyChanges: maxReadyChanges,
+	})
+}
 
+// PruneWithOptions is like Prune, but also allows the prune wait to be set
+// for each change kind.
+func (s *State) PruneWithOptions(opts *PruneOptions) {
+	now := time.Now()
+	pruneLimit := now.Add(-opts.Wait)
+	abortLimit := now.Add(-opts.AbortWait)
+	kindPruneLimit := func(kind string) time.Time {
+		if wait, ok := opts.KindWaits[kind]; ok {
+			return now.Add(-wait)
+		}
+		return pruneLimit
+	}
 	// sort from oldest to newest
 	changes := s.Changes()
 	sort.Sort(byReadyTime(changes))
@@ -392,11 +488,18 @@ func (s *State) Prune(pruneWait, abortWait time.Duration, maxReadyChanges int) {
 		}
 	}
 
+	for k, n := range s.notices {
+		if n.expired(now) {
+			delete(s.notices, k)
+		}
+	}
+
 	for _, chg := range changes {
 		spawnTime := chg.SpawnTime()
 		readyTime := chg.ReadyTime()
+		chgPruneLimit := kindPruneLimit(chg.Kind())
 		if readyTime.IsZero() {
-			if spawnTime.Before(pruneLimit) && len(chg.Tasks()) == 0 {
+			if spawnTime.Before(chgPruneLimit) && len(chg.Tasks()) == 0 {
 				chg.Abort()
 				delete(s.changes, chg.ID())
 			} else if spawnTime.Before(abortLimit) {
@@ -405,7 +508,7 @@ func (s *State) Prune(pruneWait, abortWait time.Duration, maxReadyChanges int) {
 			continue
 		}
 		// change old or we have too many changes
-		if readyTime.Before(pruneLimit) || readyChangesCount > maxReadyChanges {
+		if readyTime.Before(chgPruneLimit) || readyChangesCount > opts.MaxReadyChanges {
 			s.writing()
 			for _, t := range chg.Tasks() {
 				delete(s.tasks, t.ID())
@@ -424,9 +527,114 @@ func (s *State) Prune(pruneWait, abortWait time.Duration, maxReadyChanges int) {
 	}
 }
 
+// RemoveChange removes the change and all its tasks from the state,
+// whatever their status, along with any references to the tasks from other
+// tasks. It's meant for getting rid of changes that are stuck. Running tasks
+// are not stopped: use TaskRunner.RemoveChange to stop them first.
+func (s *State) RemoveChange(chg *Change) {
+	s.writing()
+	removed := make(map[string]bool, len(chg.taskIDs))
+	for _, tid := range chg.taskIDs {
+		removed[tid] = true
+		delete(s.tasks, tid)
+	}
+	for _, t := range s.tasks {
+		t.waitTasks = withoutIDs(t.waitTasks, removed)
+		t.haltTasks = withoutIDs(t.haltTasks, removed)
+	}
+	delete(s.changes, chg.ID())
+}
+
+func withoutIDs(ids []string, removed map[string]bool) []string {
+	var kept []string
+	for _, id := range ids {
+		if !removed[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// CheckIntegrity checks that the changes, tasks and lanes in the state
+// refer to each other consistently, and returns a description of each
+// problem found, sorted.
+func (s *State) CheckIntegrity() []string {
+	s.reading()
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	beyondLast := func(id string, last int) bool {
+		n, err := strconv.Atoi(id)
+		return err != nil || n > last
+	}
+
+	for id, chg := range s.changes {
+		if chg.id != id {
+			addProblem("change %s is stored with ID %s", chg.id, id)
+		}
+		if beyondLast(id, s.lastChangeId) {
+			addProblem("change %s has an ID beyond the last change ID %d", id, s.lastChangeId)
+		}
+		for _, tid := range chg.taskIDs {
+			t, ok := s.tasks[tid]
+			if !ok {
+				addProblem("change %s refers to missing task %s", id, tid)
+			} else if t.change != id {
+				addProblem("change %s includes task %s, which refers to change %q", id, tid, t.change)
+			}
+		}
+	}
+
+	for id, t := range s.tasks {
+		if t.id != id {
+			addProblem("task %s is stored with ID %s", t.id, id)
+		}
+		if beyondLast(id, s.lastTaskId) {
+			addProblem("task %s has an ID beyond the last task ID %d", id, s.lastTaskId)
+		}
+		if t.change != "" {
+			chg, ok := s.changes[t.change]
+			if !ok {
+				addProblem("task %s refers to missing change %s", id, t.change)
+			} else if !containsID(chg.taskIDs, id) {
+				addProblem("task %s refers to change %s, which doesn't include it", id, t.change)
+			}
+		}
+		for _, wid := range t.waitTasks {
+			if _, ok := s.tasks[wid]; !ok {
+				addProblem("task %s waits for missing task %s", id, wid)
+			}
+		}
+		for _, hid := range t.haltTasks {
+			if _, ok := s.tasks[hid]; !ok {
+				addProblem("task %s halts missing task %s", id, hid)
+			}
+		}
+		for _, lane := range t.lanes {
+			if lane <= 0 || lane > s.lastLaneId {
+				addProblem("task %s is in lane %d, beyond the last lane ID %d", id, lane, s.lastLaneId)
+			}
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+func containsID(ids []string, id string) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
 // ReadState returns the state deserialized from r.
 func ReadState(backend Backend, r io.Reader) (*State, error) {
 	s := new(State)
+	s.noticeCond = sync.NewCond(stateLocker{s})
 	s.Lock()
 	defer s.unlock()
 	d := json.NewDecoder(r)
@@ -680,6 +680,7 @@ func (ss *stateSuite) TestMethodEntrance(c *C) {
 
 	// Reset modified flag.
 	st.Lock()
+	chg := st.NewChange("install", "...")
 	st.Unlock()
 
 	writes := []func(){
@@ -691,6 +692,11 @@ func (ss *stateSuite) TestMethodEntrance(c *C) {
 		func() { st.Warnf("hello") },
 		func() { st.OkayWarnings(time.Time{}) },
 		func() { st.UnshowAllWarnings() },
+		func() { st.AddNotice(state.CustomNotice, "a.b/c", nil) },
+		func() { st.RemoveChange(chg) },
+		func() { st.Warn("hello", nil) },
+		func() { st.OkayWarning("hello") },
+		func() { st.RemoveWarning("hello") },
 	}
 
 	reads := []func(){
@@ -703,10 +709,17 @@ func (ss *stateSuite) TestMethodEntrance(c *C) {
 		func() { st.Task("foo") },
 		func() { st.MarshalJSON() },
 		func() { st.Prune(time.Hour, time.Hour, 100) },
+		func() { st.PruneWithOptions(&state.PruneOptions{}) },
+		func() { st.CheckIntegrity() },
 		func() { st.TaskCount() },
 		func() { st.AllWarnings() },
 		func() { st.PendingWarnings() },
 		func() { st.WarningsSummary() },
+		func() { st.AddTaskStatusChangedHandler(nil) },
+		func() { st.AddChangeStatusChangedHandler(nil) },
+		func() { st.AddWarningHandler(nil) },
+		func() { st.Notices(nil) },
+		func() { st.Notice("1") },
 	}
 
 	for i, f := range reads {
@@ -920,6 +933,33 @@ func (ss *stateSuite) TestPruneMaxChangesHonored(c *C) {
 	c.Assert(st.Changes(), HasLen, 11)
 }
 
+func (ss *stateSuite) TestPruneKindWaits(c *C) {
+	st := state.New(&fakeStateBackend{})
+	st.Lock()
+	defer st.Unlock()
+
+	now := time.Now()
+	for _, kind := range []string{"exec", "start", "stop"} {
+		chg := st.NewChange(kind, "...")
+		t := st.NewTask("foo", "...")
+		chg.AddTask(t)
+		t.SetStatus(state.DoneStatus)
+		state.FakeChangeTimes(chg, now.Add(-3*time.Hour), now.Add(-2*time.Hour))
+	}
+
+	st.PruneWithOptions(&state.PruneOptions{
+		Wait:            time.Hour,
+		AbortWait:       24 * time.Hour,
+		MaxReadyChanges: 100,
+		KindWaits: map[string]time.Duration{
+			"start": 3 * time.Hour,
+			"stop":  time.Minute,
+		},
+	})
+	c.Assert(st.Changes(), HasLen, 1)
+	c.Check(st.Changes()[0].Kind(), Equals, "start")
+}
+
 func (ss *stateSuite) TestReadStateInitsCache(c *C) {
 	st, err := state.ReadState(nil, bytes.NewBufferString("{}"))
 	c.Assert(err, IsNil)
@@ -929,3 +969,79 @@ func (ss *stateSuite) TestReadStateInitsCache(c *C) {
 	st.Cache("key", "value")
 	c.Assert(st.Cached("key"), Equals, "value")
 }
+
+func (ss *stateSuite) TestRemoveChange(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg1 := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "...")
+	t2 := st.NewTask("install", "...")
+	t2.WaitFor(t1)
+	chg1.AddTask(t1)
+	chg1.AddTask(t2)
+	t1.SetStatus(state.DoingStatus)
+
+	chg2 := st.NewChange("remove", "...")
+	t3 := st.NewTask("remove", "...")
+	t3.WaitFor(t2)
+	t1.WaitFor(t3)
+	chg2.AddTask(t3)
+
+	st.RemoveChange(chg1)
+
+	c.Check(st.Changes(), DeepEquals, []*state.Change{chg2})
+	c.Check(st.Tasks(), DeepEquals, []*state.Task{t3})
+	c.Check(t3.WaitTasks(), HasLen, 0)
+	c.Check(t3.HaltTasks(), HasLen, 0)
+	c.Check(st.CheckIntegrity(), HasLen, 0)
+}
+
+func (ss *stateSuite) TestCheckIntegrity(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "...")
+	t2 := st.NewTask("install", "...")
+	t2.WaitFor(t1)
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+	lane := st.NewLane()
+	t1.JoinLane(lane)
+	c.Check(st.CheckIntegrity(), HasLen, 0)
+	st.Unlock()
+
+	st, err := state.ReadState(nil, bytes.NewBufferString(`{
+		"data": {},
+		"changes": {
+			"1": {"id": "1", "kind": "install", "status": 2, "task-ids": ["1", "2", "9"]},
+			"2": {"id": "3", "kind": "remove", "status": 2, "task-ids": ["3"]}
+		},
+		"tasks": {
+			"1": {"id": "1", "kind": "download", "change": "1", "halt-tasks": ["2", "8"]},
+			"2": {"id": "2", "kind": "install", "change": "2", "wait-tasks": ["7"], "lanes": [1, 5]},
+			"3": {"id": "3", "kind": "remove", "change": "4"},
+			"4": {"id": "4", "kind": "orphan", "change": "1"}
+		},
+		"last-change-id": 2,
+		"last-task-id": 3,
+		"last-lane-id": 1
+	}`))
+	c.Assert(err, IsNil)
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.CheckIntegrity(), DeepEquals, []string{
+		`change 1 includes task 2, which refers to change "2"`,
+		"change 1 refers to missing task 9",
+		`change 2 includes task 3, which refers to change "4"`,
+		"change 3 is stored with ID 2",
+		"task 1 halts missing task 8",
+		"task 2 is in lane 5, beyond the last lane ID 1",
+		"task 2 refers to change 2, which doesn't include it",
+		"task 2 waits for missing task 7",
+		"task 3 refers to missing change 4",
+		"task 4 has an ID beyond the last task ID 3",
+		"task 4 refers to change 1, which doesn't include it",
+	})
+}
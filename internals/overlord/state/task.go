@@ -56,11 +56,13 @@ type Task struct {
 	readyTime time.Time
 
 	// TODO: add:
-	// {,Un}DoingRetries - number of retries
 	// Retry{,Un}DoingTimes - time spend to figure out a retry is needed
 	doingTime   time.Duration
 	undoingTime time.Duration
 
+	retries       int
+	lastRetryTime time.Time
+
 	atTime time.Time
 }
 
@@ -96,6 +98,9 @@ type marshalledTask struct {
 	DoingTime   time.Duration `json:"doing-time,omitempty"`
 	UndoingTime time.Duration `json:"undoing-time,omitempty"`
 
+	Retries       int        `json:"retries,omitempty"`
+	LastRetryTime *time.Time `json:"last-retry-time,omitempty"`
+
 	AtTime *time.Time `json:"at-time,omitempty"`
 }
 
@@ -110,6 +115,10 @@ func (t *Task) MarshalJSON() ([]byte, error) {
 	if !t.atTime.IsZero() {
 		atTime = &t.atTime
 	}
+	var lastRetryTime *time.Time
+	if !t.lastRetryTime.IsZero() {
+		lastRetryTime = &t.lastRetryTime
+	}
 	return json.Marshal(marshalledTask{
 		ID:        t.id,
 		Kind:      t.kind,
@@ -130,6 +139,9 @@ func (t *Task) MarshalJSON() ([]byte, error) {
 		DoingTime:   t.doingTime,
 		UndoingTime: t.undoingTime,
 
+		Retries:       t.retries,
+		LastRetryTime: lastRetryTime,
+
 		AtTime: atTime,
 	})
 }
@@ -169,6 +181,10 @@ func (t *Task) UnmarshalJSON(data []byte) error {
 	}
 	t.doingTime = unmarshalled.DoingTime
 	t.undoingTime = unmarshalled.UndoingTime
+	t.retries = unmarshalled.Retries
+	if unmarshalled.LastRetryTime != nil {
+		t.lastRetryTime = *unmarshalled.LastRetryTime
+	}
 	return nil
 }
 
@@ -200,14 +216,29 @@ func (t *Task) Status() Status {
 func (t *Task) SetStatus(new Status) {
 	t.state.writing()
 	old := t.status
+	chg := t.Change()
+	var oldChgStatus Status
+	if chg != nil && len(t.state.changeHandlers) > 0 {
+		oldChgStatus = chg.Status()
+	}
 	t.status = new
 	if !old.Ready() && new.Ready() {
 		t.readyTime = timeNow()
 	}
-	chg := t.Change()
 	if chg != nil {
 		chg.taskStatusChanged(t, old, new)
 	}
+	if old == DefaultStatus {
+		old = DoStatus
+	}
+	if old != new {
+		for _, f := range t.state.taskHandlers {
+			f(t, old, new)
+		}
+	}
+	if chg != nil && len(t.state.changeHandlers) > 0 {
+		chg.notifyStatusChanged(oldChgStatus)
+	}
 }
 
 // IsClean returns whether the task has been cleaned. See SetClean.
@@ -310,6 +341,46 @@ func (t *Task) UndoingTime() time.Duration {
 	return t.undoingTime
 }
 
+// Retries returns the number of times the task has been retried, either
+// because its handler asked to be run again or because its change was
+// retried.
+func (t *Task) Retries() int {
+	t.state.reading()
+	return t.retries
+}
+
+// LastRetryTime returns the time the task was last retried, or the zero time
+// if it hasn't been retried.
+func (t *Task) LastRetryTime() time.Time {
+	t.state.reading()
+	return t.lastRetryTime
+}
+
+func (t *Task) recordRetry() {
+	t.state.writing()
+	t.retries++
+	t.lastRetryTime = timeNow()
+}
+
+// Abort flags the task, and the tasks that wait for it, for cancellation,
+// in the same way Change.Abort does for all of a change's tasks. Unlike
+// Change.AbortLanes, other tasks in the task's lanes aren't aborted.
+// Cancellation will proceed at the next ensure pass.
+func (t *Task) Abort() {
+	t.state.writing()
+	seen := make(map[string]bool)
+	tasks := []*Task{t}
+	for i := 0; i < len(tasks); i++ {
+		task := tasks[i]
+		if seen[task.id] {
+			continue
+		}
+		seen[task.id] = true
+		abortTask(task)
+		tasks = append(tasks, task.HaltTasks()...)
+	}
+}
+
 const (
 	// Messages logged in tasks are guaranteed to use the time formatted
 	// per RFC3339 plus the following strings as a prefix, so these may
@@ -412,6 +412,7 @@ func (cs *taskSuite) TestMethodEntrance(c *C) {
 		func() { t1.JoinLane(1) },
 		func() { t1.AccumulateDoingTime(1) },
 		func() { t1.AccumulateUndoingTime(2) },
+		func() { t1.Abort() },
 	}
 
 	reads := []func(){
@@ -428,6 +429,8 @@ func (cs *taskSuite) TestMethodEntrance(c *C) {
 		func() { t1.Lanes() },
 		func() { t1.DoingTime() },
 		func() { t1.UndoingTime() },
+		func() { t1.Retries() },
+		func() { t1.LastRetryTime() },
 	}
 
 	for i, f := range reads {
@@ -630,3 +633,23 @@ func (cs *taskSuite) TestTaskAddAllWithEdges(c *C) {
 	err = ts2.AddAllWithEdges(tsWithDuplicatedEdge)
 	c.Assert(err, ErrorMatches, `cannot add taskset: duplicated edge "install"`)
 }
+
+func (ts *taskSuite) TestAbort(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "1...")
+	t2 := st.NewTask("install", "2...")
+	t3 := st.NewTask("other", "3...")
+	t2.WaitFor(t1)
+	chg.AddAll(state.NewTaskSet(t1, t2, t3))
+	t1.SetStatus(state.DoingStatus)
+
+	// Aborting a task aborts the tasks waiting for it, but not others.
+	t1.Abort()
+	c.Check(t1.Status(), Equals, state.AbortStatus)
+	c.Check(t2.Status(), Equals, state.HoldStatus)
+	c.Check(t3.Status(), Equals, state.DoStatus)
+}
@@ -20,6 +20,8 @@
 package state
 
 import (
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -227,6 +229,7 @@ func (r *TaskRunner) run(t *Task) {
 		switch x := err.(type) {
 		case *Retry:
 			// Handler asked to be called again later.
+			t.recordRetry()
 			// TODO Allow postponing retries past the next Ensure.
 			if t.Status() == AbortStatus {
 				// Would work without it but might take two ensures.
@@ -331,6 +334,18 @@ func (r *TaskRunner) tryUndo(t *Task) {
 	}
 }
 
+// Running returns the IDs of the tasks that are currently running, sorted.
+func (r *TaskRunner) Running() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.tombs))
+	for id := range r.tombs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // Ensure starts new goroutines for all known tasks with no pending
 // dependencies.
 // Note that Ensure will lock the state.
@@ -521,3 +536,61 @@ func (r *TaskRunner) StopKinds(kind ...string) {
 		r.mu.Lock()
 	}
 }
+
+// removeChangeTimeout is how long RemoveChange waits for a change's running
+// tasks to stop.
+var removeChangeTimeout = 10 * time.Second
+
+// RemoveChange stops the running tasks of the change with the given ID and
+// then removes the change and its tasks from the state (see
+// State.RemoveChange). It returns an error if there's no such change, or if
+// its tasks don't stop in time, in which case the change isn't removed.
+func (r *TaskRunner) RemoveChange(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		// Locks must be acquired in the same order everywhere:
+		// r.mu, r.state
+		r.state.Lock()
+		chg := r.state.Change(id)
+		if chg == nil {
+			r.state.Unlock()
+			return fmt.Errorf("cannot find change with id %q", id)
+		}
+		var tombs []*tomb.Tomb
+		for _, tid := range chg.taskIDs {
+			if tb, ok := r.tombs[tid]; ok {
+				tombs = append(tombs, tb)
+				tb.Kill(nil)
+			}
+		}
+		if len(tombs) == 0 {
+			// Ensure can't start any of its tasks while r.mu is held.
+			r.state.RemoveChange(chg)
+			r.state.Unlock()
+			return nil
+		}
+		r.state.Unlock()
+
+		// Ensure may have started more of the change's tasks by the time
+		// these have stopped, so go round again.
+		r.mu.Unlock()
+		timeout := time.After(removeChangeTimeout)
+		var err error
+		for _, tb := range tombs {
+			select {
+			case <-tb.Dead():
+			case <-timeout:
+				err = fmt.Errorf("cannot remove change %s: timed out waiting for its tasks to stop", id)
+			}
+			if err != nil {
+				break
+			}
+		}
+		r.mu.Lock()
+		if err != nil {
+			return err
+		}
+	}
+}
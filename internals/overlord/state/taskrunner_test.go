@@ -403,6 +403,38 @@ func (ts *taskRunnerSuite) TestStopHandlerJustFinishing(c *C) {
 	c.Check(t.UndoingTime(), Equals, time.Duration(0))
 }
 
+func (ts *taskRunnerSuite) TestRunning(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	ch := make(chan bool)
+	r.AddHandler("blocking", func(t *state.Task, tb *tomb.Tomb) error {
+		ch <- true
+		<-tb.Dying()
+		return nil
+	}, nil)
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("blocking", "...")
+	t2 := st.NewTask("blocking", "...")
+	t2.WaitFor(t1)
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+	st.Unlock()
+
+	c.Check(r.Running(), HasLen, 0)
+
+	r.Ensure()
+	<-ch
+	c.Check(r.Running(), DeepEquals, []string{t1.ID()})
+
+	r.Stop()
+	c.Check(r.Running(), HasLen, 0)
+}
+
 func (ts *taskRunnerSuite) TestStopKinds(c *C) {
 	sb := &stateBackend{}
 	st := state.New(sb)
@@ -448,6 +480,91 @@ func (ts *taskRunnerSuite) TestStopKinds(c *C) {
 	c.Check(t2.Status(), Equals, state.DoneStatus)
 }
 
+func (ts *taskRunnerSuite) TestRemoveChange(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	ch := make(chan bool)
+	r.AddHandler("error-on-stop", func(t *state.Task, tb *tomb.Tomb) error {
+		ch <- true
+		<-tb.Dying()
+		return errors.New("error at stop")
+	}, nil)
+	r.AddHandler("just-finish", func(t *state.Task, tb *tomb.Tomb) error {
+		return nil
+	}, nil)
+
+	st.Lock()
+	chg1 := st.NewChange("install", "...")
+	t1 := st.NewTask("error-on-stop", "...")
+	t1.JoinLane(st.NewLane())
+	chg1.AddTask(t1)
+	chg2 := st.NewChange("install", "...")
+	t2 := st.NewTask("just-finish", "...")
+	chg2.AddTask(t2)
+	st.Unlock()
+
+	r.Ensure()
+	<-ch
+	c.Check(r.Running(), DeepEquals, []string{t1.ID()})
+
+	err := r.RemoveChange(chg1.ID())
+	c.Assert(err, IsNil)
+	c.Check(r.Running(), HasLen, 0)
+
+	st.Lock()
+	c.Check(st.Change(chg1.ID()), IsNil)
+	c.Check(st.Task(t1.ID()), IsNil)
+	c.Check(st.CheckIntegrity(), HasLen, 0)
+	st.Unlock()
+
+	ensureChange(c, r, sb, chg2)
+	st.Lock()
+	c.Check(t2.Status(), Equals, state.DoneStatus)
+	st.Unlock()
+
+	err = r.RemoveChange(chg1.ID())
+	c.Check(err, ErrorMatches, `cannot find change with id "1"`)
+}
+
+func (ts *taskRunnerSuite) TestRemoveChangeTimeout(c *C) {
+	restore := state.FakeRemoveChangeTimeout(10 * time.Millisecond)
+	defer restore()
+
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+
+	ch := make(chan bool)
+	done := make(chan bool)
+	r.AddHandler("stuck", func(t *state.Task, tb *tomb.Tomb) error {
+		ch <- true
+		<-done
+		return nil
+	}, nil)
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t := st.NewTask("stuck", "...")
+	chg.AddTask(t)
+	st.Unlock()
+
+	r.Ensure()
+	<-ch
+
+	err := r.RemoveChange(chg.ID())
+	c.Check(err, ErrorMatches, `cannot remove change 1: timed out waiting for its tasks to stop`)
+
+	st.Lock()
+	c.Check(st.Change(chg.ID()), NotNil)
+	st.Unlock()
+
+	close(done)
+	r.Stop()
+}
+
 func (ts *taskRunnerSuite) TestErrorsOnStopAreRetried(c *C) {
 	sb := &stateBackend{}
 	st := state.New(sb)
@@ -553,6 +670,8 @@ func (ts *taskRunnerSuite) TestRetryAfterDuration(c *C) {
 	c.Check(sb.ensureBefore, Equals, 1*time.Minute)
 	schedule := t.AtTime()
 	c.Check(schedule.IsZero(), Equals, false)
+	c.Check(t.Retries(), Equals, 1)
+	c.Check(t.LastRetryTime().Equal(tock), Equals, true)
 
 	state.FakeTime(tock.Add(5 * time.Second))
 	sb.ensureBefore = time.Hour
@@ -69,6 +69,11 @@ func (w *Warning) String() string {
 	return w.message
 }
 
+// LastAdded returns the last time the warning was added.
+func (w *Warning) LastAdded() time.Time {
+	return w.lastAdded
+}
+
 func (w *Warning) MarshalJSON() ([]byte, error) {
 	jw := jsonWarning{
 		Message:     w.message,
@@ -191,6 +196,58 @@ func (s *State) Warnf(template string, args ...interface{}) {
 	}, time.Now().UTC())
 }
 
+// WarnOptions holds optional parameters for a Warn call.
+type WarnOptions struct {
+	// ExpireAfter is how long after it was last added the warning is
+	// dropped. If zero, DefaultExpireAfter is used.
+	ExpireAfter time.Duration
+
+	// RepeatAfter is how long after it was last shown the warning is shown
+	// again. If zero, DefaultRepeatAfter is used.
+	RepeatAfter time.Duration
+}
+
+// Warn records a warning with the given message, like Warnf, but returns
+// an error rather than panicking if the warning isn't valid. Non-zero
+// durations in opts replace those of an existing warning with the same
+// message.
+func (s *State) Warn(message string, opts *WarnOptions) error {
+	if opts == nil {
+		opts = &WarnOptions{}
+	}
+	if opts.ExpireAfter < 0 {
+		return fmt.Errorf("warning expire-after must not be negative")
+	}
+	if opts.RepeatAfter < 0 {
+		return fmt.Errorf("warning repeat-after must not be negative")
+	}
+	w := Warning{
+		message:     message,
+		firstAdded:  time.Now().UTC(),
+		expireAfter: DefaultExpireAfter,
+		repeatAfter: DefaultRepeatAfter,
+	}
+	if opts.ExpireAfter != 0 {
+		w.expireAfter = opts.ExpireAfter
+	}
+	if opts.RepeatAfter != 0 {
+		w.repeatAfter = opts.RepeatAfter
+	}
+	if err := w.validate(); err != nil {
+		return err
+	}
+
+	s.addWarning(w, w.firstAdded)
+	existing := s.warnings[message]
+	if opts.ExpireAfter != 0 {
+		existing.expireAfter = opts.ExpireAfter
+	}
+	if opts.RepeatAfter != 0 {
+		existing.repeatAfter = opts.RepeatAfter
+	}
+	return nil
+}
+
 func (s *State) addWarning(w Warning, t time.Time) {
 	s.writing()
 
@@ -204,6 +261,9 @@ func (s *State) addWarning(w Warning, t time.Time) {
 		s.warnings[w.message] = &w
 	}
 	s.warnings[w.message].lastAdded = t
+	for _, f := range s.warningHandlers {
+		f(w.message)
+	}
 }
 
 type byLastAdded []*Warning
@@ -290,3 +350,26 @@ func (s *State) UnshowAllWarnings() {
 		w.lastShown = time.Time{}
 	}
 }
+
+// OkayWarning marks the warning with the given message as shown now. It
+// returns false if there's no such warning.
+func (s *State) OkayWarning(message string) bool {
+	s.writing()
+	w, ok := s.warnings[message]
+	if !ok {
+		return false
+	}
+	w.lastShown = time.Now().UTC()
+	return true
+}
+
+// RemoveWarning removes the warning with the given message. It returns
+// false if there's no such warning.
+func (s *State) RemoveWarning(message string) bool {
+	s.writing()
+	if _, ok := s.warnings[message]; !ok {
+		return false
+	}
+	delete(s.warnings, message)
+	return true
+}
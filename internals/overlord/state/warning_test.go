@@ -265,3 +265,104 @@ func (stateSuite) TestShowAndOkayWithRepeats(c *check.C) {
 	c.Check(ws, check.HasLen, 1)
 	c.Check(fmt.Sprintf("%q", ws), check.Equals, `["hello"]`)
 }
+
+func (stateSuite) TestWarningHandler(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	var messages []string
+	st.AddWarningHandler(func(message string) {
+		messages = append(messages, message)
+	})
+	st.Warnf("hello %s", "world")
+	st.Warnf("hello %s", "world")
+	c.Check(messages, check.DeepEquals, []string{"hello world", "hello world"})
+}
+
+func (stateSuite) TestWarn(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	err := st.Warn("hello", &state.WarnOptions{
+		ExpireAfter: time.Hour,
+		RepeatAfter: time.Minute,
+	})
+	c.Assert(err, check.IsNil)
+	err = st.Warn("defaults", nil)
+	c.Assert(err, check.IsNil)
+
+	ws := st.AllWarnings()
+	c.Assert(ws, check.HasLen, 2)
+	var hello, defaults map[string]interface{}
+	c.Assert(unmarshalWarning(ws[0], &hello), check.IsNil)
+	c.Check(hello["message"], check.Equals, "hello")
+	c.Check(hello["expire-after"], check.Equals, "1h0m0s")
+	c.Check(hello["repeat-after"], check.Equals, "1m0s")
+	c.Assert(unmarshalWarning(ws[1], &defaults), check.IsNil)
+	c.Check(defaults["message"], check.Equals, "defaults")
+	c.Check(defaults["expire-after"], check.Equals, state.DefaultExpireAfter.String())
+	c.Check(defaults["repeat-after"], check.Equals, state.DefaultRepeatAfter.String())
+
+	// Adding it again updates the durations given.
+	err = st.Warn("hello", &state.WarnOptions{RepeatAfter: time.Second})
+	c.Assert(err, check.IsNil)
+	ws = st.AllWarnings()
+	c.Assert(ws, check.HasLen, 2)
+	c.Assert(unmarshalWarning(ws[1], &hello), check.IsNil)
+	c.Check(hello["message"], check.Equals, "hello")
+	c.Check(hello["expire-after"], check.Equals, "1h0m0s")
+	c.Check(hello["repeat-after"], check.Equals, "1s")
+}
+
+func (stateSuite) TestWarnErrors(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	c.Check(st.Warn("", nil), check.Equals, state.ErrNoWarningMessage)
+	c.Check(st.Warn(" hello ", nil), check.Equals, state.ErrBadWarningMessage)
+	c.Check(st.Warn("hello", &state.WarnOptions{ExpireAfter: -time.Second}), check.ErrorMatches,
+		"warning expire-after must not be negative")
+	c.Check(st.Warn("hello", &state.WarnOptions{RepeatAfter: -time.Second}), check.ErrorMatches,
+		"warning repeat-after must not be negative")
+	c.Check(st.AllWarnings(), check.HasLen, 0)
+}
+
+func (stateSuite) TestOkayWarning(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	st.Warnf("number one")
+	st.Warnf("number two")
+
+	c.Check(st.OkayWarning("number one"), check.Equals, true)
+	c.Check(st.OkayWarning("number three"), check.Equals, false)
+
+	ws, _ := st.PendingWarnings()
+	c.Check(fmt.Sprintf("%q", ws), check.Equals, `["number two"]`)
+	c.Check(st.AllWarnings(), check.HasLen, 2)
+}
+
+func (stateSuite) TestRemoveWarning(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	st.Warnf("number one")
+	st.Warnf("number two")
+
+	c.Check(st.RemoveWarning("number one"), check.Equals, true)
+	c.Check(st.RemoveWarning("number one"), check.Equals, false)
+
+	ws := st.AllWarnings()
+	c.Check(fmt.Sprintf("%q", ws), check.Equals, `["number two"]`)
+}
+
+func unmarshalWarning(w *state.Warning, v interface{}) error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/canonical/pebble/internals/logger"
 	"github.com/canonical/pebble/internals/overlord/state"
+	"github.com/canonical/pebble/internals/timing"
 )
 
 // StateManager is implemented by types responsible for observing
@@ -58,6 +59,9 @@ type StateEngine struct {
 	// managers in use
 	mgrLock  sync.Mutex
 	managers []StateManager
+
+	timingsLock sync.Mutex
+	lastEnsure  *timing.Span
 }
 
 // NewStateEngine returns a new state engine.
@@ -94,20 +98,36 @@ func (se *StateEngine) Ensure() error {
 	if se.stopped {
 		return fmt.Errorf("state engine already stopped")
 	}
+	timings := timing.Start("", "", map[string]string{"ensure": "state-engine"})
 	var errs []error
 	for _, m := range se.managers {
+		span := timings.StartNested(fmt.Sprintf("%T", m), "ensure manager")
 		err := m.Ensure()
+		span.Stop()
 		if err != nil {
 			logger.Noticef("state ensure error: %v", err)
 			errs = append(errs, err)
 		}
 	}
+	timings.Stop()
+	se.timingsLock.Lock()
+	se.lastEnsure = timings
+	se.timingsLock.Unlock()
 	if len(errs) != 0 {
 		return &ensureError{errs}
 	}
 	return nil
 }
 
+// LastEnsureTimings returns the timings of the most recent Ensure, or nil
+// if Ensure hasn't run yet. Only managers that took a noticeable time to
+// ensure are included as nested spans.
+func (se *StateEngine) LastEnsureTimings() *timing.Span {
+	se.timingsLock.Lock()
+	defer se.timingsLock.Unlock()
+	return se.lastEnsure
+}
+
 // AddManager adds the provided manager to take part in state operations.
 func (se *StateEngine) AddManager(m StateManager) {
 	se.mgrLock.Lock()
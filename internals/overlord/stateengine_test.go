@@ -16,6 +16,7 @@ package overlord_test
 
 import (
 	"errors"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -76,6 +77,34 @@ func (ses *stateEngineSuite) TestEnsure(c *C) {
 	c.Check(calls, DeepEquals, []string{"ensure:mgr1", "ensure:mgr2", "ensure:mgr1", "ensure:mgr2"})
 }
 
+type slowManager struct{}
+
+func (sm *slowManager) Ensure() error {
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func (ses *stateEngineSuite) TestLastEnsureTimings(c *C) {
+	s := state.New(nil)
+	se := overlord.NewStateEngine(s)
+
+	calls := []string{}
+	se.AddManager(&fakeManager{name: "mgr1", calls: &calls})
+	se.AddManager(&slowManager{})
+
+	c.Check(se.LastEnsureTimings(), IsNil)
+
+	err := se.Ensure()
+	c.Assert(err, IsNil)
+	timings := se.LastEnsureTimings()
+	c.Assert(timings, NotNil)
+	c.Check(timings.Tags, DeepEquals, map[string]string{"ensure": "state-engine"})
+	c.Check(timings.B >= uint64(10*time.Millisecond), Equals, true)
+	// Only the slow manager takes long enough to be included.
+	c.Assert(timings.Spans, HasLen, 1)
+	c.Check(timings.Spans[0].Label, Equals, "*overlord_test.slowManager")
+}
+
 func (ses *stateEngineSuite) TestEnsureError(c *C) {
 	s := state.New(nil)
 	se := overlord.NewStateEngine(s)
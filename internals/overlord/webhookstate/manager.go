@@ -0,0 +1,216 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package webhookstate delivers the webhooks configured in the plan: it POSTs
+// a JSON payload to each webhook's URL when a service exits unexpectedly, a
+// check goes up or down, or a warning is added.
+package webhookstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/plan"
+)
+
+// requestTimeout is the timeout for each attempt to deliver a webhook.
+var requestTimeout = 10 * time.Second
+
+// payload is the JSON body POSTed to a webhook's URL.
+type payload struct {
+	Time  time.Time         `json:"time"`
+	Event plan.WebhookEvent `json:"event"`
+
+	// Set for "service-exit" events.
+	Service  string `json:"service,omitempty"`
+	ExitCode *int   `json:"exit-code,omitempty"`
+	Logs     string `json:"logs,omitempty"`
+
+	// Set for "check" events: the check's name and new status ("up" or
+	// "down").
+	Check  string `json:"check,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// Set for "warning" events.
+	Message string `json:"message,omitempty"`
+}
+
+// WebhookManager delivers webhooks for the events they're configured for.
+type WebhookManager struct {
+	mutex    sync.Mutex
+	webhooks map[string]*plan.Webhook
+
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a new webhook manager.
+func NewManager() *WebhookManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookManager{
+		client: &http.Client{},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// PlanChanged handles updates to the plan (server configuration), updating
+// the webhooks that future events are delivered to.
+func (m *WebhookManager) PlanChanged(p *plan.Plan) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.webhooks = p.Webhooks
+}
+
+// ServiceExited triggers the "service-exit" webhooks. It doesn't block, so
+// it's safe to call while holding other locks.
+func (m *WebhookManager) ServiceExited(name string, exitCode int, lastLogs string) {
+	m.trigger(payload{
+		Event:    plan.WebhookServiceExit,
+		Service:  name,
+		ExitCode: &exitCode,
+		Logs:     lastLogs,
+	})
+}
+
+// CheckStatusChanged triggers the "check" webhooks. It doesn't block, so
+// it's safe to call while holding other locks.
+func (m *WebhookManager) CheckStatusChanged(name string, status string) {
+	m.trigger(payload{
+		Event:  plan.WebhookCheck,
+		Check:  name,
+		Status: status,
+	})
+}
+
+// WarningAdded triggers the "warning" webhooks. It doesn't block, so it's
+// safe to call while holding other locks.
+func (m *WebhookManager) WarningAdded(message string) {
+	m.trigger(payload{
+		Event:   plan.WebhookWarning,
+		Message: message,
+	})
+}
+
+// trigger starts delivering the payload to the webhooks configured for its
+// event, in the background.
+func (m *WebhookManager) trigger(p payload) {
+	p.Time = time.Now().UTC()
+	body, err := json.Marshal(p)
+	if err != nil {
+		logger.Noticef("Internal error: cannot marshal webhook payload: %v", err)
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.ctx.Err() != nil {
+		return // manager stopped
+	}
+	for _, webhook := range m.webhooks {
+		if !webhook.Triggers(p.Event) {
+			continue
+		}
+		m.wg.Add(1)
+		go func(webhook *plan.Webhook) {
+			defer m.wg.Done()
+			m.deliver(webhook, p.Event, body)
+		}(webhook)
+	}
+}
+
+// deliver POSTs body to the webhook's URL, retrying with exponential backoff
+// until it succeeds, the webhook's attempts are used up, or the manager is
+// stopped.
+func (m *WebhookManager) deliver(webhook *plan.Webhook, event plan.WebhookEvent, body []byte) {
+	delay := webhook.BackoffDelay.Value
+	for attempt := 1; ; attempt++ {
+		err := m.post(webhook, body)
+		if err == nil {
+			logger.Debugf("Delivered webhook %q %s event", webhook.Name, event)
+			return
+		}
+		if m.ctx.Err() != nil {
+			return
+		}
+		if attempt >= webhook.Attempts {
+			logger.Noticef("Cannot deliver webhook %q %s event after %d attempt(s): %v",
+				webhook.Name, event, attempt, err)
+			return
+		}
+		logger.Debugf("Cannot deliver webhook %q %s event, retrying in %s: %v",
+			webhook.Name, event, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		}
+		delay *= 2
+	}
+}
+
+func (m *WebhookManager) post(webhook *plan.Webhook, body []byte) error {
+	ctx, cancel := context.WithTimeout(m.ctx, requestTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for k, v := range webhook.Headers {
+		request.Header.Set(k, v)
+	}
+
+	response, err := m.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// Drain some of the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("non-2xx status code %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Ensure implements overlord.StateManager.
+func (m *WebhookManager) Ensure() error {
+	return nil
+}
+
+// Stop implements overlord.StateStopper and stops delivering webhooks,
+// cancelling any deliveries in progress.
+func (m *WebhookManager) Stop() {
+	m.mutex.Lock()
+	m.cancel()
+	m.mutex.Unlock()
+	m.wg.Wait()
+}
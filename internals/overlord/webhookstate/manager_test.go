@@ -0,0 +1,237 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package webhookstate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/logger"
+	"github.com/canonical/pebble/internals/plan"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type ManagerSuite struct{}
+
+var _ = Suite(&ManagerSuite{})
+
+type received struct {
+	path    string
+	headers http.Header
+	body    map[string]interface{}
+}
+
+// startServer starts a local HTTP server that sends the webhooks it receives
+// on the returned channel, and responds with the status codes returned by
+// status (called with the 1-based request number).
+func startServer(c *C, status func(n int) int) (*httptest.Server, chan received) {
+	ch := make(chan received, 10)
+	var n int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		c.Check(err, IsNil)
+		var body map[string]interface{}
+		c.Check(json.Unmarshal(data, &body), IsNil)
+		ch <- received{path: r.URL.Path, headers: r.Header, body: body}
+		w.WriteHeader(status(int(atomic.AddInt32(&n, 1))))
+	}))
+	return server, ch
+}
+
+func waitReceived(c *C, ch chan received) received {
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(10 * time.Second):
+		c.Fatalf("timed out waiting for webhook")
+		return received{}
+	}
+}
+
+func checkNoneReceived(c *C, ch chan received) {
+	select {
+	case r := <-ch:
+		c.Fatalf("unexpected webhook received: %v", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (s *ManagerSuite) TestDelivery(c *C) {
+	server, ch := startServer(c, func(int) int { return http.StatusOK })
+	defer server.Close()
+
+	mgr := NewManager()
+	defer mgr.Stop()
+	mgr.PlanChanged(&plan.Plan{
+		Webhooks: map[string]*plan.Webhook{
+			"hook1": {
+				Name:     "hook1",
+				URL:      server.URL + "/hook1",
+				Headers:  map[string]string{"Authorization": "Bearer xyz"},
+				Events:   []plan.WebhookEvent{plan.WebhookServiceExit, plan.WebhookWarning},
+				Attempts: 1,
+			},
+		},
+	})
+
+	mgr.ServiceExited("svc1", 2, "line 1\nline 2")
+	r := waitReceived(c, ch)
+	c.Check(r.path, Equals, "/hook1")
+	c.Check(r.headers.Get("Authorization"), Equals, "Bearer xyz")
+	c.Check(r.headers.Get("Content-Type"), Equals, "application/json")
+	_, err := time.Parse(time.RFC3339, r.body["time"].(string))
+	c.Check(err, IsNil)
+	delete(r.body, "time")
+	c.Check(r.body, DeepEquals, map[string]interface{}{
+		"event":     "service-exit",
+		"service":   "svc1",
+		"exit-code": 2.0,
+		"logs":      "line 1\nline 2",
+	})
+
+	// Check events aren't delivered to this webhook.
+	mgr.CheckStatusChanged("chk1", "down")
+	checkNoneReceived(c, ch)
+
+	mgr.WarningAdded("something happened")
+	r = waitReceived(c, ch)
+	delete(r.body, "time")
+	c.Check(r.body, DeepEquals, map[string]interface{}{
+		"event":   "warning",
+		"message": "something happened",
+	})
+
+	// After the plan changes, events go to the new webhooks.
+	mgr.PlanChanged(&plan.Plan{
+		Webhooks: map[string]*plan.Webhook{
+			"hook2": {
+				Name:     "hook2",
+				URL:      server.URL + "/hook2",
+				Attempts: 1,
+			},
+		},
+	})
+	mgr.CheckStatusChanged("chk1", "down")
+	r = waitReceived(c, ch)
+	c.Check(r.path, Equals, "/hook2")
+	delete(r.body, "time")
+	c.Check(r.body, DeepEquals, map[string]interface{}{
+		"event":  "check",
+		"check":  "chk1",
+		"status": "down",
+	})
+}
+
+func (s *ManagerSuite) TestRetry(c *C) {
+	server, ch := startServer(c, func(n int) int {
+		if n < 3 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+	defer server.Close()
+
+	mgr := NewManager()
+	defer mgr.Stop()
+	mgr.PlanChanged(&plan.Plan{
+		Webhooks: map[string]*plan.Webhook{
+			"hook1": {
+				Name:         "hook1",
+				URL:          server.URL,
+				Attempts:     3,
+				BackoffDelay: plan.OptionalDuration{Value: time.Millisecond},
+			},
+		},
+	})
+
+	mgr.WarningAdded("retry me")
+	for i := 0; i < 3; i++ {
+		r := waitReceived(c, ch)
+		c.Check(r.body["message"], Equals, "retry me")
+	}
+	checkNoneReceived(c, ch)
+}
+
+func (s *ManagerSuite) TestGiveUp(c *C) {
+	logBuf, restore := logger.MockLogger("")
+	defer restore()
+
+	server, ch := startServer(c, func(int) int { return http.StatusBadGateway })
+	defer server.Close()
+
+	mgr := NewManager()
+	mgr.PlanChanged(&plan.Plan{
+		Webhooks: map[string]*plan.Webhook{
+			"hook1": {
+				Name:         "hook1",
+				URL:          server.URL,
+				Attempts:     2,
+				BackoffDelay: plan.OptionalDuration{Value: time.Millisecond},
+			},
+		},
+	})
+
+	mgr.WarningAdded("fail")
+	waitReceived(c, ch)
+	waitReceived(c, ch)
+	checkNoneReceived(c, ch)
+	mgr.Stop()
+	c.Check(logBuf.String(), Matches, `(?s).*Cannot deliver webhook "hook1" warning event after 2 attempt\(s\): non-2xx status code 502.*`)
+}
+
+func (s *ManagerSuite) TestStop(c *C) {
+	server, ch := startServer(c, func(int) int { return http.StatusServiceUnavailable })
+	defer server.Close()
+
+	mgr := NewManager()
+	mgr.PlanChanged(&plan.Plan{
+		Webhooks: map[string]*plan.Webhook{
+			"hook1": {
+				Name:         "hook1",
+				URL:          server.URL,
+				Attempts:     10,
+				BackoffDelay: plan.OptionalDuration{Value: time.Hour},
+			},
+		},
+	})
+
+	// Stop cancels deliveries waiting to retry.
+	mgr.WarningAdded("stop")
+	waitReceived(c, ch)
+	done := make(chan struct{})
+	go func() {
+		mgr.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		c.Fatalf("timed out waiting for Stop")
+	}
+
+	// Events after stopping aren't delivered.
+	mgr.WarningAdded("stopped")
+	checkNoneReceived(c, ch)
+}
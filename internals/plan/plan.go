@@ -18,6 +18,8 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -27,6 +29,7 @@ import (
 	"time"
 
 	"github.com/canonical/x-go/strutil/shlex"
+	"golang.org/x/sys/unix"
 	"gopkg.in/yaml.v3"
 
 	"github.com/canonical/pebble/internals/logger"
@@ -41,6 +44,11 @@ const (
 	defaultCheckPeriod    = 10 * time.Second
 	defaultCheckTimeout   = 3 * time.Second
 	defaultCheckThreshold = 3
+
+	defaultCheckSuccessThreshold = 1
+
+	defaultWebhookAttempts     = 3
+	defaultWebhookBackoffDelay = time.Second
 )
 
 type Plan struct {
@@ -48,6 +56,7 @@ type Plan struct {
 	Services   map[string]*Service   `yaml:"services,omitempty"`
 	Checks     map[string]*Check     `yaml:"checks,omitempty"`
 	LogTargets map[string]*LogTarget `yaml:"log-targets,omitempty"`
+	Webhooks   map[string]*Webhook   `yaml:"webhooks,omitempty"`
 }
 
 type Layer struct {
@@ -58,6 +67,7 @@ type Layer struct {
 	Services    map[string]*Service   `yaml:"services,omitempty"`
 	Checks      map[string]*Check     `yaml:"checks,omitempty"`
 	LogTargets  map[string]*LogTarget `yaml:"log-targets,omitempty"`
+	Webhooks    map[string]*Webhook   `yaml:"webhooks,omitempty"`
 }
 
 type Service struct {
@@ -90,6 +100,15 @@ type Service struct {
 	BackoffFactor  OptionalFloat            `yaml:"backoff-factor,omitempty"`
 	BackoffLimit   OptionalDuration         `yaml:"backoff-limit,omitempty"`
 	KillDelay      OptionalDuration         `yaml:"kill-delay,omitempty"`
+
+	// Health check level at which the service's own status counts as a check
+	Health CheckLevel `yaml:"health,omitempty"`
+
+	// Actions to take when watched files change
+	Watch         []string         `yaml:"watch,omitempty"`
+	WatchAction   WatchAction      `yaml:"watch-action,omitempty"`
+	WatchSignal   string           `yaml:"watch-signal,omitempty"`
+	WatchDebounce OptionalDuration `yaml:"watch-debounce,omitempty"`
 }
 
 // Copy returns a deep copy of the service.
@@ -98,6 +117,7 @@ func (s *Service) Copy() *Service {
 	copied.After = append([]string(nil), s.After...)
 	copied.Before = append([]string(nil), s.Before...)
 	copied.Requires = append([]string(nil), s.Requires...)
+	copied.Watch = append([]string(nil), s.Watch...)
 	if s.Environment != nil {
 		copied.Environment = make(map[string]string)
 		for k, v := range s.Environment {
@@ -181,6 +201,19 @@ func (s *Service) Merge(other *Service) {
 	if other.BackoffLimit.IsSet {
 		s.BackoffLimit = other.BackoffLimit
 	}
+	s.Watch = append(s.Watch, other.Watch...)
+	if other.WatchAction != "" {
+		s.WatchAction = other.WatchAction
+	}
+	if other.WatchSignal != "" {
+		s.WatchSignal = other.WatchSignal
+	}
+	if other.WatchDebounce.IsSet {
+		s.WatchDebounce = other.WatchDebounce
+	}
+	if other.Health != UnsetLevel {
+		s.Health = other.Health
+	}
 }
 
 // Equal returns true when the two services are equal in value.
@@ -296,6 +329,17 @@ const (
 	ActionIgnore   ServiceAction = "ignore"
 )
 
+// WatchAction is the action taken when one of a service's watched files
+// changes.
+type WatchAction string
+
+const (
+	WatchActionUnset   WatchAction = ""
+	WatchActionRestart WatchAction = "restart"
+	WatchActionReload  WatchAction = "reload"
+	WatchActionSignal  WatchAction = "signal"
+)
+
 // Check specifies configuration for a single health check.
 type Check struct {
 	// Basic details
@@ -304,19 +348,37 @@ type Check struct {
 	Level    CheckLevel `yaml:"level,omitempty"`
 
 	// Common check settings
-	Period    OptionalDuration `yaml:"period,omitempty"`
-	Timeout   OptionalDuration `yaml:"timeout,omitempty"`
-	Threshold int              `yaml:"threshold,omitempty"`
+	Period           OptionalDuration `yaml:"period,omitempty"`
+	Timeout          OptionalDuration `yaml:"timeout,omitempty"`
+	Threshold        int              `yaml:"threshold,omitempty"`
+	SuccessThreshold int              `yaml:"success-threshold,omitempty"`
+	InitialDelay     OptionalDuration `yaml:"initial-delay,omitempty"`
+	StartPeriod      OptionalDuration `yaml:"start-period,omitempty"`
+
+	// Checks that must be up for this check to run.
+	Requires []string `yaml:"requires,omitempty"`
 
 	// Type-specific check settings (only one of these can be set)
-	HTTP *HTTPCheck `yaml:"http,omitempty"`
-	TCP  *TCPCheck  `yaml:"tcp,omitempty"`
-	Exec *ExecCheck `yaml:"exec,omitempty"`
+	HTTP      *HTTPCheck      `yaml:"http,omitempty"`
+	TCP       *TCPCheck       `yaml:"tcp,omitempty"`
+	Exec      *ExecCheck      `yaml:"exec,omitempty"`
+	GRPC      *GRPCCheck      `yaml:"grpc,omitempty"`
+	Composite *CompositeCheck `yaml:"composite,omitempty"`
+	Disk      *DiskCheck      `yaml:"disk,omitempty"`
+	File      *FileCheck      `yaml:"file,omitempty"`
+	Process   *ProcessCheck   `yaml:"process,omitempty"`
+	Resources *ResourcesCheck `yaml:"resources,omitempty"`
+
+	// Actions performed when the check hits its failure threshold, and
+	// when it's up again after that.
+	OnFailure  []*CheckAction `yaml:"on-failure,omitempty"`
+	OnRecovery []*CheckAction `yaml:"on-recovery,omitempty"`
 }
 
 // Copy returns a deep copy of the check configuration.
 func (c *Check) Copy() *Check {
 	copied := *c
+	copied.Requires = append([]string(nil), c.Requires...)
 	if c.HTTP != nil {
 		copied.HTTP = c.HTTP.Copy()
 	}
@@ -326,6 +388,26 @@ func (c *Check) Copy() *Check {
 	if c.Exec != nil {
 		copied.Exec = c.Exec.Copy()
 	}
+	if c.GRPC != nil {
+		copied.GRPC = c.GRPC.Copy()
+	}
+	if c.Composite != nil {
+		copied.Composite = c.Composite.Copy()
+	}
+	if c.Disk != nil {
+		copied.Disk = c.Disk.Copy()
+	}
+	if c.File != nil {
+		copied.File = c.File.Copy()
+	}
+	if c.Process != nil {
+		copied.Process = c.Process.Copy()
+	}
+	if c.Resources != nil {
+		copied.Resources = c.Resources.Copy()
+	}
+	copied.OnFailure = copyCheckActions(c.OnFailure)
+	copied.OnRecovery = copyCheckActions(c.OnRecovery)
 	return &copied
 }
 
@@ -343,6 +425,16 @@ func (c *Check) Merge(other *Check) {
 	if other.Threshold != 0 {
 		c.Threshold = other.Threshold
 	}
+	if other.SuccessThreshold != 0 {
+		c.SuccessThreshold = other.SuccessThreshold
+	}
+	if other.InitialDelay.IsSet {
+		c.InitialDelay = other.InitialDelay
+	}
+	if other.StartPeriod.IsSet {
+		c.StartPeriod = other.StartPeriod
+	}
+	c.Requires = append(c.Requires, other.Requires...)
 	if other.HTTP != nil {
 		if c.HTTP == nil {
 			c.HTTP = &HTTPCheck{}
@@ -361,6 +453,56 @@ func (c *Check) Merge(other *Check) {
 		}
 		c.Exec.Merge(other.Exec)
 	}
+	if other.GRPC != nil {
+		if c.GRPC == nil {
+			c.GRPC = &GRPCCheck{}
+		}
+		c.GRPC.Merge(other.GRPC)
+	}
+	if other.Composite != nil {
+		if c.Composite == nil {
+			c.Composite = &CompositeCheck{}
+		}
+		c.Composite.Merge(other.Composite)
+	}
+	if other.Disk != nil {
+		if c.Disk == nil {
+			c.Disk = &DiskCheck{}
+		}
+		c.Disk.Merge(other.Disk)
+	}
+	if other.File != nil {
+		if c.File == nil {
+			c.File = &FileCheck{}
+		}
+		c.File.Merge(other.File)
+	}
+	if other.Process != nil {
+		if c.Process == nil {
+			c.Process = &ProcessCheck{}
+		}
+		c.Process.Merge(other.Process)
+	}
+	if other.Resources != nil {
+		if c.Resources == nil {
+			c.Resources = &ResourcesCheck{}
+		}
+		c.Resources.Merge(other.Resources)
+	}
+	if len(other.OnFailure) > 0 {
+		c.OnFailure = copyCheckActions(other.OnFailure)
+	}
+	if len(other.OnRecovery) > 0 {
+		c.OnRecovery = copyCheckActions(other.OnRecovery)
+	}
+}
+
+// Equal returns true when the two checks are equal in value.
+func (c *Check) Equal(other *Check) bool {
+	if c == other {
+		return true
+	}
+	return reflect.DeepEqual(c, other)
 }
 
 // CheckLevel specifies the optional check level.
@@ -374,8 +516,16 @@ const (
 
 // HTTPCheck holds the configuration for an HTTP health check.
 type HTTPCheck struct {
-	URL     string            `yaml:"url,omitempty"`
-	Headers map[string]string `yaml:"headers,omitempty"`
+	URL            string            `yaml:"url,omitempty"`
+	Method         string            `yaml:"method,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"`
+	Body           string            `yaml:"body,omitempty"`
+	ExpectedStatus []int             `yaml:"expected-status,omitempty"`
+	BodyMatch      string            `yaml:"body-match,omitempty"`
+	TLSCA          string            `yaml:"tls-ca,omitempty"`
+	TLSCert        string            `yaml:"tls-cert,omitempty"`
+	TLSKey         string            `yaml:"tls-key,omitempty"`
+	TLSSkipVerify  *bool             `yaml:"tls-skip-verify,omitempty"`
 }
 
 // Copy returns a deep copy of the HTTP check configuration.
@@ -387,6 +537,8 @@ func (c *HTTPCheck) Copy() *HTTPCheck {
 			copied.Headers[k] = v
 		}
 	}
+	copied.ExpectedStatus = append([]int(nil), c.ExpectedStatus...)
+	copied.TLSSkipVerify = copyBoolPtr(c.TLSSkipVerify)
 	return &copied
 }
 
@@ -395,12 +547,79 @@ func (c *HTTPCheck) Merge(other *HTTPCheck) {
 	if other.URL != "" {
 		c.URL = other.URL
 	}
+	if other.Method != "" {
+		c.Method = other.Method
+	}
 	for k, v := range other.Headers {
 		if c.Headers == nil {
 			c.Headers = make(map[string]string)
 		}
 		c.Headers[k] = v
 	}
+	if other.Body != "" {
+		c.Body = other.Body
+	}
+	if len(other.ExpectedStatus) > 0 {
+		c.ExpectedStatus = append([]int(nil), other.ExpectedStatus...)
+	}
+	if other.BodyMatch != "" {
+		c.BodyMatch = other.BodyMatch
+	}
+	if other.TLSCA != "" {
+		c.TLSCA = other.TLSCA
+	}
+	if other.TLSCert != "" {
+		c.TLSCert = other.TLSCert
+	}
+	if other.TLSKey != "" {
+		c.TLSKey = other.TLSKey
+	}
+	if other.TLSSkipVerify != nil {
+		c.TLSSkipVerify = copyBoolPtr(other.TLSSkipVerify)
+	}
+}
+
+// UnixSocketScheme is the URL scheme for HTTP checks of servers that listen
+// on a unix socket. The URL's host is the percent-encoded socket path, for
+// example "http+unix://%2Frun%2Fapp.sock/health".
+const UnixSocketScheme = "http+unix"
+
+// ParseURL returns the check's URL, and the socket path if the URL uses the
+// UnixSocketScheme. In that case the URL returned is an "http://localhost"
+// URL with the original path and query, to be requested over the socket.
+func (c *HTTPCheck) ParseURL() (socket, requestURL string, err error) {
+	prefix := UnixSocketScheme + "://"
+	if !strings.HasPrefix(c.URL, prefix) {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return "", "", err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return "", "", fmt.Errorf("scheme must be http, https, or %s, not %q", UnixSocketScheme, u.Scheme)
+		}
+		return "", c.URL, nil
+	}
+
+	// The socket path can't go through url.Parse, which doesn't allow
+	// percent-encoded slashes in the host.
+	rest := c.URL[len(prefix):]
+	host, path := rest, ""
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		host, path = rest[:i], rest[i:]
+	}
+	socket, err = url.PathUnescape(host)
+	if err != nil {
+		return "", "", err
+	}
+	if !filepath.IsAbs(socket) {
+		return "", "", fmt.Errorf("socket path must be absolute, not %q", socket)
+	}
+	requestURL = "http://localhost" + path
+	_, err = url.Parse(requestURL)
+	if err != nil {
+		return "", "", err
+	}
+	return socket, requestURL, nil
 }
 
 // TCPCheck holds the configuration for an HTTP health check.
@@ -486,6 +705,226 @@ func (c *ExecCheck) Merge(other *ExecCheck) {
 	}
 }
 
+// CheckAction holds the configuration for an action performed when a check
+// fails or recovers. Exactly one of Exec, Warn, Signal, or Shutdown must be
+// set.
+type CheckAction struct {
+	// Exec runs the given command.
+	Exec string `yaml:"exec,omitempty"`
+
+	// Warn adds a Pebble warning with the given message.
+	Warn string `yaml:"warn,omitempty"`
+
+	// Signal sends the named signal (for example "SIGHUP") to Service.
+	Signal  string `yaml:"signal,omitempty"`
+	Service string `yaml:"service,omitempty"`
+
+	// Shutdown makes the daemon exit with the given exit code.
+	Shutdown *int `yaml:"shutdown,omitempty"`
+}
+
+// Copy returns a deep copy of the check action configuration.
+func (a *CheckAction) Copy() *CheckAction {
+	copied := *a
+	copied.Shutdown = copyIntPtr(a.Shutdown)
+	return &copied
+}
+
+func copyCheckActions(actions []*CheckAction) []*CheckAction {
+	if actions == nil {
+		return nil
+	}
+	copied := make([]*CheckAction, len(actions))
+	for i, action := range actions {
+		copied[i] = action.Copy()
+	}
+	return copied
+}
+
+// GRPCCheck holds the configuration for a gRPC health check, which uses the
+// standard gRPC health checking protocol (grpc.health.v1.Health/Check).
+type GRPCCheck struct {
+	Address       string            `yaml:"address,omitempty"`
+	Service       string            `yaml:"service,omitempty"`
+	TLS           *bool             `yaml:"tls,omitempty"`
+	TLSSkipVerify *bool             `yaml:"tls-skip-verify,omitempty"`
+	Metadata      map[string]string `yaml:"metadata,omitempty"`
+}
+
+// Copy returns a deep copy of the gRPC check configuration.
+func (c *GRPCCheck) Copy() *GRPCCheck {
+	copied := *c
+	copied.TLS = copyBoolPtr(c.TLS)
+	copied.TLSSkipVerify = copyBoolPtr(c.TLSSkipVerify)
+	if c.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(c.Metadata))
+		for k, v := range c.Metadata {
+			copied.Metadata[k] = v
+		}
+	}
+	return &copied
+}
+
+// Merge merges the fields set in other into c.
+func (c *GRPCCheck) Merge(other *GRPCCheck) {
+	if other.Address != "" {
+		c.Address = other.Address
+	}
+	if other.Service != "" {
+		c.Service = other.Service
+	}
+	if other.TLS != nil {
+		c.TLS = copyBoolPtr(other.TLS)
+	}
+	if other.TLSSkipVerify != nil {
+		c.TLSSkipVerify = copyBoolPtr(other.TLSSkipVerify)
+	}
+	for k, v := range other.Metadata {
+		if c.Metadata == nil {
+			c.Metadata = make(map[string]string)
+		}
+		c.Metadata[k] = v
+	}
+}
+
+// CompositeCheck holds the configuration for a composite check, which
+// aggregates the status of other checks.
+type CompositeCheck struct {
+	Checks []string      `yaml:"checks,omitempty"`
+	Mode   CompositeMode `yaml:"mode,omitempty"`
+}
+
+// CompositeMode defines how a composite check aggregates its checks.
+type CompositeMode string
+
+const (
+	// CompositeAll means the composite check is successful if all of its
+	// checks are up. This is the default.
+	CompositeAll CompositeMode = "all"
+
+	// CompositeAny means the composite check is successful if any of its
+	// checks are up.
+	CompositeAny CompositeMode = "any"
+
+	UnsetCompositeMode CompositeMode = ""
+)
+
+// Copy returns a deep copy of the composite check configuration.
+func (c *CompositeCheck) Copy() *CompositeCheck {
+	copied := *c
+	copied.Checks = append([]string(nil), c.Checks...)
+	return &copied
+}
+
+// Merge merges the fields set in other into c.
+func (c *CompositeCheck) Merge(other *CompositeCheck) {
+	c.Checks = append(c.Checks, other.Checks...)
+	if other.Mode != "" {
+		c.Mode = other.Mode
+	}
+}
+
+// DiskCheck holds the configuration for a disk health check, which is
+// successful if the filesystem containing Path has at least MinFree space
+// and MinFreeInodes inodes free.
+type DiskCheck struct {
+	Path          string         `yaml:"path,omitempty"`
+	MinFree       OptionalAmount `yaml:"min-free,omitempty"`
+	MinFreeInodes OptionalAmount `yaml:"min-free-inodes,omitempty"`
+}
+
+// Copy returns a deep copy of the disk check configuration.
+func (c *DiskCheck) Copy() *DiskCheck {
+	copied := *c
+	return &copied
+}
+
+// Merge merges the fields set in other into c.
+func (c *DiskCheck) Merge(other *DiskCheck) {
+	if other.Path != "" {
+		c.Path = other.Path
+	}
+	if other.MinFree.IsSet {
+		c.MinFree = other.MinFree
+	}
+	if other.MinFreeInodes.IsSet {
+		c.MinFreeInodes = other.MinFreeInodes
+	}
+}
+
+// FileCheck holds the configuration for a file health check, which is
+// successful if Path exists and (if MaxAge is set) was modified within
+// MaxAge.
+type FileCheck struct {
+	Path   string           `yaml:"path,omitempty"`
+	MaxAge OptionalDuration `yaml:"max-age,omitempty"`
+}
+
+// Copy returns a deep copy of the file check configuration.
+func (c *FileCheck) Copy() *FileCheck {
+	copied := *c
+	return &copied
+}
+
+// Merge merges the fields set in other into c.
+func (c *FileCheck) Merge(other *FileCheck) {
+	if other.Path != "" {
+		c.Path = other.Path
+	}
+	if other.MaxAge.IsSet {
+		c.MaxAge = other.MaxAge
+	}
+}
+
+// ProcessCheck holds the configuration for a process health check, which is
+// successful if a process with the given Name is running, or if the process
+// whose PID is in PIDFile is running.
+type ProcessCheck struct {
+	Name    string `yaml:"name,omitempty"`
+	PIDFile string `yaml:"pid-file,omitempty"`
+}
+
+// Copy returns a deep copy of the process check configuration.
+func (c *ProcessCheck) Copy() *ProcessCheck {
+	copied := *c
+	return &copied
+}
+
+// Merge merges the fields set in other into c.
+func (c *ProcessCheck) Merge(other *ProcessCheck) {
+	if other.Name != "" {
+		c.Name = other.Name
+	}
+	if other.PIDFile != "" {
+		c.PIDFile = other.PIDFile
+	}
+}
+
+// ResourcesCheck holds the configuration for a system resources health
+// check, which is successful if the memory used is at most MaxMemory and the
+// one-minute load average is at most MaxLoad. Memory is measured against the
+// nearest cgroup memory limit, if any; the load average is host-wide.
+type ResourcesCheck struct {
+	MaxMemory OptionalAmount `yaml:"max-memory,omitempty"`
+	MaxLoad   OptionalFloat  `yaml:"max-load,omitempty"`
+}
+
+// Copy returns a deep copy of the resources check configuration.
+func (c *ResourcesCheck) Copy() *ResourcesCheck {
+	copied := *c
+	return &copied
+}
+
+// Merge merges the fields set in other into c.
+func (c *ResourcesCheck) Merge(other *ResourcesCheck) {
+	if other.MaxMemory.IsSet {
+		c.MaxMemory = other.MaxMemory
+	}
+	if other.MaxLoad.IsSet {
+		c.MaxLoad = other.MaxLoad
+	}
+}
+
 // LogTarget specifies a remote server to forward logs to.
 type LogTarget struct {
 	Name     string        `yaml:"-"`
@@ -522,6 +961,77 @@ func (t *LogTarget) Merge(other *LogTarget) {
 	t.Services = append(t.Services, other.Services...)
 }
 
+// Webhook specifies a URL to POST a JSON payload to when events occur.
+type Webhook struct {
+	Name         string            `yaml:"-"`
+	Override     Override          `yaml:"override,omitempty"`
+	URL          string            `yaml:"url,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty"`
+	Events       []WebhookEvent    `yaml:"events,omitempty"`
+	Attempts     int               `yaml:"attempts,omitempty"`
+	BackoffDelay OptionalDuration  `yaml:"backoff-delay,omitempty"`
+}
+
+// WebhookEvent is the kind of event that triggers a webhook.
+type WebhookEvent string
+
+const (
+	// WebhookServiceExit is triggered when a service exits unexpectedly.
+	WebhookServiceExit WebhookEvent = "service-exit"
+
+	// WebhookCheck is triggered when a check goes up or down.
+	WebhookCheck WebhookEvent = "check"
+
+	// WebhookWarning is triggered when a warning is added.
+	WebhookWarning WebhookEvent = "warning"
+)
+
+// Copy returns a deep copy of the webhook configuration.
+func (w *Webhook) Copy() *Webhook {
+	copied := *w
+	if w.Headers != nil {
+		copied.Headers = make(map[string]string, len(w.Headers))
+		for k, v := range w.Headers {
+			copied.Headers[k] = v
+		}
+	}
+	copied.Events = append([]WebhookEvent(nil), w.Events...)
+	return &copied
+}
+
+// Merge merges the fields set in other into w.
+func (w *Webhook) Merge(other *Webhook) {
+	if other.URL != "" {
+		w.URL = other.URL
+	}
+	for k, v := range other.Headers {
+		if w.Headers == nil {
+			w.Headers = make(map[string]string)
+		}
+		w.Headers[k] = v
+	}
+	w.Events = append(w.Events, other.Events...)
+	if other.Attempts != 0 {
+		w.Attempts = other.Attempts
+	}
+	if other.BackoffDelay.IsSet {
+		w.BackoffDelay = other.BackoffDelay
+	}
+}
+
+// Triggers reports whether the webhook is triggered by the given event.
+func (w *Webhook) Triggers(event WebhookEvent) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
 // FormatError is the error returned when a layer has a format error, such as
 // a missing "override" field.
 type FormatError struct {
@@ -539,6 +1049,7 @@ func CombineLayers(layers ...*Layer) (*Layer, error) {
 		Services:   make(map[string]*Service),
 		Checks:     make(map[string]*Check),
 		LogTargets: make(map[string]*LogTarget),
+		Webhooks:   make(map[string]*Webhook),
 	}
 	if len(layers) == 0 {
 		return combined, nil
@@ -621,6 +1132,31 @@ func CombineLayers(layers ...*Layer) (*Layer, error) {
 				}
 			}
 		}
+
+		for name, webhook := range layer.Webhooks {
+			switch webhook.Override {
+			case MergeOverride:
+				if old, ok := combined.Webhooks[name]; ok {
+					copied := old.Copy()
+					copied.Merge(webhook)
+					combined.Webhooks[name] = copied
+					break
+				}
+				fallthrough
+			case ReplaceOverride:
+				combined.Webhooks[name] = webhook.Copy()
+			case UnknownOverride:
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`layer %q must define "override" for webhook %q`,
+						layer.Label, webhook.Name),
+				}
+			default:
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`layer %q has invalid "override" value for webhook %q`,
+						layer.Label, webhook.Name),
+				}
+			}
+		}
 	}
 
 	// Ensure fields in combined layers validate correctly (and set defaults).
@@ -653,6 +1189,16 @@ func CombineLayers(layers ...*Layer) (*Layer, error) {
 				}
 			}
 		}
+		if service.Health != UnsetLevel && service.Health != AliveLevel && service.Health != ReadyLevel {
+			return nil, &FormatError{
+				Message: fmt.Sprintf(`plan service %q health must be "alive" or "ready"`, name),
+			}
+		}
+		if _, ok := combined.Checks[name]; ok && service.Health != UnsetLevel {
+			return nil, &FormatError{
+				Message: fmt.Sprintf("plan service %q health conflicts with check of the same name", name),
+			}
+		}
 		if !service.BackoffDelay.IsSet {
 			service.BackoffDelay.Value = defaultBackoffDelay
 		}
@@ -666,7 +1212,36 @@ func CombineLayers(layers ...*Layer) (*Layer, error) {
 		if !service.BackoffLimit.IsSet {
 			service.BackoffLimit.Value = defaultBackoffLimit
 		}
-
+		for _, path := range service.Watch {
+			if !filepath.IsAbs(path) {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan service %q watch path %q must be absolute", name, path),
+				}
+			}
+		}
+		switch service.WatchAction {
+		case WatchActionUnset, WatchActionRestart, WatchActionReload:
+			if service.WatchSignal != "" {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan service %q watch-signal requires watch-action "signal"`, name),
+				}
+			}
+		case WatchActionSignal:
+			if service.WatchSignal == "" {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan service %q must set "watch-signal" for watch-action "signal"`, name),
+				}
+			}
+			if unix.SignalNum(service.WatchSignal) == 0 {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan service %q watch-signal %q invalid", name, service.WatchSignal),
+				}
+			}
+		default:
+			return nil, &FormatError{
+				Message: fmt.Sprintf("plan service %q watch-action %q invalid", name, service.WatchAction),
+			}
+		}
 	}
 
 	for name, check := range combined.Checks {
@@ -699,6 +1274,25 @@ func CombineLayers(layers ...*Layer) (*Layer, error) {
 			// what it's worth, Kubernetes probes uses a default of 3 too.
 			check.Threshold = defaultCheckThreshold
 		}
+		if check.SuccessThreshold < 0 {
+			return nil, &FormatError{
+				Message: fmt.Sprintf("plan check %q success-threshold must not be negative", name),
+			}
+		} else if check.SuccessThreshold == 0 {
+			// Default number of successes in a row before a check that's
+			// down is considered up again.
+			check.SuccessThreshold = defaultCheckSuccessThreshold
+		}
+		if check.InitialDelay.Value < 0 {
+			return nil, &FormatError{
+				Message: fmt.Sprintf("plan check %q initial-delay must not be negative", name),
+			}
+		}
+		if check.StartPeriod.Value < 0 {
+			return nil, &FormatError{
+				Message: fmt.Sprintf("plan check %q start-period must not be negative", name),
+			}
+		}
 
 		numTypes := 0
 		if check.HTTP != nil {
@@ -707,6 +1301,12 @@ func CombineLayers(layers ...*Layer) (*Layer, error) {
 					Message: fmt.Sprintf(`plan must set "url" for http check %q`, name),
 				}
 			}
+			err := validateHTTPCheck(check.HTTP)
+			if err != nil {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan check %q %v", name, err),
+				}
+			}
 			numTypes++
 		}
 		if check.TCP != nil {
@@ -744,9 +1344,133 @@ func CombineLayers(layers ...*Layer) (*Layer, error) {
 			}
 			numTypes++
 		}
+		if check.GRPC != nil {
+			if check.GRPC.Address == "" {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan must set "address" for grpc check %q`, name),
+				}
+			}
+			_, port, err := net.SplitHostPort(check.GRPC.Address)
+			if err != nil || port == "" {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan check %q address must be in "host:port" format, not %q`,
+						name, check.GRPC.Address),
+				}
+			}
+			if check.GRPC.TLSSkipVerify != nil && *check.GRPC.TLSSkipVerify &&
+				(check.GRPC.TLS == nil || !*check.GRPC.TLS) {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan check %q cannot set "tls-skip-verify" without "tls"`, name),
+				}
+			}
+			numTypes++
+		}
+		if check.Composite != nil {
+			if len(check.Composite.Checks) == 0 {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan must set "checks" for composite check %q`, name),
+				}
+			}
+			for _, other := range check.Composite.Checks {
+				if other == name {
+					return nil, &FormatError{
+						Message: fmt.Sprintf("plan composite check %q cannot include itself", name),
+					}
+				}
+				if _, ok := combined.Checks[other]; !ok {
+					return nil, &FormatError{
+						Message: fmt.Sprintf("plan composite check %q includes non-existent check %q", name, other),
+					}
+				}
+			}
+			switch check.Composite.Mode {
+			case UnsetCompositeMode, CompositeAll, CompositeAny:
+			default:
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan composite check %q mode must be "all" or "any"`, name),
+				}
+			}
+			numTypes++
+		}
+		if check.Disk != nil {
+			if check.Disk.Path == "" {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan must set "path" for disk check %q`, name),
+				}
+			}
+			if !check.Disk.MinFree.IsSet && !check.Disk.MinFreeInodes.IsSet {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan must set "min-free" or "min-free-inodes" for disk check %q`, name),
+				}
+			}
+			numTypes++
+		}
+		if check.File != nil {
+			if check.File.Path == "" {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan must set "path" for file check %q`, name),
+				}
+			}
+			if check.File.MaxAge.Value < 0 {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan check %q max-age must not be negative", name),
+				}
+			}
+			numTypes++
+		}
+		if check.Process != nil {
+			if (check.Process.Name == "") == (check.Process.PIDFile == "") {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan must set one of "name" or "pid-file" for process check %q`, name),
+				}
+			}
+			numTypes++
+		}
+		if check.Resources != nil {
+			if !check.Resources.MaxMemory.IsSet && !check.Resources.MaxLoad.IsSet {
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan must set "max-memory" or "max-load" for resources check %q`, name),
+				}
+			}
+			if check.Resources.MaxLoad.IsSet && check.Resources.MaxLoad.Value <= 0 {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan check %q max-load must be positive", name),
+				}
+			}
+			numTypes++
+		}
 		if numTypes != 1 {
 			return nil, &FormatError{
-				Message: fmt.Sprintf(`plan must specify one of "http", "tcp", or "exec" for check %q`, name),
+				Message: fmt.Sprintf(`plan must specify one of "http", "tcp", "exec", "grpc", "composite", `+
+					`"disk", "file", "process", or "resources" for check %q`, name),
+			}
+		}
+		for _, other := range check.Requires {
+			if other == name {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan check %q cannot require itself", name),
+				}
+			}
+			if _, ok := combined.Checks[other]; !ok {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan check %q requires non-existent check %q", name, other),
+				}
+			}
+		}
+		for _, action := range check.OnFailure {
+			err := validateCheckAction(action, combined.Services)
+			if err != nil {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan check %q on-failure action %v", name, err),
+				}
+			}
+		}
+		for _, action := range check.OnRecovery {
+			err := validateCheckAction(action, combined.Services)
+			if err != nil {
+				return nil, &FormatError{
+					Message: fmt.Sprintf("plan check %q on-recovery action %v", name, err),
+				}
 			}
 		}
 	}
@@ -789,6 +1513,46 @@ func CombineLayers(layers ...*Layer) (*Layer, error) {
 		}
 	}
 
+	for name, webhook := range combined.Webhooks {
+		if webhook.URL == "" {
+			return nil, &FormatError{
+				Message: fmt.Sprintf(`plan must define "url" for webhook %q`, name),
+			}
+		}
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, &FormatError{
+				Message: fmt.Sprintf(`plan webhook %q url must be an absolute "http" or "https" URL`, name),
+			}
+		}
+		for _, event := range webhook.Events {
+			switch event {
+			case WebhookServiceExit, WebhookCheck, WebhookWarning:
+			default:
+				return nil, &FormatError{
+					Message: fmt.Sprintf(`plan webhook %q event %q invalid, must be %q, %q or %q`,
+						name, event, WebhookServiceExit, WebhookCheck, WebhookWarning),
+				}
+			}
+		}
+		if webhook.Attempts < 0 {
+			return nil, &FormatError{
+				Message: fmt.Sprintf("plan webhook %q attempts must not be negative", name),
+			}
+		}
+		if webhook.Attempts == 0 {
+			webhook.Attempts = defaultWebhookAttempts
+		}
+		if webhook.BackoffDelay.Value < 0 {
+			return nil, &FormatError{
+				Message: fmt.Sprintf("plan webhook %q backoff-delay must not be negative", name),
+			}
+		}
+		if !webhook.BackoffDelay.IsSet {
+			webhook.BackoffDelay.Value = defaultWebhookBackoffDelay
+		}
+	}
+
 	// Ensure combined layers don't have cycles.
 	err := combined.checkCycles()
 	if err != nil {
@@ -894,7 +1658,26 @@ func (l *Layer) checkCycles() error {
 		names = append(names, name)
 	}
 	_, err := order(l.Services, names, false)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Checks can't (indirectly) require or include themselves.
+	successors := make(map[string][]string, len(l.Checks))
+	for name, check := range l.Checks {
+		successors[name] = append(successors[name], check.Requires...)
+		if check.Composite != nil {
+			successors[name] = append(successors[name], check.Composite.Checks...)
+		}
+	}
+	for _, names := range tarjanSort(successors) {
+		if len(names) > 1 {
+			return &FormatError{
+				Message: fmt.Sprintf("checks in requires loop: %s", strings.Join(names, ", ")),
+			}
+		}
+	}
+	return nil
 }
 
 func ParseLayer(order int, label string, data []byte) (*Layer, error) {
@@ -902,6 +1685,7 @@ func ParseLayer(order int, label string, data []byte) (*Layer, error) {
 		Services:   map[string]*Service{},
 		Checks:     map[string]*Check{},
 		LogTargets: map[string]*LogTarget{},
+		Webhooks:   map[string]*Webhook{},
 	}
 	dec := yaml.NewDecoder(bytes.NewBuffer(data))
 	dec.KnownFields(true)
@@ -972,6 +1756,20 @@ func ParseLayer(order int, label string, data []byte) (*Layer, error) {
 		target.Name = name
 	}
 
+	for name, webhook := range layer.Webhooks {
+		if name == "" {
+			return nil, &FormatError{
+				Message: fmt.Sprintf("cannot use empty string as webhook name"),
+			}
+		}
+		if webhook == nil {
+			return nil, &FormatError{
+				Message: fmt.Sprintf("webhook object cannot be null for webhook %q", name),
+			}
+		}
+		webhook.Name = name
+	}
+
 	err = layer.checkCycles()
 	if err != nil {
 		return nil, err
@@ -1075,6 +1873,7 @@ func ReadDir(dir string) (*Plan, error) {
 		Services:   combined.Services,
 		Checks:     combined.Checks,
 		LogTargets: combined.LogTargets,
+		Webhooks:   combined.Webhooks,
 	}
 	return plan, err
 }
@@ -1154,3 +1953,90 @@ func copyIntPtr(p *int) *int {
 	copied := *p
 	return &copied
 }
+
+var httpMethodRegexp = regexp.MustCompile(`^[A-Z]+$`)
+
+// validateHTTPCheck returns an error (to be prefixed with the check name)
+// if the HTTP check's fields are invalid.
+func validateHTTPCheck(c *HTTPCheck) error {
+	socket, _, err := c.ParseURL()
+	if err != nil {
+		return fmt.Errorf("has invalid url %q: %v", c.URL, err)
+	}
+	if c.Method != "" && !httpMethodRegexp.MatchString(c.Method) {
+		return fmt.Errorf("has invalid method %q", c.Method)
+	}
+	for _, status := range c.ExpectedStatus {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("has invalid expected status %d", status)
+		}
+	}
+	if c.BodyMatch != "" {
+		_, err := regexp.Compile(c.BodyMatch)
+		if err != nil {
+			return fmt.Errorf("has invalid body-match regexp: %v", err)
+		}
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf(`must set both "tls-cert" and "tls-key", or neither`)
+	}
+	usesTLS := c.TLSCA != "" || c.TLSCert != "" || (c.TLSSkipVerify != nil && *c.TLSSkipVerify)
+	if usesTLS && (socket != "" || !strings.HasPrefix(strings.ToLower(c.URL), "https:")) {
+		return fmt.Errorf("cannot set TLS options for non-https url %q", c.URL)
+	}
+	return nil
+}
+
+// validateCheckAction returns an error (to be prefixed with the check name
+// and action kind) if the check action's fields are invalid.
+func validateCheckAction(a *CheckAction, services map[string]*Service) error {
+	if a == nil {
+		return fmt.Errorf("must not be null")
+	}
+	numKinds := 0
+	if a.Exec != "" {
+		args, err := shlex.Split(a.Exec)
+		if err != nil {
+			return fmt.Errorf("has invalid command: %v", err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("has empty command")
+		}
+		numKinds++
+	}
+	if a.Warn != "" {
+		numKinds++
+	}
+	if a.Signal != "" {
+		if unix.SignalNum(a.Signal) == 0 {
+			return fmt.Errorf("has invalid signal %q", a.Signal)
+		}
+		if a.Service == "" {
+			return fmt.Errorf(`must set "service" for signal %q`, a.Signal)
+		}
+		if _, ok := services[a.Service]; !ok {
+			return fmt.Errorf("specifies non-existent service %q", a.Service)
+		}
+		numKinds++
+	} else if a.Service != "" {
+		return fmt.Errorf(`cannot set "service" without "signal"`)
+	}
+	if a.Shutdown != nil {
+		if *a.Shutdown < 0 || *a.Shutdown > 255 {
+			return fmt.Errorf("has invalid shutdown exit code %d", *a.Shutdown)
+		}
+		numKinds++
+	}
+	if numKinds != 1 {
+		return fmt.Errorf(`must specify one of "exec", "warn", "signal", or "shutdown"`)
+	}
+	return nil
+}
+
+func copyBoolPtr(p *bool) *bool {
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	return &copied
+}
@@ -37,6 +37,8 @@ const (
 	defaultCheckPeriod    = 10 * time.Second
 	defaultCheckTimeout   = 3 * time.Second
 	defaultCheckThreshold = 3
+
+	defaultCheckSuccessThreshold = 1
 )
 
 // TODOs:
@@ -204,6 +206,7 @@ var planTests = []planTest{{
 		},
 		Checks:     map[string]*plan.Check{},
 		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks:   map[string]*plan.Webhook{},
 	}, {
 		Order:       1,
 		Label:       "layer-1",
@@ -254,6 +257,7 @@ var planTests = []planTest{{
 		},
 		Checks:     map[string]*plan.Check{},
 		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks:   map[string]*plan.Webhook{},
 	}},
 	result: &plan.Layer{
 		Summary:     "Simple override layer.",
@@ -333,6 +337,7 @@ var planTests = []planTest{{
 		},
 		Checks:     map[string]*plan.Check{},
 		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks:   map[string]*plan.Webhook{},
 	},
 	start: map[string][]string{
 		"srv1": {"srv2", "srv1", "srv3"},
@@ -395,6 +400,7 @@ var planTests = []planTest{{
 		},
 		Checks:     map[string]*plan.Check{},
 		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks:   map[string]*plan.Webhook{},
 	}},
 }, {
 	summary: "Unknown keys are not accepted",
@@ -509,6 +515,7 @@ var planTests = []planTest{{
 		},
 		Checks:     map[string]*plan.Check{},
 		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks:   map[string]*plan.Webhook{},
 	}},
 }, {
 	summary: `Invalid service command: cannot have any arguments after [ ... ] group`,
@@ -547,6 +554,9 @@ var planTests = []planTest{{
 				period: 20s
 				timeout: 500ms
 				threshold: 7
+				success-threshold: 2
+				initial-delay: 5s
+				start-period: 1m
 				http:
 					url: https://example.com/foo
 					headers:
@@ -573,12 +583,15 @@ var planTests = []planTest{{
 		Services: map[string]*plan.Service{},
 		Checks: map[string]*plan.Check{
 			"chk-http": {
-				Name:      "chk-http",
-				Override:  plan.ReplaceOverride,
-				Level:     plan.AliveLevel,
-				Period:    plan.OptionalDuration{Value: 20 * time.Second, IsSet: true},
-				Timeout:   plan.OptionalDuration{Value: 500 * time.Millisecond, IsSet: true},
-				Threshold: 7,
+				Name:             "chk-http",
+				Override:         plan.ReplaceOverride,
+				Level:            plan.AliveLevel,
+				Period:           plan.OptionalDuration{Value: 20 * time.Second, IsSet: true},
+				Timeout:          plan.OptionalDuration{Value: 500 * time.Millisecond, IsSet: true},
+				Threshold:        7,
+				SuccessThreshold: 2,
+				InitialDelay:     plan.OptionalDuration{Value: 5 * time.Second, IsSet: true},
+				StartPeriod:      plan.OptionalDuration{Value: time.Minute, IsSet: true},
 				HTTP: &plan.HTTPCheck{
 					URL: "https://example.com/foo",
 					Headers: map[string]string{
@@ -588,24 +601,26 @@ var planTests = []planTest{{
 				},
 			},
 			"chk-tcp": {
-				Name:      "chk-tcp",
-				Override:  plan.MergeOverride,
-				Level:     plan.ReadyLevel,
-				Period:    plan.OptionalDuration{Value: defaultCheckPeriod},
-				Timeout:   plan.OptionalDuration{Value: defaultCheckTimeout},
-				Threshold: defaultCheckThreshold,
+				Name:             "chk-tcp",
+				Override:         plan.MergeOverride,
+				Level:            plan.ReadyLevel,
+				Period:           plan.OptionalDuration{Value: defaultCheckPeriod},
+				Timeout:          plan.OptionalDuration{Value: defaultCheckTimeout},
+				Threshold:        defaultCheckThreshold,
+				SuccessThreshold: defaultCheckSuccessThreshold,
 				TCP: &plan.TCPCheck{
 					Port: 7777,
 					Host: "somehost",
 				},
 			},
 			"chk-exec": {
-				Name:      "chk-exec",
-				Override:  plan.ReplaceOverride,
-				Level:     plan.UnsetLevel,
-				Period:    plan.OptionalDuration{Value: defaultCheckPeriod},
-				Timeout:   plan.OptionalDuration{Value: defaultCheckTimeout},
-				Threshold: defaultCheckThreshold,
+				Name:             "chk-exec",
+				Override:         plan.ReplaceOverride,
+				Level:            plan.UnsetLevel,
+				Period:           plan.OptionalDuration{Value: defaultCheckPeriod},
+				Timeout:          plan.OptionalDuration{Value: defaultCheckTimeout},
+				Threshold:        defaultCheckThreshold,
+				SuccessThreshold: defaultCheckSuccessThreshold,
 				Exec: &plan.ExecCheck{
 					Command: "sleep 1",
 					Environment: map[string]string{
@@ -617,6 +632,7 @@ var planTests = []planTest{{
 			},
 		},
 		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks:   map[string]*plan.Webhook{},
 	},
 }, {
 	summary: "Checks override replace works correctly",
@@ -663,37 +679,41 @@ var planTests = []planTest{{
 		Services: map[string]*plan.Service{},
 		Checks: map[string]*plan.Check{
 			"chk-http": {
-				Name:      "chk-http",
-				Override:  plan.ReplaceOverride,
-				Period:    plan.OptionalDuration{Value: defaultCheckPeriod},
-				Timeout:   plan.OptionalDuration{Value: defaultCheckTimeout},
-				Threshold: defaultCheckThreshold,
+				Name:             "chk-http",
+				Override:         plan.ReplaceOverride,
+				Period:           plan.OptionalDuration{Value: defaultCheckPeriod},
+				Timeout:          plan.OptionalDuration{Value: defaultCheckTimeout},
+				Threshold:        defaultCheckThreshold,
+				SuccessThreshold: defaultCheckSuccessThreshold,
 				HTTP: &plan.HTTPCheck{
 					URL: "https://example.com/bar",
 				},
 			},
 			"chk-tcp": {
-				Name:      "chk-tcp",
-				Override:  plan.ReplaceOverride,
-				Period:    plan.OptionalDuration{Value: defaultCheckPeriod},
-				Timeout:   plan.OptionalDuration{Value: defaultCheckTimeout},
-				Threshold: defaultCheckThreshold,
+				Name:             "chk-tcp",
+				Override:         plan.ReplaceOverride,
+				Period:           plan.OptionalDuration{Value: defaultCheckPeriod},
+				Timeout:          plan.OptionalDuration{Value: defaultCheckTimeout},
+				Threshold:        defaultCheckThreshold,
+				SuccessThreshold: defaultCheckSuccessThreshold,
 				TCP: &plan.TCPCheck{
 					Port: 8888,
 				},
 			},
 			"chk-exec": {
-				Name:      "chk-exec",
-				Override:  plan.ReplaceOverride,
-				Period:    plan.OptionalDuration{Value: defaultCheckPeriod},
-				Timeout:   plan.OptionalDuration{Value: defaultCheckTimeout},
-				Threshold: defaultCheckThreshold,
+				Name:             "chk-exec",
+				Override:         plan.ReplaceOverride,
+				Period:           plan.OptionalDuration{Value: defaultCheckPeriod},
+				Timeout:          plan.OptionalDuration{Value: defaultCheckTimeout},
+				Threshold:        defaultCheckThreshold,
+				SuccessThreshold: defaultCheckSuccessThreshold,
 				Exec: &plan.ExecCheck{
 					Command: "sleep 2",
 				},
 			},
 		},
 		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks:   map[string]*plan.Webhook{},
 	},
 }, {
 	summary: "Checks override merge works correctly",
@@ -740,33 +760,36 @@ var planTests = []planTest{{
 		Services: map[string]*plan.Service{},
 		Checks: map[string]*plan.Check{
 			"chk-http": {
-				Name:      "chk-http",
-				Override:  plan.MergeOverride,
-				Period:    plan.OptionalDuration{Value: time.Second, IsSet: true},
-				Timeout:   plan.OptionalDuration{Value: defaultCheckTimeout},
-				Threshold: defaultCheckThreshold,
+				Name:             "chk-http",
+				Override:         plan.MergeOverride,
+				Period:           plan.OptionalDuration{Value: time.Second, IsSet: true},
+				Timeout:          plan.OptionalDuration{Value: defaultCheckTimeout},
+				Threshold:        defaultCheckThreshold,
+				SuccessThreshold: defaultCheckSuccessThreshold,
 				HTTP: &plan.HTTPCheck{
 					URL:     "https://example.com/bar",
 					Headers: map[string]string{"Foo": "bar"},
 				},
 			},
 			"chk-tcp": {
-				Name:      "chk-tcp",
-				Override:  plan.MergeOverride,
-				Period:    plan.OptionalDuration{Value: defaultCheckPeriod},
-				Timeout:   plan.OptionalDuration{Value: 300 * time.Millisecond, IsSet: true},
-				Threshold: defaultCheckThreshold,
+				Name:             "chk-tcp",
+				Override:         plan.MergeOverride,
+				Period:           plan.OptionalDuration{Value: defaultCheckPeriod},
+				Timeout:          plan.OptionalDuration{Value: 300 * time.Millisecond, IsSet: true},
+				Threshold:        defaultCheckThreshold,
+				SuccessThreshold: defaultCheckSuccessThreshold,
 				TCP: &plan.TCPCheck{
 					Port: 80,
 					Host: "foobar",
 				},
 			},
 			"chk-exec": {
-				Name:      "chk-exec",
-				Override:  plan.MergeOverride,
-				Period:    plan.OptionalDuration{Value: defaultCheckPeriod},
-				Timeout:   plan.OptionalDuration{Value: 7 * time.Second, IsSet: true},
-				Threshold: 5,
+				Name:             "chk-exec",
+				Override:         plan.MergeOverride,
+				Period:           plan.OptionalDuration{Value: defaultCheckPeriod},
+				Timeout:          plan.OptionalDuration{Value: 7 * time.Second, IsSet: true},
+				Threshold:        5,
+				SuccessThreshold: defaultCheckSuccessThreshold,
 				Exec: &plan.ExecCheck{
 					Command:    "sleep 2",
 					WorkingDir: "/root",
@@ -777,10 +800,11 @@ var planTests = []planTest{{
 			},
 		},
 		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks:   map[string]*plan.Webhook{},
 	},
 }, {
-	summary: "One of http, tcp, or exec must be present for check",
-	error:   `plan must specify one of "http", "tcp", or "exec" for check "chk1"`,
+	summary: "One check type must be present for check",
+	error:   `plan must specify one of "http", "tcp", "exec", "grpc", "composite", "disk", "file", "process", or "resources" for check "chk1"`,
 	input: []string{`
 		checks:
 			chk1:
@@ -804,6 +828,111 @@ var planTests = []planTest{{
 				override: replace
 				tcp: {}
 `},
+}, {
+	summary: "HTTP check URL must be http, https or http+unix",
+	error:   `plan check "chk1" has invalid url "ftp://localhost/": scheme must be http, https, or http\+unix, not "ftp"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				http:
+					url: ftp://localhost/
+`},
+}, {
+	summary: "HTTP check unix socket path must be absolute",
+	error:   `plan check "chk1" has invalid url "http\+unix://app.sock/health": socket path must be absolute, not "app.sock"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				http:
+					url: http+unix://app.sock/health
+`},
+}, {
+	summary: "Invalid HTTP check method",
+	error:   `plan check "chk1" has invalid method "get it"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				http:
+					url: http://localhost/
+					method: get it
+`},
+}, {
+	summary: "Invalid HTTP check expected status",
+	error:   `plan check "chk1" has invalid expected status 1000`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				http:
+					url: http://localhost/
+					expected-status: [200, 1000]
+`},
+}, {
+	summary: "Invalid HTTP check body-match",
+	error:   `plan check "chk1" has invalid body-match regexp: .*`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				http:
+					url: http://localhost/
+					body-match: "(foo"
+`},
+}, {
+	summary: "HTTP check client certificate needs key",
+	error:   `plan check "chk1" must set both "tls-cert" and "tls-key", or neither`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				http:
+					url: https://localhost/
+					tls-cert: /etc/cert.pem
+`},
+}, {
+	summary: "HTTP check TLS options need https URL",
+	error:   `plan check "chk1" cannot set TLS options for non-https url "http://localhost/"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				http:
+					url: http://localhost/
+					tls-skip-verify: true
+`},
+}, {
+	summary: "gRPC check requires address field",
+	error:   `plan must set "address" for grpc check "chk4"`,
+	input: []string{`
+		checks:
+			chk4:
+				override: replace
+				grpc: {}
+`},
+}, {
+	summary: "gRPC check address must include port",
+	error:   `plan check "chk4" address must be in "host:port" format, not "localhost"`,
+	input: []string{`
+		checks:
+			chk4:
+				override: replace
+				grpc:
+					address: localhost
+`},
+}, {
+	summary: "gRPC check cannot skip TLS verification without TLS",
+	error:   `plan check "chk4" cannot set "tls-skip-verify" without "tls"`,
+	input: []string{`
+		checks:
+			chk4:
+				override: replace
+				grpc:
+					address: localhost:50051
+					tls-skip-verify: true
+`},
 }, {
 	summary: "Exec check requires command field",
 	error:   `plan must set "command" for exec check "chk3"`,
@@ -834,6 +963,276 @@ var planTests = []planTest{{
 					command: foo
 					service-context: nosvc
 	`},
+}, {
+	summary: "Negative check success-threshold",
+	error:   `plan check "chk1" success-threshold must not be negative`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				success-threshold: -1
+				exec:
+					command: foo
+	`},
+}, {
+	summary: "Negative check initial-delay",
+	error:   `plan check "chk1" initial-delay must not be negative`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				initial-delay: -1s
+				exec:
+					command: foo
+	`},
+}, {
+	summary: "Negative check start-period",
+	error:   `plan check "chk1" start-period must not be negative`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				start-period: -1s
+				exec:
+					command: foo
+	`},
+}, {
+	summary: "Check action must have one kind",
+	error:   `plan check "chk1" on-failure action must specify one of "exec", "warn", "signal", or "shutdown"`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: foo
+		checks:
+			chk1:
+				override: replace
+				exec:
+					command: foo
+				on-failure:
+					- exec: foo
+					  warn: bar
+	`},
+}, {
+	summary: "Check action exec command must not be empty",
+	error:   `plan check "chk1" on-failure action has empty command`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				exec:
+					command: foo
+				on-failure:
+					- exec: "   "
+	`},
+}, {
+	summary: "Invalid check action signal",
+	error:   `plan check "chk1" on-failure action has invalid signal "SIGFOO"`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: foo
+		checks:
+			chk1:
+				override: replace
+				exec:
+					command: foo
+				on-failure:
+					- signal: SIGFOO
+					  service: svc1
+	`},
+}, {
+	summary: "Check action signal requires existing service",
+	error:   `plan check "chk1" on-recovery action specifies non-existent service "nosvc"`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: foo
+		checks:
+			chk1:
+				override: replace
+				exec:
+					command: foo
+				on-recovery:
+					- signal: SIGHUP
+					  service: nosvc
+	`},
+}, {
+	summary: "Invalid check action shutdown exit code",
+	error:   `plan check "chk1" on-failure action has invalid shutdown exit code 256`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: foo
+		checks:
+			chk1:
+				override: replace
+				exec:
+					command: foo
+				on-failure:
+					- shutdown: 256
+	`},
+}, {
+	summary: "Check cannot require itself",
+	error:   `plan check "chk1" cannot require itself`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				requires: [chk1]
+				exec:
+					command: foo
+	`},
+}, {
+	summary: "Check cannot require non-existent check",
+	error:   `plan check "chk1" requires non-existent check "nochk"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				requires: [nochk]
+				exec:
+					command: foo
+	`},
+}, {
+	summary: "Composite check requires checks field",
+	error:   `plan must set "checks" for composite check "chk1"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				composite: {}
+	`},
+}, {
+	summary: "Composite check cannot include non-existent check",
+	error:   `plan composite check "chk1" includes non-existent check "nochk"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				composite:
+					checks: [nochk]
+	`},
+}, {
+	summary: "Invalid composite check mode",
+	error:   `plan composite check "chk1" mode must be "all" or "any"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				composite:
+					checks: [chk2]
+					mode: some
+			chk2:
+				override: replace
+				exec:
+					command: foo
+	`},
+}, {
+	summary: "Checks cannot require each other in a loop",
+	error:   `checks in requires loop: chk1, chk2, chk3`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				requires: [chk2]
+				exec:
+					command: foo
+			chk2:
+				override: replace
+				composite:
+					checks: [chk3]
+			chk3:
+				override: replace
+				requires: [chk1]
+				exec:
+					command: foo
+	`},
+}, {
+	summary: "Disk check requires path",
+	error:   `plan must set "path" for disk check "chk1"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				disk:
+					min-free: 10%
+	`},
+}, {
+	summary: "Disk check requires a threshold",
+	error:   `plan must set "min-free" or "min-free-inodes" for disk check "chk1"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				disk:
+					path: /
+	`},
+}, {
+	summary: "Invalid disk check amount",
+	error:   `cannot parse layer "layer-0": invalid amount "10XB"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				disk:
+					path: /
+					min-free: 10XB
+	`},
+}, {
+	summary: "Disk check percentage must be at most 100",
+	error:   `cannot parse layer "layer-0": invalid amount "110%"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				disk:
+					path: /
+					min-free: 110%
+	`},
+}, {
+	summary: "File check requires path",
+	error:   `plan must set "path" for file check "chk1"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				file:
+					max-age: 1m
+	`},
+}, {
+	summary: "Process check requires name or pid-file",
+	error:   `plan must set one of "name" or "pid-file" for process check "chk1"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				process:
+					name: nginx
+					pid-file: /run/nginx.pid
+	`},
+}, {
+	summary: "Resources check requires a threshold",
+	error:   `plan must set "max-memory" or "max-load" for resources check "chk1"`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				resources: {}
+	`},
+}, {
+	summary: "Resources check max-load must be positive",
+	error:   `plan check "chk1" max-load must be positive`,
+	input: []string{`
+		checks:
+			chk1:
+				override: replace
+				resources:
+					max-load: 0
+	`},
 }, {
 	summary: "Simple layer with log targets",
 	input: []string{`
@@ -897,6 +1296,7 @@ var planTests = []planTest{{
 				Override: plan.MergeOverride,
 			},
 		},
+		Webhooks: map[string]*plan.Webhook{},
 	},
 }, {
 	summary: "Overriding log targets",
@@ -993,6 +1393,7 @@ var planTests = []planTest{{
 				Override: plan.MergeOverride,
 			},
 		},
+		Webhooks: map[string]*plan.Webhook{},
 	}, {
 		Label: "layer-1",
 		Order: 1,
@@ -1031,6 +1432,7 @@ var planTests = []planTest{{
 				Override: plan.MergeOverride,
 			},
 		},
+		Webhooks: map[string]*plan.Webhook{},
 	}},
 	result: &plan.Layer{
 		Services: map[string]*plan.Service{
@@ -1076,6 +1478,7 @@ var planTests = []planTest{{
 				Override: plan.MergeOverride,
 			},
 		},
+		Webhooks: map[string]*plan.Webhook{},
 	},
 }, {
 	summary: "Log target requires type field",
@@ -1116,6 +1519,115 @@ var planTests = []planTest{{
 				services: [nonexistent]
 				override: merge
 `},
+}, {
+	summary: "Overriding webhooks",
+	input: []string{`
+		webhooks:
+			hook1:
+				override: merge
+				url: http://localhost:8080/old
+				headers:
+					Authorization: Bearer xyz
+				events: [service-exit]
+			hook2:
+				override: merge
+				url: https://example.com/hook
+				attempts: 5
+				backoff-delay: 10s
+`, `
+		webhooks:
+			hook1:
+				override: merge
+				url: http://localhost:8080/new
+				headers:
+					X-Source: pebble
+				events: [check]
+			hook2:
+				override: replace
+				url: https://example.com/hook2
+`},
+	result: &plan.Layer{
+		Services:   map[string]*plan.Service{},
+		Checks:     map[string]*plan.Check{},
+		LogTargets: map[string]*plan.LogTarget{},
+		Webhooks: map[string]*plan.Webhook{
+			"hook1": {
+				Name:     "hook1",
+				Override: plan.MergeOverride,
+				URL:      "http://localhost:8080/new",
+				Headers: map[string]string{
+					"Authorization": "Bearer xyz",
+					"X-Source":      "pebble",
+				},
+				Events:       []plan.WebhookEvent{plan.WebhookServiceExit, plan.WebhookCheck},
+				Attempts:     3,
+				BackoffDelay: plan.OptionalDuration{Value: time.Second},
+			},
+			"hook2": {
+				Name:         "hook2",
+				Override:     plan.ReplaceOverride,
+				URL:          "https://example.com/hook2",
+				Attempts:     3,
+				BackoffDelay: plan.OptionalDuration{Value: time.Second},
+			},
+		},
+	},
+}, {
+	summary: "Webhook must have a URL",
+	error:   `plan must define "url" for webhook "hook1"`,
+	input: []string{`
+		webhooks:
+			hook1:
+				override: merge
+				events: [warning]
+`},
+}, {
+	summary: "Webhook URL must be http or https",
+	error:   `plan webhook "hook1" url must be an absolute "http" or "https" URL`,
+	input: []string{`
+		webhooks:
+			hook1:
+				override: merge
+				url: ftp://example.com/
+`},
+}, {
+	summary: "Webhook event must be valid",
+	error:   `plan webhook "hook1" event "foo" invalid, must be "service-exit", "check" or "warning"`,
+	input: []string{`
+		webhooks:
+			hook1:
+				override: merge
+				url: http://localhost/
+				events: [warning, foo]
+`},
+}, {
+	summary: "Webhook attempts must not be negative",
+	error:   `plan webhook "hook1" attempts must not be negative`,
+	input: []string{`
+		webhooks:
+			hook1:
+				override: merge
+				url: http://localhost/
+				attempts: -1
+`},
+}, {
+	summary: "Webhook backoff-delay must not be negative",
+	error:   `plan webhook "hook1" backoff-delay must not be negative`,
+	input: []string{`
+		webhooks:
+			hook1:
+				override: merge
+				url: http://localhost/
+				backoff-delay: -1s
+`},
+}, {
+	summary: "Webhook must define override",
+	error:   `layer "layer-0" must define "override" for webhook "hook1"`,
+	input: []string{`
+		webhooks:
+			hook1:
+				url: http://localhost/
+`},
 }, {
 	summary: `Service name can't start with "-"`,
 	error:   `cannot use service name "-svc1": starting with "-" not allowed`,
@@ -1125,6 +1637,87 @@ var planTests = []planTest{{
 				command: foo
 				override: merge
 `},
+}, {
+	summary: `Watch path must be absolute`,
+	error:   `plan service "svc1" watch path "etc/svc1.conf" must be absolute`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: cmd
+				watch: [etc/svc1.conf]
+	`},
+}, {
+	summary: `Invalid watch-action`,
+	error:   `plan service "svc1" watch-action "foo" invalid`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: cmd
+				watch: [/etc/svc1.conf]
+				watch-action: foo
+	`},
+}, {
+	summary: `Invalid service health level`,
+	error:   `plan service "svc1" health must be "alive" or "ready"`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: cmd
+				health: up
+	`},
+}, {
+	summary: `Service health with check of the same name`,
+	error:   `plan service "svc1" health conflicts with check of the same name`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: cmd
+				health: alive
+		checks:
+			svc1:
+				override: replace
+				tcp:
+					port: 8080
+	`},
+}, {
+	summary: `Watch-signal without watch-action signal`,
+	error:   `plan service "svc1" watch-signal requires watch-action "signal"`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: cmd
+				watch: [/etc/svc1.conf]
+				watch-action: reload
+				watch-signal: SIGUSR1
+	`},
+}, {
+	summary: `Watch-action signal without watch-signal`,
+	error:   `plan service "svc1" must set "watch-signal" for watch-action "signal"`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: cmd
+				watch: [/etc/svc1.conf]
+				watch-action: signal
+	`},
+}, {
+	summary: `Invalid watch-signal`,
+	error:   `plan service "svc1" watch-signal "SIGFOO" invalid`,
+	input: []string{`
+		services:
+			svc1:
+				override: replace
+				command: cmd
+				watch: [/etc/svc1.conf]
+				watch-action: signal
+				watch-signal: SIGFOO
+	`},
 }}
 
 func (s *S) TestParseLayer(c *C) {
@@ -1249,6 +1842,376 @@ services:
 	c.Assert(combined.Services["srv1"].Command, Equals, "foo --bar")
 }
 
+func (s *S) TestMergeWatch(c *C) {
+	layer1, err := plan.ParseLayer(1, "label1", []byte(`
+services:
+    srv1:
+        override: replace
+        command: cmd
+        watch: [/etc/srv1.conf]
+`))
+	c.Assert(err, IsNil)
+	layer2, err := plan.ParseLayer(2, "label2", []byte(`
+services:
+    srv1:
+        override: merge
+        watch: [/etc/srv1.d/extra.conf]
+        watch-action: signal
+        watch-signal: SIGUSR1
+        watch-debounce: 5s
+`))
+	c.Assert(err, IsNil)
+	combined, err := plan.CombineLayers(layer1, layer2)
+	c.Assert(err, IsNil)
+	service := combined.Services["srv1"]
+	c.Check(service.Watch, DeepEquals, []string{"/etc/srv1.conf", "/etc/srv1.d/extra.conf"})
+	c.Check(service.WatchAction, Equals, plan.WatchActionSignal)
+	c.Check(service.WatchSignal, Equals, "SIGUSR1")
+	c.Check(service.WatchDebounce, Equals, plan.OptionalDuration{Value: 5 * time.Second, IsSet: true})
+}
+
+func (s *S) TestMergeServiceHealth(c *C) {
+	layer1, err := plan.ParseLayer(1, "label1", []byte(`
+services:
+    srv1:
+        override: replace
+        command: cmd
+        health: alive
+    srv2:
+        override: replace
+        command: cmd
+        health: alive
+`))
+	c.Assert(err, IsNil)
+	layer2, err := plan.ParseLayer(2, "label2", []byte(`
+services:
+    srv1:
+        override: merge
+        health: ready
+    srv2:
+        override: merge
+        command: cmd2
+`))
+	c.Assert(err, IsNil)
+	combined, err := plan.CombineLayers(layer1, layer2)
+	c.Assert(err, IsNil)
+	c.Check(combined.Services["srv1"].Health, Equals, plan.ReadyLevel)
+	c.Check(combined.Services["srv2"].Health, Equals, plan.AliveLevel)
+}
+
+func (s *S) TestMergeGRPCCheck(c *C) {
+	layer1, err := plan.ParseLayer(1, "label1", []byte(`
+checks:
+    chk1:
+        override: replace
+        grpc:
+            address: localhost:50051
+            service: foo.Foo
+            metadata:
+                authorization: Bearer x
+`))
+	c.Assert(err, IsNil)
+	layer2, err := plan.ParseLayer(2, "label2", []byte(`
+checks:
+    chk1:
+        override: merge
+        grpc:
+            tls: true
+            tls-skip-verify: true
+            metadata:
+                x-request-source: pebble
+`))
+	c.Assert(err, IsNil)
+	combined, err := plan.CombineLayers(layer1, layer2)
+	c.Assert(err, IsNil)
+	check := combined.Checks["chk1"]
+	tls := true
+	c.Check(check.GRPC, DeepEquals, &plan.GRPCCheck{
+		Address:       "localhost:50051",
+		Service:       "foo.Foo",
+		TLS:           &tls,
+		TLSSkipVerify: &tls,
+		Metadata: map[string]string{
+			"authorization":    "Bearer x",
+			"x-request-source": "pebble",
+		},
+	})
+
+	// Check that copies are deep.
+	copied := check.Copy()
+	*copied.GRPC.TLS = false
+	copied.GRPC.Metadata["authorization"] = "changed"
+	c.Check(*check.GRPC.TLS, Equals, true)
+	c.Check(check.GRPC.Metadata["authorization"], Equals, "Bearer x")
+}
+
+func (s *S) TestMergeCheckStartup(c *C) {
+	layer1, err := plan.ParseLayer(1, "label1", []byte(`
+checks:
+    chk1:
+        override: replace
+        success-threshold: 2
+        initial-delay: 5s
+        exec:
+            command: foo
+`))
+	c.Assert(err, IsNil)
+	layer2, err := plan.ParseLayer(2, "label2", []byte(`
+checks:
+    chk1:
+        override: merge
+        initial-delay: 0s
+        start-period: 1m
+`))
+	c.Assert(err, IsNil)
+	combined, err := plan.CombineLayers(layer1, layer2)
+	c.Assert(err, IsNil)
+	check := combined.Checks["chk1"]
+	c.Check(check.SuccessThreshold, Equals, 2)
+	c.Check(check.InitialDelay, Equals, plan.OptionalDuration{Value: 0, IsSet: true})
+	c.Check(check.StartPeriod, Equals, plan.OptionalDuration{Value: time.Minute, IsSet: true})
+}
+
+func (s *S) TestMergeCheckActions(c *C) {
+	layer1, err := plan.ParseLayer(1, "label1", []byte(`
+services:
+    svc1:
+        override: replace
+        command: foo
+checks:
+    chk1:
+        override: replace
+        exec:
+            command: foo
+        on-failure:
+            - exec: /bin/page-oncall
+            - shutdown: 3
+        on-recovery:
+            - warn: chk1 recovered
+`))
+	c.Assert(err, IsNil)
+	layer2, err := plan.ParseLayer(2, "label2", []byte(`
+checks:
+    chk1:
+        override: merge
+        on-failure:
+            - signal: SIGHUP
+              service: svc1
+`))
+	c.Assert(err, IsNil)
+	combined, err := plan.CombineLayers(layer1, layer2)
+	c.Assert(err, IsNil)
+	check := combined.Checks["chk1"]
+	c.Check(check.OnFailure, DeepEquals, []*plan.CheckAction{{Signal: "SIGHUP", Service: "svc1"}})
+	c.Check(check.OnRecovery, DeepEquals, []*plan.CheckAction{{Warn: "chk1 recovered"}})
+
+	// Check that copies are deep.
+	exitCode := 3
+	check = layer1.Checks["chk1"]
+	copied := check.Copy()
+	c.Check(copied.OnFailure, DeepEquals, []*plan.CheckAction{{Exec: "/bin/page-oncall"}, {Shutdown: &exitCode}})
+	*copied.OnFailure[1].Shutdown = 4
+	copied.OnRecovery[0].Warn = "changed"
+	c.Check(*check.OnFailure[1].Shutdown, Equals, 3)
+	c.Check(check.OnRecovery[0].Warn, Equals, "chk1 recovered")
+}
+
+func (s *S) TestMergeCheckRequiresComposite(c *C) {
+	layer1, err := plan.ParseLayer(1, "label1", []byte(`
+checks:
+    db:
+        override: replace
+        tcp:
+            port: 5432
+    cache:
+        override: replace
+        tcp:
+            port: 6379
+    web:
+        override: replace
+        requires: [db]
+        http:
+            url: http://localhost:8080/
+    ready:
+        override: replace
+        level: ready
+        composite:
+            checks: [db]
+`))
+	c.Assert(err, IsNil)
+	layer2, err := plan.ParseLayer(2, "label2", []byte(`
+checks:
+    web:
+        override: merge
+        requires: [cache]
+    ready:
+        override: merge
+        composite:
+            checks: [cache]
+            mode: any
+`))
+	c.Assert(err, IsNil)
+	combined, err := plan.CombineLayers(layer1, layer2)
+	c.Assert(err, IsNil)
+	c.Check(combined.Checks["web"].Requires, DeepEquals, []string{"db", "cache"})
+	c.Check(combined.Checks["ready"].Composite, DeepEquals, &plan.CompositeCheck{
+		Checks: []string{"db", "cache"},
+		Mode:   plan.CompositeAny,
+	})
+
+	// Check that copies are deep.
+	check := combined.Checks["ready"]
+	copied := check.Copy()
+	copied.Composite.Checks[0] = "changed"
+	c.Check(check.Composite.Checks[0], Equals, "db")
+}
+
+func (s *S) TestSystemChecks(c *C) {
+	layer1, err := plan.ParseLayer(1, "label1", []byte(`
+checks:
+    disk:
+        override: replace
+        disk:
+            path: /var/lib/data
+            min-free: 1.5GiB
+    file:
+        override: replace
+        file:
+            path: /run/heartbeat
+    process:
+        override: replace
+        process:
+            name: nginx
+    resources:
+        override: replace
+        resources:
+            max-memory: 90%
+`))
+	c.Assert(err, IsNil)
+	layer2, err := plan.ParseLayer(2, "label2", []byte(`
+checks:
+    disk:
+        override: merge
+        disk:
+            min-free-inodes: "1000"
+    file:
+        override: merge
+        file:
+            max-age: 1m
+    resources:
+        override: merge
+        resources:
+            max-load: 4.5
+`))
+	c.Assert(err, IsNil)
+	combined, err := plan.CombineLayers(layer1, layer2)
+	c.Assert(err, IsNil)
+	c.Check(combined.Checks["disk"].Disk, DeepEquals, &plan.DiskCheck{
+		Path:          "/var/lib/data",
+		MinFree:       plan.OptionalAmount{Value: 1.5 * (1 << 30), IsSet: true},
+		MinFreeInodes: plan.OptionalAmount{Value: 1000, IsSet: true},
+	})
+	c.Check(combined.Checks["file"].File, DeepEquals, &plan.FileCheck{
+		Path:   "/run/heartbeat",
+		MaxAge: plan.OptionalDuration{Value: time.Minute, IsSet: true},
+	})
+	c.Check(combined.Checks["process"].Process, DeepEquals, &plan.ProcessCheck{Name: "nginx"})
+	c.Check(combined.Checks["resources"].Resources, DeepEquals, &plan.ResourcesCheck{
+		MaxMemory: plan.OptionalAmount{Value: 90, Percent: true, IsSet: true},
+		MaxLoad:   plan.OptionalFloat{Value: 4.5, IsSet: true},
+	})
+
+	// Amounts are written back out in bytes or as a percentage.
+	out, err := yaml.Marshal(combined.Checks["resources"].Resources)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, "max-memory: 90%\nmax-load: 4.5\n")
+	out, err = yaml.Marshal(layer1.Checks["disk"].Disk)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, "path: /var/lib/data\nmin-free: \"1610612736\"\n")
+}
+
+func (s *S) TestMergeHTTPCheck(c *C) {
+	layer1, err := plan.ParseLayer(1, "label1", []byte(`
+checks:
+    chk1:
+        override: replace
+        http:
+            url: https://localhost/health
+            method: POST
+            body: '{"ping": true}'
+            expected-status: [200, 401]
+            headers:
+                X-Foo: foo
+`))
+	c.Assert(err, IsNil)
+	layer2, err := plan.ParseLayer(2, "label2", []byte(`
+checks:
+    chk1:
+        override: merge
+        http:
+            expected-status: [204]
+            body-match: '"status": ?"ok"'
+            tls-ca: /etc/ca.pem
+            tls-cert: /etc/cert.pem
+            tls-key: /etc/key.pem
+            tls-skip-verify: false
+            headers:
+                X-Bar: bar
+`))
+	c.Assert(err, IsNil)
+	combined, err := plan.CombineLayers(layer1, layer2)
+	c.Assert(err, IsNil)
+	check := combined.Checks["chk1"]
+	skip := false
+	c.Check(check.HTTP, DeepEquals, &plan.HTTPCheck{
+		URL:            "https://localhost/health",
+		Method:         "POST",
+		Headers:        map[string]string{"X-Foo": "foo", "X-Bar": "bar"},
+		Body:           `{"ping": true}`,
+		ExpectedStatus: []int{204},
+		BodyMatch:      `"status": ?"ok"`,
+		TLSCA:          "/etc/ca.pem",
+		TLSCert:        "/etc/cert.pem",
+		TLSKey:         "/etc/key.pem",
+		TLSSkipVerify:  &skip,
+	})
+
+	// Check that copies are deep.
+	copied := check.Copy()
+	copied.HTTP.ExpectedStatus[0] = 500
+	*copied.HTTP.TLSSkipVerify = true
+	c.Check(check.HTTP.ExpectedStatus, DeepEquals, []int{204})
+	c.Check(*check.HTTP.TLSSkipVerify, Equals, false)
+}
+
+func (s *S) TestHTTPCheckParseURL(c *C) {
+	tests := []struct {
+		url        string
+		socket     string
+		requestURL string
+		error      string
+	}{
+		{url: "http://localhost:8080/health", requestURL: "http://localhost:8080/health"},
+		{url: "HTTPS://example.com/", requestURL: "HTTPS://example.com/"},
+		{url: "http+unix://%2Frun%2Fapp.sock/health?full=1", socket: "/run/app.sock", requestURL: "http://localhost/health?full=1"},
+		{url: "http+unix://%2Frun%2Fapp.sock", socket: "/run/app.sock", requestURL: "http://localhost"},
+		{url: "http+unix://%zz/", error: `invalid URL escape "%zz"`},
+		{url: "unix:///run/app.sock", error: `scheme must be http, https, or http\+unix, not "unix"`},
+	}
+	for _, test := range tests {
+		c.Logf("URL %q", test.url)
+		check := &plan.HTTPCheck{URL: test.url}
+		socket, requestURL, err := check.ParseURL()
+		if test.error != "" {
+			c.Check(err, ErrorMatches, test.error)
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Check(socket, Equals, test.socket)
+		c.Check(requestURL, Equals, test.requestURL)
+	}
+}
+
 func (s *S) TestReadDir(c *C) {
 	tempDir := c.MkDir()
 
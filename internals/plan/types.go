@@ -3,6 +3,7 @@ package plan
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -65,3 +66,74 @@ func (o *OptionalFloat) UnmarshalYAML(value *yaml.Node) error {
 	o.IsSet = true
 	return nil
 }
+
+// OptionalAmount is an amount that's either absolute (for example a number of
+// bytes, written as "500MB" or "2GiB") or a percentage ("10%").
+type OptionalAmount struct {
+	Value   float64
+	Percent bool
+	IsSet   bool
+}
+
+func (o OptionalAmount) IsZero() bool {
+	return !o.IsSet
+}
+
+func (o OptionalAmount) String() string {
+	if o.Percent {
+		return strconv.FormatFloat(o.Value, 'f', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(o.Value, 'f', -1, 64)
+}
+
+func (o OptionalAmount) MarshalYAML() (interface{}, error) {
+	if !o.IsSet {
+		return nil, nil
+	}
+	return o.String(), nil
+}
+
+// amountUnits are the suffixes allowed for absolute amounts.
+var amountUnits = []struct {
+	suffix string
+	factor float64
+}{
+	// Longest suffixes first, so that "KiB" isn't taken as "B".
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"TiB", 1 << 40},
+	{"KB", 1e3},
+	{"MB", 1e6},
+	{"GB", 1e9},
+	{"TB", 1e12},
+	{"B", 1},
+}
+
+func (o *OptionalAmount) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("amount must be a YAML string")
+	}
+	s := strings.TrimSpace(value.Value)
+	percent := strings.HasSuffix(s, "%")
+	factor := 1.0
+	if percent {
+		s = strings.TrimSuffix(s, "%")
+	} else {
+		for _, unit := range amountUnits {
+			if strings.HasSuffix(s, unit.suffix) {
+				s = strings.TrimSuffix(s, unit.suffix)
+				factor = unit.factor
+				break
+			}
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 || (percent && n > 100) {
+		return fmt.Errorf("invalid amount %q", value.Value)
+	}
+	o.Value = n * factor
+	o.Percent = percent
+	o.IsSet = true
+	return nil
+}
@@ -53,8 +53,15 @@ func Start() error {
 		return fmt.Errorf("child subreaping unavailable on this platform")
 	}
 
+	// Register for SIGCHLD before returning, so that the signal isn't missed
+	// for a child that's started (and exits) before the goroutine runs.
+	sigChld := make(chan os.Signal, 1)
+	signal.Notify(sigChld, unix.SIGCHLD)
+
 	started = true
-	reaperTomb.Go(reapChildren)
+	reaperTomb.Go(func() error {
+		return reapChildren(sigChld)
+	})
 	return nil
 }
 
@@ -95,10 +102,8 @@ func setChildSubreaper() (bool, error) {
 
 // reapChildren "reaps" (waits for) child processes whose parents didn't
 // wait() for them. It stops when the reaper tomb is killed.
-func reapChildren() error {
+func reapChildren(sigChld chan os.Signal) error {
 	logger.Debugf("Reaper started, waiting for SIGCHLD.")
-	sigChld := make(chan os.Signal, 1)
-	signal.Notify(sigChld, unix.SIGCHLD)
 	for {
 		select {
 		case <-sigChld:
@@ -0,0 +1,61 @@
+// Copyright (c) 2023 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License version 3 as
+// published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reaper_test
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/canonical/pebble/internals/reaper"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type reaperSuite struct{}
+
+var _ = Suite(&reaperSuite{})
+
+func (s *reaperSuite) TestWaitCommandRightAfterStart(c *C) {
+	// A command that exits straight after the reaper is started must still
+	// be reaped: the reaper must already be listening for SIGCHLD by the
+	// time Start returns.
+	for i := 0; i < 50; i++ {
+		err := reaper.Start()
+		c.Assert(err, IsNil)
+
+		cmd := exec.Command("true")
+		err = reaper.StartCommand(cmd)
+		c.Assert(err, IsNil)
+
+		done := make(chan int, 1)
+		go func() {
+			exitCode, err := reaper.WaitCommand(cmd)
+			c.Check(err, IsNil)
+			done <- exitCode
+		}()
+		select {
+		case exitCode := <-done:
+			c.Check(exitCode, Equals, 0)
+		case <-time.After(5 * time.Second):
+			c.Fatalf("timed out waiting for command to be reaped (iteration %d)", i)
+		}
+
+		err = reaper.Stop()
+		c.Assert(err, IsNil)
+	}
+}